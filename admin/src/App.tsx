@@ -2,6 +2,8 @@ import { BrowserRouter, Routes, Route, Navigate } from "react-router";
 import { AuthProvider } from "@/lib/auth";
 import { AppLayout } from "@/components/layout/AppLayout";
 import { LoginPage } from "@/pages/LoginPage";
+import { ForgotPasswordPage } from "@/pages/ForgotPasswordPage";
+import { SetPasswordPage } from "@/pages/SetPasswordPage";
 import { ContentListPage } from "@/pages/ContentListPage";
 import { ContentEditPage } from "@/pages/ContentEditPage";
 import { MediaPage } from "@/pages/MediaPage";
@@ -14,6 +16,9 @@ export function App() {
       <AuthProvider>
         <Routes>
           <Route path="/admin/login" element={<LoginPage />} />
+          <Route path="/admin/forgot-password" element={<ForgotPasswordPage />} />
+          <Route path="/admin/accept-invite" element={<SetPasswordPage mode="invite" />} />
+          <Route path="/admin/reset-password" element={<SetPasswordPage mode="reset" />} />
 
           <Route path="/admin" element={<AppLayout />}>
             <Route index element={<Navigate to="/admin/content" replace />} />
@@ -55,6 +55,8 @@ export function ContentForm({ fields, values, errors, onChange, disabled }: Cont
   return (
     <div className="space-y-6">
       {fields.map((field) => {
+        // Inverse relations are computed server-side and cannot be edited.
+        if (field.inverse_of) return null;
         const Component = FIELD_COMPONENT_MAP[field.type];
         if (!Component) return null;
 
@@ -1,9 +1,59 @@
 import { Input } from "@/components/ui/input";
 import { FieldWrapper } from "./FieldWrapper";
-import type { FieldComponentProps } from "@/lib/types";
+import type { FieldComponentProps, StringFormat } from "@/lib/types";
+
+/** Maps built-in string formats to the matching HTML input type. */
+const inputTypes: Partial<Record<StringFormat, string>> = {
+  email: "email",
+  url: "url",
+  phone: "tel",
+};
+
+const placeholders: Partial<Record<StringFormat, string>> = {
+  email: "name@example.com",
+  uuid: "00000000-0000-0000-0000-000000000000",
+  hex_color: "#000000",
+  phone: "+14155550123",
+};
 
 export function StringField({ name, label, value, onChange, error, field, disabled }: FieldComponentProps) {
   const strValue = typeof value === "string" ? value : "";
+  const format = field.format;
+
+  if (format === "hex_color") {
+    const pickerValue = /^#[0-9a-fA-F]{6}$/.test(strValue) ? strValue : "#000000";
+    return (
+      <FieldWrapper name={name} label={label} required={field.required} error={error}>
+        <div className="flex items-center gap-2">
+          <input
+            type="color"
+            aria-label={`${label} picker`}
+            value={pickerValue}
+            onChange={(e) => onChange(e.target.value)}
+            disabled={disabled}
+            className="h-9 w-12 cursor-pointer rounded-md border bg-transparent p-1"
+          />
+          <Input
+            id={name}
+            name={name}
+            value={strValue}
+            onChange={(e) => onChange(e.target.value)}
+            placeholder={placeholders.hex_color}
+            disabled={disabled}
+            aria-invalid={!!error}
+            aria-describedby={error ? `${name}-error` : undefined}
+          />
+        </div>
+      </FieldWrapper>
+    );
+  }
+
+  const placeholder =
+    format === "url"
+      ? `${(field.allowed_schemes ?? ["https"])[0]}://`
+      : format
+        ? placeholders[format]
+        : undefined;
 
   return (
     <FieldWrapper name={name} label={label} required={field.required} error={error}>
@@ -11,9 +61,11 @@ export function StringField({ name, label, value, onChange, error, field, disabl
         <Input
           id={name}
           name={name}
+          type={(format && inputTypes[format]) || "text"}
           value={strValue}
           onChange={(e) => onChange(e.target.value)}
           maxLength={field.max_length}
+          placeholder={placeholder}
           disabled={disabled}
           aria-invalid={!!error}
           aria-describedby={error ? `${name}-error` : undefined}
@@ -8,9 +8,17 @@ import {
 } from "react";
 import { api, setAccessToken, ApiRequestError } from "./api";
 
+type Permission = {
+  resource: string;
+  action: string;
+  own?: boolean;
+};
+
 type Admin = {
   id: string;
   email: string;
+  role: string;
+  permissions: Permission[];
 };
 
 type AuthState =
@@ -18,9 +26,23 @@ type AuthState =
   | { status: "authenticated"; admin: Admin }
   | { status: "unauthenticated" };
 
+/**
+ * Returned by login() when the admin must complete a second factor: enter a
+ * code, or enroll first if two-factor authentication is required.
+ */
+export type LoginChallenge = {
+  challengeToken: string;
+  setupRequired: boolean;
+};
+
+type LoginResponse =
+  | { access_token: string }
+  | { two_factor_required: true; setup_required: boolean; challenge_token: string };
+
 type AuthContextValue = {
   state: AuthState;
-  login: (email: string, password: string) => Promise<void>;
+  login: (email: string, password: string) => Promise<LoginChallenge | null>;
+  startSession: (accessToken: string) => Promise<void>;
   logout: () => Promise<void>;
 };
 
@@ -60,24 +82,43 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     };
   }, []);
 
-  const login = useCallback(async (email: string, password: string) => {
+  const startSession = useCallback(async (accessToken: string) => {
+    setAccessToken(accessToken);
     try {
-      const result = await api.post<{ access_token: string }>(
-        "/admin/api/auth/login",
-        { email, password },
-      );
-      setAccessToken(result.access_token);
       const admin = await api.get<Admin>("/admin/api/auth/me");
       setState({ status: "authenticated", admin });
     } catch (err) {
       setAccessToken(null);
-      if (err instanceof ApiRequestError) {
-        throw err;
-      }
-      throw new Error("Login failed. Please try again.");
+      throw err;
     }
   }, []);
 
+  const login = useCallback(
+    async (email: string, password: string) => {
+      try {
+        const result = await api.post<LoginResponse>(
+          "/admin/api/auth/login",
+          { email, password },
+        );
+        if ("two_factor_required" in result) {
+          return {
+            challengeToken: result.challenge_token,
+            setupRequired: result.setup_required,
+          };
+        }
+        await startSession(result.access_token);
+        return null;
+      } catch (err) {
+        setAccessToken(null);
+        if (err instanceof ApiRequestError) {
+          throw err;
+        }
+        throw new Error("Login failed. Please try again.");
+      }
+    },
+    [startSession],
+  );
+
   const logout = useCallback(async () => {
     try {
       await api.post("/admin/api/auth/logout");
@@ -90,7 +131,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   }, []);
 
   return (
-    <AuthContext.Provider value={{ state, login, logout }}>
+    <AuthContext.Provider value={{ state, login, startSession, logout }}>
       {children}
     </AuthContext.Provider>
   );
@@ -20,6 +20,8 @@ export type FieldType =
 
 export type RelationType = "one" | "many";
 
+export type StringFormat = "email" | "url" | "uuid" | "hex_color" | "phone";
+
 export type FieldDefinition = {
   name: string;
   type: FieldType;
@@ -31,9 +33,15 @@ export type FieldDefinition = {
   min?: number;
   max?: number;
   regex?: string;
+  /** Built-in string format; selects the input widget for string fields. */
+  format?: StringFormat;
+  /** Schemes accepted by url-formatted fields. */
+  allowed_schemes?: string[];
   values?: string[];
   relates_to?: string;
   relation_type?: RelationType;
+  /** Set on virtual, read-only inverse relations; names the forward field. */
+  inverse_of?: string;
   media_type?: string;
 };
 
@@ -43,6 +51,14 @@ export type ContentTypeSchema = {
   public_read: boolean;
   entry_count: number;
   fields: FieldDefinition[];
+  /** Editorial workflow; when present entries are published via transitions. */
+  workflow?: WorkflowDefinition;
+};
+
+export type WorkflowDefinition = {
+  initial: string;
+  stages: { name: string; published: boolean }[];
+  transitions: { from: string; to: string; roles: string[] }[];
 };
 
 // --- Content Entries ---
@@ -110,6 +126,7 @@ export type AuditLogEntry = {
   id: string;
   action: string;
   actor_id: string;
+  actor_email?: string;
   resource: string;
   resource_id: string;
   payload: Record<string, unknown> | null;
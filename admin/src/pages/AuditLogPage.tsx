@@ -287,7 +287,7 @@ function AuditLogRow({
           </Badge>
         </TableCell>
         <TableCell className="max-w-[200px] truncate font-mono text-xs">
-          {entry.actor_id || "--"}
+          {entry.actor_email || entry.actor_id || "--"}
         </TableCell>
         <TableCell>{entry.resource || "--"}</TableCell>
         <TableCell className="max-w-[200px] truncate font-mono text-xs">
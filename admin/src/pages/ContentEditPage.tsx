@@ -120,6 +120,7 @@ export function ContentEditPage() {
       // only strip truly unset optional fields.
       const payload: Record<string, unknown> = {};
       for (const field of schema.fields) {
+        if (field.inverse_of) continue;
         const val = values[field.name];
         if (field.required || (val !== null && val !== undefined && val !== "")) {
           payload[field.name] = val;
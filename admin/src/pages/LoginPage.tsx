@@ -1,7 +1,7 @@
-import { useState, type FormEvent } from "react";
-import { Navigate } from "react-router";
-import { useAuth } from "@/lib/auth";
-import { ApiRequestError } from "@/lib/api";
+import { useEffect, useState, type FormEvent } from "react";
+import { Link, Navigate, useSearchParams } from "react-router";
+import { useAuth, type LoginChallenge } from "@/lib/auth";
+import { api, ApiRequestError } from "@/lib/api";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
@@ -14,24 +14,85 @@ import {
 } from "@/components/ui/card";
 import { Loader2 } from "lucide-react";
 
+type Enrollment = {
+  secret: string;
+  uri: string;
+};
+
+type SetupResult = {
+  access_token: string;
+  recovery_codes: string[];
+};
+
+const ssoErrors: Record<string, string> = {
+  denied: "Sign-in was cancelled at your identity provider.",
+  state: "The single sign-on attempt expired. Please try again.",
+  provider: "Your identity provider could not be reached or sent an invalid response.",
+  email_unverified: "Your identity provider has not verified your email address.",
+  domain: "Your email domain is not allowed to sign in.",
+  no_role: "Your account has not been granted access to Mithril CMS.",
+  disabled: "Your account is disabled.",
+  two_factor_link:
+    "Your account uses two-factor authentication and cannot be linked to single sign-on.",
+};
+
+// ssoChallenge reads the challenge token single sign-on passes in the URL
+// fragment when a second factor is required, and clears the fragment.
+function ssoChallenge(): LoginChallenge | null {
+  const params = new URLSearchParams(window.location.hash.slice(1));
+  const challengeToken = params.get("challenge_token");
+  if (!challengeToken) return null;
+  window.history.replaceState(null, "", window.location.pathname + window.location.search);
+  return { challengeToken, setupRequired: params.get("setup_required") === "true" };
+}
+
 export function LoginPage() {
-  const { state, login } = useAuth();
+  const { state, login, startSession } = useAuth();
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
-  const [error, setError] = useState<string | null>(null);
+  const [code, setCode] = useState("");
+  const [challenge, setChallenge] = useState<LoginChallenge | null>(null);
+  const [enrollment, setEnrollment] = useState<Enrollment | null>(null);
+  const [setupResult, setSetupResult] = useState<SetupResult | null>(null);
+  const [searchParams] = useSearchParams();
+  const ssoError = searchParams.get("sso_error");
+  const [error, setError] = useState<string | null>(
+    ssoError ? (ssoErrors[ssoError] ?? "Single sign-on failed.") : null,
+  );
   const [submitting, setSubmitting] = useState(false);
+  const [ssoEnabled, setSsoEnabled] = useState(false);
+
+  useEffect(() => {
+    const sso = ssoChallenge();
+    if (!sso) return;
+    run(async () => {
+      if (sso.setupRequired) {
+        const started = await api.post<Enrollment>("/admin/api/auth/2fa/setup", {
+          challenge_token: sso.challengeToken,
+        });
+        setEnrollment(started);
+      }
+      setChallenge(sso);
+    });
+  }, []);
+
+  useEffect(() => {
+    api
+      .get<{ enabled: boolean }>("/admin/api/auth/oidc")
+      .then((res) => setSsoEnabled(res.enabled))
+      .catch(() => setSsoEnabled(false));
+  }, []);
 
   if (state.status === "authenticated") {
     return <Navigate to="/admin/content" replace />;
   }
 
-  async function handleSubmit(e: FormEvent) {
-    e.preventDefault();
+  async function run(action: () => Promise<void>) {
     setError(null);
     setSubmitting(true);
 
     try {
-      await login(email, password);
+      await action();
     } catch (err) {
       if (err instanceof ApiRequestError) {
         setError(err.message);
@@ -45,57 +106,188 @@ export function LoginPage() {
     }
   }
 
+  function handleSubmit(e: FormEvent) {
+    e.preventDefault();
+    run(async () => {
+      const result = await login(email, password);
+      if (result?.setupRequired) {
+        const started = await api.post<Enrollment>("/admin/api/auth/2fa/setup", {
+          challenge_token: result.challengeToken,
+        });
+        setEnrollment(started);
+      }
+      setChallenge(result);
+    });
+  }
+
+  function handleCode(e: FormEvent) {
+    e.preventDefault();
+    if (!challenge) return;
+    run(async () => {
+      if (challenge.setupRequired) {
+        const result = await api.post<SetupResult>("/admin/api/auth/2fa/setup/confirm", {
+          challenge_token: challenge.challengeToken,
+          code,
+        });
+        setSetupResult(result);
+        return;
+      }
+      const result = await api.post<{ access_token: string }>("/admin/api/auth/2fa/verify", {
+        challenge_token: challenge.challengeToken,
+        code: code.trim(),
+      });
+      await startSession(result.access_token);
+    });
+  }
+
+  function restart() {
+    setChallenge(null);
+    setEnrollment(null);
+    setCode("");
+    setPassword("");
+    setError(null);
+  }
+
+  const errorAlert = error && (
+    <div
+      role="alert"
+      className="rounded-md bg-destructive/10 px-3 py-2 text-sm text-destructive"
+    >
+      {error}
+    </div>
+  );
+
+  let description = "Sign in to your admin account";
+  let body;
+  if (setupResult) {
+    description = "Save your recovery codes";
+    body = (
+      <div className="space-y-4">
+        {errorAlert}
+        <p className="text-sm text-muted-foreground">
+          Each code signs you in once if you lose your authenticator. They are not shown
+          again.
+        </p>
+        <ul className="grid grid-cols-2 gap-2 rounded-md bg-muted p-3 font-mono text-sm">
+          {setupResult.recovery_codes.map((c) => (
+            <li key={c}>{c}</li>
+          ))}
+        </ul>
+        <Button
+          className="w-full"
+          disabled={submitting}
+          onClick={() => run(() => startSession(setupResult.access_token))}
+        >
+          {submitting && <Loader2 className="h-4 w-4 animate-spin" />}
+          Continue
+        </Button>
+      </div>
+    );
+  } else if (challenge) {
+    description = challenge.setupRequired
+      ? "Two-factor authentication is required"
+      : "Enter your two-factor code";
+    body = (
+      <form onSubmit={handleCode} className="space-y-4">
+        {errorAlert}
+
+        {enrollment && (
+          <div className="space-y-2 text-sm">
+            <p className="text-muted-foreground">
+              Add this account to your authenticator app by opening the link on your
+              phone or entering the key manually, then enter the code it shows.
+            </p>
+            <a href={enrollment.uri} className="block break-all text-primary underline">
+              {enrollment.uri}
+            </a>
+            <p className="break-all rounded-md bg-muted p-2 font-mono">{enrollment.secret}</p>
+          </div>
+        )}
+
+        <div className="space-y-2">
+          <Label htmlFor="code">
+            {challenge.setupRequired ? "Authenticator code" : "Authenticator or recovery code"}
+          </Label>
+          <Input
+            id="code"
+            autoComplete="one-time-code"
+            autoFocus
+            required
+            value={code}
+            onChange={(e) => setCode(e.target.value)}
+            disabled={submitting}
+          />
+        </div>
+
+        <Button type="submit" className="w-full" disabled={submitting}>
+          {submitting && <Loader2 className="h-4 w-4 animate-spin" />}
+          Verify
+        </Button>
+        <Button type="button" variant="ghost" className="w-full" onClick={restart}>
+          Back
+        </Button>
+      </form>
+    );
+  } else {
+    body = (
+      <>
+        <form onSubmit={handleSubmit} className="space-y-4">
+          {errorAlert}
+
+          <div className="space-y-2">
+            <Label htmlFor="email">Email</Label>
+            <Input
+              id="email"
+              type="email"
+              placeholder="admin@example.com"
+              autoComplete="email"
+              required
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              disabled={submitting}
+            />
+          </div>
+
+          <div className="space-y-2">
+            <Label htmlFor="password">Password</Label>
+            <Input
+              id="password"
+              type="password"
+              autoComplete="current-password"
+              required
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              disabled={submitting}
+            />
+          </div>
+
+          <Button type="submit" className="w-full" disabled={submitting}>
+            {submitting && <Loader2 className="h-4 w-4 animate-spin" />}
+            Sign in
+          </Button>
+        </form>
+        {ssoEnabled && (
+          <Button asChild variant="outline" className="mt-4 w-full">
+            <a href="/admin/api/auth/oidc/login">Sign in with single sign-on</a>
+          </Button>
+        )}
+        <p className="mt-4 text-center text-sm">
+          <Link to="/admin/forgot-password" className="text-muted-foreground hover:underline">
+            Forgot your password?
+          </Link>
+        </p>
+      </>
+    );
+  }
+
   return (
     <div className="flex min-h-screen items-center justify-center px-4">
       <Card className="w-full max-w-sm">
         <CardHeader className="text-center">
           <CardTitle className="text-2xl">Mithril CMS</CardTitle>
-          <CardDescription>Sign in to your admin account</CardDescription>
+          <CardDescription>{description}</CardDescription>
         </CardHeader>
-        <CardContent>
-          <form onSubmit={handleSubmit} className="space-y-4">
-            {error && (
-              <div
-                role="alert"
-                className="rounded-md bg-destructive/10 px-3 py-2 text-sm text-destructive"
-              >
-                {error}
-              </div>
-            )}
-
-            <div className="space-y-2">
-              <Label htmlFor="email">Email</Label>
-              <Input
-                id="email"
-                type="email"
-                placeholder="admin@example.com"
-                autoComplete="email"
-                required
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                disabled={submitting}
-              />
-            </div>
-
-            <div className="space-y-2">
-              <Label htmlFor="password">Password</Label>
-              <Input
-                id="password"
-                type="password"
-                autoComplete="current-password"
-                required
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                disabled={submitting}
-              />
-            </div>
-
-            <Button type="submit" className="w-full" disabled={submitting}>
-              {submitting && <Loader2 className="h-4 w-4 animate-spin" />}
-              Sign in
-            </Button>
-          </form>
-        </CardContent>
+        <CardContent>{body}</CardContent>
       </Card>
     </div>
   );
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/auth"
+	"github.com/GyroZepelix/mithril-cms/internal/database"
+)
+
+// adminCLI holds what the admin commands share. Their actions are recorded
+// in the audit log without an actor and with "source": "cli".
+type adminCLI struct {
+	db      *database.DB
+	service *auth.Service
+	audit   *audit.Repository
+}
+
+// initAdminCLI connects to the database for an admin command. Logs are
+// written to stderr, so the output of the command can be used in scripts.
+func initAdminCLI() *adminCLI {
+	cfg, db := initBase(os.Stderr)
+	return &adminCLI{
+		db:      db,
+		service: auth.NewService(auth.NewRepository(db), cfg.JWTSecret),
+		audit:   audit.NewRepository(db),
+	}
+}
+
+// logAudit records an admin command in the audit log. Failures are logged,
+// as the action itself has succeeded.
+func (c *adminCLI) logAudit(ctx context.Context, action string, admin *auth.Admin, payload map[string]any) {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	payload["source"] = "cli"
+	payload["email"] = admin.Email
+
+	err := c.audit.Insert(ctx, audit.Event{
+		Action:     action,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    payload,
+	})
+	if err != nil {
+		slog.Error("failed to record audit event", "action", action, "error", err)
+	}
+}
+
+// parseAdminArgs parses the flags of an admin command, which may come before
+// or after its positional arguments, and checks the number of positional
+// arguments. On errors it prints the usage and exits.
+func parseAdminArgs(fs *flag.FlagSet, args []string, positional ...string) []string {
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: mithril admin %s", fs.Name())
+		fs.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(os.Stderr, " [--%s]", f.Name)
+		})
+		for _, p := range positional {
+			fmt.Fprintf(os.Stderr, " <%s>", p)
+		}
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+
+	var values []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			os.Exit(2)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		values = append(values, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(values) != len(positional) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return values
+}
+
+// findAdmin returns the admin with the given ID or email, or exits.
+func (c *adminCLI) findAdmin(ctx context.Context, idOrEmail string) *auth.Admin {
+	admin, err := c.service.FindAdmin(ctx, idOrEmail)
+	if err != nil {
+		if errors.Is(err, auth.ErrAdminNotFound) {
+			fmt.Fprintf(os.Stderr, "No admin %s.\n", idOrEmail)
+		} else {
+			slog.Error("failed to look up admin", "error", err)
+		}
+		os.Exit(1)
+	}
+	return admin
+}
+
+// exitAdminError reports an error of an admin command and exits.
+func exitAdminError(msg string, err error) {
+	var validation *auth.AdminValidationError
+	if errors.As(err, &validation) {
+		for _, f := range validation.Fields {
+			fmt.Fprintf(os.Stderr, "Invalid %s: %s.\n", f.Field, f.Message)
+		}
+		os.Exit(1)
+	}
+	if errors.Is(err, auth.ErrEmailTaken) || errors.Is(err, auth.ErrUnknownRole) || errors.Is(err, auth.ErrLastSuperuser) {
+		fmt.Fprintf(os.Stderr, "%s: %v.\n", msg, err)
+		os.Exit(1)
+	}
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// runAdminCreate creates an admin with a password read from a prompt or stdin.
+func runAdminCreate(args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	role := fs.String("role", auth.SuperuserRole, "role of the admin")
+	email := parseAdminArgs(fs, args, "email")[0]
+
+	password, err := readPassword("Password")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v.\n", err)
+		os.Exit(1)
+	}
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin, err := c.service.CreateAdmin(ctx, auth.CreateAdminInput{Email: email, Password: password, Role: *role})
+	if err != nil {
+		exitAdminError("failed to create admin", err)
+	}
+	c.logAudit(ctx, "admin.create", admin, map[string]any{"role": admin.Role})
+	fmt.Printf("Created admin %s with role %s (id %s).\n", admin.Email, admin.Role, admin.ID)
+}
+
+// runAdminList prints all admins as a table, or as JSON for scripts.
+func runAdminList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the admins as JSON")
+	parseAdminArgs(fs, args)
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admins, err := c.service.ListAdmins(ctx)
+	if err != nil {
+		slog.Error("failed to list admins", "error", err)
+		os.Exit(1)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(admins); err != nil {
+			slog.Error("failed to encode admins", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printAdmins(os.Stdout, admins)
+}
+
+// printAdmins writes admins as a table.
+func printAdmins(out io.Writer, admins []auth.Admin) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tROLE\tSTATUS\t2FA\tCREATED")
+	for _, a := range admins {
+		status := "active"
+		if a.DisabledAt != nil {
+			status = "disabled"
+		}
+		twoFactor := "off"
+		if a.TOTPEnabledAt != nil {
+			twoFactor = "on"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.ID, a.Email, a.Role, status, twoFactor, a.CreatedAt.Format(time.DateOnly))
+	}
+	w.Flush()
+}
+
+// runAdminResetPassword sets an admin's password, read from a prompt or
+// stdin, and signs them out everywhere.
+func runAdminResetPassword(args []string) {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	idOrEmail := parseAdminArgs(fs, args, "id|email")[0]
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin := c.findAdmin(ctx, idOrEmail)
+	password, err := readPassword("New password")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v.\n", err)
+		os.Exit(1)
+	}
+	if err := c.service.SetPassword(ctx, admin.ID, password); err != nil {
+		exitAdminError("failed to reset password", err)
+	}
+	c.logAudit(ctx, "admin.password.reset", admin, nil)
+	fmt.Printf("Reset the password of %s and signed them out everywhere.\n", admin.Email)
+}
+
+// runAdminDisable disables an admin and signs them out everywhere.
+func runAdminDisable(args []string) {
+	fs := flag.NewFlagSet("disable", flag.ExitOnError)
+	idOrEmail := parseAdminArgs(fs, args, "id|email")[0]
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin := c.findAdmin(ctx, idOrEmail)
+	if _, err := c.service.DisableAdmin(ctx, admin.ID, nil); err != nil {
+		exitAdminError("failed to disable admin", err)
+	}
+	c.logAudit(ctx, "admin.disable", admin, nil)
+	fmt.Printf("Disabled %s and signed them out everywhere.\n", admin.Email)
+}
+
+// runAdminEnable re-enables a disabled admin.
+func runAdminEnable(args []string) {
+	fs := flag.NewFlagSet("enable", flag.ExitOnError)
+	idOrEmail := parseAdminArgs(fs, args, "id|email")[0]
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin := c.findAdmin(ctx, idOrEmail)
+	if _, err := c.service.EnableAdmin(ctx, admin.ID, nil); err != nil {
+		exitAdminError("failed to enable admin", err)
+	}
+	c.logAudit(ctx, "admin.enable", admin, nil)
+	fmt.Printf("Enabled %s.\n", admin.Email)
+}
+
+// runAdminRevokeSessions signs an admin out everywhere.
+func runAdminRevokeSessions(args []string) {
+	fs := flag.NewFlagSet("revoke-sessions", flag.ExitOnError)
+	idOrEmail := parseAdminArgs(fs, args, "id|email")[0]
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin := c.findAdmin(ctx, idOrEmail)
+	if err := c.service.RevokeAllSessions(ctx, admin.ID); err != nil {
+		exitAdminError("failed to revoke sessions", err)
+	}
+	c.logAudit(ctx, "admin.session.revoke_all", admin, nil)
+	fmt.Printf("Signed %s out everywhere.\n", admin.Email)
+}
+
+// runAdminUnlock lifts the lock and delays on logins for an email or from an
+// IP address after failed attempts.
+func runAdminUnlock(args []string) {
+	fs := flag.NewFlagSet("unlock", flag.ExitOnError)
+	emailOrIP := parseAdminArgs(fs, args, "email|ip")[0]
+
+	c := initAdminCLI()
+	defer c.db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cleared, err := c.service.UnlockLogin(ctx, emailOrIP)
+	if err != nil {
+		slog.Error("failed to unlock login", "error", err)
+		os.Exit(1)
+	}
+	if !cleared {
+		fmt.Printf("No failed logins recorded for %s.\n", emailOrIP)
+		return
+	}
+	fmt.Printf("Unlocked logins for %s.\n", emailOrIP)
+}
+
+// stdin is shared by all password reads, so buffered input is not lost
+// between them.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassword reads a new password. If stdin is a terminal, it is prompted
+// for twice without echo; otherwise the first line of stdin is used, for
+// scripts.
+func readPassword(prompt string) (string, error) {
+	if !isTerminal(os.Stdin) {
+		return readLine(stdin)
+	}
+
+	password, err := promptPassword(prompt + ": ")
+	if err != nil {
+		return "", err
+	}
+	confirm, err := promptPassword("Repeat " + strings.ToLower(prompt) + ": ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirm {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+// promptPassword prompts for a line on the terminal, hiding the input where
+// stty is available.
+func promptPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if stty("-echo") == nil {
+		defer func() {
+			stty("echo") //nolint:errcheck // best effort; input was hidden
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	return readLine(stdin)
+}
+
+// readLine reads a line without its line ending. A last line without one is
+// accepted.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stty changes the settings of the terminal on stdin.
+func stty(setting string) error {
+	cmd := exec.Command("stty", setting)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/auth"
+)
+
+func TestParseAdminArgs_Interleaved(t *testing.T) {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	role := fs.String("role", "", "")
+	got := parseAdminArgs(fs, []string{"jane@example.com", "--role", "editor"}, "email")
+	if len(got) != 1 || got[0] != "jane@example.com" || *role != "editor" {
+		t.Errorf("parseAdminArgs() = %v, role %q", got, *role)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("first secret\r\nsecond\nlast"))
+	for _, want := range []string{"first secret", "second", "last"} {
+		if got, err := readLine(r); err != nil || got != want {
+			t.Errorf("readLine() = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := readLine(r); err == nil {
+		t.Error("readLine() at EOF succeeded")
+	}
+}
+
+func TestPrintAdmins(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var out bytes.Buffer
+	printAdmins(&out, []auth.Admin{
+		{ID: "id-1", Email: "jane@example.com", Role: "admin", CreatedAt: created, TOTPEnabledAt: &created},
+		{ID: "id-2", Email: "joe@example.com", Role: "editor", CreatedAt: created, DisabledAt: &created},
+	})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printAdmins() wrote %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	if f := strings.Fields(lines[1]); strings.Join(f, " ") != "id-1 jane@example.com admin active on 2026-01-02" {
+		t.Errorf("row 1 = %q", lines[1])
+	}
+	if f := strings.Fields(lines[2]); strings.Join(f, " ") != "id-2 joe@example.com editor disabled off 2026-01-02" {
+		t.Errorf("row 2 = %q", lines[2])
+	}
+}
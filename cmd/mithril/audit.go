@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/config"
+)
+
+// setupAuditCheckpoints enables the audit service's signed checkpoints if
+// MITHRIL_AUDIT_CHECKPOINT_FILE is set, or exits.
+func setupAuditCheckpoints(cfg *config.Config, auditService *audit.Service) {
+	if cfg.AuditCheckpointFile == "" {
+		return
+	}
+	if cfg.JWTSecret == "" {
+		slog.Error("MITHRIL_JWT_SECRET is required to sign audit checkpoints")
+		os.Exit(1)
+	}
+	if err := auditService.SetCheckpoints(cfg.AuditCheckpointFile, cfg.AuditCheckpointInterval, cfg.JWTSecret); err != nil {
+		slog.Error("failed to set up audit checkpoints", "error", err)
+		os.Exit(1)
+	}
+}
+
+// runAuditVerify walks the audit log's hash chain, checking it against the
+// checkpoints if they are enabled, and reports the first broken link. It
+// exits with status 2 if the chain is broken, and 1 on errors.
+func runAuditVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mithril audit verify [--json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, db := initBase(os.Stderr)
+	defer db.Close()
+	auditService := audit.NewService(audit.NewRepository(db))
+	setupAuditCheckpoints(cfg, auditService)
+
+	// The whole chain is read, so there is no timeout.
+	result, err := auditService.Verify(context.Background())
+	if err != nil {
+		slog.Error("failed to verify audit log", "error", err)
+		os.Exit(1)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			slog.Error("failed to encode result", "error", err)
+			os.Exit(1)
+		}
+	} else {
+		printVerifyResult(os.Stdout, result)
+	}
+	if !result.OK {
+		db.Close()
+		os.Exit(2)
+	}
+}
+
+// printVerifyResult writes the result of verifying the audit log.
+func printVerifyResult(out io.Writer, result audit.VerifyResult) {
+	if result.LastSeq > 0 {
+		fmt.Fprintf(out, "Verified %d chained entries, up to entry %d with hash %s.\n",
+			result.Verified, result.LastSeq, result.LastHash)
+	} else {
+		fmt.Fprintln(out, "Verified 0 chained entries.")
+	}
+	if result.Checkpoints > 0 {
+		fmt.Fprintf(out, "Checked against %d checkpoints.\n", result.Checkpoints)
+	}
+	if result.Unchained > 0 {
+		fmt.Fprintf(out, "%d entries are not part of the chain and were not verified.\n", result.Unchained)
+	}
+
+	if b := result.Break; b != nil {
+		switch {
+		case b.ID != "" && b.Seq > 0:
+			fmt.Fprintf(out, "BROKEN at entry %d (%s): %s.\n", b.Seq, b.ID, b.Reason)
+		case b.ID != "":
+			fmt.Fprintf(out, "BROKEN at entry %s: %s.\n", b.ID, b.Reason)
+		default:
+			fmt.Fprintf(out, "BROKEN at entry %d: %s.\n", b.Seq, b.Reason)
+		}
+		return
+	}
+	fmt.Fprintln(out, "OK: no tampering detected.")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+)
+
+func TestPrintVerifyResult(t *testing.T) {
+	var out bytes.Buffer
+	printVerifyResult(&out, audit.VerifyResult{OK: true, Verified: 3, LastSeq: 3, LastHash: "ab12", Checkpoints: 1})
+	if got := out.String(); !strings.Contains(got, "Verified 3 chained entries, up to entry 3 with hash ab12.") ||
+		!strings.Contains(got, "OK: no tampering detected.") {
+		t.Errorf("printVerifyResult() = %q", got)
+	}
+
+	out.Reset()
+	printVerifyResult(&out, audit.VerifyResult{
+		Verified: 1, LastSeq: 1, LastHash: "ab12", Unchained: 4,
+		Break: &audit.ChainBreak{Seq: 2, ID: "id-2", Reason: "hash does not match the checkpoint"},
+	})
+	got := out.String()
+	if !strings.Contains(got, "4 entries are not part of the chain") {
+		t.Errorf("printVerifyResult() did not report unchained entries: %q", got)
+	}
+	if !strings.Contains(got, "BROKEN at entry 2 (id-2): hash does not match the checkpoint.") {
+		t.Errorf("printVerifyResult() did not report the break: %q", got)
+	}
+}
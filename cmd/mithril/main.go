@@ -7,11 +7,16 @@
 //	mithril schema diff  — load schemas, diff against DB, print changes, exit
 //	mithril schema apply — apply safe schema changes, exit
 //	mithril schema apply --force — apply ALL schema changes including breaking, exit
+//	mithril admin <command> — manage admin accounts, exit (see printUsage)
+//	mithril auth rotate-keys — create a new access token signing key, exit
+//	mithril audit verify — verify the audit log's hash chain, exit
 package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -21,11 +26,14 @@ import (
 	"github.com/GyroZepelix/mithril-cms/admin"
 	"github.com/GyroZepelix/mithril-cms/internal/audit"
 	"github.com/GyroZepelix/mithril-cms/internal/auth"
+	"github.com/GyroZepelix/mithril-cms/internal/comments"
 	"github.com/GyroZepelix/mithril-cms/internal/config"
 	"github.com/GyroZepelix/mithril-cms/internal/content"
 	"github.com/GyroZepelix/mithril-cms/internal/contenttypes"
 	"github.com/GyroZepelix/mithril-cms/internal/database"
+	"github.com/GyroZepelix/mithril-cms/internal/mail"
 	"github.com/GyroZepelix/mithril-cms/internal/media"
+	"github.com/GyroZepelix/mithril-cms/internal/oidc"
 	"github.com/GyroZepelix/mithril-cms/internal/schema"
 	"github.com/GyroZepelix/mithril-cms/internal/schemaapi"
 	"github.com/GyroZepelix/mithril-cms/internal/server"
@@ -44,6 +52,24 @@ func main() {
 		runSchemaApply(false)
 	case cmdSchemaApplyForce:
 		runSchemaApply(true)
+	case cmdAdminCreate:
+		runAdminCreate(os.Args[3:])
+	case cmdAdminList:
+		runAdminList(os.Args[3:])
+	case cmdAdminResetPassword:
+		runAdminResetPassword(os.Args[3:])
+	case cmdAdminDisable:
+		runAdminDisable(os.Args[3:])
+	case cmdAdminEnable:
+		runAdminEnable(os.Args[3:])
+	case cmdAdminRevokeSessions:
+		runAdminRevokeSessions(os.Args[3:])
+	case cmdAdminUnlock:
+		runAdminUnlock(os.Args[3:])
+	case cmdAuthRotateKeys:
+		runAuthRotateKeys()
+	case cmdAuditVerify:
+		runAuditVerify(os.Args[3:])
 	default:
 		printUsage()
 		os.Exit(1)
@@ -58,6 +84,15 @@ const (
 	cmdSchemaDiff
 	cmdSchemaApply
 	cmdSchemaApplyForce
+	cmdAdminCreate
+	cmdAdminList
+	cmdAdminResetPassword
+	cmdAdminDisable
+	cmdAdminEnable
+	cmdAdminRevokeSessions
+	cmdAdminUnlock
+	cmdAuthRotateKeys
+	cmdAuditVerify
 	cmdUnknown
 )
 
@@ -85,6 +120,38 @@ func parseCommand(args []string) command {
 		default:
 			return cmdUnknown
 		}
+	case "admin":
+		if len(args) < 2 {
+			return cmdUnknown
+		}
+		switch args[1] {
+		case "create":
+			return cmdAdminCreate
+		case "list":
+			return cmdAdminList
+		case "reset-password":
+			return cmdAdminResetPassword
+		case "disable":
+			return cmdAdminDisable
+		case "enable":
+			return cmdAdminEnable
+		case "revoke-sessions":
+			return cmdAdminRevokeSessions
+		case "unlock":
+			return cmdAdminUnlock
+		default:
+			return cmdUnknown
+		}
+	case "auth":
+		if len(args) == 2 && args[1] == "rotate-keys" {
+			return cmdAuthRotateKeys
+		}
+		return cmdUnknown
+	case "audit":
+		if len(args) >= 2 && args[1] == "verify" {
+			return cmdAuditVerify
+		}
+		return cmdUnknown
 	default:
 		return cmdUnknown
 	}
@@ -98,12 +165,60 @@ Commands:
   serve                  Start the HTTP server (default)
   schema diff            Show pending schema changes
   schema apply           Apply safe schema changes
-  schema apply --force   Apply ALL schema changes (including breaking)`)
+  schema apply --force   Apply ALL schema changes (including breaking)
+  admin create [--role <role>] <email>
+                         Create an admin
+  admin list [--json]    List admins
+  admin reset-password <id|email>
+                         Set an admin's password and sign them out
+  admin disable <id|email>
+                         Disable an admin and sign them out
+  admin enable <id|email>
+                         Re-enable a disabled admin
+  admin revoke-sessions <id|email>
+                         Sign an admin out everywhere
+  admin unlock <email|ip>
+                         Lift a login lock after failed attempts
+  auth rotate-keys       Create a new access token signing key
+  audit verify [--json]  Verify the audit log's hash chain
+
+Admin commands read passwords from a prompt, or from the first line of
+stdin when it is not a terminal. audit verify exits with status 2 if the
+audit log has been tampered with.`)
+}
+
+// runAuthRotateKeys creates a new signing key for access tokens. Running
+// servers switch to it within a minute, and keep verifying tokens signed with
+// the previous key until they expire.
+func runAuthRotateKeys() {
+	cfg, db := initBase(os.Stderr)
+	defer db.Close()
+
+	if cfg.JWTSecret == "" {
+		slog.Error("MITHRIL_JWT_SECRET is required")
+		os.Exit(1)
+	}
+	if cfg.JWTAlgorithm == auth.AlgorithmHS256 {
+		slog.Error("signing keys are only used with MITHRIL_JWT_ALGORITHM=EdDSA or RS256; rotate MITHRIL_JWT_SECRET instead")
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	key, err := auth.RotateSigningKey(ctx, auth.NewRepository(db), cfg.JWTSecret, cfg.JWTAlgorithm)
+	if err != nil {
+		slog.Error("failed to rotate signing keys", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created %s signing key %s.\n", key.Algorithm, key.ID)
+	fmt.Println("Running servers switch to it within a minute; previous keys keep verifying tokens until they expire.")
 }
 
 // initBase performs common initialization steps shared by all commands:
-// config loading, logging setup, DB connection, and migrations.
-func initBase() (*config.Config, *database.DB) {
+// config loading, logging setup, DB connection, and migrations. Logs are
+// written to logOut.
+func initBase(logOut io.Writer) (*config.Config, *database.DB) {
 	cfg := config.Load()
 
 	logLevel := slog.LevelInfo
@@ -111,7 +226,7 @@ func initBase() (*config.Config, *database.DB) {
 		logLevel = slog.LevelDebug
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	logger := slog.New(slog.NewJSONHandler(logOut, &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 	slog.SetDefault(logger)
@@ -144,7 +259,7 @@ func initBase() (*config.Config, *database.DB) {
 // to stdout. Exits with code 0 if no changes, 1 on error, 2 if breaking
 // changes are detected.
 func runSchemaDiff() {
-	cfg, db := initBase()
+	cfg, db := initBase(os.Stdout)
 	defer db.Close()
 
 	engine := schema.NewEngine(db, cfg.DevMode)
@@ -248,7 +363,7 @@ func printChanges(changes []schema.Change) int {
 // runSchemaApply loads schemas, applies changes (safe only, or all if force),
 // and prints the results to stdout.
 func runSchemaApply(force bool) {
-	cfg, db := initBase()
+	cfg, db := initBase(os.Stdout)
 	defer db.Close()
 
 	engine := schema.NewEngine(db, cfg.DevMode)
@@ -299,7 +414,7 @@ func runSchemaApply(force bool) {
 
 // runServe starts the full HTTP server with all handlers wired up.
 func runServe() {
-	cfg, db := initBase()
+	cfg, db := initBase(os.Stdout)
 	defer db.Close()
 
 	slog.Info("starting Mithril CMS",
@@ -338,9 +453,23 @@ func runServe() {
 	// --- Set up audit logging ---
 	auditRepo := audit.NewRepository(db)
 	auditService := audit.NewService(auditRepo)
+	auditService.SetDiffPolicy(audit.DiffPolicy{
+		Redact:         cfg.AuditRedactFields,
+		MaxValueLength: cfg.AuditMaxValueLength,
+	})
+	auditMode, err := audit.ParseMode(cfg.AuditMode)
+	if err != nil {
+		slog.Error("invalid MITHRIL_AUDIT_MODE", "error", err)
+		os.Exit(1)
+	}
+	if err := auditService.SetMode(auditMode, cfg.AuditWALDir); err != nil {
+		slog.Error("failed to open audit WAL", "error", err)
+		os.Exit(1)
+	}
+	setupAuditCheckpoints(cfg, auditService)
 	auditService.Start()
 	auditHandler := audit.NewHandler(auditService)
-	slog.Info("audit logging started")
+	slog.Info("audit logging started", "mode", auditMode)
 
 	// --- Set up authentication ---
 	if cfg.JWTSecret == "" {
@@ -349,7 +478,22 @@ func runServe() {
 	}
 
 	authRepo := auth.NewRepository(db)
+	keysCtx, keysCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer keysCancel()
+	keys, err := auth.LoadKeySet(keysCtx, authRepo, cfg.JWTSecret, cfg.JWTAlgorithm)
+	if err != nil {
+		slog.Error("failed to load signing keys", "error", err)
+		os.Exit(1)
+	}
+	keys.Start()
+	slog.Info("access token signing configured", "algorithm", keys.Algorithm())
+
 	authService := auth.NewService(authRepo, cfg.JWTSecret)
+	authService.SetKeySet(keys)
+	revocations := auth.NewRevocations(authRepo)
+	authService.SetRevocations(revocations)
+	authService.SetRequireTwoFactor(cfg.Require2FA)
+	authService.SetLockoutPolicy(lockoutPolicy(cfg))
 
 	// Create initial admin if configured and no admins exist yet.
 	if cfg.AdminEmail != "" && cfg.AdminPassword != "" {
@@ -362,8 +506,21 @@ func runServe() {
 		}
 	}
 
+	tokenService := auth.NewTokenService(authRepo, auditService)
+	tokenService.Start()
+	tokenHandler := auth.NewTokenHandler(tokenService)
+
 	authHandler := auth.NewHandler(authService, auditService, cfg.DevMode)
-	authMiddleware := auth.Middleware(cfg.JWTSecret)
+	authMiddleware := auth.Middleware(keys, revocations, tokenService)
+	roleHandler := auth.NewRoleHandler(authService, auditService)
+	adminHandler := auth.NewAdminHandler(authService, auditService)
+	accountService := auth.NewAccountService(authService, authRepo, setupMailSender(cfg), cfg.PublicURL, auditService)
+	accountHandler := auth.NewAccountHandler(accountService)
+	oidcHandler, err := setupOIDC(cfg, authService, authRepo, auditService)
+	if err != nil {
+		slog.Error("invalid single sign-on configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// --- Set up content CRUD ---
 	schemaMap := make(map[string]schema.ContentType, len(schemas))
@@ -375,6 +532,32 @@ func runServe() {
 	contentService := content.NewService(contentRepo, schemaMap, auditService)
 	contentHandler := content.NewHandler(contentService, schemaMap)
 
+	// --- Set up API keys ---
+	apiKeyService := auth.NewAPIKeyService(authRepo, auditService, contentService.HasContentType)
+	apiKeyService.Start()
+	apiKeyHandler := auth.NewAPIKeyHandler(apiKeyService)
+
+	// --- Set up comments ---
+	commentRepo := comments.NewRepository(db)
+	commentService := comments.NewService(commentRepo, auditService, func(ctx context.Context, contentType, entryID string) (schema.ContentType, error) {
+		ct, err := contentService.Lookup(ctx, contentType, entryID)
+		if errors.Is(err, content.ErrNotFound) {
+			return schema.ContentType{}, fmt.Errorf("%w: %v", comments.ErrEntryNotFound, err)
+		}
+		return ct, err
+	})
+	contentService.OnDelete(commentService.DeleteForEntry)
+	commentHandler := comments.NewHandler(commentService, func(ctx context.Context, contentType, entryID, adminID string) error {
+		err := contentService.CheckOwner(ctx, contentType, entryID, adminID)
+		switch {
+		case errors.Is(err, content.ErrNotOwner):
+			return fmt.Errorf("%w: %v", comments.ErrNotEntryOwner, err)
+		case errors.Is(err, content.ErrNotFound):
+			return fmt.Errorf("%w: %v", comments.ErrEntryNotFound, err)
+		}
+		return err
+	})
+
 	// --- Set up content type introspection ---
 	contentTypeHandler := contenttypes.NewHandler(db.Pool(), schemaMap)
 
@@ -387,7 +570,7 @@ func runServe() {
 	slog.Info("media storage initialized", "dir", cfg.MediaDir)
 
 	mediaRepo := media.NewRepository(db)
-	mediaService := media.NewService(mediaRepo, mediaStorage, auditService)
+	mediaService := media.NewService(mediaRepo, mediaStorage, auditService, contentService.MediaReferences)
 	mediaHandler := media.NewHandler(mediaService, cfg.DevMode)
 
 	// --- Set up schema handler ---
@@ -403,6 +586,19 @@ func runServe() {
 		contentTypeHandler.UpdateSchemas(newMap)
 	})
 
+	// --- Set up rate limiting ---
+	rateLimitStore, publicRateLimit, loginRateLimit, err := setupRateLimits(cfg, db)
+	if err != nil {
+		slog.Error("invalid rate limit configuration", "error", err)
+		os.Exit(1)
+	}
+
+	trustedProxies, err := server.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		slog.Error("invalid MITHRIL_TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+
 	// --- Build router and start server ---
 	deps := server.Dependencies{
 		DB:             db,
@@ -413,10 +609,24 @@ func runServe() {
 		AuthHandler:    authHandler,
 		AuthMiddleware: authMiddleware,
 		ContentHandler: contentHandler,
+		CommentHandler: commentHandler,
 		MediaHandler:   mediaHandler,
 		AuditHandler:       auditHandler,
 		SchemaHandler:      schemaHandler,
 		ContentTypeHandler: contentTypeHandler,
+		APIKeyHandler:      apiKeyHandler,
+		TokenHandler:       tokenHandler,
+		RoleHandler:        roleHandler,
+		AdminHandler:       adminHandler,
+		AccountHandler:     accountHandler,
+		OIDCHandler:        oidcHandler,
+		KeysHandler:        auth.NewKeysHandler(keys),
+		Authorize:          auth.Authorize,
+		APIKeyMiddleware:   auth.APIKeyMiddleware(apiKeyService),
+		RateLimitStore:     rateLimitStore,
+		PublicRateLimit:    publicRateLimit,
+		LoginRateLimit:     loginRateLimit,
+		TrustedProxies:     trustedProxies,
 	}
 
 	router := server.NewRouter(deps)
@@ -453,9 +663,109 @@ func runServe() {
 		os.Exit(1)
 	}
 
-	// Drain remaining audit events before closing the database.
+	// Finish sending account emails, flush API key and personal access
+	// token usage and drain remaining audit events before closing the
+	// database.
+	accountService.Shutdown(shutdownCtx)
+	apiKeyService.Shutdown(shutdownCtx)
+	tokenService.Shutdown(shutdownCtx)
+	keys.Shutdown()
 	slog.Info("draining audit events...")
 	auditService.Shutdown(shutdownCtx)
 
 	slog.Info("Mithril CMS stopped")
 }
+
+// setupRateLimits builds the rate limit store and route policies from the
+// configuration.
+func setupRateLimits(cfg *config.Config, db *database.DB) (server.RateLimitStore, server.RateLimitPolicy, server.RateLimitPolicy, error) {
+	public := server.RateLimitPolicy{Name: "public"}
+	login := server.RateLimitPolicy{Name: "login"}
+
+	var err error
+	if public.PerIP, err = server.ParseRate(cfg.RateLimitPublic); err != nil {
+		return nil, public, login, fmt.Errorf("MITHRIL_RATE_LIMIT_PUBLIC: %w", err)
+	}
+	if public.PerAPIKey, err = server.ParseRate(cfg.RateLimitPublicAPIKey); err != nil {
+		return nil, public, login, fmt.Errorf("MITHRIL_RATE_LIMIT_PUBLIC_API_KEY: %w", err)
+	}
+	if login.PerIP, err = server.ParseRate(cfg.RateLimitLogin); err != nil {
+		return nil, public, login, fmt.Errorf("MITHRIL_RATE_LIMIT_LOGIN: %w", err)
+	}
+
+	switch cfg.RateLimitStore {
+	case "memory":
+		return server.NewMemoryRateLimitStore(), public, login, nil
+	case "postgres":
+		return server.NewPostgresRateLimitStore(db), public, login, nil
+	default:
+		return nil, public, login, fmt.Errorf("MITHRIL_RATE_LIMIT_STORE must be 'memory' or 'postgres', got %q", cfg.RateLimitStore)
+	}
+}
+
+// lockoutPolicy returns the brute-force protection of admin logins.
+func lockoutPolicy(cfg *config.Config) auth.LockoutPolicy {
+	return auth.LockoutPolicy{
+		Threshold:   cfg.LockoutThreshold,
+		IPThreshold: cfg.LockoutIPThreshold,
+		Duration:    cfg.LockoutDuration,
+		DelayBase:   cfg.LoginDelay,
+		DelayMax:    cfg.LoginDelayMax,
+	}
+}
+
+// setupOIDC returns the single sign-on handler, or nil if no OpenID Connect
+// provider is configured.
+func setupOIDC(cfg *config.Config, authService *auth.Service, authRepo *auth.Repository, auditService *audit.Service) (server.OIDCHandler, error) {
+	if cfg.OIDCIssuer == "" {
+		return nil, nil
+	}
+	if cfg.OIDCClientID == "" {
+		return nil, fmt.Errorf("MITHRIL_OIDC_CLIENT_ID is required with MITHRIL_OIDC_ISSUER")
+	}
+	mapping, err := auth.ParseRoleMapping(cfg.OIDCRoleMapping)
+	if err != nil {
+		return nil, fmt.Errorf("MITHRIL_OIDC_ROLE_MAPPING: %w", err)
+	}
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.OIDCIssuer,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.PublicURL + "/admin/api/auth/oidc/callback",
+		Scopes:       cfg.OIDCScopes,
+		GroupsClaim:  cfg.OIDCGroupsClaim,
+	})
+	service := auth.NewOIDCService(authService, authRepo, provider, auth.OIDCConfig{
+		AllowedDomains:       cfg.OIDCAllowedDomains,
+		RoleMapping:          mapping,
+		DefaultRole:          cfg.OIDCDefaultRole,
+		TrustUnverifiedEmail: cfg.OIDCTrustUnverifiedEmail,
+	}, auditService)
+
+	slog.Info("single sign-on enabled", "issuer", cfg.OIDCIssuer)
+	return auth.NewOIDCHandler(service, auditService, cfg.DevMode), nil
+}
+
+// setupMailSender returns the sender for invitation and password reset
+// emails. Without an SMTP server, emails are only logged in dev mode and not
+// sent at all otherwise.
+func setupMailSender(cfg *config.Config) mail.Sender {
+	switch {
+	case cfg.SMTPHost != "":
+		slog.Info("email delivery via smtp", "host", cfg.SMTPHost, "port", cfg.SMTPPort)
+		return mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	case cfg.DevMode:
+		slog.Info("email delivery is log-only (dev mode, MITHRIL_SMTP_HOST not set)")
+		return mail.LogSender{}
+	default:
+		slog.Warn("email delivery disabled: MITHRIL_SMTP_HOST not set; invitations and password resets are unavailable")
+		return nil
+	}
+}
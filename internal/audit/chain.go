@@ -0,0 +1,232 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// chainLockID is the key of the PostgreSQL advisory lock that serializes
+// appends to the hash chain across processes.
+const chainLockID int64 = 0x6d697468726c6175
+
+// genesisHash is the hash preceding the first entry of the chain.
+var genesisHash = make([]byte, sha256.Size)
+
+// chainRow is an audit log entry as it is hashed into the chain.
+type chainRow struct {
+	Seq        int64
+	ID         string
+	CreatedAt  time.Time
+	Action     string
+	ActorID    *string
+	Resource   *string
+	ResourceID *string
+	TokenID    *string
+	Payload    []byte // JSON, or nil
+	Hash       []byte // as stored
+}
+
+// chainRecord is the canonical form of an entry's contents that is hashed.
+// Values are normalized the way PostgreSQL stores them, so that an entry
+// read back hashes the same as when it was written.
+type chainRecord struct {
+	Seq        int64   `json:"seq"`
+	ID         string  `json:"id"`
+	CreatedAt  string  `json:"created_at"`
+	Action     string  `json:"action"`
+	ActorID    *string `json:"actor_id"`
+	Resource   *string `json:"resource"`
+	ResourceID *string `json:"resource_id"`
+	TokenID    *string `json:"token_id"`
+	Payload    any     `json:"payload"`
+}
+
+// digest returns the hash of the row following an entry with hash prev:
+// SHA-256 of prev and the row's canonical contents.
+func (r chainRow) digest(prev []byte) ([]byte, error) {
+	rec := chainRecord{
+		Seq:        r.Seq,
+		ID:         strings.ToLower(r.ID),
+		CreatedAt:  chainTime(r.CreatedAt).Format(time.RFC3339Nano),
+		Action:     r.Action,
+		ActorID:    lowerUUID(r.ActorID),
+		Resource:   r.Resource,
+		ResourceID: lowerUUID(r.ResourceID),
+		TokenID:    lowerUUID(r.TokenID),
+	}
+	if len(r.Payload) > 0 {
+		// JSONB does not keep key order or formatting, so the payload is
+		// hashed as re-encoded by encoding/json, which sorts keys.
+		if err := json.Unmarshal(r.Payload, &rec.Payload); err != nil {
+			return nil, fmt.Errorf("decoding payload of audit entry %s: %w", r.ID, err)
+		}
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("encoding audit entry %s: %w", r.ID, err)
+	}
+
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(b)
+	return h.Sum(nil), nil
+}
+
+// chainTime returns t as PostgreSQL stores it: in microseconds.
+func chainTime(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond).UTC()
+}
+
+// lowerUUID returns the UUID s points to in lowercase, as PostgreSQL outputs
+// UUIDs.
+func lowerUUID(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	lower := strings.ToLower(*s)
+	return &lower
+}
+
+// ChainBreak describes where the audit log's hash chain is broken.
+type ChainBreak struct {
+	// Seq is the sequence number of the first entry that fails
+	// verification, or that is missing. It is 0 for entries outside the
+	// chain.
+	Seq int64 `json:"seq"`
+	// ID is the ID of the entry, if it exists.
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// VerifyResult is the outcome of verifying the audit log's hash chain.
+type VerifyResult struct {
+	OK bool `json:"ok"`
+	// Verified is the number of entries in the chain that were verified.
+	Verified int64 `json:"verified"`
+	// Unchained is the number of entries written before the chain was
+	// introduced, which are not protected by it.
+	Unchained int64 `json:"unchained"`
+	// LastSeq and LastHash identify the newest verified entry.
+	LastSeq  int64  `json:"last_seq"`
+	LastHash string `json:"last_hash,omitempty"`
+	// Checkpoints is the number of checkpoints the chain was checked
+	// against.
+	Checkpoints int `json:"checkpoints"`
+	// Break is the first broken link, if any.
+	Break *ChainBreak `json:"break,omitempty"`
+}
+
+// Verify walks the audit log's hash chain from its first entry and reports
+// the first broken link: an entry that is missing, or whose hash does not
+// match its contents and the previous entry's hash. If checkpoints are
+// enabled, the chain must also match every checkpoint and reach the newest
+// one. Entries outside the chain that were created after it began are
+// reported as well, as they can only have been inserted around it.
+func (s *Service) Verify(ctx context.Context) (VerifyResult, error) {
+	var checkpoints []Checkpoint
+	var key []byte
+	if s.checkpoints != nil {
+		var err error
+		if checkpoints, err = readCheckpoints(s.checkpoints.path); err != nil {
+			return VerifyResult{}, err
+		}
+		key = s.checkpoints.key
+	}
+
+	v := newChainVerifier(checkpoints, key)
+	if err := s.repo.walkChain(ctx, v.check); err != nil {
+		return VerifyResult{}, fmt.Errorf("verifying audit chain: %w", err)
+	}
+	count, id, err := s.repo.unchained(ctx)
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("verifying audit chain: %w", err)
+	}
+	return v.finish(count, id), nil
+}
+
+// chainVerifier checks the rows of the chain in sequence order.
+type chainVerifier struct {
+	prev        []byte
+	checkpoints map[int64][]byte
+	result      VerifyResult
+}
+
+// newChainVerifier returns a verifier that also compares the chain with the
+// given checkpoints, which must be signed with key.
+func newChainVerifier(checkpoints []Checkpoint, key []byte) *chainVerifier {
+	v := &chainVerifier{prev: genesisHash, checkpoints: make(map[int64][]byte, len(checkpoints))}
+	v.result.Checkpoints = len(checkpoints)
+	for _, cp := range checkpoints {
+		hash, err := hex.DecodeString(cp.Hash)
+		if err != nil || !cp.valid(key) {
+			v.fail(ChainBreak{Seq: cp.Seq, Reason: fmt.Sprintf("checkpoint of entry %d has an invalid signature", cp.Seq)})
+			break
+		}
+		v.checkpoints[cp.Seq] = hash
+	}
+	return v
+}
+
+// check verifies the next row of the chain. It returns false once the chain
+// is broken, recording the break.
+func (v *chainVerifier) check(row chainRow) (bool, error) {
+	if v.result.Break != nil {
+		return false, nil
+	}
+	want := v.result.LastSeq + 1
+	if row.Seq != want {
+		return v.fail(ChainBreak{Seq: want, Reason: fmt.Sprintf("entry %d is missing", want)}), nil
+	}
+	hash, err := row.digest(v.prev)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(hash, row.Hash) {
+		return v.fail(ChainBreak{Seq: row.Seq, ID: row.ID, Reason: "hash does not match the entry's contents and the previous entry"}), nil
+	}
+	if cp, ok := v.checkpoints[row.Seq]; ok && !bytes.Equal(cp, row.Hash) {
+		return v.fail(ChainBreak{Seq: row.Seq, ID: row.ID, Reason: "hash does not match the checkpoint"}), nil
+	}
+
+	v.prev = hash
+	v.result.Verified++
+	v.result.LastSeq = row.Seq
+	v.result.LastHash = hex.EncodeToString(hash)
+	return true, nil
+}
+
+// finish completes the verification after the last row, given the number of
+// entries outside the chain and the ID of the oldest of them created after
+// the chain began, and returns the result.
+func (v *chainVerifier) finish(unchained int64, insertedID string) VerifyResult {
+	v.result.Unchained = unchained
+	if v.result.Break == nil {
+		var newest int64
+		for seq := range v.checkpoints {
+			newest = max(newest, seq)
+		}
+		if newest > v.result.LastSeq {
+			v.fail(ChainBreak{
+				Seq:    v.result.LastSeq + 1,
+				Reason: fmt.Sprintf("chain ends at entry %d, but is checkpointed up to entry %d", v.result.LastSeq, newest),
+			})
+		}
+	}
+	if v.result.Break == nil && insertedID != "" {
+		v.fail(ChainBreak{ID: insertedID, Reason: "entry created after the chain began is not part of it"})
+	}
+	v.result.OK = v.result.Break == nil
+	return v.result
+}
+
+// fail records a break and returns false.
+func (v *chainVerifier) fail(b ChainBreak) bool {
+	v.result.Break = &b
+	return false
+}
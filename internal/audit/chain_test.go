@@ -0,0 +1,203 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testChain returns n chained rows, as InsertBatch writes them.
+func testChain(t *testing.T, n int) []chainRow {
+	t.Helper()
+	resource := "blog_posts"
+	rows := make([]chainRow, n)
+	prev := genesisHash
+	for i := range rows {
+		rows[i] = chainRow{
+			Seq:       int64(i + 1),
+			ID:        newEventID(),
+			CreatedAt: chainTime(time.Date(2026, 1, 2, 3, 4, 5, i*1000, time.UTC)),
+			Action:    "entry.update",
+			Resource:  &resource,
+			Payload:   []byte(`{"title":"Hello","changes":{"title":{"old":"Hi","new":"Hello"}}}`),
+		}
+		hash, err := rows[i].digest(prev)
+		if err != nil {
+			t.Fatalf("digest() error = %v", err)
+		}
+		rows[i].Hash, prev = hash, hash
+	}
+	return rows
+}
+
+// verify runs the verifier over rows.
+func verify(t *testing.T, rows []chainRow, checkpoints []Checkpoint, key []byte) VerifyResult {
+	t.Helper()
+	v := newChainVerifier(checkpoints, key)
+	for _, row := range rows {
+		more, err := v.check(row)
+		if err != nil {
+			t.Fatalf("check() error = %v", err)
+		}
+		if !more {
+			break
+		}
+	}
+	return v.finish(0, "")
+}
+
+func TestChainRow_DigestIsCanonical(t *testing.T) {
+	actor := "0B6E4C9A-1D2F-4E3A-8B7C-6D5E4F3A2B1C"
+	row := chainRow{
+		Seq:       1,
+		ID:        "5F2D9E4A-3B1C-4D8E-9F7A-1B2C3D4E5F6A",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.FixedZone("CET", 3600)),
+		Action:    "entry.create",
+		ActorID:   &actor,
+		Payload:   []byte(`{"b": 1, "a": [true, null]}`),
+	}
+	want, err := row.digest(genesisHash)
+	if err != nil {
+		t.Fatalf("digest() error = %v", err)
+	}
+
+	// The entry as PostgreSQL returns it: lowercase UUIDs, microseconds,
+	// and JSONB's own key order and spacing.
+	lowerActor := strings.ToLower(actor)
+	stored := row
+	stored.ID = strings.ToLower(row.ID)
+	stored.ActorID = &lowerActor
+	stored.CreatedAt = time.Date(2026, 1, 2, 2, 4, 5, 123456000, time.UTC)
+	stored.Payload = []byte(`{"a":[true,null],"b":1}`)
+	if got, err := stored.digest(genesisHash); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("digest() of stored entry = %x, %v; want %x", got, err, want)
+	}
+
+	changed := stored
+	changed.Action = "entry.delete"
+	if got, _ := changed.digest(genesisHash); bytes.Equal(got, want) {
+		t.Error("digest() did not change with the action")
+	}
+	if got, _ := stored.digest(want); bytes.Equal(got, want) {
+		t.Error("digest() did not change with the previous hash")
+	}
+}
+
+func TestChainVerifier_Intact(t *testing.T) {
+	rows := testChain(t, 3)
+	result := verify(t, rows, nil, nil)
+	if !result.OK || result.Verified != 3 || result.LastSeq != 3 {
+		t.Fatalf("result = %+v, want 3 verified entries", result)
+	}
+	if result.LastHash != hex.EncodeToString(rows[2].Hash) {
+		t.Errorf("LastHash = %s, want %x", result.LastHash, rows[2].Hash)
+	}
+}
+
+func TestChainVerifier_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func([]chainRow) []chainRow
+		wantSeq int64
+	}{
+		{"edited payload", func(rows []chainRow) []chainRow {
+			rows[1].Payload = []byte(`{"title":"Forged"}`)
+			return rows
+		}, 2},
+		{"edited time", func(rows []chainRow) []chainRow {
+			rows[2].CreatedAt = rows[2].CreatedAt.Add(time.Hour)
+			return rows
+		}, 3},
+		{"deleted entry", func(rows []chainRow) []chainRow {
+			return append(rows[:1], rows[2:]...)
+		}, 2},
+		{"rehashed entry", func(rows []chainRow) []chainRow {
+			// Recomputing an edited entry's hash breaks the next link.
+			rows[0].Action = "entry.delete"
+			rows[0].Hash, _ = rows[0].digest(genesisHash)
+			return rows
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := verify(t, tt.tamper(testChain(t, 4)), nil, nil)
+			if result.OK || result.Break == nil {
+				t.Fatalf("result = %+v, want a break", result)
+			}
+			if result.Break.Seq != tt.wantSeq {
+				t.Errorf("Break = %+v, want entry %d", result.Break, tt.wantSeq)
+			}
+			if result.Verified != tt.wantSeq-1 {
+				t.Errorf("Verified = %d, want %d", result.Verified, tt.wantSeq-1)
+			}
+		})
+	}
+}
+
+func TestChainVerifier_Checkpoints(t *testing.T) {
+	key := checkpointKey("secret")
+	rows := testChain(t, 3)
+	checkpoint := func(seq int64, hash []byte) Checkpoint {
+		cp := Checkpoint{Seq: seq, Hash: hex.EncodeToString(hash), Time: time.Now().UTC()}
+		cp.Signature = cp.sign(key)
+		return cp
+	}
+
+	t.Run("matching", func(t *testing.T) {
+		result := verify(t, rows, []Checkpoint{checkpoint(2, rows[1].Hash), checkpoint(3, rows[2].Hash)}, key)
+		if !result.OK || result.Checkpoints != 2 {
+			t.Errorf("result = %+v, want OK with 2 checkpoints", result)
+		}
+	})
+
+	t.Run("rewritten chain", func(t *testing.T) {
+		// A chain rewritten from entry 2 on is consistent in itself.
+		rewritten := append([]chainRow(nil), rows...)
+		rewritten[1].Action = "entry.delete"
+		rewritten[1].Hash, _ = rewritten[1].digest(rewritten[0].Hash)
+		rewritten[2].Hash, _ = rewritten[2].digest(rewritten[1].Hash)
+		if result := verify(t, rewritten, nil, nil); !result.OK {
+			t.Fatalf("rewritten chain without checkpoints: %+v", result)
+		}
+
+		result := verify(t, rewritten, []Checkpoint{checkpoint(2, rows[1].Hash)}, key)
+		if result.OK || result.Break.Seq != 2 {
+			t.Errorf("result = %+v, want a break at entry 2", result)
+		}
+	})
+
+	t.Run("truncated chain", func(t *testing.T) {
+		result := verify(t, rows[:2], []Checkpoint{checkpoint(3, rows[2].Hash)}, key)
+		if result.OK || result.Break.Seq != 3 {
+			t.Errorf("result = %+v, want a break at entry 3", result)
+		}
+	})
+
+	t.Run("forged checkpoint", func(t *testing.T) {
+		forged := checkpoint(3, rows[2].Hash)
+		forged.Signature = Checkpoint{Seq: 3, Hash: forged.Hash, Time: forged.Time}.sign(checkpointKey("other"))
+		result := verify(t, rows, []Checkpoint{forged}, key)
+		if result.OK || result.Verified != 0 {
+			t.Errorf("result = %+v, want a break before any entry", result)
+		}
+	})
+}
+
+func TestChainVerifier_InsertedOutsideChain(t *testing.T) {
+	v := newChainVerifier(nil, nil)
+	for _, row := range testChain(t, 2) {
+		if _, err := v.check(row); err != nil {
+			t.Fatalf("check() error = %v", err)
+		}
+	}
+
+	result := v.finish(5, "5f2d9e4a-3b1c-4d8e-9f7a-1b2c3d4e5f6a")
+	if result.OK || result.Break.ID != "5f2d9e4a-3b1c-4d8e-9f7a-1b2c3d4e5f6a" {
+		t.Errorf("result = %+v, want a break at the inserted entry", result)
+	}
+	if result.Unchained != 5 {
+		t.Errorf("Unchained = %d, want 5", result.Unchained)
+	}
+}
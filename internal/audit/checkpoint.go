@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// checkpointTimeout bounds the database query of each checkpoint.
+const checkpointTimeout = 10 * time.Second
+
+// Checkpoint records the head of the audit log's hash chain at a point in
+// time. Checkpoints are signed and kept outside the database, so that
+// rewriting the chain from some entry on, or cutting entries off its end,
+// is detected even by someone who can recompute every hash in it.
+type Checkpoint struct {
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"` // hex
+	Time      time.Time `json:"time"`
+	Signature string    `json:"signature"` // hex HMAC-SHA256
+}
+
+// checkpointKey derives the key checkpoints are signed with from the JWT
+// secret.
+func checkpointKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("mithril-cms audit checkpoint"))
+	return mac.Sum(nil)
+}
+
+// sign returns the checkpoint's signature with key.
+func (c Checkpoint) sign(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strconv.FormatInt(c.Seq, 10) + "|" + c.Hash + "|" + c.Time.UTC().Format(time.RFC3339Nano)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// valid reports whether the checkpoint is signed with key.
+func (c Checkpoint) valid(key []byte) bool {
+	sig, err := hex.DecodeString(c.Signature)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(c.sign(key))
+	return hmac.Equal(sig, want)
+}
+
+// appendCheckpoint appends the checkpoint to the file at path as a JSON
+// line and syncs it to disk.
+func appendCheckpoint(path string, c Checkpoint) error {
+	line, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encoding audit checkpoint: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit checkpoint file: %w", err)
+	}
+	if _, err = f.Write(append(line, '\n')); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing audit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// readCheckpoints returns the checkpoints in the file at path, oldest
+// first, or none if the file does not exist. A malformed line, such as one
+// cut short by a crash while it was appended, is skipped with a warning.
+func readCheckpoints(path string) ([]Checkpoint, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening audit checkpoint file: %w", err)
+	}
+	defer f.Close()
+
+	var checkpoints []Checkpoint
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if len(b) > 0 {
+			var c Checkpoint
+			if jsonErr := json.Unmarshal(b, &c); jsonErr != nil {
+				slog.Warn("skipping malformed audit checkpoint", "file", path, "line", line, "error", jsonErr)
+			} else {
+				checkpoints = append(checkpoints, c)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return checkpoints, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading audit checkpoint file: %w", err)
+		}
+	}
+}
+
+// checkpointer holds the configuration and state of the checkpoints written
+// by a Service.
+type checkpointer struct {
+	path     string
+	interval time.Duration
+	key      []byte
+	seq      int64 // sequence number of the newest checkpoint written
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// SetCheckpoints makes the service append a signed checkpoint of the hash
+// chain to the file at path every interval, if the chain has grown, and
+// once more on Shutdown. Checkpoints are signed with a key derived from
+// secret, and Verify checks the chain against them. The file should be kept
+// where those with access to the database cannot change it. Must be called
+// before Start.
+func (s *Service) SetCheckpoints(path string, interval time.Duration, secret string) error {
+	if interval <= 0 {
+		return fmt.Errorf("audit checkpoint interval must be positive, got %s", interval)
+	}
+	checkpoints, err := readCheckpoints(path)
+	if err != nil {
+		return err
+	}
+	c := &checkpointer{
+		path:     path,
+		interval: interval,
+		key:      checkpointKey(secret),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, cp := range checkpoints {
+		c.seq = max(c.seq, cp.Seq)
+	}
+	s.checkpoints = c
+	return nil
+}
+
+// writeCheckpoints is the background goroutine that writes checkpoints
+// until Shutdown, and a last one then.
+func (s *Service) writeCheckpoints() {
+	defer close(s.checkpoints.done)
+
+	ticker := time.NewTicker(s.checkpoints.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.checkpoints.stop:
+			s.checkpoint()
+			return
+		}
+		s.checkpoint()
+	}
+}
+
+// checkpoint appends a checkpoint of the chain's head to the file, unless
+// the chain has not grown since the last one.
+func (s *Service) checkpoint() {
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	seq, hash, err := s.repo.chainHead(ctx)
+	if err != nil {
+		slog.Error("failed to checkpoint audit chain", "error", err)
+		return
+	}
+	if seq <= s.checkpoints.seq {
+		return
+	}
+	cp := Checkpoint{Seq: seq, Hash: hex.EncodeToString(hash), Time: time.Now().UTC()}
+	cp.Signature = cp.sign(s.checkpoints.key)
+	if err := appendCheckpoint(s.checkpoints.path, cp); err != nil {
+		slog.Error("failed to checkpoint audit chain", "error", err)
+		return
+	}
+	s.checkpoints.seq = seq
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint_Sign(t *testing.T) {
+	key := checkpointKey("secret")
+	cp := Checkpoint{Seq: 42, Hash: "ab12", Time: time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)}
+	cp.Signature = cp.sign(key)
+
+	if !cp.valid(key) {
+		t.Error("valid() = false for a signed checkpoint")
+	}
+	if cp.valid(checkpointKey("other")) {
+		t.Error("valid() = true with another key")
+	}
+	changed := cp
+	changed.Seq = 41
+	if changed.valid(key) {
+		t.Error("valid() = true for a changed checkpoint")
+	}
+}
+
+func TestCheckpoint_AppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints")
+	if checkpoints, err := readCheckpoints(path); err != nil || checkpoints != nil {
+		t.Fatalf("readCheckpoints() of missing file = %v, %v; want none", checkpoints, err)
+	}
+
+	key := checkpointKey("secret")
+	for seq := int64(1); seq <= 2; seq++ {
+		cp := Checkpoint{Seq: seq, Hash: "ab12", Time: time.Now().UTC()}
+		cp.Signature = cp.sign(key)
+		if err := appendCheckpoint(path, cp); err != nil {
+			t.Fatalf("appendCheckpoint() error = %v", err)
+		}
+	}
+	// A line cut short by a crash is skipped.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":3,"ha`)
+	f.Close()
+
+	checkpoints, err := readCheckpoints(path)
+	if err != nil {
+		t.Fatalf("readCheckpoints() error = %v", err)
+	}
+	if len(checkpoints) != 2 || checkpoints[1].Seq != 2 {
+		t.Fatalf("readCheckpoints() = %+v, want checkpoints 1 and 2", checkpoints)
+	}
+	// Signatures survive the round trip through the file.
+	for _, cp := range checkpoints {
+		if !cp.valid(key) {
+			t.Errorf("checkpoint %d has an invalid signature after reading", cp.Seq)
+		}
+	}
+}
+
+func TestSetCheckpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints")
+	cp := Checkpoint{Seq: 7, Hash: "ab12", Time: time.Now().UTC()}
+	if err := appendCheckpoint(path, cp); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService(nil)
+	if err := s.SetCheckpoints(path, 0, "secret"); err == nil {
+		t.Error("SetCheckpoints() with zero interval succeeded")
+	}
+	if err := s.SetCheckpoints(path, time.Hour, "secret"); err != nil {
+		t.Fatalf("SetCheckpoints() error = %v", err)
+	}
+	// Checkpoints continue after the newest one in the file.
+	if s.checkpoints.seq != 7 {
+		t.Errorf("seq = %d, want 7", s.checkpoints.seq)
+	}
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Change is the value of a field before and after an audited change. Old is
+// nil for fields that were added, and New for fields that were removed.
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+
+	// Redacted is set when the values are withheld by the DiffPolicy; Old
+	// and New are then nil.
+	Redacted bool `json:"redacted,omitempty"`
+
+	// Truncated is set when Old or New were cut to the policy's
+	// MaxValueLength.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DiffPolicy configures the field-level changes recorded in audit events.
+// The zero value records all values in full.
+type DiffPolicy struct {
+	// Redact lists the fields whose values are never recorded, only that
+	// they changed: "field" for that field of any resource, or
+	// "resource.field" (e.g. "blog_posts.body", "media.original_name").
+	Redact []string
+
+	// MaxValueLength truncates longer values to this many characters.
+	// Values other than strings are measured and truncated as JSON. Zero
+	// disables truncation.
+	MaxValueLength int
+}
+
+// Diff returns the changes from before to after of the resource's fields,
+// keyed by field name. Either map may be nil, for created and deleted
+// resources. Returns nil if no field changed.
+func (p DiffPolicy) Diff(resource string, before, after map[string]any) map[string]Change {
+	fields := make(map[string]struct{}, len(after))
+	for k := range before {
+		fields[k] = struct{}{}
+	}
+	for k := range after {
+		fields[k] = struct{}{}
+	}
+
+	var changes map[string]Change
+	for field := range fields {
+		oldValue, inOld := before[field]
+		newValue, inNew := after[field]
+		if inOld && inNew && equalValues(oldValue, newValue) {
+			continue
+		}
+		if changes == nil {
+			changes = make(map[string]Change)
+		}
+		changes[field] = p.change(resource, field, oldValue, newValue)
+	}
+	return changes
+}
+
+// change builds the recorded change of a field.
+func (p DiffPolicy) change(resource, field string, oldValue, newValue any) Change {
+	if p.redacts(resource, field) {
+		return Change{Redacted: true}
+	}
+	oldValue, oldCut := p.truncate(oldValue)
+	newValue, newCut := p.truncate(newValue)
+	return Change{Old: oldValue, New: newValue, Truncated: oldCut || newCut}
+}
+
+// redacts reports whether the values of the resource's field are withheld.
+func (p DiffPolicy) redacts(resource, field string) bool {
+	for _, r := range p.Redact {
+		if r == field || r == resource+"."+field {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate cuts v to MaxValueLength characters, returning the truncated
+// value as a string and whether it was cut.
+func (p DiffPolicy) truncate(v any) (any, bool) {
+	if p.MaxValueLength <= 0 || v == nil {
+		return v, false
+	}
+	s, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil || len(b) <= p.MaxValueLength {
+			return v, false
+		}
+		s = string(b)
+	}
+	if utf8.RuneCountInString(s) <= p.MaxValueLength {
+		return v, false
+	}
+	runes := []rune(s)
+	return string(runes[:p.MaxValueLength]) + "…", true
+}
+
+// equalValues reports whether two field values are the same, comparing them
+// as they are recorded in the payload.
+func equalValues(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
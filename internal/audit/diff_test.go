@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffPolicy_Diff(t *testing.T) {
+	published := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	before := map[string]any{"title": "Hello", "views": int32(3), "status": "draft", "published_at": nil}
+	after := map[string]any{"title": "Hello, world", "views": int32(3), "status": "published", "published_at": published}
+
+	changes := DiffPolicy{}.Diff("posts", before, after)
+	want := map[string]Change{
+		"title":        {Old: "Hello", New: "Hello, world"},
+		"status":       {Old: "draft", New: "published"},
+		"published_at": {Old: nil, New: published},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Diff() = %+v, want %+v", changes, want)
+	}
+	for field, w := range want {
+		if c := changes[field]; c.Old != w.Old || c.New != w.New || c.Redacted || c.Truncated {
+			t.Errorf("change of %s = %+v, want %+v", field, c, w)
+		}
+	}
+
+	if changes := (DiffPolicy{}).Diff("posts", before, before); changes != nil {
+		t.Errorf("Diff(unchanged) = %+v, want nil", changes)
+	}
+}
+
+func TestDiffPolicy_CreatedAndDeleted(t *testing.T) {
+	values := map[string]any{"filename": "a.png", "size": int64(10)}
+
+	for name, changes := range map[string]map[string]Change{
+		"created": DiffPolicy{}.Diff("media", nil, values),
+		"deleted": DiffPolicy{}.Diff("media", values, nil),
+	} {
+		if len(changes) != 2 {
+			t.Errorf("%s: Diff() = %+v, want both fields", name, changes)
+		}
+	}
+	if c := (DiffPolicy{}).Diff("media", nil, values)["size"]; c.Old != nil || c.New != int64(10) {
+		t.Errorf("created size = %+v, want nil -> 10", c)
+	}
+}
+
+func TestDiffPolicy_Redact(t *testing.T) {
+	p := DiffPolicy{Redact: []string{"password_hint", "posts.body"}}
+	before := map[string]any{"password_hint": "a", "body": "old", "title": "old"}
+	after := map[string]any{"password_hint": "b", "body": "new", "title": "new"}
+
+	changes := p.Diff("posts", before, after)
+	for _, field := range []string{"password_hint", "body"} {
+		if c := changes[field]; !c.Redacted || c.Old != nil || c.New != nil {
+			t.Errorf("change of %s = %+v, want redacted", field, c)
+		}
+	}
+	if c := changes["title"]; c.Redacted {
+		t.Errorf("change of title = %+v, want it recorded", c)
+	}
+	// Resource-qualified entries only apply to that resource.
+	if c := p.Diff("pages", before, after)["body"]; c.Redacted {
+		t.Errorf("change of pages.body = %+v, want it recorded", c)
+	}
+}
+
+func TestDiffPolicy_Truncate(t *testing.T) {
+	p := DiffPolicy{MaxValueLength: 5}
+	before := map[string]any{"title": "short", "body": "héllo wörld", "tags": []string{"alpha", "beta"}}
+	after := map[string]any{"title": "tiny", "body": "héllo", "tags": []string{"alpha"}}
+
+	changes := p.Diff("posts", before, after)
+	if c := changes["title"]; c.Truncated || c.Old != "short" {
+		t.Errorf("change of title = %+v, want it in full", c)
+	}
+	if c := changes["body"]; !c.Truncated || c.Old != "héllo…" || c.New != "héllo" {
+		t.Errorf("change of body = %+v, want old value truncated to 5 characters", c)
+	}
+	if c := changes["tags"]; !c.Truncated || !strings.HasPrefix(c.Old.(string), `["alp`) {
+		t.Errorf("change of tags = %+v, want JSON truncated to 5 characters", c)
+	}
+}
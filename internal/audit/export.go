@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// exportFlushEvery is how many entries an export writes between flushes,
+// so clients receive large exports progressively.
+const exportFlushEvery = 500
+
+// exportColumns are the CSV columns of an audit log export.
+var exportColumns = []string{
+	"id", "created_at", "action", "actor_id", "actor_email",
+	"token_id", "resource", "resource_id", "payload",
+}
+
+// exportWriter writes audit entries to an export response.
+type exportWriter interface {
+	header() error
+	write(e *AuditEntry) error
+	flush() error
+}
+
+// flusher flushes a response writer every exportFlushEvery entries.
+type flusher struct {
+	rc    *http.ResponseController
+	count int
+}
+
+// due counts a written entry and reports whether output should be flushed.
+func (f *flusher) due() bool {
+	f.count++
+	return f.count%exportFlushEvery == 0
+}
+
+// flush sends buffered output to the client. Response writers that cannot
+// flush are left to send it when the handler returns.
+func (f *flusher) flush() error {
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// csvExportWriter writes entries as CSV rows, with the payload as JSON.
+type csvExportWriter struct {
+	w *csv.Writer
+	f flusher
+}
+
+func newCSVExportWriter(w http.ResponseWriter) *csvExportWriter {
+	return &csvExportWriter{w: csv.NewWriter(w), f: flusher{rc: http.NewResponseController(w)}}
+}
+
+func (c *csvExportWriter) header() error {
+	return c.w.Write(exportColumns)
+}
+
+func (c *csvExportWriter) write(e *AuditEntry) error {
+	payload := ""
+	if len(e.Payload) > 0 {
+		b, err := json.Marshal(e.Payload)
+		if err != nil {
+			return fmt.Errorf("encoding payload of audit entry %s: %w", e.ID, err)
+		}
+		payload = string(b)
+	}
+	row := []string{
+		e.ID,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.Action,
+		deref(e.ActorID),
+		deref(e.ActorEmail),
+		deref(e.TokenID),
+		deref(e.Resource),
+		deref(e.ResourceID),
+		payload,
+	}
+	for i, cell := range row {
+		row[i] = csvCell(cell)
+	}
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	if c.f.due() {
+		return c.flush()
+	}
+	return nil
+}
+
+func (c *csvExportWriter) flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return c.f.flush()
+}
+
+// csvCell escapes a cell that spreadsheets would evaluate as a formula, by
+// prefixing it with a single quote, so that values such as resource IDs or
+// emails cannot run commands when the export is opened.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ndjsonExportWriter writes entries as one JSON object per line, in the
+// format of the list endpoint.
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+	f   flusher
+}
+
+func newNDJSONExportWriter(w http.ResponseWriter) *ndjsonExportWriter {
+	return &ndjsonExportWriter{enc: json.NewEncoder(w), f: flusher{rc: http.NewResponseController(w)}}
+}
+
+func (n *ndjsonExportWriter) header() error {
+	return nil
+}
+
+func (n *ndjsonExportWriter) write(e *AuditEntry) error {
+	if err := n.enc.Encode(e); err != nil {
+		return err
+	}
+	if n.f.due() {
+		return n.flush()
+	}
+	return nil
+}
+
+func (n *ndjsonExportWriter) flush() error {
+	return n.f.flush()
+}
+
+// deref returns the string s points to, or "" if s is nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -1,9 +1,13 @@
 package audit
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/GyroZepelix/mithril-cms/internal/server"
 )
@@ -18,18 +22,26 @@ func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
-// List handles GET /admin/api/audit-log. It returns a paginated list of audit
-// entries, optionally filtered by action and/or resource.
-func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
+// uuidPattern matches a UUID string.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 
-	filters := AuditFilters{
-		Action:   q.Get("action"),
-		Resource: q.Get("resource"),
+// List handles GET /admin/api/audit-log. It returns a list of audit entries,
+// optionally filtered (see parseFilters). Requests with a cursor parameter
+// are paginated by keyset: an empty cursor starts with the newest entry, and
+// meta.next_cursor continues the list. Other requests are paginated by page.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	filters, ok := parseFilters(w, r)
+	if !ok {
+		return
 	}
 
 	page, perPage := parsePagination(r)
 
+	if r.URL.Query().Has("cursor") {
+		h.listAfter(w, r, filters, perPage)
+		return
+	}
+
 	entries, total, err := h.service.List(r.Context(), filters, page, perPage)
 	if err != nil {
 		slog.Error("audit log list failed", "error", err)
@@ -51,6 +63,173 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// listAfter writes a page of audit entries after the request's cursor.
+func (h *Handler) listAfter(w http.ResponseWriter, r *http.Request, filters AuditFilters, perPage int) {
+	var after *Cursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		cursor, err := ParseCursor(v)
+		if err != nil {
+			server.Error(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor is invalid", nil)
+			return
+		}
+		after = &cursor
+	}
+
+	entries, next, err := h.service.ListAfter(r.Context(), filters, after, perPage)
+	if err != nil {
+		slog.Error("audit log list failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"an internal error occurred", nil)
+		return
+	}
+
+	meta := server.CursorMeta{PerPage: perPage}
+	if next != nil {
+		meta.NextCursor = next.String()
+	}
+	server.CursorPaginated(w, entries, meta)
+}
+
+// Export handles GET /admin/api/audit-log/export. It streams all audit
+// entries matching the filters of List, oldest first, as CSV or NDJSON
+// depending on the format parameter (default csv).
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	filters, ok := parseFilters(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	var writer exportWriter
+	switch format {
+	case "csv":
+		writer = newCSVExportWriter(w)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case "ndjson":
+		writer = newNDJSONExportWriter(w)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed",
+			[]server.FieldError{{Field: "format", Message: "must be csv or ndjson"}})
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log-%s.%s"`,
+		time.Now().UTC().Format("20060102T150405Z"), format))
+
+	// Large exports may take longer than the server's write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("audit log export could not lift write deadline", "error", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := writer.header(); err != nil {
+		slog.Error("audit log export failed", "error", err)
+		return
+	}
+	// Headers are sent; failures can only be logged and end the export early.
+	err := h.service.Export(r.Context(), filters, writer.write)
+	if err == nil {
+		err = writer.flush()
+	}
+	if err != nil {
+		slog.Error("audit log export failed", "error", err)
+	}
+}
+
+// Stats handles GET /admin/api/audit-log/stats. It returns the state of the
+// audit pipeline: its mode, queue depth, event counts and write latencies.
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	server.JSON(w, http.StatusOK, h.service.Stats())
+}
+
+// Verify handles GET /admin/api/audit-log/verify. It walks the audit log's
+// hash chain and returns the VerifyResult, with the first broken link if
+// the log was tampered with.
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	// Verification reads the whole chain, which may take longer than the
+	// server's write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("audit chain verification could not lift write deadline", "error", err)
+	}
+
+	result, err := h.service.Verify(r.Context())
+	if err != nil {
+		slog.Error("audit chain verification failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"an internal error occurred", nil)
+		return
+	}
+	if !result.OK {
+		slog.Warn("audit chain is broken", "seq", result.Break.Seq, "id", result.Break.ID, "reason", result.Break.Reason)
+	}
+	server.JSON(w, http.StatusOK, result)
+}
+
+// parseFilters reads the audit log filters from the query parameters:
+// action (exact, or a prefix such as "entry.*"), resource, resource_id,
+// actor_id, token_id, and the date range from (inclusive) and to
+// (exclusive), as RFC 3339 timestamps or dates. It writes an error response
+// and returns false if a parameter is invalid.
+func parseFilters(w http.ResponseWriter, r *http.Request) (AuditFilters, bool) {
+	q := r.URL.Query()
+	filters := AuditFilters{
+		Action:     q.Get("action"),
+		Resource:   q.Get("resource"),
+		ResourceID: q.Get("resource_id"),
+		ActorID:    q.Get("actor_id"),
+		TokenID:    q.Get("token_id"),
+	}
+
+	for _, id := range []struct{ name, value string }{
+		{"resource_id", filters.ResourceID},
+		{"actor_id", filters.ActorID},
+		{"token_id", filters.TokenID},
+	} {
+		if id.value != "" && !uuidPattern.MatchString(id.value) {
+			server.Error(w, http.StatusBadRequest, "INVALID_ID", id.name+" must be a valid UUID", nil)
+			return AuditFilters{}, false
+		}
+	}
+
+	var errs []server.FieldError
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"from", &filters.From},
+		{"to", &filters.To},
+	} {
+		v := q.Get(bound.name)
+		if v == "" {
+			continue
+		}
+		t, err := parseTime(v)
+		if err != nil {
+			errs = append(errs, server.FieldError{Field: bound.name, Message: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			continue
+		}
+		*bound.dst = t
+	}
+	if len(errs) > 0 {
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", errs)
+		return AuditFilters{}, false
+	}
+	return filters, true
+}
+
+// parseTime parses an RFC 3339 timestamp, or a date as midnight UTC.
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
 // parsePagination extracts page and per_page query parameters with defaults.
 func parsePagination(r *http.Request) (page, perPage int) {
 	page = 1
@@ -5,7 +5,9 @@ package audit
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -15,21 +17,107 @@ import (
 	"github.com/GyroZepelix/mithril-cms/internal/database"
 )
 
-// AuditEntry represents a single row in the audit_log table.
+// AuditEntry represents a single row in the audit_log table, with the email
+// of its actor if the actor is an admin that still exists.
 type AuditEntry struct {
 	ID         string         `json:"id"`
 	Action     string         `json:"action"`
 	ActorID    *string        `json:"actor_id,omitempty"`
+	ActorEmail *string        `json:"actor_email,omitempty"`
 	Resource   *string        `json:"resource,omitempty"`
 	ResourceID *string        `json:"resource_id,omitempty"`
 	Payload    map[string]any `json:"payload,omitempty"`
+	TokenID    *string        `json:"token_id,omitempty"`
 	CreatedAt  time.Time      `json:"created_at"`
 }
 
 // AuditFilters holds optional filter parameters for listing audit entries.
 type AuditFilters struct {
-	Action   string // filter by action (exact match)
-	Resource string // filter by resource (exact match)
+	Action     string    // filter by action (exact match, or prefix match for e.g. "entry.*")
+	Resource   string    // filter by resource (exact match)
+	ResourceID string    // filter by resource ID (exact match)
+	ActorID    string    // filter by actor (exact match)
+	TokenID    string    // filter by personal access token (exact match)
+	From       time.Time // entries created at or after From, if set
+	To         time.Time // entries created before To, if set
+}
+
+// where builds the WHERE clause of the filters, with parameters numbered
+// from $1. It returns "" if no filter is set.
+//
+// SECURITY: All column names below are hardcoded constants and must never
+// be replaced with user input. Filter values are parameterized ($1, $2...).
+func (f AuditFilters) where() (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if prefix, ok := strings.CutSuffix(f.Action, "*"); ok {
+		add("l.action LIKE $%d", likeEscaper.Replace(prefix)+"%")
+	} else if f.Action != "" {
+		add("l.action = $%d", f.Action)
+	}
+	if f.Resource != "" {
+		add("l.resource = $%d", f.Resource)
+	}
+	if f.ResourceID != "" {
+		add("l.resource_id = $%d", f.ResourceID)
+	}
+	if f.ActorID != "" {
+		add("l.actor_id = $%d", f.ActorID)
+	}
+	if f.TokenID != "" {
+		add("l.token_id = $%d", f.TokenID)
+	}
+	if !f.From.IsZero() {
+		add("l.created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("l.created_at < $%d", f.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the LIKE wildcards in an action prefix.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Cursor is a position in the audit log for keyset pagination. Listing after
+// it continues with older entries, or as old ones with a smaller ID.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// ErrInvalidCursor is returned by ParseCursor for malformed cursors.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// String encodes the cursor as an opaque URL-safe string.
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// ParseCursor decodes a cursor encoded by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || !uuidPattern.MatchString(id) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: createdAt, ID: id}, nil
 }
 
 // Repository provides database operations for the audit_log table.
@@ -42,63 +130,249 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
-// Insert writes a single audit event to the database. Empty string values for
-// ActorID, Resource, and ResourceID are stored as NULL.
+// ErrInvalidEvent is returned by InsertBatch for events that cannot be
+// stored, such as events with a payload that cannot be encoded as JSON.
+var ErrInvalidEvent = errors.New("invalid audit event")
+
+// Insert writes a single audit event to the database.
 func (r *Repository) Insert(ctx context.Context, event Event) error {
-	var payloadJSON []byte
-	if event.Payload != nil {
-		var err error
-		payloadJSON, err = json.Marshal(event.Payload)
+	_, err := r.InsertBatch(ctx, []Event{event})
+	return err
+}
+
+// insertColumns is the number of values inserted per event by InsertBatch.
+const insertColumns = 10
+
+// InsertBatch writes audit events to the database in one transaction and
+// returns how many were inserted. Events are identified by their ID, so
+// events that were already written are skipped; events without an ID or
+// time are given a new ID and the current time. Empty string values for
+// ActorID, Resource, ResourceID and TokenID are stored as NULL.
+//
+// Each inserted entry is appended to the hash chain: it is numbered after the
+// newest chained entry, and its hash covers that entry's hash and its own
+// contents. Appends are serialized by an advisory lock.
+func (r *Repository) InsertBatch(ctx context.Context, events []Event) (int64, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockID); err != nil {
+		return 0, fmt.Errorf("locking audit chain: %w", err)
+	}
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		if event.ID != "" {
+			ids = append(ids, event.ID)
+		}
+	}
+	written := make(map[string]bool)
+	if len(ids) > 0 {
+		rows, err := tx.Query(ctx, `SELECT id FROM audit_log WHERE id = ANY($1::uuid[])`, ids)
 		if err != nil {
-			return fmt.Errorf("marshaling audit payload: %w", err)
+			return 0, fmt.Errorf("checking audit events: %w", err)
+		}
+		existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return 0, fmt.Errorf("checking audit events: %w", err)
+		}
+		for _, id := range existing {
+			written[strings.ToLower(id)] = true
 		}
 	}
 
-	_, err := r.db.Pool().Exec(ctx,
-		`INSERT INTO audit_log (action, actor_id, resource, resource_id, payload)
-		 VALUES ($1, $2, $3, $4, $5)`,
-		event.Action,
-		nullIfEmpty(event.ActorID),
-		nullIfEmpty(event.Resource),
-		nullIfEmpty(event.ResourceID),
-		nullableJSON(payloadJSON),
-	)
+	seq, prev, err := scanChainHead(tx.QueryRow(ctx, chainHeadQuery))
+	if err != nil {
+		return 0, err
+	}
+	if prev == nil {
+		prev = genesisHash
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`INSERT INTO audit_log (seq, hash, id, action, actor_id, resource, resource_id, payload, token_id, created_at) VALUES `)
+	args := make([]any, 0, len(events)*insertColumns)
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = newEventID()
+		}
+		if written[strings.ToLower(event.ID)] {
+			continue
+		}
+		written[strings.ToLower(event.ID)] = true
+		if event.Time.IsZero() {
+			event.Time = time.Now()
+		}
+
+		var payloadJSON []byte
+		if event.Payload != nil {
+			payloadJSON, err = json.Marshal(event.Payload)
+			if err != nil {
+				return 0, fmt.Errorf("%w: marshaling payload: %v", ErrInvalidEvent, err)
+			}
+		}
+		row := chainRow{
+			Seq:        seq + 1,
+			ID:         event.ID,
+			CreatedAt:  chainTime(event.Time),
+			Action:     event.Action,
+			ActorID:    nullIfEmpty(event.ActorID),
+			Resource:   nullIfEmpty(event.Resource),
+			ResourceID: nullIfEmpty(event.ResourceID),
+			TokenID:    nullIfEmpty(event.TokenID),
+			Payload:    payloadJSON,
+		}
+		row.Hash, err = row.digest(prev)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		seq, prev = row.Seq, row.Hash
+
+		if len(args) > 0 {
+			sb.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10)
+		args = append(args,
+			row.Seq,
+			row.Hash,
+			row.ID,
+			row.Action,
+			row.ActorID,
+			row.Resource,
+			row.ResourceID,
+			nullableJSON(payloadJSON),
+			row.TokenID,
+			row.CreatedAt,
+		)
+	}
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	tag, err := tx.Exec(ctx, sb.String(), args...)
+	if err != nil {
+		return 0, fmt.Errorf("inserting audit events: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing audit events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// chainHeadQuery selects the sequence number and hash of the newest entry in
+// the hash chain.
+const chainHeadQuery = `SELECT seq, hash FROM audit_log WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1`
+
+// scanChainHead scans the result of chainHeadQuery. It returns 0 and nil if
+// the chain is empty.
+func scanChainHead(row pgx.Row) (int64, []byte, error) {
+	var seq int64
+	var hash []byte
+	if err := row.Scan(&seq, &hash); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, fmt.Errorf("reading audit chain head: %w", err)
+	}
+	return seq, hash, nil
+}
+
+// chainHead returns the sequence number and hash of the newest entry in the
+// hash chain, or 0 and nil if the chain is empty.
+func (r *Repository) chainHead(ctx context.Context) (int64, []byte, error) {
+	return scanChainHead(r.db.Pool().QueryRow(ctx, chainHeadQuery))
+}
+
+// walkChain calls fn for every entry in the hash chain in sequence order,
+// without holding them all in memory, until fn returns false or an error.
+func (r *Repository) walkChain(ctx context.Context, fn func(chainRow) (bool, error)) error {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT seq, id, created_at, action, actor_id, resource, resource_id, token_id, payload, hash
+		 FROM audit_log WHERE seq IS NOT NULL ORDER BY seq`)
 	if err != nil {
-		return fmt.Errorf("inserting audit event: %w", err)
+		return fmt.Errorf("querying audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row chainRow
+		if err := rows.Scan(&row.Seq, &row.ID, &row.CreatedAt, &row.Action, &row.ActorID, &row.Resource,
+			&row.ResourceID, &row.TokenID, &row.Payload, &row.Hash); err != nil {
+			return fmt.Errorf("scanning audit chain entry: %w", err)
+		}
+		more, err := fn(row)
+		if err != nil || !more {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading audit chain: %w", err)
 	}
 	return nil
 }
 
+// unchained returns the number of entries outside the hash chain, and the
+// ID of the oldest of them created after the chain began, if any. Entries
+// written before the chain was introduced are expected; later ones were
+// inserted around it.
+func (r *Repository) unchained(ctx context.Context) (int64, string, error) {
+	var count int64
+	if err := r.db.Pool().QueryRow(ctx, `SELECT COUNT(*) FROM audit_log WHERE seq IS NULL`).Scan(&count); err != nil {
+		return 0, "", fmt.Errorf("counting unchained audit entries: %w", err)
+	}
+	if count == 0 {
+		return 0, "", nil
+	}
+
+	var id string
+	err := r.db.Pool().QueryRow(ctx,
+		`SELECT id FROM audit_log
+		 WHERE seq IS NULL AND created_at >= (SELECT MIN(created_at) FROM audit_log WHERE seq IS NOT NULL)
+		 ORDER BY created_at, id LIMIT 1`,
+	).Scan(&id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", fmt.Errorf("finding unchained audit entries: %w", err)
+	}
+	return count, id, nil
+}
+
+// entryColumns is the column list scanned by scanEntry, for audit_log as l
+// joined with the admins table as a.
+const entryColumns = `l.id, l.action, l.actor_id, a.email, l.resource, l.resource_id, l.payload, l.token_id, l.created_at`
+
+// entryFrom is the FROM clause selecting entryColumns.
+const entryFrom = `FROM audit_log l LEFT JOIN admins a ON a.id = l.actor_id`
+
+func scanEntry(row pgx.Row) (*AuditEntry, error) {
+	var e AuditEntry
+	var payloadJSON []byte
+	if err := row.Scan(&e.ID, &e.Action, &e.ActorID, &e.ActorEmail, &e.Resource, &e.ResourceID,
+		&payloadJSON, &e.TokenID, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	if payloadJSON != nil {
+		if err := json.Unmarshal(payloadJSON, &e.Payload); err != nil {
+			return nil, fmt.Errorf("unmarshaling audit payload: %w", err)
+		}
+	}
+	return &e, nil
+}
+
 // List retrieves a paginated, filtered list of audit entries ordered by
 // created_at DESC. It returns the entries, total count, and any error.
 // The caller (service/handler layer) is responsible for validating and
 // clamping pagination parameters.
 func (r *Repository) List(ctx context.Context, filters AuditFilters, page, perPage int) ([]*AuditEntry, int, error) {
-	// Build WHERE clause from filters.
-	// SECURITY: All column names below are hardcoded constants and must never
-	// be replaced with user input. Filter values are parameterized ($1, $2...).
-	var conditions []string
-	var args []any
-	paramIdx := 1
-
-	if filters.Action != "" {
-		conditions = append(conditions, fmt.Sprintf("action = $%d", paramIdx))
-		args = append(args, filters.Action)
-		paramIdx++
-	}
-	if filters.Resource != "" {
-		conditions = append(conditions, fmt.Sprintf("resource = $%d", paramIdx))
-		args = append(args, filters.Resource)
-		paramIdx++
-	}
-
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
-	}
+	whereClause, args := filters.where()
 
 	// Count total matching rows.
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM audit_log %s", whereClause)
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM audit_log l %s", whereClause)
 	var total int
 	if err := r.db.Pool().QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("counting audit entries: %w", err)
@@ -107,38 +381,100 @@ func (r *Repository) List(ctx context.Context, filters AuditFilters, page, perPa
 	// Fetch the page.
 	offset := (page - 1) * perPage
 	selectQuery := fmt.Sprintf(
-		`SELECT id, action, actor_id, resource, resource_id, payload, created_at
-		 FROM audit_log %s
-		 ORDER BY created_at DESC
+		`SELECT %s %s %s
+		 ORDER BY l.created_at DESC, l.id DESC
 		 LIMIT $%d OFFSET $%d`,
-		whereClause, paramIdx, paramIdx+1,
+		entryColumns, entryFrom, whereClause, len(args)+1, len(args)+2,
 	)
 	args = append(args, perPage, offset)
 
-	rows, err := r.db.Pool().Query(ctx, selectQuery, args...)
+	entries, err := r.query(ctx, selectQuery, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("querying audit entries: %w", err)
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// ListAfter retrieves up to limit filtered audit entries ordered by
+// created_at DESC, starting after the cursor, or with the newest entry if it
+// is nil. Unlike List it does not count the matching entries, and pages stay
+// stable while entries are added. The returned cursor continues after the
+// last entry; it is nil if there are no more.
+func (r *Repository) ListAfter(ctx context.Context, filters AuditFilters, after *Cursor, limit int) ([]*AuditEntry, *Cursor, error) {
+	whereClause, args := filters.where()
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		keyset := fmt.Sprintf("(l.created_at, l.id) < ($%d, $%d)", len(args)-1, len(args))
+		if whereClause == "" {
+			whereClause = "WHERE " + keyset
+		} else {
+			whereClause += " AND " + keyset
+		}
+	}
+
+	// Fetch one more entry than requested to know whether there are more.
+	query := fmt.Sprintf(
+		`SELECT %s %s %s
+		 ORDER BY l.created_at DESC, l.id DESC
+		 LIMIT $%d`,
+		entryColumns, entryFrom, whereClause, len(args)+1,
+	)
+	args = append(args, limit+1)
+
+	entries, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(entries) <= limit {
+		return entries, nil, nil
+	}
+	entries = entries[:limit]
+	last := entries[limit-1]
+	return entries, &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
+// Export calls fn for every filtered audit entry, oldest first, without
+// holding them all in memory. It stops at the first error fn returns.
+func (r *Repository) Export(ctx context.Context, filters AuditFilters, fn func(*AuditEntry) error) error {
+	whereClause, args := filters.where()
+	rows, err := r.db.Pool().Query(ctx,
+		fmt.Sprintf(`SELECT %s %s %s ORDER BY l.created_at, l.id`, entryColumns, entryFrom, whereClause),
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("querying audit entries: %w", err)
 	}
 	defer rows.Close()
 
-	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*AuditEntry, error) {
-		var e AuditEntry
-		var payloadJSON []byte
-		if err := row.Scan(&e.ID, &e.Action, &e.ActorID, &e.Resource, &e.ResourceID, &payloadJSON, &e.CreatedAt); err != nil {
-			return nil, err
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return fmt.Errorf("scanning audit entry: %w", err)
 		}
-		if payloadJSON != nil {
-			if err := json.Unmarshal(payloadJSON, &e.Payload); err != nil {
-				return nil, fmt.Errorf("unmarshaling audit payload: %w", err)
-			}
+		if err := fn(e); err != nil {
+			return err
 		}
-		return &e, nil
-	})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading audit entries: %w", err)
+	}
+	return nil
+}
+
+// query runs a query selecting entryColumns and collects the entries.
+func (r *Repository) query(ctx context.Context, query string, args ...any) ([]*AuditEntry, error) {
+	rows, err := r.db.Pool().Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("scanning audit entries: %w", err)
+		return nil, fmt.Errorf("querying audit entries: %w", err)
 	}
 
-	return entries, total, nil
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*AuditEntry, error) {
+		return scanEntry(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning audit entries: %w", err)
+	}
+	return entries, nil
 }
 
 // nullIfEmpty returns nil if s is empty, otherwise returns a pointer to s.
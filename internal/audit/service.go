@@ -2,76 +2,251 @@ package audit
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync/atomic"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/database"
 )
 
 const (
 	// eventChannelSize is the buffer size for the async event channel.
-	// If the channel is full, events are dropped with a warning log.
 	eventChannelSize = 256
+
+	// batchSize is the maximum number of events written in one insert.
+	batchSize = 100
+
+	// writeAttempts is how often events queued in memory are tried to be
+	// written before they are counted as failed and lost in ModeDrop, and
+	// in the other modes once shutting down.
+	writeAttempts = 3
+
+	// retryDelay is the wait before retrying a failed write. It doubles
+	// with each further failure, up to maxRetryDelay.
+	retryDelay    = 100 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+// Mode selects what happens to audit events that arrive faster than they
+// can be written, or cannot be written at all.
+type Mode string
+
+const (
+	// ModeDrop drops events when the queue is full, so that audit logging
+	// never slows requests down. Events that fail to be written are lost.
+	ModeDrop Mode = "drop"
+
+	// ModeBlock makes Log wait for room in the queue, slowing requests down
+	// to the speed of the database instead of dropping events. Events that
+	// fail to be written are retried until Shutdown, so that an outage fills
+	// the queue and blocks requests; events still queued in a crash are lost.
+	ModeBlock Mode = "block"
+
+	// ModeWAL appends every event to an on-disk write-ahead log before Log
+	// returns. Events are kept until they are written, across database
+	// outages and restarts. It is the only lossless mode.
+	ModeWAL Mode = "wal"
 )
 
+// ParseMode parses "drop", "block" or "wal".
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeDrop, ModeBlock, ModeWAL:
+		return m, nil
+	}
+	return "", fmt.Errorf("audit mode must be 'drop', 'block' or 'wal', got %q", s)
+}
+
 // Event represents an audit event to be logged.
 type Event struct {
-	Action     string         // e.g., "entry.create", "admin.login.success"
-	ActorID    string         // admin UUID (can be empty for login failures)
-	Resource   string         // e.g., "blog_posts", "media"
-	ResourceID string         // UUID of affected resource
-	Payload    map[string]any // additional context data
+	ID         string         `json:"id"`          // set by Log; identifies the event when it is written again
+	Time       time.Time      `json:"time"`        // set by Log; stored as created_at
+	Action     string         `json:"action"`      // e.g., "entry.create", "admin.login.success"
+	ActorID    string         `json:"actor_id"`    // admin UUID (can be empty for login failures)
+	Resource   string         `json:"resource"`    // e.g., "blog_posts", "media"
+	ResourceID string         `json:"resource_id"` // UUID of affected resource
+	Payload    map[string]any `json:"payload"`     // additional context data
+	TokenID    string         `json:"token_id"`    // personal access token the action was taken with
+}
+
+// contextKey is the type of the audit context keys.
+type contextKey string
+
+// contextKeyTokenID is the context key for the personal access token a
+// request was made with.
+const contextKeyTokenID contextKey = "token_id"
+
+// WithTokenID returns a copy of ctx recording that the request was made with
+// the personal access token tokenID. Events logged with the context are
+// attributed to the token.
+func WithTokenID(ctx context.Context, tokenID string) context.Context {
+	return context.WithValue(ctx, contextKeyTokenID, tokenID)
 }
 
-// Service provides asynchronous audit logging. Events are sent to a buffered
-// channel and written to the database by a background goroutine, ensuring
-// that audit logging never blocks or fails API requests.
+// TokenIDFromContext returns the token ID set by WithTokenID, or "" if the
+// request was not made with a personal access token.
+func TokenIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(contextKeyTokenID).(string)
+	return v
+}
+
+// Service provides asynchronous audit logging. Events are queued and written
+// to the database in batches by a background goroutine, so that audit
+// logging never fails API requests. What happens when the database is slow
+// or unavailable depends on the service's Mode.
 type Service struct {
-	repo         *Repository
-	eventCh      chan Event
-	done         chan struct{}
+	repo       *Repository
+	mode       Mode
+	wal        *wal
+	eventCh    chan Event
+	walCh      chan struct{} // signals events appended to the write-ahead log
+	stop       chan struct{} // closed by Shutdown to end retries of queued events
+	done       chan struct{}
+	diffPolicy DiffPolicy
+
+	// checkpoints writes signed checkpoints of the hash chain, if set by
+	// SetCheckpoints.
+	checkpoints *checkpointer
+
 	droppedCount atomic.Uint64 // count of events dropped due to full channel
+	failedCount  atomic.Uint64 // count of events that could not be written
+	writtenCount atomic.Uint64 // count of events written
+	writeCount   atomic.Uint64 // count of inserts
+	lastWrite    atomic.Int64  // duration of the last insert in nanoseconds
+	maxWrite     atomic.Int64
+	totalWrite   atomic.Int64
 }
 
-// NewService creates a new audit Service with the given repository.
-// Call Start() to begin processing events, and Shutdown() to drain and stop.
+// NewService creates a new audit Service with the given repository, in
+// ModeDrop. Call Start() to begin processing events, and Shutdown() to drain
+// and stop.
 func NewService(repo *Repository) *Service {
 	return &Service{
 		repo:    repo,
+		mode:    ModeDrop,
 		eventCh: make(chan Event, eventChannelSize),
+		walCh:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
 		done:    make(chan struct{}),
 	}
 }
 
-// Log sends an audit event for asynchronous persistence. It never blocks the
-// caller. If the internal channel is full, the event is dropped and a warning
-// is logged.
+// SetMode sets how events are queued. In ModeWAL the write-ahead log is kept
+// in walDir, and events left in it by a previous run are written after Start.
+// Must be called before Start.
+func (s *Service) SetMode(mode Mode, walDir string) error {
+	if mode == ModeWAL {
+		w, err := openWAL(walDir)
+		if err != nil {
+			return err
+		}
+		if n := w.pending.Load(); n > 0 {
+			slog.Info("audit WAL has unwritten events, replaying", "events", n)
+		}
+		s.wal = w
+	}
+	s.mode = mode
+	return nil
+}
+
+// SetDiffPolicy sets the redaction and truncation of the changes returned by
+// Diff.
+func (s *Service) SetDiffPolicy(policy DiffPolicy) {
+	s.diffPolicy = policy
+}
+
+// Diff returns the field-level changes from before to after of a resource,
+// as recorded under "changes" in event payloads, applying the service's
+// DiffPolicy.
+func (s *Service) Diff(resource string, before, after map[string]any) map[string]Change {
+	return s.diffPolicy.Diff(resource, before, after)
+}
+
+// Log sends an audit event for asynchronous persistence. In ModeDrop it never
+// blocks the caller: if the queue is full, the event is dropped and a warning
+// is logged. In ModeBlock it waits for room in the queue, and in ModeWAL for
+// the event to be synced to the write-ahead log. Events of requests made with
+// a personal access token are attributed to it.
 func (s *Service) Log(ctx context.Context, event Event) {
-	select {
-	case s.eventCh <- event:
-		// Event queued successfully.
-	default:
-		dropped := s.droppedCount.Add(1)
-		slog.Warn("audit event channel full, dropping event",
+	if event.TokenID == "" {
+		event.TokenID = TokenIDFromContext(ctx)
+	}
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	switch s.mode {
+	case ModeWAL:
+		err := s.wal.append(event)
+		if err == nil {
+			select {
+			case s.walCh <- struct{}{}:
+			default: // The writer has been signalled already.
+			}
+			return
+		}
+		slog.Error("failed to append audit event to WAL, queueing it in memory",
 			"action", event.Action,
-			"actor_id", event.ActorID,
-			"resource", event.Resource,
-			"resource_id", event.ResourceID,
-			"total_dropped", dropped,
+			"error", err,
 		)
+		s.eventCh <- event
+	case ModeBlock:
+		s.eventCh <- event
+	default:
+		select {
+		case s.eventCh <- event:
+			// Event queued successfully.
+		default:
+			dropped := s.droppedCount.Add(1)
+			slog.Warn("audit event channel full, dropping event",
+				"action", event.Action,
+				"actor_id", event.ActorID,
+				"resource", event.Resource,
+				"resource_id", event.ResourceID,
+				"total_dropped", dropped,
+			)
+		}
+	}
+}
+
+// newEventID returns a random (version 4) UUID for an event.
+func newEventID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic("crypto/rand failed: " + err.Error())
 	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+		uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
 }
 
-// Start begins the background goroutine that reads events from the channel
-// and writes them to the database. Must be called once after NewService.
+// Start begins the background goroutine that writes queued events to the
+// database, starting with any left in the write-ahead log. Must be called
+// once after NewService.
 func (s *Service) Start() {
 	go s.processEvents()
+	if s.checkpoints != nil {
+		go s.writeCheckpoints()
+	}
 }
 
 // Shutdown signals the background goroutine to stop, drains any remaining
 // events in the channel, and waits for completion. The provided context
 // controls the maximum time to wait; if the context times out, a warning is
 // logged, but Shutdown always waits for the background goroutine to finish
-// to prevent race conditions with database writes.
+// to prevent race conditions with database writes. Events in the write-ahead
+// log that cannot be written are kept for the next start, while events
+// queued in memory are tried writeAttempts times in every mode. A last
+// checkpoint is written if checkpoints are enabled.
 func (s *Service) Shutdown(ctx context.Context) {
+	close(s.stop)
 	close(s.eventCh)
 
 	select {
@@ -81,36 +256,229 @@ func (s *Service) Shutdown(ctx context.Context) {
 		slog.Warn("audit service shutdown timeout, still waiting for drain")
 		<-s.done // Always wait for completion even if context times out
 	}
+
+	// Checkpoint the chain including the events drained above.
+	if s.checkpoints != nil {
+		close(s.checkpoints.stop)
+		<-s.checkpoints.done
+	}
 }
 
-// processEvents is the background goroutine that reads events from the channel
-// and inserts them into the database. It runs until the channel is closed
-// (via Shutdown), then drains any remaining buffered events before signalling
-// completion on the done channel.
+// processEvents is the background goroutine that writes events to the
+// database. It runs until the channel is closed (via Shutdown), then drains
+// any remaining buffered events and makes a last attempt to write the
+// write-ahead log before signalling completion on the done channel.
 func (s *Service) processEvents() {
 	defer close(s.done)
 
-	for event := range s.eventCh {
-		s.writeEvent(event)
+	// retry fires when a failed write of the write-ahead log is due to be
+	// retried; new events do not trigger writes while it is pending.
+	var retry <-chan time.Time
+	delay := retryDelay
+	writeWAL := func() {
+		if s.writeWAL() {
+			retry, delay = nil, retryDelay
+			return
+		}
+		retry = time.After(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+	if s.wal != nil {
+		writeWAL()
+	}
+
+	for {
+		select {
+		case event, ok := <-s.eventCh:
+			if !ok {
+				s.closeWAL()
+				return
+			}
+			s.writeQueued(s.collect(event))
+		case <-s.walCh:
+			if retry == nil {
+				writeWAL()
+			}
+		case <-retry:
+			writeWAL()
+		}
 	}
 }
 
-// writeEvent inserts a single event into the database. Errors are logged but
-// never propagated, so a database failure cannot break the caller.
-func (s *Service) writeEvent(event Event) {
-	// Use a background context because the original request context may
-	// already be cancelled by the time we process the event.
-	ctx := context.Background()
+// collect returns first and the events queued behind it, up to batchSize.
+func (s *Service) collect(first Event) []Event {
+	batch := []Event{first}
+	for len(batch) < batchSize {
+		select {
+		case event, ok := <-s.eventCh:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// writeQueued writes events queued in memory, trying writeAttempts times
+// before they are counted as failed. Unless in ModeDrop it keeps retrying
+// until Shutdown, holding up the queue so that Log waits instead of events
+// being lost.
+func (s *Service) writeQueued(events []Event) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := s.write(events)
+		if err == nil {
+			return
+		}
+		if attempt >= writeAttempts {
+			if !s.retrying() {
+				failed := s.failedCount.Add(uint64(len(events)))
+				slog.Error("failed to write audit events",
+					"events", len(events),
+					"total_failed", failed,
+					"error", err,
+				)
+				return
+			}
+			slog.Warn("failed to write audit events, retrying",
+				"events", len(events),
+				"attempt", attempt,
+				"retry_in", delay,
+				"error", err,
+			)
+		}
+		select {
+		case <-time.After(delay):
+		case <-s.stop:
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// retrying reports whether failed writes of events queued in memory are
+// retried until they succeed, which they are in ModeBlock, and for events
+// that could not be appended to the write-ahead log, until Shutdown.
+func (s *Service) retrying() bool {
+	if s.mode == ModeDrop {
+		return false
+	}
+	select {
+	case <-s.stop:
+		return false
+	default:
+		return true
+	}
+}
+
+// writeWAL writes the sealed segments of the write-ahead log to the
+// database, removing each once it is written, and reports whether all were
+// written.
+func (s *Service) writeWAL() bool {
+	if err := s.wal.rotate(); err != nil {
+		slog.Error("failed to seal audit WAL segment", "error", err)
+	}
+	paths, err := s.wal.sealed()
+	if err != nil {
+		slog.Error("failed to list audit WAL segments", "error", err)
+		return false
+	}
+
+	for _, path := range paths {
+		events, err := readSegment(path)
+		if err != nil {
+			slog.Error("failed to read audit WAL segment", "segment", path, "error", err)
+			return false
+		}
+		for start := 0; start < len(events); start += batchSize {
+			if err := s.write(events[start:min(start+batchSize, len(events))]); err != nil {
+				slog.Error("failed to write audit WAL, retrying later",
+					"segment", path,
+					"pending", s.wal.pending.Load(),
+					"error", err,
+				)
+				return false
+			}
+		}
+		if err := s.wal.remove(path, len(events)); err != nil {
+			slog.Error("failed to remove written audit WAL segment", "segment", path, "error", err)
+			return false
+		}
+	}
+	return true
+}
+
+// closeWAL makes a last attempt to write the write-ahead log on shutdown.
+func (s *Service) closeWAL() {
+	if s.wal == nil {
+		return
+	}
+	if !s.writeWAL() {
+		slog.Warn("audit WAL not fully written, the remaining events are written on the next start",
+			"pending", s.wal.pending.Load())
+	}
+}
 
-	if err := s.repo.Insert(ctx, event); err != nil {
-		slog.Error("failed to write audit event",
+// write inserts events into the database, recording the insert's duration.
+// If the database rejects the values of the batch, the events are written
+// one at a time and those rejected are logged and counted as failed, so that
+// one invalid event cannot hold up the others. Other errors are returned for
+// the caller to retry.
+func (s *Service) write(events []Event) error {
+	err := s.insert(events)
+	if err == nil || !isInvalid(err) {
+		return err
+	}
+	for _, event := range events {
+		if len(events) > 1 {
+			err = s.insert([]Event{event})
+		}
+		if err == nil {
+			continue
+		}
+		if !isInvalid(err) {
+			return err
+		}
+		failed := s.failedCount.Add(1)
+		slog.Error("audit event rejected by the database",
+			"id", event.ID,
 			"action", event.Action,
-			"actor_id", event.ActorID,
 			"resource", event.Resource,
 			"resource_id", event.ResourceID,
+			"total_failed", failed,
 			"error", err,
 		)
 	}
+	return nil
+}
+
+// insert inserts events into the database and records the insert's metrics.
+func (s *Service) insert(events []Event) error {
+	// Use a background context because the original request context may
+	// already be cancelled by the time we process the event.
+	start := time.Now()
+	n, err := s.repo.InsertBatch(context.Background(), events)
+	d := int64(time.Since(start))
+
+	s.writeCount.Add(1)
+	s.lastWrite.Store(d)
+	s.totalWrite.Add(d)
+	for {
+		m := s.maxWrite.Load()
+		if d <= m || s.maxWrite.CompareAndSwap(m, d) {
+			break
+		}
+	}
+	s.writtenCount.Add(uint64(n))
+	return err
+}
+
+// isInvalid reports whether err means that events cannot be written however
+// often they are tried.
+func isInvalid(err error) bool {
+	return errors.Is(err, ErrInvalidEvent) || database.IsDataError(err)
 }
 
 // DroppedCount returns the total number of events dropped since service start.
@@ -119,8 +487,70 @@ func (s *Service) DroppedCount() uint64 {
 	return s.droppedCount.Load()
 }
 
+// Stats describes the state of the audit pipeline, for monitoring.
+type Stats struct {
+	Mode Mode `json:"mode"`
+	// QueueDepth is the number of events queued in memory.
+	QueueDepth int `json:"queue_depth"`
+	// WALPending is the number of events in the write-ahead log that are
+	// not written yet (ModeWAL only).
+	WALPending int64 `json:"wal_pending"`
+	// Written, Dropped and Failed count the events written, dropped because
+	// the queue was full, and lost because they could not be written, since
+	// the service started.
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+	// Writes counts the inserts, each of up to 100 events, and the
+	// latencies describe their durations in milliseconds.
+	Writes             uint64  `json:"writes"`
+	LastWriteLatencyMS float64 `json:"last_write_latency_ms"`
+	MeanWriteLatencyMS float64 `json:"mean_write_latency_ms"`
+	MaxWriteLatencyMS  float64 `json:"max_write_latency_ms"`
+}
+
+// Stats returns the current state of the audit pipeline.
+func (s *Service) Stats() Stats {
+	stats := Stats{
+		Mode:               s.mode,
+		QueueDepth:         len(s.eventCh),
+		Written:            s.writtenCount.Load(),
+		Dropped:            s.droppedCount.Load(),
+		Failed:             s.failedCount.Load(),
+		Writes:             s.writeCount.Load(),
+		LastWriteLatencyMS: milliseconds(s.lastWrite.Load()),
+		MaxWriteLatencyMS:  milliseconds(s.maxWrite.Load()),
+	}
+	if s.wal != nil {
+		stats.WALPending = s.wal.pending.Load()
+	}
+	if stats.Writes > 0 {
+		stats.MeanWriteLatencyMS = milliseconds(s.totalWrite.Load() / int64(stats.Writes))
+	}
+	return stats
+}
+
+// milliseconds converts nanoseconds to milliseconds.
+func milliseconds(ns int64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
+
 // List retrieves a paginated, filtered list of audit entries ordered by
 // created_at DESC. It returns the entries, total count, and any error.
 func (s *Service) List(ctx context.Context, filters AuditFilters, page, perPage int) ([]*AuditEntry, int, error) {
 	return s.repo.List(ctx, filters, page, perPage)
 }
+
+// ListAfter retrieves up to limit filtered audit entries ordered by
+// created_at DESC, starting after the cursor, or with the newest entry if it
+// is nil. The returned cursor continues after the last entry; it is nil if
+// there are no more.
+func (s *Service) ListAfter(ctx context.Context, filters AuditFilters, after *Cursor, limit int) ([]*AuditEntry, *Cursor, error) {
+	return s.repo.ListAfter(ctx, filters, after, limit)
+}
+
+// Export calls fn for every filtered audit entry, oldest first. It stops at
+// the first error fn returns.
+func (s *Service) Export(ctx context.Context, filters AuditFilters, fn func(*AuditEntry) error) error {
+	return s.repo.Export(ctx, filters, fn)
+}
@@ -3,6 +3,7 @@ package audit
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -48,6 +49,7 @@ func TestShutdown_AlwaysWaitsForDone(t *testing.T) {
 	// Create a service that simulates a slow drain.
 	s := &Service{
 		eventCh: make(chan Event),
+		stop:    make(chan struct{}),
 		done:    make(chan struct{}),
 	}
 
@@ -142,3 +144,210 @@ func TestParsePagination(t *testing.T) {
 		})
 	}
 }
+
+func TestLog_TokenIDFromContext(t *testing.T) {
+	s := &Service{eventCh: make(chan Event, 2), done: make(chan struct{})}
+	ctx := WithTokenID(context.Background(), "token-1")
+
+	s.Log(ctx, Event{Action: "entry.create"})
+	s.Log(ctx, Event{Action: "entry.update", TokenID: "token-2"})
+
+	if got := (<-s.eventCh).TokenID; got != "token-1" {
+		t.Errorf("TokenID = %q, want token-1 from the context", got)
+	}
+	if got := (<-s.eventCh).TokenID; got != "token-2" {
+		t.Errorf("TokenID = %q, want explicit token-2", got)
+	}
+	if got := TokenIDFromContext(context.Background()); got != "" {
+		t.Errorf("TokenIDFromContext(empty) = %q, want empty", got)
+	}
+}
+
+func TestAuditFilters_Where(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		filters  AuditFilters
+		wantSQL  string
+		wantArgs []any
+	}{
+		{"none", AuditFilters{}, "", nil},
+		{"exact action", AuditFilters{Action: "entry.create"}, "WHERE l.action = $1", []any{"entry.create"}},
+		{"action prefix", AuditFilters{Action: "entry.*"}, "WHERE l.action LIKE $1", []any{"entry.%"}},
+		{"escaped prefix", AuditFilters{Action: `a_b%*`}, "WHERE l.action LIKE $1", []any{`a\_b\%%`}},
+		{
+			"combined",
+			AuditFilters{ActorID: "actor", ResourceID: "res", From: from},
+			"WHERE l.resource_id = $1 AND l.actor_id = $2 AND l.created_at >= $3",
+			[]any{"res", "actor", from},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.filters.where()
+			if sql != tt.wantSQL {
+				t.Errorf("where() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("where() args = %v, want %v", args, tt.wantArgs)
+			}
+			for i := range args {
+				if args[i] != tt.wantArgs[i] {
+					t.Errorf("arg %d = %v, want %v", i, args[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{
+		CreatedAt: time.Date(2025, 1, 15, 10, 30, 0, 123456000, time.UTC),
+		ID:        "550e8400-e29b-41d4-a716-446655440000",
+	}
+
+	got, err := ParseCursor(c.String())
+	if err != nil {
+		t.Fatalf("ParseCursor() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Errorf("ParseCursor() = %+v, want %+v", got, c)
+	}
+
+	for _, s := range []string{"!!", "bm9waXBl", Cursor{CreatedAt: c.CreatedAt, ID: "nope"}.String()} {
+		if _, err := ParseCursor(s); err != ErrInvalidCursor {
+			t.Errorf("ParseCursor(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantFrom   time.Time
+	}{
+		{"action=entry.*&resource=posts", 0, time.Time{}},
+		{"from=2025-01-15", 0, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"from=2025-01-15T10:00:00Z", 0, time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)},
+		{"from=yesterday", http.StatusBadRequest, time.Time{}},
+		{"to=15/01/2025", http.StatusBadRequest, time.Time{}},
+		{"actor_id=jane", http.StatusBadRequest, time.Time{}},
+		{"resource_id=nope", http.StatusBadRequest, time.Time{}},
+		{"token_id=nope", http.StatusBadRequest, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			filters, ok := parseFilters(rec, httptest.NewRequest(http.MethodGet, "/audit-log?"+tt.query, nil))
+			if ok != (tt.wantStatus == 0) {
+				t.Fatalf("parseFilters() ok = %v, status %d", ok, rec.Code)
+			}
+			if !ok && rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !filters.From.Equal(tt.wantFrom) {
+				t.Errorf("From = %v, want %v", filters.From, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestHandler_InvalidParameters(t *testing.T) {
+	// Invalid parameters are rejected before the service is used.
+	h := NewHandler(nil)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		query   string
+	}{
+		{"list cursor", h.List, "cursor=nope"},
+		{"export format", h.Export, "format=xml"},
+		{"export date", h.Export, "to=tomorrow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/audit-log?"+tt.query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newCSVExportWriter(rec)
+	actor, email, resource := "actor-id", "jane@example.com", "posts"
+
+	if err := w.header(); err != nil {
+		t.Fatalf("header() error = %v", err)
+	}
+	entries := []*AuditEntry{
+		{
+			ID:         "1",
+			Action:     "entry.create",
+			ActorID:    &actor,
+			ActorEmail: &email,
+			Resource:   &resource,
+			Payload:    map[string]any{"title": "Hello, world"},
+			CreatedAt:  time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+		},
+		{ID: "2", Action: "auth.login_failed", CreatedAt: time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+	}
+	for _, e := range entries {
+		if err := w.write(e); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+
+	want := "id,created_at,action,actor_id,actor_email,token_id,resource,resource_id,payload\n" +
+		`1,2025-01-15T10:30:00Z,entry.create,actor-id,jane@example.com,,posts,,"{""title"":""Hello, world""}"` + "\n" +
+		"2,2025-01-15T11:00:00Z,auth.login_failed,,,,,,\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCSVExportWriter_Formulas(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newCSVExportWriter(rec)
+	email, resource, resourceID := "@SUM(1+1)@example.com", "+posts", "-2+3"
+
+	entry := &AuditEntry{
+		ID:         "1",
+		Action:     "=HYPERLINK(\"http://evil.example\")",
+		ActorEmail: &email,
+		Resource:   &resource,
+		ResourceID: &resourceID,
+		Payload:    map[string]any{"title": "=1+1"},
+		CreatedAt:  time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+	}
+	if err := w.write(entry); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	if err := w.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+
+	want := `1,2025-01-15T10:30:00Z,"'=HYPERLINK(""http://evil.example"")",,'@SUM(1+1)@example.com,,'+posts,'-2+3,"{""title"":""=1+1""}"` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+
+	for _, s := range []string{"", "jane@example.com", "entry.create", "2025-01-15"} {
+		if got := csvCell(s); got != s {
+			t.Errorf("csvCell(%q) = %q, want unchanged", s, got)
+		}
+	}
+	if got := csvCell("\t=1"); got != "'\t=1" {
+		t.Errorf("csvCell(%q) = %q, want quoted", "\t=1", got)
+	}
+}
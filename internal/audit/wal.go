@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// walSuffix is the file extension of write-ahead log segments.
+const walSuffix = ".wal"
+
+// wal is an on-disk write-ahead log of audit events. Events are appended as
+// JSON lines to the active segment and synced before Log returns. The writer
+// seals the active segment, writes sealed segments to the database and
+// removes them once written, so segments left behind by a crash or an
+// outage are written on the next attempt or the next start.
+type wal struct {
+	dir string
+
+	mu     sync.Mutex
+	active *os.File // nil until the first append after a rotation
+	seq    uint64   // number of the newest segment
+
+	// pending counts the events in segments that are not written yet.
+	pending atomic.Int64
+}
+
+// openWAL opens the write-ahead log in dir, creating the directory if
+// needed, and counts the events left in it.
+func openWAL(dir string) (*wal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating audit WAL directory: %w", err)
+	}
+	w := &wal{dir: dir}
+
+	paths, err := w.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		events, err := readSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		w.pending.Add(int64(len(events)))
+		if seq, ok := segmentSeq(path); ok && seq > w.seq {
+			w.seq = seq
+		}
+	}
+	return w, nil
+}
+
+// append writes the event to the active segment and syncs it to disk.
+func (w *wal) append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.active == nil {
+		w.seq++
+		f, err := os.OpenFile(w.segmentPath(w.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			w.seq--
+			return fmt.Errorf("creating audit WAL segment: %w", err)
+		}
+		w.active = f
+	}
+	_, err = w.active.Write(line)
+	if err == nil {
+		err = w.active.Sync()
+	}
+	if err != nil {
+		// The segment may end in a partial line; start a new one so that the
+		// next event is not appended to it.
+		w.active.Close()
+		w.active = nil
+		return fmt.Errorf("writing audit WAL: %w", err)
+	}
+	w.pending.Add(1)
+	return nil
+}
+
+// rotate seals the active segment, so that it is included in sealed. The
+// next append starts a new segment.
+func (w *wal) rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.active == nil {
+		return nil
+	}
+	err := w.active.Close()
+	w.active = nil
+	if err != nil {
+		return fmt.Errorf("closing audit WAL segment: %w", err)
+	}
+	return nil
+}
+
+// sealed returns the paths of the segments that are no longer appended to,
+// oldest first.
+func (w *wal) sealed() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	paths, err := w.segments()
+	if err != nil {
+		return nil, err
+	}
+	if w.active == nil {
+		return paths, nil
+	}
+	active := w.segmentPath(w.seq)
+	sealed := paths[:0]
+	for _, path := range paths {
+		if path != active {
+			sealed = append(sealed, path)
+		}
+	}
+	return sealed, nil
+}
+
+// remove deletes a segment whose events have been written.
+func (w *wal) remove(path string, events int) error {
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("removing audit WAL segment: %w", err)
+	}
+	w.pending.Add(-int64(events))
+	return nil
+}
+
+// segments returns the paths of all segments in the directory, oldest
+// first.
+func (w *wal) segments() ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading audit WAL directory: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), walSuffix) {
+			paths = append(paths, filepath.Join(w.dir, e.Name()))
+		}
+	}
+	// Segment names are zero-padded, so they sort by number.
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// segmentPath returns the path of the segment numbered seq.
+func (w *wal) segmentPath(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, walSuffix))
+}
+
+// segmentSeq returns the number of the segment at path.
+func segmentSeq(path string) (uint64, bool) {
+	seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), walSuffix), 10, 64)
+	return seq, err == nil
+}
+
+// readSegment returns the events in a segment. A malformed line, such as
+// one cut short by a crash while it was appended, is skipped with a warning.
+func readSegment(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit WAL segment: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if len(b) > 0 {
+			var event Event
+			if jsonErr := json.Unmarshal(b, &event); jsonErr != nil {
+				slog.Warn("skipping malformed audit WAL entry", "segment", path, "line", line, "error", jsonErr)
+			} else {
+				events = append(events, event)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading audit WAL segment: %w", err)
+		}
+	}
+}
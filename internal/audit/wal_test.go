@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWAL_AppendAndSeal(t *testing.T) {
+	w, err := openWAL(t.TempDir())
+	if err != nil {
+		t.Fatalf("openWAL() error = %v", err)
+	}
+
+	for _, action := range []string{"entry.create", "entry.update"} {
+		if err := w.append(Event{ID: "id-" + action, Action: action}); err != nil {
+			t.Fatalf("append() error = %v", err)
+		}
+	}
+	// The active segment is not sealed until it is rotated.
+	if sealed, err := w.sealed(); err != nil || len(sealed) != 0 {
+		t.Fatalf("sealed() = %v, %v; want no segments", sealed, err)
+	}
+
+	if err := w.rotate(); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	if err := w.append(Event{Action: "entry.delete"}); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	sealed, err := w.sealed()
+	if err != nil || len(sealed) != 1 {
+		t.Fatalf("sealed() = %v, %v; want one segment", sealed, err)
+	}
+
+	events, err := readSegment(sealed[0])
+	if err != nil {
+		t.Fatalf("readSegment() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Action != "entry.create" || events[1].ID != "id-entry.update" {
+		t.Errorf("readSegment() = %+v, want the two events of the first segment", events)
+	}
+	if n := w.pending.Load(); n != 3 {
+		t.Errorf("pending = %d, want 3", n)
+	}
+
+	if err := w.remove(sealed[0], len(events)); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if n := w.pending.Load(); n != 1 {
+		t.Errorf("pending after remove = %d, want 1", n)
+	}
+}
+
+func TestWAL_Reopen(t *testing.T) {
+	// Segments left behind, including one cut short by a crash, are counted
+	// when the log is opened again, and new events go to a new segment.
+	dir := t.TempDir()
+	first := filepath.Join(dir, fmt.Sprintf("%020d%s", 7, walSuffix))
+	content := `{"id":"a","action":"entry.create","time":"2025-01-15T10:30:00Z"}` + "\n" + `{"id":"b","act`
+	if err := os.WriteFile(first, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := openWAL(dir)
+	if err != nil {
+		t.Fatalf("openWAL() error = %v", err)
+	}
+	if n := w.pending.Load(); n != 1 {
+		t.Errorf("pending = %d, want 1", n)
+	}
+	events, err := readSegment(first)
+	if err != nil || len(events) != 1 || !events[0].Time.Equal(time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("readSegment() = %+v, %v; want the complete event", events, err)
+	}
+
+	if err := w.append(Event{Action: "entry.update"}); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("%020d%s", 8, walSuffix))); err != nil {
+		t.Errorf("new events not appended to segment 8: %v", err)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"drop", "block", "wal"} {
+		if m, err := ParseMode(s); err != nil || string(m) != s {
+			t.Errorf("ParseMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseMode("lossless"); err == nil {
+		t.Error("ParseMode(lossless) should fail")
+	}
+}
+
+func TestLog_BlockMode(t *testing.T) {
+	s := NewService(nil)
+	s.mode = ModeBlock
+	s.eventCh = make(chan Event, 1)
+	s.Log(context.Background(), Event{Action: "test.one"})
+
+	done := make(chan struct{})
+	go func() {
+		s.Log(context.Background(), Event{Action: "test.two"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Log returned while the channel was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := (<-s.eventCh).Action; got != "test.one" {
+		t.Errorf("first event = %q, want test.one", got)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Log still blocked after the channel had room")
+	}
+	if s.DroppedCount() != 0 {
+		t.Errorf("dropped count = %d, want 0", s.DroppedCount())
+	}
+}
+
+func TestRetrying(t *testing.T) {
+	if NewService(nil).retrying() {
+		t.Error("retrying() = true in drop mode")
+	}
+	for _, mode := range []Mode{ModeBlock, ModeWAL} {
+		s := NewService(nil)
+		s.mode = mode
+		if !s.retrying() {
+			t.Errorf("retrying() = false in %s mode", mode)
+		}
+		close(s.stop)
+		if s.retrying() {
+			t.Errorf("retrying() = true in %s mode after Shutdown", mode)
+		}
+	}
+}
+
+func TestLog_WALMode(t *testing.T) {
+	s := NewService(nil)
+	if err := s.SetMode(ModeWAL, t.TempDir()); err != nil {
+		t.Fatalf("SetMode() error = %v", err)
+	}
+
+	s.Log(context.Background(), Event{Action: "entry.create"})
+
+	if len(s.eventCh) != 0 {
+		t.Error("event queued in memory instead of the WAL")
+	}
+	select {
+	case <-s.walCh:
+	default:
+		t.Error("writer not signalled")
+	}
+	stats := s.Stats()
+	if stats.Mode != ModeWAL || stats.WALPending != 1 {
+		t.Errorf("Stats() = %+v, want one pending WAL event", stats)
+	}
+
+	if err := s.wal.rotate(); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := s.wal.sealed()
+	events, err := readSegment(sealed[0])
+	if err != nil || len(events) != 1 || events[0].ID == "" || events[0].Time.IsZero() {
+		t.Errorf("WAL events = %+v, %v; want one event with an ID and time", events, err)
+	}
+}
+
+func TestIsInvalid(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("%w: bad payload", ErrInvalidEvent), true},
+		{fmt.Errorf("inserting: %w", &pgconn.PgError{Code: "22P02"}), true},
+		{&pgconn.PgError{Code: "23503"}, true},
+		{&pgconn.PgError{Code: "57P01"}, false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		if got := isInvalid(tt.err); got != tt.want {
+			t.Errorf("isInvalid(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
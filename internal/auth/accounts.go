@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/mail"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+const (
+	accountTokenBytes   = 32
+	inviteExpiry        = 7 * 24 * time.Hour
+	passwordResetExpiry = time.Hour
+
+	// backgroundTimeout bounds the work AccountService does after a
+	// request has been answered.
+	backgroundTimeout = time.Minute
+)
+
+// Kinds of account tokens.
+const (
+	tokenKindInvite        = "invite"
+	tokenKindPasswordReset = "password_reset"
+)
+
+// Sentinel errors for invitations and password resets.
+var (
+	ErrInvalidAccountToken = errors.New("invalid, expired or already used token")
+	ErrInviteNotFound      = errors.New("invite not found")
+	ErrMailNotConfigured   = errors.New("email delivery is not configured")
+	ErrMailDelivery        = errors.New("email could not be delivered")
+)
+
+// Invite is a pending invitation to become an admin.
+type Invite struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedBy *string   `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AccountService manages admin invitations and password resets. Both work
+// with single-use, expiring tokens that are sent by email as links into the
+// admin UI; only the tokens' hashes are stored.
+type AccountService struct {
+	service      *Service
+	repo         *Repository
+	sender       mail.Sender
+	baseURL      string
+	auditService *audit.Service
+	now          func() time.Time
+
+	// async runs f in the background; tests replace it.
+	async func(f func())
+	wg    sync.WaitGroup
+}
+
+// NewAccountService creates a new AccountService. baseURL is the externally
+// visible URL of the server, used to build links. sender may be nil, in
+// which case invitations fail with ErrMailNotConfigured and reset requests
+// are ignored. The audit service is optional.
+func NewAccountService(service *Service, repo *Repository, sender mail.Sender, baseURL string, auditService *audit.Service) *AccountService {
+	s := &AccountService{
+		service:      service,
+		repo:         repo,
+		sender:       sender,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		auditService: auditService,
+		now:          time.Now,
+	}
+	s.async = func(f func()) {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			f()
+		}()
+	}
+	return s
+}
+
+// Shutdown waits for background work, such as sending password reset
+// emails, to finish. The provided context controls the maximum time to wait.
+func (s *AccountService) Shutdown(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("waiting for account emails timed out")
+	}
+}
+
+// Invite creates an invitation for email to become an admin with the given
+// role and emails it. As with CreateAdmin, the inviter must be allowed to
+// grant the role. A previous pending invitation for the same email is
+// replaced.
+func (s *AccountService) Invite(ctx context.Context, email, role string, by Grantor) (*Invite, error) {
+	email = strings.TrimSpace(email)
+	errs := validateEmail(email)
+	if role == "" {
+		errs = append(errs, server.FieldError{Field: "role", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		return nil, &AdminValidationError{Fields: errs}
+	}
+	if s.sender == nil {
+		return nil, ErrMailNotConfigured
+	}
+	if err := s.service.authorizeGrant(ctx, by, role); err != nil {
+		return nil, err
+	}
+	actorID := by.AdminID
+
+	if _, err := s.repo.GetAdminByEmail(ctx, email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	token, err := newAccountToken()
+	if err != nil {
+		return nil, err
+	}
+	invite, err := s.repo.CreateInvite(ctx, hashToken(token), email, role, actorID, s.now().Add(inviteExpiry))
+	if err != nil {
+		return nil, adminWriteError(err)
+	}
+
+	err = s.sender.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "You have been invited to Mithril CMS",
+		Body: fmt.Sprintf("You have been invited to manage content in Mithril CMS as %s.\n\n"+
+			"Choose a password to activate your account:\n%s\n\n"+
+			"The link expires on %s.\n",
+			role, s.link("/admin/accept-invite", token), invite.ExpiresAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		slog.Error("sending invite failed", "email", email, "error", err)
+		if _, delErr := s.repo.DeleteInvite(ctx, invite.ID); delErr != nil {
+			slog.Error("deleting undelivered invite failed", "invite_id", invite.ID, "error", delErr)
+		}
+		return nil, ErrMailDelivery
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.invite.create",
+		ActorID:    actorID,
+		Resource:   "invite",
+		ResourceID: invite.ID,
+		Payload:    map[string]any{"email": email, "role": role},
+	})
+	return invite, nil
+}
+
+// ListInvites returns pending invitations, newest first.
+func (s *AccountService) ListInvites(ctx context.Context) ([]Invite, error) {
+	invites, err := s.repo.ListInvites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if invites == nil {
+		invites = []Invite{}
+	}
+	return invites, nil
+}
+
+// RevokeInvite deletes a pending invitation so its link stops working.
+func (s *AccountService) RevokeInvite(ctx context.Context, id, actorID string) error {
+	invite, err := s.repo.DeleteInvite(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.invite.revoke",
+		ActorID:    actorID,
+		Resource:   "invite",
+		ResourceID: invite.ID,
+		Payload:    map[string]any{"email": invite.Email},
+	})
+	return nil
+}
+
+// AcceptInvite redeems an invitation token, creating the invited admin with
+// the given password.
+func (s *AccountService) AcceptInvite(ctx context.Context, token, password string) (*Admin, error) {
+	if err := validatePassword(password); err != nil {
+		return nil, &AdminValidationError{Fields: []server.FieldError{{Field: "password", Message: err.Error()}}}
+	}
+	hash, err := s.service.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, inviteID, err := s.repo.AcceptInvite(ctx, hashToken(token), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.invite.accept",
+		ActorID:    admin.ID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email, "role": admin.Role, "invite_id": inviteID},
+	})
+	return admin, nil
+}
+
+// RequestPasswordReset emails a password reset link to the admin with the
+// given email. It returns before looking up the email, and the lookup and
+// delivery happen in the background, so that neither the response nor its
+// timing reveals which emails belong to admins. Unknown and disabled
+// accounts are silently ignored, and failures are only logged. Earlier reset
+// links of the admin stop working.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) {
+	email = strings.TrimSpace(email)
+	if s.sender == nil {
+		slog.Warn("password reset requested but email delivery is not configured")
+		return
+	}
+
+	// The request's context is canceled once it has been answered.
+	ctx = context.WithoutCancel(ctx)
+	s.async(func() {
+		ctx, cancel := context.WithTimeout(ctx, backgroundTimeout)
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			slog.Error("password reset request failed", "error", err)
+		}
+	})
+}
+
+// sendPasswordReset creates a password reset for the admin with the given
+// email, if there is an enabled one, and emails them the link.
+func (s *AccountService) sendPasswordReset(ctx context.Context, email string) error {
+	admin, err := s.repo.GetAdminByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if admin.DisabledAt != nil {
+		return nil
+	}
+
+	token, err := newAccountToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := s.now().Add(passwordResetExpiry)
+	if err := s.repo.CreatePasswordReset(ctx, hashToken(token), admin.ID, admin.Email, expiresAt); err != nil {
+		return err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.password.reset_request",
+		Resource:   "admin",
+		ResourceID: admin.ID,
+	})
+
+	err = s.sender.Send(ctx, mail.Message{
+		To:      admin.Email,
+		Subject: "Reset your Mithril CMS password",
+		Body: fmt.Sprintf("A password reset was requested for your Mithril CMS account.\n\n"+
+			"Choose a new password here:\n%s\n\n"+
+			"The link expires in one hour. If you did not request a reset, ignore this email.\n",
+			s.link("/admin/reset-password", token)),
+	})
+	if err != nil {
+		slog.Error("sending password reset failed", "admin_id", admin.ID, "error", err)
+	}
+	return nil
+}
+
+// ResetPassword redeems a password reset token, setting the admin's password
+// and revoking all of their sessions.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	if err := validatePassword(password); err != nil {
+		return &AdminValidationError{Fields: []server.FieldError{{Field: "password", Message: err.Error()}}}
+	}
+	hash, err := s.service.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	adminID, err := s.repo.ResetPassword(ctx, hashToken(token), hash)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAllForAdmin(ctx, adminID); err != nil {
+		return fmt.Errorf("revoking sessions after password reset: %w", err)
+	}
+	if err := s.service.revokeAccessTokens(ctx, adminID); err != nil {
+		return fmt.Errorf("revoking access tokens after password reset: %w", err)
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.password.reset",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
+	})
+	return nil
+}
+
+// link returns an absolute admin UI URL carrying token.
+func (s *AccountService) link(path, token string) string {
+	return s.baseURL + path + "?token=" + token
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (s *AccountService) logAudit(ctx context.Context, event audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Log(ctx, event)
+	}
+}
+
+// newAccountToken generates a random hex-encoded token.
+func newAccountToken() (string, error) {
+	raw := make([]byte, accountTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generating account token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
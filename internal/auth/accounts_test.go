@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GyroZepelix/mithril-cms/internal/mail"
+)
+
+// recordingSender is a mail.Sender that records sent messages.
+type recordingSender struct {
+	sent []mail.Message
+}
+
+func (s *recordingSender) Send(_ context.Context, msg mail.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestInvite_Validation(t *testing.T) {
+	sender := &recordingSender{}
+	svc := NewAccountService(NewService(nil, testSecret), nil, sender, "http://cms.example.com", nil)
+
+	for _, email := range []string{"", "not-an-email", "Jane <jane@example.com>"} {
+		_, err := svc.Invite(context.Background(), email, "editor", Grantor{AdminID: "actor"})
+		var valErr *AdminValidationError
+		if !errors.As(err, &valErr) || valErr.Fields[0].Field != "email" {
+			t.Errorf("Invite(%q) error = %v, want email validation error", email, err)
+		}
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d messages for invalid invites, want 0", len(sender.sent))
+	}
+}
+
+func TestInvite_MailNotConfigured(t *testing.T) {
+	svc := NewAccountService(NewService(nil, testSecret), nil, nil, "http://cms.example.com", nil)
+
+	_, err := svc.Invite(context.Background(), "jane@example.com", "editor", Grantor{AdminID: "actor"})
+	if !errors.Is(err, ErrMailNotConfigured) {
+		t.Errorf("Invite() error = %v, want ErrMailNotConfigured", err)
+	}
+}
+
+func TestInvite_RoleGrants(t *testing.T) {
+	sender := &recordingSender{}
+	svc := NewAccountService(newGrantTestService(), nil, sender, "http://cms.example.com", nil)
+	manager := Grantor{AdminID: testManagerID, Permissions: testRoles["manager"]}
+
+	_, err := svc.Invite(context.Background(), "jane@example.com", "", manager)
+	var valErr *AdminValidationError
+	if !errors.As(err, &valErr) || valErr.Fields[0].Field != "role" {
+		t.Errorf("Invite() without role error = %v, want role validation error", err)
+	}
+	for _, role := range []string{SuperuserRole, "editor"} {
+		if _, err := svc.Invite(context.Background(), "jane@example.com", role, manager); !errors.Is(err, ErrRoleEscalation) {
+			t.Errorf("Invite(%s) error = %v, want ErrRoleEscalation", role, err)
+		}
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d messages for rejected invites, want 0", len(sender.sent))
+	}
+}
+
+func TestRequestPasswordReset_MailNotConfigured(t *testing.T) {
+	svc := NewAccountService(NewService(nil, testSecret), nil, nil, "http://cms.example.com", nil)
+	svc.async = func(func()) { t.Error("RequestPasswordReset() started background work without a sender") }
+
+	svc.RequestPasswordReset(context.Background(), "jane@example.com")
+}
+
+func TestRequestPasswordReset_Background(t *testing.T) {
+	sender := &recordingSender{}
+	// Without a repository, any lookup before returning would panic.
+	svc := NewAccountService(NewService(nil, testSecret), nil, sender, "http://cms.example.com", nil)
+	var jobs int
+	svc.async = func(func()) { jobs++ }
+
+	// Known or not, every email takes the same path: nothing is looked up
+	// or sent before the request returns.
+	for _, email := range []string{"nobody@example.com", "jane@example.com"} {
+		svc.RequestPasswordReset(context.Background(), email)
+	}
+	if jobs != 2 {
+		t.Errorf("started %d background jobs, want 2", jobs)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d messages before returning, want 0", len(sender.sent))
+	}
+}
+
+func TestRedeem_PasswordValidation(t *testing.T) {
+	svc := NewAccountService(NewService(nil, testSecret), nil, nil, "http://cms.example.com", nil)
+	long := strings.Repeat("x", 65)
+
+	for _, password := range []string{"short", long} {
+		var valErr *AdminValidationError
+		if _, err := svc.AcceptInvite(context.Background(), "token", password); !errors.As(err, &valErr) {
+			t.Errorf("AcceptInvite(len %d) error = %v, want AdminValidationError", len(password), err)
+		}
+		if err := svc.ResetPassword(context.Background(), "token", password); !errors.As(err, &valErr) {
+			t.Errorf("ResetPassword(len %d) error = %v, want AdminValidationError", len(password), err)
+		}
+	}
+}
+
+func TestAccountService_Link(t *testing.T) {
+	svc := NewAccountService(nil, nil, nil, "https://cms.example.com/", nil)
+
+	got := svc.link("/admin/accept-invite", "abc123")
+	want := "https://cms.example.com/admin/accept-invite?token=abc123"
+	if got != want {
+		t.Errorf("link() = %q, want %q", got, want)
+	}
+}
+
+func TestNewAccountToken(t *testing.T) {
+	a, err := newAccountToken()
+	if err != nil {
+		t.Fatalf("newAccountToken: %v", err)
+	}
+	b, _ := newAccountToken()
+	if len(a) != 2*accountTokenBytes {
+		t.Errorf("token length = %d, want %d", len(a), 2*accountTokenBytes)
+	}
+	if a == b {
+		t.Error("two tokens are equal")
+	}
+}
+
+func TestWriteAccountError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{ErrInvalidAccountToken, http.StatusBadRequest, "INVALID_TOKEN"},
+		{ErrInviteNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{ErrMailNotConfigured, http.StatusServiceUnavailable, "MAIL_NOT_CONFIGURED"},
+		{ErrMailDelivery, http.StatusBadGateway, "MAIL_DELIVERY_FAILED"},
+		{fmt.Errorf("wrapped: %w", ErrEmailTaken), http.StatusConflict, "EMAIL_TAKEN"},
+		{&AdminValidationError{}, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{errors.New("boom"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		writeAccountError(rr, tt.err)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("writeAccountError(%v) status = %d, want %d", tt.err, rr.Code, tt.wantStatus)
+		}
+		if !strings.Contains(rr.Body.String(), tt.wantCode) {
+			t.Errorf("writeAccountError(%v) body = %s, want code %s", tt.err, rr.Body.String(), tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// Sentinel errors for admin management.
+var (
+	ErrAdminNotFound   = errors.New("admin not found")
+	ErrEmailTaken      = errors.New("an admin with this email already exists")
+	ErrUnknownRole     = errors.New("role does not exist")
+	ErrLastSuperuser   = errors.New("the last enabled admin with the admin role cannot be removed")
+	ErrAdminInUse      = errors.New("admin has created content; disable the account instead")
+	ErrSelfAction      = errors.New("admins cannot disable or delete their own account")
+	ErrSelfRoleChange  = errors.New("admins cannot change their own role")
+	ErrRoleEscalation  = errors.New("the role has permissions you do not have")
+	ErrAccountDisabled = errors.New("account is disabled")
+	ErrWrongPassword   = errors.New("current password is incorrect")
+)
+
+// AdminValidationError is returned when admin input is invalid.
+type AdminValidationError struct {
+	Fields []server.FieldError
+}
+
+func (e *AdminValidationError) Error() string {
+	return fmt.Sprintf("admin validation failed: %d field error(s)", len(e.Fields))
+}
+
+// CreateAdminInput holds the settings of a new admin.
+type CreateAdminInput struct {
+	Email    string
+	Password string
+	Role     string
+
+	// GrantedBy is the admin creating the account, who must be allowed to
+	// grant Role. Nil for trusted callers such as the CLI.
+	GrantedBy *Grantor
+}
+
+// UpdateAdminInput holds changes to an admin. Nil fields are left unchanged.
+type UpdateAdminInput struct {
+	Email *string
+	Role  *string
+
+	// GrantedBy is the admin making the change. Unless it is their own
+	// account, they must be allowed to grant its current role and Role.
+	// They cannot change their own role. Nil for trusted callers such as the
+	// CLI.
+	GrantedBy *Grantor
+}
+
+// Grantor is an admin granting roles or managing other accounts, with the
+// permissions their request is authorized with.
+type Grantor struct {
+	AdminID     string
+	Permissions Permissions
+
+	// Token is the personal access token of the request, if any; its scopes
+	// limit the grantor as well.
+	Token *PersonalAccessToken
+}
+
+// GrantorFromContext returns the authenticated admin of the request as a
+// Grantor.
+func GrantorFromContext(ctx context.Context) Grantor {
+	return Grantor{
+		AdminID:     AdminIDFromContext(ctx),
+		Permissions: PermissionsFromContext(ctx),
+		Token:       PersonalAccessTokenFromContext(ctx),
+	}
+}
+
+// mayGrant reports whether the grantor may grant a role with perms: they
+// have every permission of the role themselves, or may manage roles, which
+// allows giving themselves any permission anyway.
+func (g Grantor) mayGrant(perms Permissions) bool {
+	allows := func(ps Permissions) bool {
+		if manage, _ := ps.Check("roles", "manage"); manage {
+			return true
+		}
+		return ps.Covers(perms)
+	}
+	return allows(g.Permissions) && (g.Token == nil || allows(g.Token.Scopes))
+}
+
+// mayReadAll reports whether the grantor may read every entry of the content
+// type, drafts included, as Can checks it: the read permission must not be
+// limited to their own entries.
+func (g Grantor) mayReadAll(contentType string) bool {
+	reads := func(ps Permissions) bool {
+		allowed, ownOnly := ps.Check("content:"+contentType, "read")
+		return allowed && !ownOnly
+	}
+	return reads(g.Permissions) && (g.Token == nil || reads(g.Token.Scopes))
+}
+
+// authorizeGrant returns ErrRoleEscalation unless the grantor may grant
+// role.
+func (s *Service) authorizeGrant(ctx context.Context, g Grantor, role string) error {
+	perms, err := s.rolePermissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !g.mayGrant(perms) {
+		return ErrRoleEscalation
+	}
+	return nil
+}
+
+// ListAdmins returns all admins ordered by email.
+func (s *Service) ListAdmins(ctx context.Context) ([]Admin, error) {
+	admins, err := s.repo.ListAdmins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if admins == nil {
+		admins = []Admin{}
+	}
+	return admins, nil
+}
+
+// GetAdmin returns the admin with the given ID, or ErrAdminNotFound.
+func (s *Service) GetAdmin(ctx context.Context, id string) (*Admin, error) {
+	admin, err := s.adminByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdminNotFound
+		}
+		return nil, err
+	}
+	return admin, nil
+}
+
+// FindAdmin returns the admin with the given ID, or with the given email if
+// it contains an @, or ErrAdminNotFound.
+func (s *Service) FindAdmin(ctx context.Context, idOrEmail string) (*Admin, error) {
+	if !strings.Contains(idOrEmail, "@") {
+		if !uuidRegex.MatchString(idOrEmail) {
+			return nil, ErrAdminNotFound
+		}
+		return s.GetAdmin(ctx, idOrEmail)
+	}
+
+	admin, err := s.repo.FindAdminByEmail(ctx, strings.TrimSpace(idOrEmail))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdminNotFound
+		}
+		return nil, err
+	}
+	return admin, nil
+}
+
+// CreateAdmin validates the input and creates a new admin.
+func (s *Service) CreateAdmin(ctx context.Context, in CreateAdminInput) (*Admin, error) {
+	in.Email = strings.TrimSpace(in.Email)
+
+	var errs []server.FieldError
+	errs = append(errs, validateEmail(in.Email)...)
+	if err := validatePassword(in.Password); err != nil {
+		errs = append(errs, server.FieldError{Field: "password", Message: err.Error()})
+	}
+	if in.Role == "" {
+		errs = append(errs, server.FieldError{Field: "role", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		return nil, &AdminValidationError{Fields: errs}
+	}
+	if in.GrantedBy != nil {
+		if err := s.authorizeGrant(ctx, *in.GrantedBy, in.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := s.HashPassword(in.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.repo.InsertAdmin(ctx, in.Email, hash, in.Role)
+	if err != nil {
+		return nil, adminWriteError(err)
+	}
+	return admin, nil
+}
+
+// UpdateAdmin changes an admin's email and/or role. A new role revokes the
+// admin's access tokens, so it takes effect when the admin next refreshes
+// them.
+func (s *Service) UpdateAdmin(ctx context.Context, id string, in UpdateAdminInput) (*Admin, error) {
+	if in.Email != nil {
+		email := strings.TrimSpace(*in.Email)
+		if errs := validateEmail(email); len(errs) > 0 {
+			return nil, &AdminValidationError{Fields: errs}
+		}
+		in.Email = &email
+	}
+	if g := in.GrantedBy; g != nil {
+		if err := s.authorizeUpdate(ctx, *g, id, in.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	admin, err := s.updateAdmin(ctx, id, in.Email, in.Role)
+	if err != nil {
+		return nil, adminWriteError(err)
+	}
+	if in.Role != nil {
+		// A new role revoked the admin's access tokens.
+		s.forgetAdminTokens(id)
+	}
+	return admin, nil
+}
+
+// authorizeUpdate checks that the grantor may change the admin with the
+// given ID, and give them role if it is not nil. Changing another admin's
+// email could be used to take over their account through a password reset,
+// so it needs the same privilege as granting their role.
+func (s *Service) authorizeUpdate(ctx context.Context, g Grantor, id string, role *string) error {
+	if id == g.AdminID {
+		if role != nil {
+			return ErrSelfRoleChange
+		}
+		return nil
+	}
+
+	target, err := s.authorizeManage(ctx, g, id)
+	if err != nil {
+		return err
+	}
+	if role != nil && *role != target.Role {
+		return s.authorizeGrant(ctx, g, *role)
+	}
+	return nil
+}
+
+// authorizeManage checks that the grantor may act on the account of the
+// admin with the given ID, such as disabling it or signing it out: they must
+// be allowed to grant its current role, so that admins cannot act on
+// accounts more privileged than their own. Their own account is allowed;
+// actions they may not take on it check that first. It returns the target
+// admin.
+func (s *Service) authorizeManage(ctx context.Context, g Grantor, id string) (*Admin, error) {
+	target, err := s.GetAdmin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if id == g.AdminID {
+		return target, nil
+	}
+	if err := s.authorizeGrant(ctx, g, target.Role); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// authorizeOther checks that by, if not nil, may act on the account of the
+// admin with the given ID, which must not be their own.
+func (s *Service) authorizeOther(ctx context.Context, by *Grantor, id string) error {
+	if by == nil {
+		return nil
+	}
+	if id == by.AdminID {
+		return ErrSelfAction
+	}
+	_, err := s.authorizeManage(ctx, *by, id)
+	return err
+}
+
+// DisableAdmin disables an admin's account and revokes all of its sessions.
+// by is the admin disabling it, who must be allowed to grant its role and
+// cannot disable themselves; nil for trusted callers such as the CLI.
+func (s *Service) DisableAdmin(ctx context.Context, id string, by *Grantor) (*Admin, error) {
+	if err := s.authorizeOther(ctx, by, id); err != nil {
+		return nil, err
+	}
+
+	admin, err := s.repo.SetAdminDisabled(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteAllForAdmin(ctx, id); err != nil {
+		return nil, fmt.Errorf("revoking sessions of disabled admin: %w", err)
+	}
+	if err := s.revokeAccessTokens(ctx, id); err != nil {
+		return nil, fmt.Errorf("revoking access tokens of disabled admin: %w", err)
+	}
+	return admin, nil
+}
+
+// EnableAdmin re-enables a disabled admin's account. by is the admin
+// enabling it, who must be allowed to grant its role; nil for trusted
+// callers such as the CLI.
+func (s *Service) EnableAdmin(ctx context.Context, id string, by *Grantor) (*Admin, error) {
+	if err := s.authorizeOther(ctx, by, id); err != nil {
+		return nil, err
+	}
+	return s.repo.SetAdminDisabled(ctx, id, false)
+}
+
+// DeleteAdmin deletes an admin. by is the admin deleting it, who must be
+// allowed to grant its role and cannot delete themselves; nil for trusted
+// callers. Admins who created content can only be disabled.
+func (s *Service) DeleteAdmin(ctx context.Context, id string, by *Grantor) (*Admin, error) {
+	if err := s.authorizeOther(ctx, by, id); err != nil {
+		return nil, err
+	}
+	admin, err := s.repo.DeleteAdmin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.forgetAdminTokens(id)
+	return admin, nil
+}
+
+// ChangePassword sets a new password for the admin after verifying the
+// current one. All of the admin's sessions are revoked and a new one is
+// started, whose access and refresh tokens are returned.
+func (s *Service) ChangePassword(ctx context.Context, adminID, currentPassword, newPassword string) (accessToken, refreshToken string, err error) {
+	admin, err := s.GetAdmin(ctx, adminID)
+	if err != nil {
+		return "", "", err
+	}
+
+	match, err := s.VerifyPassword(admin.PasswordHash, currentPassword)
+	if err != nil {
+		return "", "", err
+	}
+	if !match {
+		return "", "", ErrWrongPassword
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return "", "", &AdminValidationError{Fields: []server.FieldError{{Field: "new_password", Message: err.Error()}}}
+	}
+
+	hash, err := s.HashPassword(newPassword)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repo.UpdatePassword(ctx, adminID, hash); err != nil {
+		return "", "", err
+	}
+	if err := s.repo.DeleteAllForAdmin(ctx, adminID); err != nil {
+		return "", "", fmt.Errorf("revoking sessions after password change: %w", err)
+	}
+	if err := s.revokeAccessTokens(ctx, adminID); err != nil {
+		return "", "", fmt.Errorf("revoking access tokens after password change: %w", err)
+	}
+
+	accessToken, err = s.createAccessToken(ctx, admin)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err = s.createRefreshToken(ctx, adminID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// SetPassword sets a new password for the admin without asking for the
+// current one, as for a password reset. All of the admin's sessions and
+// access tokens are revoked.
+func (s *Service) SetPassword(ctx context.Context, adminID, password string) error {
+	if err := validatePassword(password); err != nil {
+		return &AdminValidationError{Fields: []server.FieldError{{Field: "password", Message: err.Error()}}}
+	}
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, adminID, hash); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAllForAdmin(ctx, adminID); err != nil {
+		return fmt.Errorf("revoking sessions after password reset: %w", err)
+	}
+	if err := s.revokeAccessTokens(ctx, adminID); err != nil {
+		return fmt.Errorf("revoking access tokens after password reset: %w", err)
+	}
+	return nil
+}
+
+// validateEmail checks that email is a bare address.
+func validateEmail(email string) []server.FieldError {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return []server.FieldError{{Field: "email", Message: "must be a valid email address"}}
+	}
+	return nil
+}
+
+// adminWriteError turns repository errors from writing an admin into
+// validation errors where they stem from the input.
+func adminWriteError(err error) error {
+	if errors.Is(err, ErrUnknownRole) {
+		return &AdminValidationError{Fields: []server.FieldError{{Field: "role", Message: err.Error()}}}
+	}
+	return err
+}
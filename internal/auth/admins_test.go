@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestCreateAdmin_Validation(t *testing.T) {
+	svc := NewService(nil, testSecret)
+
+	tests := []struct {
+		name       string
+		in         CreateAdminInput
+		wantFields []string
+	}{
+		{"invalid email", CreateAdminInput{Email: "not-an-email", Password: "long enough", Role: "editor"}, []string{"email"}},
+		{"display name", CreateAdminInput{Email: "Jane <jane@example.com>", Password: "long enough", Role: "editor"}, []string{"email"}},
+		{"short password", CreateAdminInput{Email: "jane@example.com", Password: "short", Role: "editor"}, []string{"password"}},
+		{"missing role", CreateAdminInput{Email: "jane@example.com", Password: "long enough"}, []string{"role"}},
+		{"all", CreateAdminInput{Password: "short"}, []string{"email", "password", "role"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateAdmin(context.Background(), tt.in)
+			var valErr *AdminValidationError
+			if !errors.As(err, &valErr) {
+				t.Fatalf("CreateAdmin() error = %v, want AdminValidationError", err)
+			}
+			if len(valErr.Fields) != len(tt.wantFields) {
+				t.Fatalf("fields = %+v, want %v", valErr.Fields, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if valErr.Fields[i].Field != field {
+					t.Errorf("field %d = %q, want %q", i, valErr.Fields[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAdmin_InvalidEmail(t *testing.T) {
+	svc := NewService(nil, testSecret)
+	email := "nope"
+
+	_, err := svc.UpdateAdmin(context.Background(), "id", UpdateAdminInput{Email: &email})
+	var valErr *AdminValidationError
+	if !errors.As(err, &valErr) || valErr.Fields[0].Field != "email" {
+		t.Errorf("UpdateAdmin() error = %v, want email validation error", err)
+	}
+}
+
+func TestFindAdmin_InvalidID(t *testing.T) {
+	// Neither a UUID nor an email: no admin, without touching the repository.
+	svc := NewService(nil, testSecret)
+	if _, err := svc.FindAdmin(context.Background(), "jane"); !errors.Is(err, ErrAdminNotFound) {
+		t.Errorf("FindAdmin() error = %v, want ErrAdminNotFound", err)
+	}
+}
+
+func TestSetPassword_Validation(t *testing.T) {
+	svc := NewService(nil, testSecret)
+
+	err := svc.SetPassword(context.Background(), "id", "short")
+	var valErr *AdminValidationError
+	if !errors.As(err, &valErr) || valErr.Fields[0].Field != "password" {
+		t.Errorf("SetPassword() error = %v, want password validation error", err)
+	}
+}
+
+func TestAdminSelfAction(t *testing.T) {
+	svc := NewService(nil, testSecret)
+	id := "550e8400-e29b-41d4-a716-446655440000"
+
+	self := &Grantor{AdminID: id}
+
+	if _, err := svc.DisableAdmin(context.Background(), id, self); !errors.Is(err, ErrSelfAction) {
+		t.Errorf("DisableAdmin(self) error = %v, want ErrSelfAction", err)
+	}
+	if _, err := svc.DeleteAdmin(context.Background(), id, self); !errors.Is(err, ErrSelfAction) {
+		t.Errorf("DeleteAdmin(self) error = %v, want ErrSelfAction", err)
+	}
+}
+
+func TestAdminWriteError(t *testing.T) {
+	var valErr *AdminValidationError
+	if err := adminWriteError(ErrUnknownRole); !errors.As(err, &valErr) || valErr.Fields[0].Field != "role" {
+		t.Errorf("adminWriteError(ErrUnknownRole) = %v, want role validation error", err)
+	}
+	if err := adminWriteError(ErrEmailTaken); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("adminWriteError(ErrEmailTaken) = %v, want it unchanged", err)
+	}
+}
+
+func TestWriteAdminError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{&AdminValidationError{}, http.StatusBadRequest},
+		{ErrAdminNotFound, http.StatusNotFound},
+		{fmt.Errorf("wrapped: %w", ErrEmailTaken), http.StatusConflict},
+		{ErrLastSuperuser, http.StatusConflict},
+		{ErrAdminInUse, http.StatusConflict},
+		{ErrSelfAction, http.StatusConflict},
+		{ErrSelfRoleChange, http.StatusConflict},
+		{ErrRoleEscalation, http.StatusForbidden},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		writeAdminError(rr, tt.err)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("writeAdminError(%v) status = %d, want %d", tt.err, rr.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestAdminHandler_InvalidID(t *testing.T) {
+	h := NewAdminHandler(nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.Get(rr, httptest.NewRequest(http.MethodGet, "/admin/api/admins/not-a-uuid", nil))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// testRoles are the roles known to newGrantTestService.
+var testRoles = map[string]Permissions{
+	SuperuserRole: {{Resource: "*", Action: "*"}},
+	"manager": {
+		{Resource: "admins", Action: "read"},
+		{Resource: "admins", Action: "manage"},
+		{Resource: "content:*", Action: "read"},
+	},
+	"editor": {
+		{Resource: "content:*", Action: "read"},
+		{Resource: "content:*", Action: "update"},
+	},
+	"viewer": {{Resource: "content:*", Action: "read"}},
+}
+
+const (
+	testManagerID   = "550e8400-e29b-41d4-a716-446655440001"
+	testSuperuserID = "550e8400-e29b-41d4-a716-446655440002"
+	testViewerID    = "550e8400-e29b-41d4-a716-446655440003"
+)
+
+// newGrantTestService returns a Service that reads testRoles and the
+// admins with the test IDs instead of the database.
+func newGrantTestService() *Service {
+	admins := map[string]*Admin{
+		testManagerID:   {ID: testManagerID, Email: "manager@example.com", Role: "manager"},
+		testSuperuserID: {ID: testSuperuserID, Email: "root@example.com", Role: SuperuserRole},
+		testViewerID:    {ID: testViewerID, Email: "viewer@example.com", Role: "viewer"},
+	}
+	svc := NewService(nil, testSecret)
+	svc.rolePermissions = func(_ context.Context, role string) (Permissions, error) {
+		return testRoles[role], nil
+	}
+	svc.adminByID = func(_ context.Context, id string) (*Admin, error) {
+		if a, ok := admins[id]; ok {
+			return a, nil
+		}
+		return nil, pgx.ErrNoRows
+	}
+	return svc
+}
+
+// adminRequest returns a request by the manager, with the {id} URL
+// parameter set if id is not empty.
+func adminRequest(method, id, body string) *http.Request {
+	r := httptest.NewRequest(method, "/admin/api/admins", strings.NewReader(body))
+	ctx := context.WithValue(r.Context(), ContextKeyAdminID, testManagerID)
+	ctx = context.WithValue(ctx, ContextKeyPermissions, testRoles["manager"])
+	if id != "" {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestAdminHandler_Create_RoleGrants(t *testing.T) {
+	h := NewAdminHandler(newGrantTestService(), nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing role", `{"email":"jane@example.com","password":"long enough"}`, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{"superuser role", `{"email":"jane@example.com","password":"long enough","role":"admin"}`, http.StatusForbidden, "FORBIDDEN"},
+		{"role with more permissions", `{"email":"jane@example.com","password":"long enough","role":"editor"}`, http.StatusForbidden, "FORBIDDEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.Create(rr, adminRequest(http.MethodPost, "", tt.body))
+			if rr.Code != tt.wantStatus || !strings.Contains(rr.Body.String(), tt.wantCode) {
+				t.Errorf("status = %d, body = %s; want %d %s", rr.Code, rr.Body.String(), tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_Update_RoleGrants(t *testing.T) {
+	h := NewAdminHandler(newGrantTestService(), nil)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"own role", testManagerID, `{"role":"admin"}`, http.StatusConflict, "SELF_ACTION"},
+		{"own role unchanged", testManagerID, `{"role":"manager"}`, http.StatusConflict, "SELF_ACTION"},
+		{"grant superuser role", testViewerID, `{"role":"admin"}`, http.StatusForbidden, "FORBIDDEN"},
+		{"grant role with more permissions", testViewerID, `{"role":"editor"}`, http.StatusForbidden, "FORBIDDEN"},
+		{"email of superuser", testSuperuserID, `{"email":"attacker@example.com"}`, http.StatusForbidden, "FORBIDDEN"},
+		{"demote superuser", testSuperuserID, `{"role":"viewer"}`, http.StatusForbidden, "FORBIDDEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.Update(rr, adminRequest(http.MethodPut, tt.id, tt.body))
+			if rr.Code != tt.wantStatus || !strings.Contains(rr.Body.String(), tt.wantCode) {
+				t.Errorf("status = %d, body = %s; want %d %s", rr.Code, rr.Body.String(), tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_ManageSuperuser(t *testing.T) {
+	// The manager may manage admins but not grant the superuser role, so
+	// every action on the superuser's account is refused before the
+	// repository is touched.
+	h := NewAdminHandler(newGrantTestService(), nil)
+
+	tests := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+	}{
+		{"disable", http.MethodPost, h.Disable},
+		{"enable", http.MethodPost, h.Enable},
+		{"delete", http.MethodDelete, h.Delete},
+		{"reset two-factor", http.MethodDelete, h.ResetTwoFactor},
+		{"revoke sessions", http.MethodDelete, h.RevokeSessions},
+		{"revoke session", http.MethodDelete, h.RevokeSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := adminRequest(tt.method, testSuperuserID, "")
+			chi.RouteContext(r.Context()).URLParams.Add("sessionID", "550e8400-e29b-41d4-a716-446655440009")
+			rr := httptest.NewRecorder()
+			tt.handler(rr, r)
+			if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "FORBIDDEN") {
+				t.Errorf("status = %d, body = %s; want 403 FORBIDDEN", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGrantor_MayGrant(t *testing.T) {
+	manager := Grantor{Permissions: testRoles["manager"]}
+	roleManager := Grantor{Permissions: Permissions{{Resource: "roles", Action: "manage"}}}
+	superuser := Grantor{Permissions: testRoles[SuperuserRole]}
+	ownEditor := Grantor{Permissions: Permissions{
+		{Resource: "content:*", Action: "read"},
+		{Resource: "content:*", Action: "update", Own: true},
+	}}
+	scoped := Grantor{
+		Permissions: testRoles[SuperuserRole],
+		Token:       &PersonalAccessToken{Scopes: testRoles["manager"]},
+	}
+
+	tests := []struct {
+		name    string
+		grantor Grantor
+		role    string
+		want    bool
+	}{
+		{"subset", manager, "viewer", true},
+		{"own role", manager, "manager", true},
+		{"missing permission", manager, "editor", false},
+		{"superuser role", manager, SuperuserRole, false},
+		{"superuser", superuser, SuperuserRole, true},
+		{"roles manage", roleManager, SuperuserRole, true},
+		{"own only does not cover full grant", ownEditor, "editor", false},
+		{"token scopes limit", scoped, "editor", false},
+		{"within token scopes", scoped, "viewer", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.grantor.mayGrant(testRoles[tt.role]); got != tt.want {
+				t.Errorf("mayGrant(%s) = %v, want %v", tt.role, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+const (
+	// apiKeyPrefix starts every raw API key, so keys are recognisable in
+	// configuration and distinguishable from JWTs.
+	apiKeyPrefix = "mk_"
+	apiKeyBytes  = 32
+	// apiKeyDisplayLength is how much of the raw key is stored in clear text
+	// to identify it in listings.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+
+	maxAPIKeyNameLength = 100
+)
+
+// Sentinel errors for API keys.
+var (
+	ErrInvalidAPIKey  = errors.New("invalid or revoked API key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey is a read-only token for the public content API. It grants access to
+// the listed content types, public or not, and to their drafts if Drafts is
+// set.
+type APIKey struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	ContentTypes []string   `json:"content_types"`
+	Drafts       bool       `json:"drafts"`
+	CreatedBy    *string    `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	RequestCount int64      `json:"request_count"`
+}
+
+// CanRead reports whether the key grants access to the content type.
+func (k *APIKey) CanRead(contentType string) bool {
+	return k != nil && slices.Contains(k.ContentTypes, contentType)
+}
+
+// CanReadDrafts reports whether the key grants access to unpublished entries
+// of the content type.
+func (k *APIKey) CanReadDrafts(contentType string) bool {
+	return k.CanRead(contentType) && k.Drafts
+}
+
+// APIKeyValidationError is returned when API key input is invalid, or with
+// Forbidden set when the creating admin may not grant the key's scopes.
+type APIKeyValidationError struct {
+	Fields    []server.FieldError
+	Forbidden bool
+}
+
+func (e *APIKeyValidationError) Error() string {
+	return fmt.Sprintf("api key validation failed: %d field error(s)", len(e.Fields))
+}
+
+// CreateAPIKeyInput holds the settings of a new API key.
+type CreateAPIKeyInput struct {
+	Name         string
+	ContentTypes []string
+	Drafts       bool
+
+	// GrantedBy is the admin creating the key, who must be allowed to read
+	// every entry of each of ContentTypes, drafts included. Nil for trusted
+	// callers.
+	GrantedBy *Grantor
+}
+
+// APIKeyService manages API keys. Key usage is buffered in memory and written
+// to the database periodically by a background goroutine, so authenticating
+// a request never waits for a write.
+type APIKeyService struct {
+	repo              *Repository
+	auditService      *audit.Service
+	contentTypeExists func(name string) bool
+	usage             *usageRecorder
+}
+
+// NewAPIKeyService creates a new APIKeyService. contentTypeExists is used to
+// validate key scopes. The audit service is optional. Call Start to begin
+// recording usage, and Shutdown to flush and stop.
+func NewAPIKeyService(repo *Repository, auditService *audit.Service, contentTypeExists func(name string) bool) *APIKeyService {
+	return &APIKeyService{
+		repo:              repo,
+		auditService:      auditService,
+		contentTypeExists: contentTypeExists,
+		usage:             newUsageRecorder("api key", repo.AddAPIKeyUsage),
+	}
+}
+
+// Start begins the background goroutine that writes usage to the database.
+// Must be called once after NewAPIKeyService.
+func (s *APIKeyService) Start() {
+	s.usage.start()
+}
+
+// Shutdown stops the background goroutine after a final flush. The provided
+// context controls the maximum time to wait.
+func (s *APIKeyService) Shutdown(ctx context.Context) {
+	s.usage.shutdown(ctx)
+}
+
+// Create validates the input and creates a new API key. The raw key is only
+// returned here; only its hash is stored.
+func (s *APIKeyService) Create(ctx context.Context, in CreateAPIKeyInput, adminID string) (*APIKey, string, error) {
+	if errs := s.validate(in); len(errs) > 0 {
+		return nil, "", &APIKeyValidationError{Fields: errs}
+	}
+	if errs := authorizeScopes(in); len(errs) > 0 {
+		return nil, "", &APIKeyValidationError{Fields: errs, Forbidden: true}
+	}
+
+	raw := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("generating api key: %w", err)
+	}
+	token := apiKeyPrefix + hex.EncodeToString(raw)
+
+	key, err := s.repo.CreateAPIKey(ctx, strings.TrimSpace(in.Name), hashToken(token),
+		token[:apiKeyDisplayLength], in.ContentTypes, in.Drafts, adminID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "api_key.create",
+		ActorID:    adminID,
+		Resource:   "api_key",
+		ResourceID: key.ID,
+		Payload: map[string]any{
+			"name":          key.Name,
+			"content_types": key.ContentTypes,
+			"drafts":        key.Drafts,
+		},
+	})
+	return key, token, nil
+}
+
+// validate checks the settings of a new API key.
+func (s *APIKeyService) validate(in CreateAPIKeyInput) []server.FieldError {
+	var errs []server.FieldError
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		errs = append(errs, server.FieldError{Field: "name", Message: "is required"})
+	} else if utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		errs = append(errs, server.FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", maxAPIKeyNameLength)})
+	}
+
+	if len(in.ContentTypes) == 0 {
+		errs = append(errs, server.FieldError{Field: "content_types", Message: "must list at least one content type"})
+	}
+	seen := make(map[string]bool, len(in.ContentTypes))
+	for _, ct := range in.ContentTypes {
+		switch {
+		case seen[ct]:
+			errs = append(errs, server.FieldError{Field: "content_types", Message: fmt.Sprintf("duplicate content type '%s'", ct)})
+		case s.contentTypeExists != nil && !s.contentTypeExists(ct):
+			errs = append(errs, server.FieldError{Field: "content_types", Message: fmt.Sprintf("unknown content type '%s'", ct)})
+		}
+		seen[ct] = true
+	}
+	return errs
+}
+
+// authorizeScopes checks that the creating admin may read what the key
+// grants. A key reads all published entries of its content types, and with
+// Drafts all unpublished ones too, so a permission limited to the admin's own
+// entries is not enough.
+func authorizeScopes(in CreateAPIKeyInput) []server.FieldError {
+	if in.GrantedBy == nil {
+		return nil
+	}
+	var errs []server.FieldError
+	for _, ct := range in.ContentTypes {
+		if in.GrantedBy.mayReadAll(ct) {
+			continue
+		}
+		msg := fmt.Sprintf("you may not read all entries of content type '%s'", ct)
+		if in.Drafts {
+			msg = fmt.Sprintf("you may not read all entries, including drafts, of content type '%s'", ct)
+		}
+		errs = append(errs, server.FieldError{Field: "content_types", Message: msg})
+	}
+	return errs
+}
+
+// List returns all API keys, including revoked ones.
+func (s *APIKeyService) List(ctx context.Context) ([]APIKey, error) {
+	keys, err := s.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []APIKey{}
+	}
+	return keys, nil
+}
+
+// Revoke revokes an API key. Requests made with it fail from then on.
+func (s *APIKeyService) Revoke(ctx context.Context, id, adminID string) error {
+	key, err := s.repo.RevokeAPIKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "api_key.revoke",
+		ActorID:    adminID,
+		Resource:   "api_key",
+		ResourceID: key.ID,
+		Payload:    map[string]any{"name": key.Name},
+	})
+	return nil
+}
+
+// Authenticate returns the unrevoked API key matching the raw token and
+// records its use. Returns ErrInvalidAPIKey if there is none.
+func (s *APIKeyService) Authenticate(ctx context.Context, token string) (*APIKey, error) {
+	if !strings.HasPrefix(token, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetActiveAPIKey(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("looking up api key: %w", err)
+	}
+
+	s.usage.record(key.ID, time.Now())
+	return key, nil
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (s *APIKeyService) logAudit(ctx context.Context, event audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Log(ctx, event)
+	}
+}
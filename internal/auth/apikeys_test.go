@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+func TestAPIKey_CanRead(t *testing.T) {
+	key := &APIKey{ContentTypes: []string{"posts"}, Drafts: true}
+	readOnly := &APIKey{ContentTypes: []string{"posts"}}
+	var none *APIKey
+
+	if !key.CanRead("posts") || !key.CanReadDrafts("posts") {
+		t.Error("expected draft access to scoped content type")
+	}
+	if key.CanRead("pages") || key.CanReadDrafts("pages") {
+		t.Error("expected no access to content type outside scope")
+	}
+	if !readOnly.CanRead("posts") || readOnly.CanReadDrafts("posts") {
+		t.Error("expected published-only access without drafts")
+	}
+	if none.CanRead("posts") || none.CanReadDrafts("posts") {
+		t.Error("expected no access without a key")
+	}
+}
+
+func TestAPIKeyService_CreateValidation(t *testing.T) {
+	svc := NewAPIKeyService(nil, nil, func(name string) bool { return name == "posts" })
+
+	tests := []struct {
+		name       string
+		in         CreateAPIKeyInput
+		wantFields []string
+	}{
+		{"empty", CreateAPIKeyInput{Name: "  "}, []string{"name", "content_types"}},
+		{"long name", CreateAPIKeyInput{Name: strings.Repeat("x", maxAPIKeyNameLength+1), ContentTypes: []string{"posts"}}, []string{"name"}},
+		{"unknown type", CreateAPIKeyInput{Name: "site", ContentTypes: []string{"posts", "pages"}}, []string{"content_types"}},
+		{"duplicate type", CreateAPIKeyInput{Name: "site", ContentTypes: []string{"posts", "posts"}}, []string{"content_types"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.Create(context.Background(), tt.in, "admin-id")
+			var valErr *APIKeyValidationError
+			if !errors.As(err, &valErr) {
+				t.Fatalf("expected *APIKeyValidationError, got %v", err)
+			}
+			if len(valErr.Fields) != len(tt.wantFields) {
+				t.Fatalf("got field errors %+v, want fields %v", valErr.Fields, tt.wantFields)
+			}
+			for i, f := range tt.wantFields {
+				if valErr.Fields[i].Field != f {
+					t.Errorf("field error %d = %q, want %q", i, valErr.Fields[i].Field, f)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_CreateScopes(t *testing.T) {
+	svc := NewAPIKeyService(nil, nil, func(name string) bool { return name == "posts" || name == "pages" })
+
+	tests := []struct {
+		name      string
+		grantor   Grantor
+		in        CreateAPIKeyInput
+		wantTypes int
+	}{
+		{"no read permission", Grantor{Permissions: Permissions{{Resource: "content:pages", Action: "read"}}},
+			CreateAPIKeyInput{Name: "site", ContentTypes: []string{"posts", "pages"}}, 1},
+		{"own entries only", Grantor{Permissions: Permissions{{Resource: "content:*", Action: "read", Own: true}}},
+			CreateAPIKeyInput{Name: "preview", ContentTypes: []string{"posts"}, Drafts: true}, 1},
+		{"token without read scope", Grantor{
+			Permissions: Permissions{{Resource: "content:*", Action: "read"}},
+			Token:       &PersonalAccessToken{Scopes: Permissions{{Resource: "content:pages", Action: "read"}}},
+		}, CreateAPIKeyInput{Name: "site", ContentTypes: []string{"posts"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.GrantedBy = &tt.grantor
+			_, _, err := svc.Create(context.Background(), tt.in, "admin-id")
+			var valErr *APIKeyValidationError
+			if !errors.As(err, &valErr) || !valErr.Forbidden {
+				t.Fatalf("expected forbidden *APIKeyValidationError, got %v", err)
+			}
+			if len(valErr.Fields) != tt.wantTypes {
+				t.Errorf("got field errors %+v, want %d", valErr.Fields, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHandler_Create_Forbidden(t *testing.T) {
+	h := NewAPIKeyHandler(NewAPIKeyService(nil, nil, func(name string) bool { return name == "posts" }))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/api-keys",
+		strings.NewReader(`{"name":"preview","content_types":["posts"],"drafts":true}`))
+	ctx := context.WithValue(req.Context(), ContextKeyAdminID, "admin-id")
+	ctx = context.WithValue(ctx, ContextKeyPermissions, Permissions{{Resource: "api_keys", Action: "create"}})
+	w := httptest.NewRecorder()
+	h.Create(w, req.WithContext(ctx))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	// Keys without the mk_ prefix are rejected before the repository is
+	// consulted, so no database is needed.
+	mw := APIKeyMiddleware(NewAPIKeyService(nil, nil, nil))
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"no key", "", "", http.StatusOK},
+		{"bad authorization format", "Authorization", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"invalid bearer key", "Authorization", "Bearer not-a-key", http.StatusUnauthorized},
+		{"invalid header key", "X-Api-Key", "not-a-key", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if APIKeyFromContext(r.Context()) != nil || server.APIKeyFromContext(r.Context()) != "" {
+					t.Error("expected no API key in context")
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
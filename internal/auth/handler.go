@@ -5,10 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/oidc"
 	"github.com/GyroZepelix/mithril-cms/internal/server"
 )
 
@@ -17,6 +24,9 @@ const (
 	refreshCookiePath = "/admin/api/auth"
 	refreshCookieAge  = 7 * 24 * 60 * 60 // 7 days in seconds
 
+	oidcFlowCookieName = "oidc_flow"
+	oidcFlowCookiePath = "/admin/api/auth/oidc"
+
 	// maxRequestBodySize is the maximum allowed size for JSON request bodies
 	// (1 MB). This prevents clients from sending excessively large payloads.
 	maxRequestBodySize = 1 << 20
@@ -49,7 +59,9 @@ type loginRequest struct {
 
 // Login handles POST /admin/api/auth/login. It validates the credentials,
 // returns an access token in the JSON response body, and sets the refresh
-// token as an httpOnly cookie.
+// token as an httpOnly cookie. Admins with two-factor authentication, or who
+// must enroll in it, get a challenge token instead, which they exchange for
+// the tokens at TwoFactorVerify or TwoFactorSetupConfirm.
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
 
@@ -64,7 +76,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	adminID, accessToken, refreshToken, err := h.service.Login(r.Context(), req.Email, req.Password)
+	result, err := h.service.Login(withClientInfo(r), req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
 			h.logAudit(r.Context(), audit.Event{
@@ -74,19 +86,45 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 			server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid email or password", nil)
 			return
 		}
+		if errors.Is(err, ErrAccountDisabled) {
+			h.logAudit(r.Context(), audit.Event{
+				Action:  "admin.login.failure",
+				Payload: map[string]any{"email": req.Email, "reason": "disabled"},
+			})
+			server.Error(w, http.StatusForbidden, "ACCOUNT_DISABLED", err.Error(), nil)
+			return
+		}
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) {
+			h.logAudit(r.Context(), audit.Event{
+				Action:  "admin.login.failure",
+				Payload: map[string]any{"email": req.Email, "reason": "blocked"},
+			})
+			writeLoginBlocked(w, blocked)
+			return
+		}
 		slog.Error("login failed", "error", err)
 		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
 		return
 	}
 
+	if result.ChallengeToken != "" {
+		server.JSON(w, http.StatusOK, map[string]any{
+			"two_factor_required": true,
+			"setup_required":      result.SetupRequired,
+			"challenge_token":     result.ChallengeToken,
+		})
+		return
+	}
+
 	h.logAudit(r.Context(), audit.Event{
 		Action:  "admin.login.success",
-		ActorID: adminID,
+		ActorID: result.AdminID,
 	})
 
-	h.setRefreshCookie(w, refreshToken)
+	h.setRefreshCookie(w, result.RefreshToken)
 	server.JSON(w, http.StatusOK, map[string]string{
-		"access_token": accessToken,
+		"access_token": result.AccessToken,
 	})
 }
 
@@ -105,7 +143,7 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, newRefreshToken, err := h.service.Refresh(r.Context(), cookie.Value)
+	accessToken, newRefreshToken, err := h.service.Refresh(withClientInfo(r), cookie.Value)
 	if err != nil {
 		if errors.Is(err, ErrInvalidToken) {
 			h.clearRefreshCookie(w)
@@ -124,7 +162,8 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 }
 
 // Logout handles POST /admin/api/auth/logout. It reads the refresh token from
-// the cookie, deletes it from the database, and clears the cookie.
+// the cookie, deletes it from the database, and clears the cookie. The access
+// token in the Authorization header, if any, is revoked.
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie(refreshCookieName)
 	if err == nil && cookie.Value != "" {
@@ -133,6 +172,11 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 			// Continue to clear cookie even if DB delete fails.
 		}
 	}
+	if token := bearerToken(r); token != "" {
+		if err := h.service.RevokeAccessToken(r.Context(), token); err != nil {
+			slog.Error("logout failed to revoke access token", "error", err)
+		}
+	}
 
 	h.clearRefreshCookie(w)
 	server.JSON(w, http.StatusOK, map[string]string{
@@ -140,9 +184,10 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Me handles GET /admin/api/auth/me. It reads the authenticated admin's ID
-// and email from the request context (set by the auth middleware) and returns
-// them in the response.
+// Me handles GET /admin/api/auth/me. It reads the authenticated admin's ID,
+// email, role and permissions from the request context (set by the auth middleware) and
+// returns them in the response, along with the personal access token the
+// request was made with, if any.
 func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 	adminID := AdminIDFromContext(r.Context())
 	email := EmailFromContext(r.Context())
@@ -152,10 +197,414 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	perms := PermissionsFromContext(r.Context())
+	if perms == nil {
+		perms = Permissions{}
+	}
+	resp := map[string]any{
+		"id":          adminID,
+		"email":       email,
+		"role":        RoleFromContext(r.Context()),
+		"permissions": perms,
+	}
+	if token := PersonalAccessTokenFromContext(r.Context()); token != nil {
+		resp["token"] = token
+	}
+	server.JSON(w, http.StatusOK, resp)
+}
+
+// changePasswordRequest is the expected JSON body for
+// POST /admin/api/auth/password.
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword handles POST /admin/api/auth/password. It changes the
+// authenticated admin's password after verifying the current one. All other
+// sessions are signed out; this one continues with the returned access token
+// and a new refresh cookie.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	adminID := AdminIDFromContext(r.Context())
+	accessToken, refreshToken, err := h.service.ChangePassword(withClientInfo(r), adminID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		var valErr *AdminValidationError
+		switch {
+		case errors.Is(err, ErrWrongPassword):
+			h.logAudit(r.Context(), audit.Event{
+				Action:     "admin.password.change_failure",
+				ActorID:    adminID,
+				Resource:   "admin",
+				ResourceID: adminID,
+			})
+			server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed",
+				[]server.FieldError{{Field: "current_password", Message: "is incorrect"}})
+		case errors.As(err, &valErr):
+			server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", valErr.Fields)
+		case errors.Is(err, ErrAdminNotFound):
+			server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated", nil)
+		default:
+			slog.Error("changing password failed", "error", err)
+			server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		}
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.password.change",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
+	})
+
+	h.setRefreshCookie(w, refreshToken)
+	server.JSON(w, http.StatusOK, map[string]string{
+		"access_token": accessToken,
+	})
+}
+
+// ListSessions handles GET /admin/api/auth/sessions. It lists the
+// authenticated admin's active sessions, marking the one the request was
+// made from.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.service.ListSessions(r.Context(), AdminIDFromContext(r.Context()), refreshCookieValue(r))
+	if err != nil {
+		slog.Error("listing sessions failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+	server.JSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /admin/api/auth/sessions/{id}. It signs one
+// of the authenticated admin's sessions out; revoking the current session
+// also clears the refresh cookie.
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	adminID := AdminIDFromContext(r.Context())
+	currentID, err := h.service.SessionID(r.Context(), refreshCookieValue(r))
+	if err == nil {
+		err = h.service.RevokeSession(r.Context(), adminID, id)
+	}
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.session.revoke",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
+		Payload:    map[string]any{"session_id": id},
+	})
+	if id == currentID {
+		h.clearRefreshCookie(w)
+	}
+	server.JSON(w, http.StatusOK, map[string]string{"message": "session revoked"})
+}
+
+// RevokeAllSessions handles DELETE /admin/api/auth/sessions. It signs the
+// authenticated admin out everywhere, including the current session.
+func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	adminID := AdminIDFromContext(r.Context())
+	if err := h.service.RevokeAllSessions(r.Context(), adminID); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.session.revoke_all",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
+	})
+	h.clearRefreshCookie(w)
+	server.JSON(w, http.StatusOK, map[string]string{"message": "all sessions revoked"})
+}
+
+// twoFactorCodeRequest is the expected JSON body for the endpoints that take
+// a two-factor code. ChallengeToken is only used during login.
+type twoFactorCodeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// passwordConfirmRequest is the expected JSON body for two-factor changes
+// that require the admin's password.
+type passwordConfirmRequest struct {
+	Password string `json:"password"`
+}
+
+// TwoFactorVerify handles POST /admin/api/auth/2fa/verify. It completes a
+// login with a code from the admin's authenticator or a recovery code, and
+// responds like Login.
+func (h *Handler) TwoFactorVerify(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	result, err := h.service.CompleteTwoFactorLogin(withClientInfo(r), req.ChallengeToken, req.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "login")
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:  "admin.login.success",
+		ActorID: result.AdminID,
+		Payload: map[string]any{"two_factor": result.Method},
+	})
+
+	h.setRefreshCookie(w, result.RefreshToken)
 	server.JSON(w, http.StatusOK, map[string]string{
-		"id":    adminID,
-		"email": email,
+		"access_token": result.AccessToken,
+	})
+}
+
+// TwoFactorSetup handles POST /admin/api/auth/2fa/setup. During login of an
+// admin who must enroll, it starts the enrollment and returns the secret.
+func (h *Handler) TwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	enrollment, err := h.service.BeginTwoFactorSetup(r.Context(), req.ChallengeToken)
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "enroll")
+		return
+	}
+
+	server.JSON(w, http.StatusOK, enrollment)
+}
+
+// TwoFactorSetupConfirm handles POST /admin/api/auth/2fa/setup/confirm. It
+// confirms the enrollment started at TwoFactorSetup and completes the login,
+// returning the access token together with the recovery codes.
+func (h *Handler) TwoFactorSetupConfirm(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	result, codes, err := h.service.CompleteTwoFactorSetup(withClientInfo(r), req.ChallengeToken, req.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "enroll")
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.2fa.enroll",
+		ActorID:    result.AdminID,
+		Resource:   "admin",
+		ResourceID: result.AdminID,
+	})
+	h.logAudit(r.Context(), audit.Event{
+		Action:  "admin.login.success",
+		ActorID: result.AdminID,
+		Payload: map[string]any{"two_factor": result.Method},
+	})
+
+	h.setRefreshCookie(w, result.RefreshToken)
+	server.JSON(w, http.StatusOK, map[string]any{
+		"access_token":   result.AccessToken,
+		"recovery_codes": codes,
+	})
+}
+
+// TwoFactorStatus handles GET /admin/api/auth/2fa.
+func (h *Handler) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.service.TwoFactorStatus(r.Context(), AdminIDFromContext(r.Context()))
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "")
+		return
+	}
+
+	server.JSON(w, http.StatusOK, status)
+}
+
+// TwoFactorEnroll handles POST /admin/api/auth/2fa/enroll. It starts the
+// authenticated admin's enrollment and returns the secret; it takes effect
+// once confirmed at TwoFactorEnrollConfirm.
+func (h *Handler) TwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	enrollment, err := h.service.BeginTwoFactorEnrollment(r.Context(), AdminIDFromContext(r.Context()))
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "enroll")
+		return
+	}
+
+	server.JSON(w, http.StatusOK, enrollment)
+}
+
+// TwoFactorEnrollConfirm handles POST /admin/api/auth/2fa/enroll/confirm. It
+// enables two-factor authentication and returns the recovery codes.
+func (h *Handler) TwoFactorEnrollConfirm(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	adminID := AdminIDFromContext(r.Context())
+	codes, err := h.service.ConfirmTwoFactorEnrollment(r.Context(), adminID, req.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "enroll")
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.2fa.enroll",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
+	})
+	server.JSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// TwoFactorDisable handles POST /admin/api/auth/2fa/disable. The admin's
+// password is required.
+func (h *Handler) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req passwordConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	adminID := AdminIDFromContext(r.Context())
+	if err := h.service.DisableTwoFactor(r.Context(), adminID, req.Password); err != nil {
+		h.writeTwoFactorError(w, r, err, "")
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.2fa.disable",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
+	})
+	server.JSON(w, http.StatusOK, map[string]string{"message": "two-factor authentication disabled"})
+}
+
+// TwoFactorRecoveryCodes handles POST /admin/api/auth/2fa/recovery-codes. It
+// replaces the admin's recovery codes; the admin's password is required.
+func (h *Handler) TwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req passwordConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	adminID := AdminIDFromContext(r.Context())
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), adminID, req.Password)
+	if err != nil {
+		h.writeTwoFactorError(w, r, err, "")
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.2fa.recovery_codes",
+		ActorID:    adminID,
+		Resource:   "admin",
+		ResourceID: adminID,
 	})
+	server.JSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// writeTwoFactorError writes the error response for two-factor errors. Wrong
+// codes are audited as admin.2fa.failure with the given stage.
+func (h *Handler) writeTwoFactorError(w http.ResponseWriter, r *http.Request, err error, stage string) {
+	var codeErr *TwoFactorCodeError
+	var blocked *LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		writeLoginBlocked(w, blocked)
+	case errors.As(err, &codeErr):
+		h.logAudit(r.Context(), audit.Event{
+			Action:     "admin.2fa.failure",
+			ActorID:    codeErr.AdminID,
+			Resource:   "admin",
+			ResourceID: codeErr.AdminID,
+			Payload:    map[string]any{"stage": stage},
+		})
+		server.Error(w, http.StatusUnauthorized, "INVALID_CODE", err.Error(), nil)
+	case errors.Is(err, ErrInvalidChallenge):
+		server.Error(w, http.StatusUnauthorized, "INVALID_CHALLENGE", err.Error(), nil)
+	case errors.Is(err, ErrAccountDisabled):
+		server.Error(w, http.StatusForbidden, "ACCOUNT_DISABLED", err.Error(), nil)
+	case errors.Is(err, ErrWrongPassword):
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed",
+			[]server.FieldError{{Field: "password", Message: "is incorrect"}})
+	case errors.Is(err, ErrTwoFactorEnabled):
+		server.Error(w, http.StatusConflict, "TWO_FACTOR_ENABLED", err.Error(), nil)
+	case errors.Is(err, ErrTwoFactorNotEnabled):
+		server.Error(w, http.StatusConflict, "TWO_FACTOR_NOT_ENABLED", err.Error(), nil)
+	case errors.Is(err, ErrTwoFactorNotPending):
+		server.Error(w, http.StatusConflict, "TWO_FACTOR_NOT_PENDING", err.Error(), nil)
+	case errors.Is(err, ErrTwoFactorEnforced):
+		server.Error(w, http.StatusConflict, "TWO_FACTOR_REQUIRED", err.Error(), nil)
+	case errors.Is(err, ErrAdminNotFound):
+		server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "not authenticated", nil)
+	default:
+		slog.Error("two-factor request failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+	}
+}
+
+// writeLoginBlocked writes the response for a login refused after failed
+// attempts, telling the client when to retry.
+func writeLoginBlocked(w http.ResponseWriter, err *LoginBlockedError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	server.Error(w, http.StatusTooManyRequests, "LOGIN_BLOCKED", err.Error(), nil)
 }
 
 // logAudit sends an audit event if the audit service is configured.
@@ -167,15 +616,16 @@ func (h *Handler) logAudit(ctx context.Context, event audit.Event) {
 
 // setRefreshCookie sets the refresh token as an httpOnly cookie on the response.
 func (h *Handler) setRefreshCookie(w http.ResponseWriter, token string) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     refreshCookieName,
-		Value:    token,
-		Path:     refreshCookiePath,
-		MaxAge:   refreshCookieAge,
-		HttpOnly: true,
-		Secure:   !h.devMode,
-		SameSite: http.SameSiteStrictMode,
-	})
+	writeRefreshCookie(w, token, h.devMode)
+}
+
+// refreshCookieValue returns the refresh token from the request's cookie, or
+// "" if there is none.
+func refreshCookieValue(r *http.Request) string {
+	if cookie, err := r.Cookie(refreshCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
 }
 
 // clearRefreshCookie removes the refresh token cookie by setting it to an
@@ -192,3 +642,1066 @@ func (h *Handler) clearRefreshCookie(w http.ResponseWriter) {
 		SameSite: http.SameSiteStrictMode,
 	})
 }
+
+// writeRefreshCookie sets the refresh token cookie. It is only sent over
+// HTTPS unless devMode is set.
+func writeRefreshCookie(w http.ResponseWriter, token string, devMode bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    token,
+		Path:     refreshCookiePath,
+		MaxAge:   refreshCookieAge,
+		HttpOnly: true,
+		Secure:   !devMode,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// uuidRegex matches a UUID string.
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// APIKeyHandler provides HTTP handlers for API key management.
+type APIKeyHandler struct {
+	service *APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(service *APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// createAPIKeyRequest is the expected JSON body for POST /admin/api/api-keys.
+type createAPIKeyRequest struct {
+	Name         string   `json:"name"`
+	ContentTypes []string `json:"content_types"`
+	Drafts       bool     `json:"drafts"`
+}
+
+// createdAPIKey is the response to key creation: the key plus its raw token,
+// which is never shown again.
+type createdAPIKey struct {
+	*APIKey
+	Token string `json:"token"`
+}
+
+// List handles GET /admin/api/api-keys.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.List(r.Context())
+	if err != nil {
+		slog.Error("listing api keys failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, keys)
+}
+
+// Create handles POST /admin/api/api-keys. The admin must be able to read
+// everything the key grants.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	grantor := GrantorFromContext(r.Context())
+	key, token, err := h.service.Create(r.Context(), CreateAPIKeyInput{
+		Name:         req.Name,
+		ContentTypes: req.ContentTypes,
+		Drafts:       req.Drafts,
+		GrantedBy:    &grantor,
+	}, grantor.AdminID)
+	if err != nil {
+		var valErr *APIKeyValidationError
+		if errors.As(err, &valErr) {
+			if valErr.Forbidden {
+				server.Error(w, http.StatusForbidden, "FORBIDDEN", "the key grants access you do not have", valErr.Fields)
+				return
+			}
+			server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", valErr.Fields)
+			return
+		}
+		slog.Error("creating api key failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusCreated, createdAPIKey{APIKey: key, Token: token})
+}
+
+// Revoke handles DELETE /admin/api/api-keys/{id}.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !uuidRegex.MatchString(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), id, AdminIDFromContext(r.Context())); err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			server.Error(w, http.StatusNotFound, "NOT_FOUND", "api key not found", nil)
+			return
+		}
+		slog.Error("revoking api key failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "revoked"})
+}
+
+// TokenHandler provides HTTP handlers for personal access tokens: an admin's
+// own under /auth/tokens, and any admin's under /admins/{id}/tokens.
+type TokenHandler struct {
+	service *TokenService
+}
+
+// NewTokenHandler creates a new TokenHandler.
+func NewTokenHandler(service *TokenService) *TokenHandler {
+	return &TokenHandler{service: service}
+}
+
+// createTokenRequest is the expected JSON body for
+// POST /admin/api/auth/tokens.
+type createTokenRequest struct {
+	Name          string      `json:"name"`
+	Scopes        Permissions `json:"scopes"`
+	ExpiresInDays int         `json:"expires_in_days"`
+}
+
+// createdToken is the response to token creation: the token plus its raw
+// value, which is never shown again.
+type createdToken struct {
+	*PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// List handles GET /admin/api/auth/tokens. It lists the authenticated
+// admin's personal access tokens.
+func (h *TokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, AdminIDFromContext(r.Context()))
+}
+
+// Create handles POST /admin/api/auth/tokens. It creates a personal access
+// token for the authenticated admin. Tokens cannot create tokens.
+func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if rejectPersonalAccessToken(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	token, raw, err := h.service.Create(r.Context(), AdminIDFromContext(r.Context()), CreateTokenInput{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusCreated, createdToken{PersonalAccessToken: token, Token: raw})
+}
+
+// Revoke handles DELETE /admin/api/auth/tokens/{id}. It revokes one of the
+// authenticated admin's personal access tokens.
+func (h *TokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	adminID := AdminIDFromContext(r.Context())
+	h.revoke(w, r, adminID, chi.URLParam(r, "id"))
+}
+
+// AdminList handles GET /admin/api/admins/{id}/tokens.
+func (h *TokenHandler) AdminList(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, id)
+}
+
+// AdminRevoke handles DELETE /admin/api/admins/{id}/tokens/{tokenID}.
+func (h *TokenHandler) AdminRevoke(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+	h.revoke(w, r, id, chi.URLParam(r, "tokenID"))
+}
+
+// list writes the personal access tokens of an admin.
+func (h *TokenHandler) list(w http.ResponseWriter, r *http.Request, adminID string) {
+	tokens, err := h.service.List(r.Context(), adminID)
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+	server.JSON(w, http.StatusOK, tokens)
+}
+
+// revoke revokes a personal access token of an admin.
+func (h *TokenHandler) revoke(w http.ResponseWriter, r *http.Request, adminID, tokenID string) {
+	if !uuidRegex.MatchString(tokenID) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "token id must be a valid UUID", nil)
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), adminID, tokenID, AdminIDFromContext(r.Context())); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+	server.JSON(w, http.StatusOK, map[string]string{"message": "revoked"})
+}
+
+// writeTokenError writes the error response for personal access token
+// errors.
+func writeTokenError(w http.ResponseWriter, err error) {
+	var valErr *TokenValidationError
+	switch {
+	case errors.As(err, &valErr):
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", valErr.Fields)
+	case errors.Is(err, ErrAdminNotFound), errors.Is(err, ErrPersonalAccessTokenNotFound):
+		server.Error(w, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	default:
+		slog.Error("personal access token management failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+	}
+}
+
+// rejectPersonalAccessToken refuses requests made with a personal access
+// token on endpoints that manage the admin's credentials, so a leaked token
+// cannot be used to take over the account. It writes a 403 response and
+// returns true for such requests.
+func rejectPersonalAccessToken(w http.ResponseWriter, r *http.Request) bool {
+	if PersonalAccessTokenFromContext(r.Context()) == nil {
+		return false
+	}
+	server.Error(w, http.StatusForbidden, "FORBIDDEN", "not allowed with a personal access token", nil)
+	return true
+}
+
+// RoleHandler provides HTTP handlers for role management.
+type RoleHandler struct {
+	service      *Service
+	auditService *audit.Service
+}
+
+// NewRoleHandler creates a new RoleHandler. The audit service is optional; if
+// nil, audit events are silently skipped.
+func NewRoleHandler(service *Service, auditService *audit.Service) *RoleHandler {
+	return &RoleHandler{service: service, auditService: auditService}
+}
+
+// saveRoleRequest is the expected JSON body for PUT /admin/api/roles/{name}.
+type saveRoleRequest struct {
+	Description string      `json:"description"`
+	Permissions Permissions `json:"permissions"`
+}
+
+// List handles GET /admin/api/roles.
+func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.ListRoles(r.Context())
+	if err != nil {
+		slog.Error("listing roles failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, roles)
+}
+
+// Save handles PUT /admin/api/roles/{name}. It creates the role or replaces
+// its description and permissions.
+func (h *RoleHandler) Save(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req saveRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	role := Role{Name: chi.URLParam(r, "name"), Description: req.Description, Permissions: req.Permissions}
+	created, err := h.service.SaveRole(r.Context(), role)
+	if err != nil {
+		var valErr *RoleValidationError
+		switch {
+		case errors.As(err, &valErr):
+			server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", valErr.Fields)
+		case errors.Is(err, ErrRoleBuiltIn):
+			server.Error(w, http.StatusConflict, "BUILT_IN_ROLE", err.Error(), nil)
+		default:
+			slog.Error("saving role failed", "error", err, "role", role.Name)
+			server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		}
+		return
+	}
+
+	action, status := "role.update", http.StatusOK
+	if created {
+		action, status = "role.create", http.StatusCreated
+	}
+	h.logAudit(r.Context(), audit.Event{
+		Action:   action,
+		ActorID:  AdminIDFromContext(r.Context()),
+		Resource: "role",
+		Payload:  map[string]any{"name": role.Name, "permissions": role.Permissions},
+	})
+
+	if role.Permissions == nil {
+		role.Permissions = Permissions{}
+	}
+	server.JSON(w, status, role)
+}
+
+// Delete handles DELETE /admin/api/roles/{name}. Roles still assigned to
+// admins cannot be deleted.
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.service.DeleteRole(r.Context(), name); err != nil {
+		switch {
+		case errors.Is(err, ErrRoleNotFound):
+			server.Error(w, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+		case errors.Is(err, ErrRoleInUse):
+			server.Error(w, http.StatusConflict, "ROLE_IN_USE", err.Error(), nil)
+		case errors.Is(err, ErrRoleBuiltIn):
+			server.Error(w, http.StatusConflict, "BUILT_IN_ROLE", err.Error(), nil)
+		default:
+			slog.Error("deleting role failed", "error", err, "role", name)
+			server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		}
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:   "role.delete",
+		ActorID:  AdminIDFromContext(r.Context()),
+		Resource: "role",
+		Payload:  map[string]any{"name": name},
+	})
+	server.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (h *RoleHandler) logAudit(ctx context.Context, event audit.Event) {
+	if h.auditService != nil {
+		h.auditService.Log(ctx, event)
+	}
+}
+
+// AdminHandler provides HTTP handlers for managing admin accounts.
+type AdminHandler struct {
+	service      *Service
+	auditService *audit.Service
+}
+
+// NewAdminHandler creates a new AdminHandler. The audit service is optional;
+// if nil, audit events are silently skipped.
+func NewAdminHandler(service *Service, auditService *audit.Service) *AdminHandler {
+	return &AdminHandler{service: service, auditService: auditService}
+}
+
+// createAdminRequest is the expected JSON body for POST /admin/api/admins.
+type createAdminRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// updateAdminRequest is the expected JSON body for PUT /admin/api/admins/{id}.
+type updateAdminRequest struct {
+	Email *string `json:"email"`
+	Role  *string `json:"role"`
+}
+
+// List handles GET /admin/api/admins.
+func (h *AdminHandler) List(w http.ResponseWriter, r *http.Request) {
+	admins, err := h.service.ListAdmins(r.Context())
+	if err != nil {
+		slog.Error("listing admins failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, admins)
+}
+
+// Get handles GET /admin/api/admins/{id}.
+func (h *AdminHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	admin, err := h.service.GetAdmin(r.Context(), id)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, admin)
+}
+
+// Create handles POST /admin/api/admins. The role is required, and must not
+// have permissions the caller lacks, unless they may manage roles.
+func (h *AdminHandler) Create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req createAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	grantor := GrantorFromContext(r.Context())
+	admin, err := h.service.CreateAdmin(r.Context(), CreateAdminInput{
+		Email:     req.Email,
+		Password:  req.Password,
+		Role:      req.Role,
+		GrantedBy: &grantor,
+	})
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.create",
+		ActorID:    AdminIDFromContext(r.Context()),
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email, "role": admin.Role},
+	})
+	server.JSON(w, http.StatusCreated, admin)
+}
+
+// Update handles PUT /admin/api/admins/{id}. Only the fields present in the
+// body are changed. Admins cannot change their own role, and can only change
+// other admins whose role, and give roles whose permissions, they have
+// themselves, unless they may manage roles.
+func (h *AdminHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req updateAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	before, err := h.service.GetAdmin(r.Context(), id)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	grantor := GrantorFromContext(r.Context())
+	admin, err := h.service.UpdateAdmin(r.Context(), id, UpdateAdminInput{Email: req.Email, Role: req.Role, GrantedBy: &grantor})
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	changes := make(map[string]any)
+	if admin.Email != before.Email {
+		changes["email"] = map[string]string{"from": before.Email, "to": admin.Email}
+	}
+	if admin.Role != before.Role {
+		changes["role"] = map[string]string{"from": before.Role, "to": admin.Role}
+	}
+	if len(changes) > 0 {
+		h.logAudit(r.Context(), audit.Event{
+			Action:     "admin.update",
+			ActorID:    AdminIDFromContext(r.Context()),
+			Resource:   "admin",
+			ResourceID: admin.ID,
+			Payload:    changes,
+		})
+	}
+	server.JSON(w, http.StatusOK, admin)
+}
+
+// Disable handles POST /admin/api/admins/{id}/disable. The admin can no
+// longer log in and all of their sessions are revoked.
+func (h *AdminHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	grantor := GrantorFromContext(r.Context())
+	admin, err := h.service.DisableAdmin(r.Context(), id, &grantor)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.disable",
+		ActorID:    grantor.AdminID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email},
+	})
+	server.JSON(w, http.StatusOK, admin)
+}
+
+// Enable handles POST /admin/api/admins/{id}/enable.
+func (h *AdminHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	grantor := GrantorFromContext(r.Context())
+	admin, err := h.service.EnableAdmin(r.Context(), id, &grantor)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.enable",
+		ActorID:    grantor.AdminID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email},
+	})
+	server.JSON(w, http.StatusOK, admin)
+}
+
+// Delete handles DELETE /admin/api/admins/{id}.
+func (h *AdminHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	grantor := GrantorFromContext(r.Context())
+	admin, err := h.service.DeleteAdmin(r.Context(), id, &grantor)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.delete",
+		ActorID:    grantor.AdminID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email, "role": admin.Role},
+	})
+	server.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// ResetTwoFactor handles DELETE /admin/api/admins/{id}/two-factor. It removes
+// the admin's two-factor authentication, for when they lost their
+// authenticator and recovery codes.
+func (h *AdminHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	grantor := GrantorFromContext(r.Context())
+	admin, err := h.service.ResetTwoFactor(r.Context(), id, &grantor)
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			server.Error(w, http.StatusConflict, "TWO_FACTOR_NOT_ENABLED", err.Error(), nil)
+			return
+		}
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.2fa.disable",
+		ActorID:    grantor.AdminID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email, "reset": true},
+	})
+	server.JSON(w, http.StatusOK, map[string]string{"message": "two-factor authentication reset"})
+}
+
+// Unlock handles POST /admin/api/admins/{id}/unlock. It lifts the lock and
+// delays on logins for the admin's email after failed attempts.
+func (h *AdminHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	admin, err := h.service.UnlockAdmin(r.Context(), id)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.unlock",
+		ActorID:    AdminIDFromContext(r.Context()),
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email},
+	})
+	server.JSON(w, http.StatusOK, admin)
+}
+
+// ListSessions handles GET /admin/api/admins/{id}/sessions.
+func (h *AdminHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.service.GetAdmin(r.Context(), id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	sessions, err := h.service.ListSessions(r.Context(), id, refreshCookieValue(r))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	server.JSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /admin/api/admins/{id}/sessions/{sessionID}.
+// Signing out another admin needs the privilege to grant their role.
+func (h *AdminHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+	sessionID := chi.URLParam(r, "sessionID")
+	if !uuidRegex.MatchString(sessionID) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "sessionID must be a valid UUID", nil)
+		return
+	}
+
+	if _, err := h.service.authorizeManage(r.Context(), GrantorFromContext(r.Context()), id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err := h.service.RevokeSession(r.Context(), id, sessionID); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.session.revoke",
+		ActorID:    AdminIDFromContext(r.Context()),
+		Resource:   "admin",
+		ResourceID: id,
+		Payload:    map[string]any{"session_id": sessionID},
+	})
+	server.JSON(w, http.StatusOK, map[string]string{"message": "session revoked"})
+}
+
+// RevokeSessions handles DELETE /admin/api/admins/{id}/sessions. It signs
+// the admin out everywhere, which for another admin needs the privilege to
+// grant their role.
+func (h *AdminHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	admin, err := h.service.authorizeManage(r.Context(), GrantorFromContext(r.Context()), id)
+	if err == nil {
+		err = h.service.RevokeAllSessions(r.Context(), id)
+	}
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:     "admin.session.revoke_all",
+		ActorID:    AdminIDFromContext(r.Context()),
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload:    map[string]any{"email": admin.Email},
+	})
+	server.JSON(w, http.StatusOK, map[string]string{"message": "all sessions revoked"})
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (h *AdminHandler) logAudit(ctx context.Context, event audit.Event) {
+	if h.auditService != nil {
+		h.auditService.Log(ctx, event)
+	}
+}
+
+// adminIDParam reads the {id} URL parameter. It writes an error response and
+// returns false if it is not a UUID.
+func adminIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := chi.URLParam(r, "id")
+	if !uuidRegex.MatchString(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return "", false
+	}
+	return id, true
+}
+
+// writeAdminError writes the error response for admin management errors.
+func writeAdminError(w http.ResponseWriter, err error) {
+	var valErr *AdminValidationError
+	switch {
+	case errors.As(err, &valErr):
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", valErr.Fields)
+	case errors.Is(err, ErrAdminNotFound), errors.Is(err, ErrSessionNotFound):
+		server.Error(w, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrEmailTaken):
+		server.Error(w, http.StatusConflict, "EMAIL_TAKEN", err.Error(), nil)
+	case errors.Is(err, ErrLastSuperuser):
+		server.Error(w, http.StatusConflict, "LAST_SUPERUSER", err.Error(), nil)
+	case errors.Is(err, ErrAdminInUse):
+		server.Error(w, http.StatusConflict, "ADMIN_IN_USE", err.Error(), nil)
+	case errors.Is(err, ErrSelfAction), errors.Is(err, ErrSelfRoleChange):
+		server.Error(w, http.StatusConflict, "SELF_ACTION", err.Error(), nil)
+	case errors.Is(err, ErrRoleEscalation):
+		server.Error(w, http.StatusForbidden, "FORBIDDEN", err.Error(), nil)
+	default:
+		slog.Error("admin management failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+	}
+}
+
+// AccountHandler provides HTTP handlers for admin invitations and password
+// resets.
+type AccountHandler struct {
+	service *AccountService
+}
+
+// NewAccountHandler creates a new AccountHandler.
+func NewAccountHandler(service *AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// createInviteRequest is the expected JSON body for POST /admin/api/invites.
+type createInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// redeemTokenRequest is the expected JSON body for
+// POST /admin/api/auth/invite/accept and
+// POST /admin/api/auth/password-reset/confirm.
+type redeemTokenRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// passwordResetRequest is the expected JSON body for
+// POST /admin/api/auth/password-reset.
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// ListInvites handles GET /admin/api/invites.
+func (h *AccountHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.service.ListInvites(r.Context())
+	if err != nil {
+		slog.Error("listing invites failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, invites)
+}
+
+// CreateInvite handles POST /admin/api/invites. It emails the invitation link
+// to the invitee.
+func (h *AccountHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req createInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	invite, err := h.service.Invite(r.Context(), req.Email, req.Role, GrantorFromContext(r.Context()))
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusCreated, invite)
+}
+
+// RevokeInvite handles DELETE /admin/api/invites/{id}.
+func (h *AccountHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeInvite(r.Context(), id, AdminIDFromContext(r.Context())); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "revoked"})
+}
+
+// AcceptInvite handles POST /admin/api/auth/invite/accept. It creates the
+// invited admin with the chosen password; the admin then logs in normally.
+func (h *AccountHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req redeemTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	admin, err := h.service.AcceptInvite(r.Context(), req.Token, req.Password)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusCreated, admin)
+}
+
+// RequestPasswordReset handles POST /admin/api/auth/password-reset. The
+// response is the same whether or not the email belongs to an admin.
+func (h *AccountHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	h.service.RequestPasswordReset(r.Context(), req.Email)
+
+	server.JSON(w, http.StatusOK, map[string]string{
+		"message": "if the email belongs to an account, a reset link has been sent",
+	})
+}
+
+// ResetPassword handles POST /admin/api/auth/password-reset/confirm. All of
+// the admin's sessions are signed out; the admin then logs in normally.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req redeemTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON", "invalid or too-large JSON body", nil)
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "password reset"})
+}
+
+// writeAccountError writes the error response for invitation and password
+// reset errors.
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidAccountToken):
+		server.Error(w, http.StatusBadRequest, "INVALID_TOKEN", err.Error(), nil)
+	case errors.Is(err, ErrInviteNotFound):
+		server.Error(w, http.StatusNotFound, "NOT_FOUND", err.Error(), nil)
+	case errors.Is(err, ErrMailNotConfigured):
+		server.Error(w, http.StatusServiceUnavailable, "MAIL_NOT_CONFIGURED", err.Error(), nil)
+	case errors.Is(err, ErrMailDelivery):
+		server.Error(w, http.StatusBadGateway, "MAIL_DELIVERY_FAILED", err.Error(), nil)
+	default:
+		writeAdminError(w, err)
+	}
+}
+
+// OIDCHandler provides HTTP handlers for single sign-on with an OpenID
+// Connect identity provider. Unlike the other auth handlers, its login and
+// callback are browser navigations: they answer with redirects, and the
+// admin UI restores the session from the refresh cookie.
+type OIDCHandler struct {
+	service      *OIDCService
+	auditService *audit.Service
+	devMode      bool
+}
+
+// NewOIDCHandler creates a new OIDCHandler. The devMode flag controls the
+// Secure flag of its cookies, as for NewHandler. The audit service is
+// optional.
+func NewOIDCHandler(service *OIDCService, auditService *audit.Service, devMode bool) *OIDCHandler {
+	return &OIDCHandler{
+		service:      service,
+		auditService: auditService,
+		devMode:      devMode,
+	}
+}
+
+// Status handles GET /admin/api/auth/oidc. It tells the login page that
+// single sign-on is available.
+func (h *OIDCHandler) Status(w http.ResponseWriter, r *http.Request) {
+	server.JSON(w, http.StatusOK, map[string]bool{"enabled": true})
+}
+
+// Login handles GET /admin/api/auth/oidc/login. It redirects to the identity
+// provider, keeping the values to check the callback against in a cookie.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, flowToken, err := h.service.Begin(r.Context())
+	if err != nil {
+		slog.Error("starting oidc login failed", "error", err)
+		h.redirectError(w, r, "provider")
+		return
+	}
+
+	// SameSite=Lax, as the callback is a navigation from the provider's site.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookieName,
+		Value:    flowToken,
+		Path:     oidcFlowCookiePath,
+		MaxAge:   int(oidcFlowExpiry / time.Second),
+		HttpOnly: true,
+		Secure:   !h.devMode,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback handles GET /admin/api/auth/oidc/callback, where the identity
+// provider sends the user back. On success it sets the refresh cookie and
+// redirects to the admin UI. If a second factor is required, it redirects to
+// the login page with the challenge token in the fragment; otherwise it
+// redirects to the login page with the sso_error query parameter set.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookieName,
+		Value:    "",
+		Path:     oidcFlowCookiePath,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		HttpOnly: true,
+		Secure:   !h.devMode,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := r.URL.Query()
+	if reason := q.Get("error"); reason != "" {
+		slog.Info("oidc login denied by provider", "error", reason, "description", q.Get("error_description"))
+		h.redirectError(w, r, "denied")
+		return
+	}
+	cookie, err := r.Cookie(oidcFlowCookieName)
+	if err != nil {
+		h.redirectError(w, r, "state")
+		return
+	}
+
+	result, err := h.service.Complete(withClientInfo(r), cookie.Value, q.Get("state"), q.Get("code"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if result.ChallengeToken != "" {
+		// The fragment is not sent to servers or in the Referer header.
+		fragment := url.Values{"challenge_token": {result.ChallengeToken}}
+		if result.SetupRequired {
+			fragment.Set("setup_required", "true")
+		}
+		http.Redirect(w, r, "/admin/login#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	h.logAudit(r.Context(), audit.Event{
+		Action:  "admin.login.success",
+		ActorID: result.AdminID,
+		Payload: map[string]any{"method": "oidc"},
+	})
+
+	writeRefreshCookie(w, result.RefreshToken, h.devMode)
+	http.Redirect(w, r, "/admin/", http.StatusFound)
+}
+
+// writeError redirects to the login page with the code of a sign-in error,
+// auditing users the provider authenticated but who may not sign in.
+func (h *OIDCHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var denied *OIDCError
+	switch {
+	case errors.Is(err, ErrOIDCState):
+		h.redirectError(w, r, "state")
+	case errors.As(err, &denied):
+		code := "not_allowed"
+		switch {
+		case errors.Is(err, ErrOIDCEmailUnverified):
+			code = "email_unverified"
+		case errors.Is(err, ErrOIDCDomainNotAllowed):
+			code = "domain"
+		case errors.Is(err, ErrOIDCNoRole):
+			code = "no_role"
+		case errors.Is(err, ErrOIDCIdentityConflict):
+			code = "identity_conflict"
+		case errors.Is(err, ErrOIDCTwoFactorLink):
+			code = "two_factor_link"
+		case errors.Is(err, ErrAccountDisabled):
+			code = "disabled"
+		}
+		h.logAudit(r.Context(), audit.Event{
+			Action:  "admin.login.failure",
+			Payload: map[string]any{"email": denied.Email, "method": "oidc", "reason": code},
+		})
+		h.redirectError(w, r, code)
+	case errors.Is(err, oidc.ErrProvider), errors.Is(err, oidc.ErrInvalidIDToken):
+		slog.Warn("oidc login failed", "error", err)
+		h.redirectError(w, r, "provider")
+	default:
+		slog.Error("oidc login failed", "error", err)
+		h.redirectError(w, r, "internal")
+	}
+}
+
+// redirectError redirects to the login page with the given sso_error code.
+func (h *OIDCHandler) redirectError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, "/admin/login?sso_error="+code, http.StatusFound)
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (h *OIDCHandler) logAudit(ctx context.Context, event audit.Event) {
+	if h.auditService != nil {
+		h.auditService.Log(ctx, event)
+	}
+}
+
+// KeysHandler publishes the public keys access tokens are signed with, so
+// other services can verify them.
+type KeysHandler struct {
+	keys *KeySet
+}
+
+// NewKeysHandler creates a new KeysHandler.
+func NewKeysHandler(keys *KeySet) *KeysHandler {
+	return &KeysHandler{keys: keys}
+}
+
+// JWKS handles GET /.well-known/jwks.json. The key set is written as is,
+// without the usual data envelope, as JWT libraries expect. It is empty when
+// tokens are signed with the HS256 secret.
+func (h *KeysHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(h.keys.JWKS()); err != nil {
+		slog.Error("failed to encode jwks", "error", err)
+	}
+}
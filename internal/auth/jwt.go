@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/rand"
 	"fmt"
 	"time"
 
@@ -10,10 +11,12 @@ import (
 const accessTokenExpiry = 15 * time.Minute
 
 // Claims holds the JWT claims for an access token. The admin ID is stored in
-// the standard "sub" (Subject) field of RegisteredClaims, and email is an
-// additional custom claim.
+// the standard "sub" (Subject) field of RegisteredClaims; email, role and the
+// role's permissions are additional custom claims.
 type Claims struct {
-	Email string `json:"email"`
+	Email       string      `json:"email"`
+	Role        string      `json:"role"`
+	Permissions Permissions `json:"perms,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -21,26 +24,33 @@ type Claims struct {
 func (c *Claims) AdminID() string { return c.Subject }
 
 // CreateAccessToken creates a signed JWT access token with the given admin ID
-// as subject, email as a custom claim, and a 15-minute expiry. The token is
-// signed with HMAC-SHA256.
-func CreateAccessToken(adminID, email, secret string) (string, error) {
+// as subject, email, role and permissions as custom claims, and a 15-minute
+// expiry. The token is signed with HMAC-SHA256.
+func CreateAccessToken(adminID, email, role string, perms Permissions, secret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newAccessClaims(adminID, email, role, perms))
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("signing access token: %w", err)
+	}
+	return signed, nil
+}
+
+// newAccessClaims returns the claims of a new access token. Its random jti
+// identifies it for revocation.
+func newAccessClaims(adminID, email, role string, perms Permissions) Claims {
 	now := time.Now()
-	claims := Claims{
-		Email: email,
+	return Claims{
+		Email:       email,
+		Role:        role,
+		Permissions: perms,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        rand.Text(),
 			Subject:   adminID,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenExpiry)),
 			Issuer:    "mithril-cms",
 		},
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", fmt.Errorf("signing access token: %w", err)
-	}
-	return signed, nil
 }
 
 // ValidateAccessToken parses and validates the given JWT string using the
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -14,8 +15,10 @@ const testSecret = "test-secret-key-for-jwt-signing"
 func TestCreateAndValidateAccessToken(t *testing.T) {
 	adminID := "550e8400-e29b-41d4-a716-446655440000"
 	email := "admin@example.com"
+	role := "editor"
+	perms := Permissions{{Resource: "content:*", Action: "update", Own: true}}
 
-	token, err := CreateAccessToken(adminID, email, testSecret)
+	token, err := CreateAccessToken(adminID, email, role, perms, testSecret)
 	if err != nil {
 		t.Fatalf("CreateAccessToken: unexpected error: %v", err)
 	}
@@ -36,13 +39,19 @@ func TestCreateAndValidateAccessToken(t *testing.T) {
 	if claims.Email != email {
 		t.Errorf("Email = %q, want %q", claims.Email, email)
 	}
+	if claims.Role != role {
+		t.Errorf("Role = %q, want %q", claims.Role, role)
+	}
+	if !reflect.DeepEqual(claims.Permissions, perms) {
+		t.Errorf("Permissions = %+v, want %+v", claims.Permissions, perms)
+	}
 	if claims.Issuer != "mithril-cms" {
 		t.Errorf("Issuer = %q, want %q", claims.Issuer, "mithril-cms")
 	}
 }
 
 func TestValidateAccessToken_WrongSecret(t *testing.T) {
-	token, err := CreateAccessToken("id", "email@test.com", testSecret)
+	token, err := CreateAccessToken("id", "email@test.com", "admin", nil, testSecret)
 	if err != nil {
 		t.Fatalf("CreateAccessToken: unexpected error: %v", err)
 	}
@@ -0,0 +1,427 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms access tokens can be signed with.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+const (
+	// keyReloadInterval is how often a KeySet picks up keys rotated by other
+	// processes.
+	keyReloadInterval = time.Minute
+
+	// unknownKeyReloadInterval bounds how often a token with an unknown key
+	// ID makes a KeySet reload, as another process may have rotated to a new
+	// key before this one picked it up.
+	unknownKeyReloadInterval = 10 * time.Second
+
+	// retiredKeyRetention is how long a retired key still verifies tokens:
+	// those it signed until every process stopped using it have expired.
+	retiredKeyRetention = accessTokenExpiry + keyReloadInterval
+
+	rsaKeyBits = 2048
+)
+
+// ErrUnsupportedAlgorithm is returned for signing algorithms other than
+// HS256, EdDSA and RS256.
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// SigningKey is a key pair access tokens are signed with.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	// RetiredAt is set once a newer key signs tokens; the key then only
+	// verifies tokens issued before.
+	RetiredAt *time.Time
+
+	sealed []byte        // Encrypted PKCS #8 private key, as stored.
+	signer crypto.Signer // Decrypted private key.
+}
+
+// method returns the JWT signing method of the key.
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// JSONWebKeySet is a JWK set (RFC 7517) of the public keys access tokens are
+// verified with.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is the public half of a SigningKey.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// jwk returns the public key as a JSON Web Key.
+func (k *SigningKey) jwk() JSONWebKey {
+	key := JSONWebKey{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch pub := k.signer.Public().(type) {
+	case ed25519.PublicKey:
+		key.Kty, key.Crv = "OKP", "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return key
+}
+
+// KeySet signs and verifies access tokens. With HS256 it uses the JWT secret;
+// with EdDSA or RS256 it uses the signing keys stored in the database, which
+// it reloads every minute once started, so keys rotated by another process
+// are picked up.
+type KeySet struct {
+	repo      *Repository
+	secret    string
+	algorithm string
+
+	mu       sync.RWMutex
+	signing  *SigningKey
+	keys     map[string]*SigningKey
+	loadedAt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewHMACKeySet returns a KeySet that signs and verifies access tokens with
+// the JWT secret.
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{secret: secret, algorithm: AlgorithmHS256}
+}
+
+// LoadKeySet returns the KeySet for algorithm. For EdDSA and RS256 it loads
+// the stored signing keys, and creates a key if the current one is missing
+// or of another algorithm.
+func LoadKeySet(ctx context.Context, repo *Repository, secret, algorithm string) (*KeySet, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return NewHMACKeySet(secret), nil
+	case AlgorithmEdDSA, AlgorithmRS256:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	k := &KeySet{repo: repo, secret: secret, algorithm: algorithm}
+	if err := k.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if k.signing == nil || k.signing.Algorithm != algorithm {
+		key, err := RotateSigningKey(ctx, repo, secret, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("created access token signing key", "kid", key.ID, "algorithm", algorithm)
+		if err := k.Reload(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Algorithm returns the algorithm new access tokens are signed with.
+func (k *KeySet) Algorithm() string { return k.algorithm }
+
+// Start begins the background goroutine that reloads the signing keys. It
+// does nothing for HS256.
+func (k *KeySet) Start() {
+	if k.algorithm == AlgorithmHS256 {
+		return
+	}
+	k.stop = make(chan struct{})
+	k.done = make(chan struct{})
+	go k.reloadLoop()
+}
+
+// Shutdown stops the background goroutine started by Start.
+func (k *KeySet) Shutdown() {
+	if k.stop == nil {
+		return
+	}
+	close(k.stop)
+	<-k.done
+}
+
+func (k *KeySet) reloadLoop() {
+	defer close(k.done)
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := k.Reload(ctx); err != nil {
+				slog.Error("failed to reload signing keys", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Reload reads the signing keys from the database. Keys that cannot be
+// decrypted, for example after the JWT secret changed, are skipped.
+func (k *KeySet) Reload(ctx context.Context) error {
+	stored, err := k.repo.ListSigningKeys(ctx, retiredKeyRetention)
+	if err != nil {
+		return err
+	}
+
+	var signing *SigningKey
+	keys := make(map[string]*SigningKey, len(stored))
+	for _, key := range stored {
+		if key.signer, err = openSigningKey(key.sealed, k.secret); err != nil {
+			slog.Warn("skipping signing key", "kid", key.ID, "error", err)
+			continue
+		}
+		keys[key.ID] = key
+		if signing == nil && key.RetiredAt == nil {
+			signing = key
+		}
+	}
+	k.set(signing, keys)
+	return nil
+}
+
+// set replaces the keys.
+func (k *KeySet) set(signing *SigningKey, keys map[string]*SigningKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signing = signing
+	k.keys = keys
+	k.loadedAt = time.Now()
+}
+
+// CreateAccessToken creates a signed JWT access token like the package-level
+// CreateAccessToken, signed with the current key. Tokens signed with a key
+// carry its ID in the "kid" header.
+func (k *KeySet) CreateAccessToken(adminID, email, role string, perms Permissions) (string, error) {
+	if k.algorithm == AlgorithmHS256 {
+		return CreateAccessToken(adminID, email, role, perms, k.secret)
+	}
+
+	k.mu.RLock()
+	key := k.signing
+	k.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no access token signing key")
+	}
+
+	token := jwt.NewWithClaims(key.method(), newAccessClaims(adminID, email, role, perms))
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.signer)
+	if err != nil {
+		return "", fmt.Errorf("signing access token: %w", err)
+	}
+	return signed, nil
+}
+
+// ValidateAccessToken parses and validates an access token signed by the set.
+// With EdDSA or RS256, tokens must name a current or recently retired key,
+// and HS256 tokens are rejected.
+func (k *KeySet) ValidateAccessToken(tokenString string) (*Claims, error) {
+	if k.algorithm == AlgorithmHS256 {
+		return ValidateAccessToken(tokenString, k.secret)
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key := k.key(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.signer.Public(), nil
+	}, jwt.WithValidMethods([]string{AlgorithmEdDSA, AlgorithmRS256}))
+	if err != nil {
+		return nil, fmt.Errorf("parsing access token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid access token claims")
+	}
+	return claims, nil
+}
+
+// key returns the key with the ID, reloading the keys if it is unknown and
+// they were not loaded recently.
+func (k *KeySet) key(id string) *SigningKey {
+	k.mu.RLock()
+	key, loadedAt := k.keys[id], k.loadedAt
+	k.mu.RUnlock()
+	if key != nil || id == "" || time.Since(loadedAt) < unknownKeyReloadInterval {
+		return key
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := k.Reload(ctx); err != nil {
+		slog.Error("failed to reload signing keys", "error", err)
+		return nil
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[id]
+}
+
+// JWKS returns the public keys tokens are verified with, newest first. It is
+// empty for HS256.
+func (k *KeySet) JWKS() JSONWebKeySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	slices.SortFunc(set.Keys, func(a, b JSONWebKey) int {
+		return k.keys[b.Kid].CreatedAt.Compare(k.keys[a.Kid].CreatedAt)
+	})
+	return set
+}
+
+// RotateSigningKey creates a new signing key for algorithm, which signs all
+// access tokens from then on. The keys in use before are retired; they keep
+// verifying tokens until those have expired. Running processes switch to the
+// new key within a minute.
+func RotateSigningKey(ctx context.Context, repo *Repository, secret, algorithm string) (*SigningKey, error) {
+	key, err := generateSigningKey(algorithm, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.RotateSigningKey(ctx, key, retiredKeyRetention); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// generateSigningKey creates a key pair for algorithm, sealed with the JWT
+// secret.
+func generateSigningKey(algorithm, secret string) (*SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generating %s key: %w", algorithm, err)
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generating key ID: %w", err)
+	}
+	sealed, err := sealSigningKey(signer, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Algorithm: algorithm,
+		sealed:    sealed,
+		signer:    signer,
+	}, nil
+}
+
+// signingKeyCipher returns the AEAD private keys are stored with, keyed by a
+// key derived from the JWT secret.
+func signingKeyCipher(secret string) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("mithril-cms signing key"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSigningKey encrypts a private key for storage.
+func sealSigningKey(signer crypto.Signer, secret string) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("encoding signing key: %w", err)
+	}
+	aead, err := signingKeyCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("sealing signing key: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, der, nil), nil
+}
+
+// openSigningKey decrypts a private key sealed by sealSigningKey.
+func openSigningKey(sealed []byte, secret string) (crypto.Signer, error) {
+	aead, err := signingKeyCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("opening signing key: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("opening signing key: too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	der, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening signing key: %w", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("decoding signing key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("decoding signing key: unsupported type %T", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestKeySet returns a KeySet for algorithm holding keys, the first of
+// which signs. It has no repository, so it must not need to reload.
+func newTestKeySet(algorithm string, keys ...*SigningKey) *KeySet {
+	k := &KeySet{secret: testSecret, algorithm: algorithm}
+	byID := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		byID[key.ID] = key
+	}
+	k.set(keys[0], byID)
+	return k
+}
+
+func generateTestKey(t *testing.T, algorithm string) *SigningKey {
+	t.Helper()
+	key, err := generateSigningKey(algorithm, testSecret)
+	if err != nil {
+		t.Fatalf("generateSigningKey(%s): %v", algorithm, err)
+	}
+	key.CreatedAt = time.Now()
+	return key
+}
+
+func TestKeySet_CreateAndValidateAccessToken(t *testing.T) {
+	for _, alg := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(alg, func(t *testing.T) {
+			key := generateTestKey(t, alg)
+			keys := newTestKeySet(alg, key)
+
+			token, err := keys.CreateAccessToken("550e8400-e29b-41d4-a716-446655440000", "a@example.com", "editor", nil)
+			if err != nil {
+				t.Fatalf("CreateAccessToken: %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != alg {
+				t.Errorf("header = %v, want kid %s and alg %s", parsed.Header, key.ID, alg)
+			}
+
+			claims, err := keys.ValidateAccessToken(token)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken: %v", err)
+			}
+			if claims.AdminID() != "550e8400-e29b-41d4-a716-446655440000" || claims.Role != "editor" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestKeySet_RetiredKeyVerifies(t *testing.T) {
+	old := generateTestKey(t, AlgorithmEdDSA)
+	token, err := newTestKeySet(AlgorithmEdDSA, old).CreateAccessToken("id", "a@example.com", "editor", nil)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+
+	retiredAt := time.Now()
+	old.RetiredAt = &retiredAt
+	current := generateTestKey(t, AlgorithmRS256)
+	rotated := newTestKeySet(AlgorithmRS256, current, old)
+
+	if _, err := rotated.ValidateAccessToken(token); err != nil {
+		t.Errorf("token of retired key rejected: %v", err)
+	}
+	fresh, err := rotated.CreateAccessToken("id", "a@example.com", "editor", nil)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	if parsed, _, _ := jwt.NewParser().ParseUnverified(fresh, &Claims{}); parsed.Header["kid"] != current.ID {
+		t.Errorf("signed with kid %v, want current key %s", parsed.Header["kid"], current.ID)
+	}
+
+	// Once dropped from the set, the old key's tokens are rejected.
+	if _, err := newTestKeySet(AlgorithmRS256, current).ValidateAccessToken(token); err == nil {
+		t.Error("token of unknown key accepted")
+	}
+}
+
+func TestKeySet_RejectsOtherTokens(t *testing.T) {
+	key := generateTestKey(t, AlgorithmEdDSA)
+	keys := newTestKeySet(AlgorithmEdDSA, key)
+
+	hmacToken, err := CreateAccessToken("id", "a@example.com", "editor", nil, testSecret)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	if _, err := keys.ValidateAccessToken(hmacToken); err == nil {
+		t.Error("HS256 token accepted by EdDSA key set")
+	}
+
+	signed, err := keys.CreateAccessToken("id", "a@example.com", "editor", nil)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	if _, err := NewHMACKeySet(testSecret).ValidateAccessToken(signed); err == nil {
+		t.Error("EdDSA token accepted by HS256 key set")
+	}
+
+	// A token claiming another algorithm for the key is rejected.
+	rsaKey := generateTestKey(t, AlgorithmRS256)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, newAccessClaims("id", "a@example.com", SuperuserRole, nil))
+	forged.Header["kid"] = key.ID
+	forgedString, err := forged.SignedString(rsaKey.signer)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	if _, err := keys.ValidateAccessToken(forgedString); err == nil {
+		t.Error("token with mismatched algorithm accepted")
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	ed := generateTestKey(t, AlgorithmEdDSA)
+	rs := generateTestKey(t, AlgorithmRS256)
+	rs.CreatedAt = ed.CreatedAt.Add(-time.Minute)
+	set := newTestKeySet(AlgorithmEdDSA, ed, rs).JWKS()
+
+	if len(set.Keys) != 2 || set.Keys[0].Kid != ed.ID || set.Keys[1].Kid != rs.ID {
+		t.Fatalf("JWKS() = %+v, want Ed25519 then RSA key", set.Keys)
+	}
+
+	okp := set.Keys[0]
+	x, err := base64.RawURLEncoding.DecodeString(okp.X)
+	if err != nil || okp.Kty != "OKP" || okp.Crv != "Ed25519" || okp.Alg != AlgorithmEdDSA || okp.Use != "sig" ||
+		!ed25519.PublicKey(x).Equal(ed.signer.Public()) {
+		t.Errorf("Ed25519 JWK = %+v", okp)
+	}
+
+	r := set.Keys[1]
+	n, errN := base64.RawURLEncoding.DecodeString(r.N)
+	e, errE := base64.RawURLEncoding.DecodeString(r.E)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if errN != nil || errE != nil || r.Kty != "RSA" || r.Alg != AlgorithmRS256 || !pub.Equal(rs.signer.Public()) {
+		t.Errorf("RSA JWK = %+v", r)
+	}
+}
+
+func TestSealSigningKey(t *testing.T) {
+	key := generateTestKey(t, AlgorithmEdDSA)
+
+	signer, err := openSigningKey(key.sealed, testSecret)
+	if err != nil {
+		t.Fatalf("openSigningKey: %v", err)
+	}
+	if !signer.Public().(ed25519.PublicKey).Equal(key.signer.Public()) {
+		t.Error("opened key differs from generated key")
+	}
+
+	if _, err := openSigningKey(key.sealed, "other-secret"); err == nil {
+		t.Error("openSigningKey with another secret succeeded")
+	}
+	if _, err := openSigningKey([]byte("short"), testSecret); err == nil {
+		t.Error("openSigningKey of truncated key succeeded")
+	}
+}
+
+func TestLoadKeySet_Algorithms(t *testing.T) {
+	keys, err := LoadKeySet(context.Background(), nil, testSecret, AlgorithmHS256)
+	if err != nil || keys.Algorithm() != AlgorithmHS256 {
+		t.Fatalf("LoadKeySet(HS256) = %v, %v", keys, err)
+	}
+	if _, err := LoadKeySet(context.Background(), nil, testSecret, "ES256"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("LoadKeySet(ES256) error = %v, want ErrUnsupportedAlgorithm", err)
+	}
+	if _, err := generateSigningKey(AlgorithmHS256, testSecret); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("generateSigningKey(HS256) error = %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
+
+func TestKeysHandler_JWKS(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewKeysHandler(NewHMACKeySet(testSecret)).JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if string(body["keys"]) != "[]" || len(body) != 1 {
+		t.Errorf("body = %s, want an empty key set without envelope", rec.Body.String())
+	}
+}
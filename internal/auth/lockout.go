@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Kinds of login failure counters.
+const (
+	failureKindEmail = "email"
+	failureKindIP    = "ip"
+)
+
+// ErrLoginBlocked is wrapped by LoginBlockedError.
+var ErrLoginBlocked = errors.New("too many failed login attempts; try again later")
+
+// LoginBlockedError is returned when logins for an email or from an IP
+// address are refused after failed attempts. It is returned for unknown
+// emails alike, so it does not reveal whether an admin has the email.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string { return ErrLoginBlocked.Error() }
+
+// Unwrap makes errors.Is(err, ErrLoginBlocked) match.
+func (e *LoginBlockedError) Unwrap() error { return ErrLoginBlocked }
+
+// LockoutPolicy configures brute-force protection of admin logins. Failed
+// logins are counted per email and per client IP address; a count is
+// forgotten once there has been no failure for Duration. The zero value
+// disables the protection.
+type LockoutPolicy struct {
+	// Threshold is the number of failures after which an email is locked
+	// for Duration. Zero disables locking emails.
+	Threshold int
+	// IPThreshold is the number of failures after which an IP address is
+	// locked for Duration. Zero disables locking IP addresses.
+	IPThreshold int
+	// Duration is how long a lock lasts.
+	Duration time.Duration
+	// DelayBase is the wait after the first failure for an email, which
+	// doubles with each further failure up to DelayMax. Zero disables
+	// delays.
+	DelayBase time.Duration
+	DelayMax  time.Duration
+}
+
+// block returns how long logins are refused after failures, and whether
+// that is a lock rather than a delay.
+func (p LockoutPolicy) block(kind string, failures int) (time.Duration, bool) {
+	threshold := p.Threshold
+	if kind == failureKindIP {
+		threshold = p.IPThreshold
+	}
+	if threshold > 0 && failures >= threshold {
+		return p.Duration, true
+	}
+	// Shared addresses fail often, so they are only ever locked.
+	if kind == failureKindIP || p.DelayBase <= 0 || failures < 1 {
+		return 0, false
+	}
+
+	delay := p.DelayBase
+	for i := 1; i < failures && delay < p.DelayMax; i++ {
+		delay *= 2
+	}
+	return min(delay, p.DelayMax), false
+}
+
+// SetLockoutPolicy sets the brute-force protection of logins.
+func (s *Service) SetLockoutPolicy(policy LockoutPolicy) {
+	s.lockout = policy
+}
+
+// loginKeys returns the failure counters that apply to a login for email
+// from the client in ctx.
+func (s *Service) loginKeys(ctx context.Context, email string) map[string]string {
+	keys := make(map[string]string, 2)
+	if s.lockout.Threshold > 0 {
+		keys[failureKindEmail] = normalizeLoginEmail(email)
+	}
+	if ip := clientInfoFromContext(ctx).IP; s.lockout.IPThreshold > 0 && ip != "" {
+		keys[failureKindIP] = ip
+	}
+	return keys
+}
+
+// checkLoginBlocked returns a *LoginBlockedError if logins for email from the
+// client in ctx are currently refused.
+func (s *Service) checkLoginBlocked(ctx context.Context, email string) error {
+	if s.lockout == (LockoutPolicy{}) {
+		return nil
+	}
+	until, err := s.repo.GetLoginBlock(ctx, s.loginKeys(ctx, email))
+	if err != nil {
+		return err
+	}
+	if wait := time.Until(until); wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login for email from the client in ctx
+// and blocks further logins as the policy says.
+func (s *Service) recordLoginFailure(ctx context.Context, email string) error {
+	if s.lockout == (LockoutPolicy{}) {
+		return nil
+	}
+	for kind, key := range s.loginKeys(ctx, email) {
+		failures, err := s.repo.RecordLoginFailure(ctx, kind, key, s.lockout.Duration)
+		if err != nil {
+			return err
+		}
+		wait, locked := s.lockout.block(kind, failures)
+		if wait <= 0 {
+			continue
+		}
+		if err := s.repo.BlockLogin(ctx, kind, key, time.Now().Add(wait)); err != nil {
+			return err
+		}
+		if locked {
+			slog.Warn("admin logins locked after failed attempts", "kind", kind, "key", key, "failures", failures)
+		}
+	}
+	return nil
+}
+
+// clearLoginFailures forgets the failed logins for email after a successful
+// login. Failures from the client's IP address are kept, so that one known
+// password does not reset the count for guessing others.
+func (s *Service) clearLoginFailures(ctx context.Context, email string) error {
+	if s.lockout.Threshold <= 0 {
+		return nil
+	}
+	_, err := s.repo.ClearLoginFailures(ctx, failureKindEmail, normalizeLoginEmail(email))
+	return err
+}
+
+// UnlockAdmin lifts the lock and delays on logins for the admin's email.
+func (s *Service) UnlockAdmin(ctx context.Context, id string) (*Admin, error) {
+	admin, err := s.GetAdmin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.ClearLoginFailures(ctx, failureKindEmail, normalizeLoginEmail(admin.Email)); err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// UnlockLogin lifts the lock and delays on logins for an email or from an IP
+// address, reporting whether there were any.
+func (s *Service) UnlockLogin(ctx context.Context, emailOrIP string) (bool, error) {
+	kind, key := failureKindEmail, normalizeLoginEmail(emailOrIP)
+	if !strings.Contains(key, "@") {
+		kind = failureKindIP
+	}
+	cleared, err := s.repo.ClearLoginFailures(ctx, kind, key)
+	if err != nil {
+		return false, fmt.Errorf("unlocking %s: %w", emailOrIP, err)
+	}
+	return cleared, nil
+}
+
+// normalizeLoginEmail returns the key failures for email are counted under.
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
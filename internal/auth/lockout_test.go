@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy_Block(t *testing.T) {
+	p := LockoutPolicy{
+		Threshold:   5,
+		IPThreshold: 20,
+		Duration:    15 * time.Minute,
+		DelayBase:   time.Second,
+		DelayMax:    4 * time.Second,
+	}
+
+	tests := []struct {
+		kind       string
+		failures   int
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{failureKindEmail, 0, 0, false},
+		{failureKindEmail, 1, time.Second, false},
+		{failureKindEmail, 2, 2 * time.Second, false},
+		{failureKindEmail, 3, 4 * time.Second, false},
+		{failureKindEmail, 4, 4 * time.Second, false}, // Capped at DelayMax.
+		{failureKindEmail, 5, 15 * time.Minute, true},
+		{failureKindIP, 19, 0, false}, // Addresses are never delayed.
+		{failureKindIP, 20, 15 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		wait, locked := p.block(tt.kind, tt.failures)
+		if wait != tt.wantWait || locked != tt.wantLocked {
+			t.Errorf("block(%s, %d) = %v, %v; want %v, %v",
+				tt.kind, tt.failures, wait, locked, tt.wantWait, tt.wantLocked)
+		}
+	}
+}
+
+func TestLockoutPolicy_BlockDisabled(t *testing.T) {
+	p := LockoutPolicy{Duration: 15 * time.Minute}
+	if wait, locked := p.block(failureKindEmail, 1000); wait != 0 || locked {
+		t.Errorf("block() without thresholds or delay = %v, %v; want 0, false", wait, locked)
+	}
+}
+
+func TestService_LockoutDisabled(t *testing.T) {
+	// Without a policy the repository is never touched.
+	svc := NewService(nil, testSecret)
+	ctx := context.Background()
+
+	if err := svc.checkLoginBlocked(ctx, "jane@example.com"); err != nil {
+		t.Errorf("checkLoginBlocked() = %v, want nil", err)
+	}
+	if err := svc.recordLoginFailure(ctx, "jane@example.com"); err != nil {
+		t.Errorf("recordLoginFailure() = %v, want nil", err)
+	}
+	if err := svc.clearLoginFailures(ctx, "jane@example.com"); err != nil {
+		t.Errorf("clearLoginFailures() = %v, want nil", err)
+	}
+}
+
+func TestService_LoginKeys(t *testing.T) {
+	svc := NewService(nil, testSecret)
+	svc.SetLockoutPolicy(LockoutPolicy{Threshold: 5, IPThreshold: 20})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/auth/login", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	keys := svc.loginKeys(withClientInfo(req), " Jane@Example.com ")
+	if len(keys) != 2 || keys[failureKindEmail] != "jane@example.com" || keys[failureKindIP] != "203.0.113.7" {
+		t.Errorf("loginKeys() = %v", keys)
+	}
+
+	// Without client info only the email is counted.
+	keys = svc.loginKeys(context.Background(), "jane@example.com")
+	if _, ok := keys[failureKindIP]; ok || len(keys) != 1 {
+		t.Errorf("loginKeys() without client = %v, want email only", keys)
+	}
+}
+
+func TestLoginBlockedError(t *testing.T) {
+	err := error(&LoginBlockedError{RetryAfter: time.Minute})
+	if !errors.Is(err, ErrLoginBlocked) {
+		t.Error("errors.Is(LoginBlockedError, ErrLoginBlocked) = false")
+	}
+}
+
+func TestWriteLoginBlocked(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeLoginBlocked(rec, &LoginBlockedError{RetryAfter: 1500 * time.Millisecond})
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
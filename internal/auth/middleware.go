@@ -2,9 +2,12 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
 	"github.com/GyroZepelix/mithril-cms/internal/server"
 )
 
@@ -15,12 +18,30 @@ const (
 	ContextKeyAdminID contextKey = "admin_id"
 	// ContextKeyEmail is the context key for the authenticated admin's email.
 	ContextKeyEmail contextKey = "email"
+	// ContextKeyRole is the context key for the authenticated admin's role.
+	ContextKeyRole contextKey = "role"
+	// ContextKeyPermissions is the context key for the authenticated admin's
+	// Permissions.
+	ContextKeyPermissions contextKey = "permissions"
+	// ContextKeyOwnOnly is the context key set by Authorize when a request is
+	// only allowed on the admin's own entries.
+	ContextKeyOwnOnly contextKey = "own_only"
+	// ContextKeyAPIKey is the context key for the *APIKey a public API
+	// request was made with.
+	ContextKeyAPIKey contextKey = "api_key"
+	// ContextKeyPersonalAccessToken is the context key for the
+	// *PersonalAccessToken an admin API request was made with.
+	ContextKeyPersonalAccessToken contextKey = "personal_access_token"
 )
 
 // Middleware returns an HTTP middleware that validates JWT Bearer tokens from
-// the Authorization header. On success it sets the admin ID and email in the
-// request context. On failure it returns a 401 JSON error response.
-func Middleware(jwtSecret string) func(http.Handler) http.Handler {
+// the Authorization header. On success it sets the admin ID, email, role and
+// permissions in the request context. On failure it returns a 401 JSON error
+// response. Tokens are also checked against revocations, unless it is nil.
+// Personal access tokens are accepted too, unless tokens is nil; the token is
+// then set in the request context as well, and audit events of the request
+// are attributed to it.
+func Middleware(keys *KeySet, revocations *Revocations, tokens *TokenService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -37,20 +58,62 @@ func Middleware(jwtSecret string) func(http.Handler) http.Handler {
 			}
 
 			tokenString := parts[1]
-			claims, err := ValidateAccessToken(tokenString, jwtSecret)
-			if err != nil {
-				server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token", nil)
-				return
+			ctx := r.Context()
+			var claims *Claims
+			if tokens != nil && IsPersonalAccessToken(tokenString) {
+				token, tokenClaims, err := tokens.Authenticate(ctx, tokenString)
+				if err != nil {
+					if errors.Is(err, ErrInvalidPersonalAccessToken) {
+						server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", err.Error(), nil)
+						return
+					}
+					slog.Error("personal access token authentication failed", "error", err)
+					server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+					return
+				}
+				claims = tokenClaims
+				ctx = context.WithValue(ctx, ContextKeyPersonalAccessToken, token)
+				ctx = audit.WithTokenID(ctx, token.ID)
+			} else {
+				var err error
+				claims, err = keys.ValidateAccessToken(tokenString)
+				if err != nil {
+					server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token", nil)
+					return
+				}
+				if revocations != nil {
+					revoked, err := revocations.Revoked(ctx, claims)
+					if err != nil {
+						slog.Error("access token revocation check failed", "error", err)
+						server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+						return
+					}
+					if revoked {
+						server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "token has been revoked", nil)
+						return
+					}
+				}
 			}
 
 			// Set admin info in context for downstream handlers.
-			ctx := context.WithValue(r.Context(), ContextKeyAdminID, claims.AdminID())
+			ctx = context.WithValue(ctx, ContextKeyAdminID, claims.AdminID())
 			ctx = context.WithValue(ctx, ContextKeyEmail, claims.Email)
+			ctx = context.WithValue(ctx, ContextKeyRole, claims.Role)
+			ctx = context.WithValue(ctx, ContextKeyPermissions, claims.Permissions)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// bearerToken returns the token of an "Authorization: Bearer" header, or "".
+func bearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return parts[1]
+}
+
 // AdminIDFromContext extracts the authenticated admin's UUID from the request
 // context. Returns an empty string if no admin is authenticated.
 func AdminIDFromContext(ctx context.Context) string {
@@ -64,3 +127,67 @@ func EmailFromContext(ctx context.Context) string {
 	v, _ := ctx.Value(ContextKeyEmail).(string)
 	return v
 }
+
+// RoleFromContext extracts the authenticated admin's role from the request
+// context. Returns an empty string if no admin is authenticated.
+func RoleFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ContextKeyRole).(string)
+	return v
+}
+
+// PersonalAccessTokenFromContext returns the personal access token an admin
+// API request was made with, or nil if it was made with an access token.
+func PersonalAccessTokenFromContext(ctx context.Context) *PersonalAccessToken {
+	v, _ := ctx.Value(ContextKeyPersonalAccessToken).(*PersonalAccessToken)
+	return v
+}
+
+// APIKeyMiddleware returns an HTTP middleware that authenticates public API
+// requests carrying an API key in the X-Api-Key header or as an
+// "Authorization: Bearer" token. Requests without a key pass through
+// unauthenticated; requests with an invalid or revoked key get a 401 JSON
+// error response. On success the key is set in the request context, and
+// identified for per-key rate limiting.
+func APIKeyMiddleware(service *APIKeyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Api-Key")
+			if token == "" {
+				if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+					parts := strings.SplitN(authHeader, " ", 2)
+					if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+						server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid authorization header format", nil)
+						return
+					}
+					token = parts[1]
+				}
+			}
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := service.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, ErrInvalidAPIKey) {
+					server.Error(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or revoked API key", nil)
+					return
+				}
+				slog.Error("api key authentication failed", "error", err)
+				server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR", "an internal error occurred", nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextKeyAPIKey, key)
+			ctx = server.WithAPIKey(ctx, key.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// APIKeyFromContext returns the API key a public API request was made with,
+// or nil if it was made without one.
+func APIKeyFromContext(ctx context.Context) *APIKey {
+	v, _ := ctx.Value(ContextKeyAPIKey).(*APIKey)
+	return v
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func TestMiddleware_MissingHeader(t *testing.T) {
-	mw := Middleware(testSecret)
+	mw := Middleware(NewHMACKeySet(testSecret), nil, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Error("handler should not be called")
 	}))
@@ -23,7 +23,7 @@ func TestMiddleware_MissingHeader(t *testing.T) {
 }
 
 func TestMiddleware_InvalidFormat(t *testing.T) {
-	mw := Middleware(testSecret)
+	mw := Middleware(NewHMACKeySet(testSecret), nil, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Error("handler should not be called")
 	}))
@@ -39,7 +39,7 @@ func TestMiddleware_InvalidFormat(t *testing.T) {
 }
 
 func TestMiddleware_InvalidToken(t *testing.T) {
-	mw := Middleware(testSecret)
+	mw := Middleware(NewHMACKeySet(testSecret), nil, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Error("handler should not be called")
 	}))
@@ -57,17 +57,22 @@ func TestMiddleware_InvalidToken(t *testing.T) {
 func TestMiddleware_ValidToken(t *testing.T) {
 	adminID := "550e8400-e29b-41d4-a716-446655440000"
 	email := "admin@example.com"
+	role := "reviewer"
+	perms := Permissions{{Resource: "content:*", Action: "read"}}
 
-	token, err := CreateAccessToken(adminID, email, testSecret)
+	token, err := CreateAccessToken(adminID, email, role, perms, testSecret)
 	if err != nil {
 		t.Fatalf("CreateAccessToken: %v", err)
 	}
 
-	var gotAdminID, gotEmail string
-	mw := Middleware(testSecret)
+	var gotAdminID, gotEmail, gotRole string
+	var gotPerms Permissions
+	mw := Middleware(NewHMACKeySet(testSecret), nil, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gotAdminID = AdminIDFromContext(r.Context())
 		gotEmail = EmailFromContext(r.Context())
+		gotRole = RoleFromContext(r.Context())
+		gotPerms = PermissionsFromContext(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -85,6 +90,12 @@ func TestMiddleware_ValidToken(t *testing.T) {
 	if gotEmail != email {
 		t.Errorf("EmailFromContext = %q, want %q", gotEmail, email)
 	}
+	if gotRole != role {
+		t.Errorf("RoleFromContext = %q, want %q", gotRole, role)
+	}
+	if len(gotPerms) != 1 || gotPerms[0] != perms[0] {
+		t.Errorf("PermissionsFromContext = %+v, want %+v", gotPerms, perms)
+	}
 }
 
 func TestAdminIDFromContext_Empty(t *testing.T) {
@@ -100,3 +111,10 @@ func TestEmailFromContext_Empty(t *testing.T) {
 		t.Errorf("EmailFromContext on empty context = %q, want empty", got)
 	}
 }
+
+func TestRoleFromContext_Empty(t *testing.T) {
+	ctx := context.Background()
+	if got := RoleFromContext(ctx); got != "" {
+		t.Errorf("RoleFromContext on empty context = %q, want empty", got)
+	}
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/oidc"
+)
+
+// oidcFlowExpiry bounds how long the user may take at the identity provider.
+const oidcFlowExpiry = 10 * time.Minute
+
+// Sentinel errors for single sign-on.
+var (
+	ErrOIDCState            = errors.New("invalid or expired single sign-on attempt")
+	ErrOIDCEmailUnverified  = errors.New("the identity provider has not verified the email address")
+	ErrOIDCDomainNotAllowed = errors.New("email domain is not allowed to sign in")
+	ErrOIDCNoRole           = errors.New("no role is granted to the user")
+	ErrOIDCIdentityConflict = errors.New("the admin is linked to another account at the identity provider")
+	ErrOIDCTwoFactorLink    = errors.New("admins with two-factor authentication are not linked by email")
+)
+
+// OIDCError is returned when a user authenticated by the identity provider
+// may not sign in. It carries the user's email, so the failure can be
+// audited.
+type OIDCError struct {
+	Email string
+	Err   error
+}
+
+func (e *OIDCError) Error() string { return e.Err.Error() }
+
+// Unwrap makes errors.Is match the reason.
+func (e *OIDCError) Unwrap() error { return e.Err }
+
+// GroupRole grants a role to the members of an identity provider group.
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// OIDCConfig configures who may sign in with single sign-on and which role
+// they get.
+type OIDCConfig struct {
+	// AllowedDomains restricts sign-in to emails in these domains. Empty
+	// allows all.
+	AllowedDomains []string
+	// RoleMapping grants the role of the first group the user is in. If set,
+	// the roles of existing admins are synced on every sign-in.
+	RoleMapping []GroupRole
+	// DefaultRole is granted to users in none of the mapped groups. If
+	// empty, such users cannot sign in unless they already are an admin and
+	// no mapping is set.
+	DefaultRole string
+	// TrustUnverifiedEmail accepts emails from providers that do not send
+	// the email_verified claim. Emails the provider reports as unverified
+	// are always rejected.
+	TrustUnverifiedEmail bool
+}
+
+// ParseRoleMapping parses a comma-separated list of group=role pairs.
+func ParseRoleMapping(s string) ([]GroupRole, error) {
+	var mapping []GroupRole
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid role mapping %q: want group=role", pair)
+		}
+		mapping = append(mapping, GroupRole{Group: group, Role: role})
+	}
+	return mapping, nil
+}
+
+// OIDCService signs admins in with an OpenID Connect identity provider.
+// Admins are matched by email on their first sign-in, or created, and linked
+// to the provider's issuer and subject, by which they are matched from then
+// on. Signing in then continues as after the password step of Login, so
+// local two-factor authentication is asked for as usual.
+type OIDCService struct {
+	service      *Service
+	provider     *oidc.Provider
+	cfg          OIDCConfig
+	auditService *audit.Service
+
+	// adminByIdentity, adminByEmail and linkIdentity read and write the
+	// repository; tests replace them.
+	adminByIdentity func(ctx context.Context, issuer, subject string) (*Admin, error)
+	adminByEmail    func(ctx context.Context, email string) (*Admin, error)
+	linkIdentity    func(ctx context.Context, adminID, issuer, subject string) (bool, error)
+}
+
+// NewOIDCService creates a new OIDCService. The audit service is optional.
+func NewOIDCService(service *Service, repo *Repository, provider *oidc.Provider, cfg OIDCConfig, auditService *audit.Service) *OIDCService {
+	for i, d := range cfg.AllowedDomains {
+		cfg.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+	}
+	return &OIDCService{
+		service:      service,
+		provider:     provider,
+		cfg:          cfg,
+		auditService: auditService,
+
+		adminByIdentity: repo.FindAdminByIdentity,
+		adminByEmail:    repo.FindAdminByEmail,
+		linkIdentity:    repo.LinkIdentity,
+	}
+}
+
+// oidcFlowClaims are the claims of a flow token, which keeps the values of
+// a sign-in attempt on the client between Begin and Complete.
+type oidcFlowClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// Begin starts a sign-in. It returns the provider URL to send the user to,
+// and a flow token to keep on the client and pass to Complete.
+func (s *OIDCService) Begin(ctx context.Context) (authURL, flowToken string, err error) {
+	claims := oidcFlowClaims{}
+	for _, v := range []*string{&claims.State, &claims.Nonce, &claims.Verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			return "", "", err
+		}
+	}
+
+	authURL, err = s.provider.AuthCodeURL(ctx, claims.State, claims.Nonce, claims.Verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(oidcFlowExpiry)),
+		Issuer:    "mithril-cms",
+	}
+	flowToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(oidcFlowKey(s.service.jwtSecret))
+	if err != nil {
+		return "", "", fmt.Errorf("signing oidc flow token: %w", err)
+	}
+	return authURL, flowToken, nil
+}
+
+// Complete finishes a sign-in with the state and code the provider sent to
+// the callback. It returns ErrOIDCState if they do not belong to the flow
+// token, an error wrapping oidc.ErrProvider or oidc.ErrInvalidIDToken if the
+// provider does not confirm the user, and an *OIDCError if the user may not
+// sign in. Like Login, it returns a challenge token instead of the tokens
+// if the admin has two-factor authentication or must enroll in it.
+func (s *OIDCService) Complete(ctx context.Context, flowToken, state, code string) (*LoginResult, error) {
+	flow, err := parseOIDCFlowToken(flowToken, s.service.jwtSecret)
+	if err != nil || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, ErrOIDCState
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, flow.Verifier, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.resolveAdmin(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	return s.service.completeLogin(ctx, admin)
+}
+
+// resolveAdmin returns the admin for a verified identity, creating it or
+// syncing its role as configured.
+func (s *OIDCService) resolveAdmin(ctx context.Context, identity *oidc.Identity) (*Admin, error) {
+	email := strings.TrimSpace(identity.Email)
+	deny := func(err error) error { return &OIDCError{Email: email, Err: err} }
+
+	if email == "" || !s.emailVerified(identity) {
+		return nil, deny(ErrOIDCEmailUnverified)
+	}
+	if !s.domainAllowed(email) {
+		return nil, deny(ErrOIDCDomainNotAllowed)
+	}
+	role := s.roleFor(identity.Groups)
+
+	admin, err := s.findAdmin(ctx, identity, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if role == "" {
+			return nil, deny(ErrOIDCNoRole)
+		}
+		return s.createAdmin(ctx, email, role, identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if admin.DisabledAt != nil {
+		return nil, deny(ErrAccountDisabled)
+	}
+	if len(s.cfg.RoleMapping) == 0 {
+		return admin, nil
+	}
+	if role == "" {
+		return nil, deny(ErrOIDCNoRole)
+	}
+	if role == admin.Role {
+		return admin, nil
+	}
+
+	updated, err := s.service.UpdateAdmin(ctx, admin.ID, UpdateAdminInput{Role: &role})
+	if errors.Is(err, ErrLastSuperuser) {
+		slog.Warn("oidc role sync skipped: last superuser", "admin_id", admin.ID, "role", role)
+		return admin, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("syncing role of admin %s: %w", admin.ID, err)
+	}
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.update",
+		ActorID:    admin.ID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload: map[string]any{
+			"role":   map[string]string{"from": admin.Role, "to": updated.Role},
+			"source": "oidc",
+		},
+	})
+	return updated, nil
+}
+
+// findAdmin returns the admin linked to the identity, or an error wrapping
+// pgx.ErrNoRows if there is none. An identity signing in for the first time
+// is linked to the admin with its email, unless that admin is linked to
+// another subject of the issuer already, or has two-factor authentication:
+// the link would otherwise be made before the second factor is checked.
+func (s *OIDCService) findAdmin(ctx context.Context, identity *oidc.Identity, email string) (*Admin, error) {
+	admin, err := s.adminByIdentity(ctx, identity.Issuer, identity.Subject)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return admin, err
+	}
+
+	admin, err = s.adminByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if admin.TOTPEnabledAt != nil {
+		return nil, &OIDCError{Email: email, Err: ErrOIDCTwoFactorLink}
+	}
+	linked, err := s.linkIdentity(ctx, admin.ID, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		return nil, &OIDCError{Email: email, Err: ErrOIDCIdentityConflict}
+	}
+	return admin, nil
+}
+
+// createAdmin creates the admin for a first sign-in and links it to the
+// identity. Its random password is never shown; the admin can still set one
+// through a password reset.
+func (s *OIDCService) createAdmin(ctx context.Context, email, role string, identity *oidc.Identity) (*Admin, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.service.CreateAdmin(ctx, CreateAdminInput{Email: email, Password: password, Role: role})
+	if err != nil {
+		return nil, fmt.Errorf("creating admin for %s: %w", email, err)
+	}
+	linked, err := s.linkIdentity(ctx, admin.ID, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		return nil, &OIDCError{Email: email, Err: ErrOIDCIdentityConflict}
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "admin.create",
+		ActorID:    admin.ID,
+		Resource:   "admin",
+		ResourceID: admin.ID,
+		Payload: map[string]any{
+			"email":   admin.Email,
+			"role":    admin.Role,
+			"source":  "oidc",
+			"issuer":  identity.Issuer,
+			"subject": identity.Subject,
+		},
+	})
+	return admin, nil
+}
+
+// emailVerified reports whether the identity's email may be trusted. A
+// missing email_verified claim counts as unverified unless configured
+// otherwise.
+func (s *OIDCService) emailVerified(identity *oidc.Identity) bool {
+	if identity.EmailVerified == nil {
+		return s.cfg.TrustUnverifiedEmail
+	}
+	return *identity.EmailVerified
+}
+
+// domainAllowed reports whether email is in one of the allowed domains.
+func (s *OIDCService) domainAllowed(email string) bool {
+	if len(s.cfg.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range s.cfg.AllowedDomains {
+		if domain == d {
+			return true
+		}
+	}
+	return false
+}
+
+// roleFor returns the role of the first mapped group in groups, or the
+// default role.
+func (s *OIDCService) roleFor(groups []string) string {
+	for _, m := range s.cfg.RoleMapping {
+		for _, g := range groups {
+			if g == m.Group {
+				return m.Role
+			}
+		}
+	}
+	return s.cfg.DefaultRole
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (s *OIDCService) logAudit(ctx context.Context, event audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Log(ctx, event)
+	}
+}
+
+// oidcFlowKey derives the key flow tokens are signed with from the JWT
+// secret, so a flow token can never pass as another kind of token.
+func oidcFlowKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("mithril-cms oidc flow"))
+	return mac.Sum(nil)
+}
+
+// parseOIDCFlowToken validates a flow token and returns its claims.
+func parseOIDCFlowToken(tokenString, secret string) (*oidcFlowClaims, error) {
+	var claims oidcFlowClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return oidcFlowKey(secret), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parsing oidc flow token: %w", err)
+	}
+	if !token.Valid || claims.State == "" || claims.Nonce == "" || claims.Verifier == "" {
+		return nil, fmt.Errorf("invalid oidc flow token claims")
+	}
+	return &claims, nil
+}
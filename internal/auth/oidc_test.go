@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/oidc"
+	"github.com/GyroZepelix/mithril-cms/internal/oidc/oidctest"
+)
+
+// newOIDCService returns an OIDCService using a mock provider. The service
+// has no repository; flows that look up admins need an identityStore.
+func newOIDCService(t *testing.T, cfg OIDCConfig) (*oidctest.Provider, *OIDCService) {
+	t.Helper()
+	mock := oidctest.NewProvider()
+	t.Cleanup(mock.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       mock.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/admin/api/auth/oidc/callback",
+	})
+	return mock, NewOIDCService(NewService(nil, testSecret), nil, provider, cfg, nil)
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	got, err := ParseRoleMapping(" cms-admins=admin, writers = editor ,,")
+	if err != nil {
+		t.Fatalf("ParseRoleMapping: %v", err)
+	}
+	want := []GroupRole{{Group: "cms-admins", Role: "admin"}, {Group: "writers", Role: "editor"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ParseRoleMapping() = %v, want %v", got, want)
+	}
+
+	if got, err := ParseRoleMapping(""); err != nil || got != nil {
+		t.Errorf("ParseRoleMapping(\"\") = %v, %v; want nil, nil", got, err)
+	}
+	for _, s := range []string{"writers", "writers=", "=editor"} {
+		if _, err := ParseRoleMapping(s); err == nil {
+			t.Errorf("ParseRoleMapping(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestOIDCService_RoleFor(t *testing.T) {
+	_, svc := newOIDCService(t, OIDCConfig{
+		RoleMapping: []GroupRole{{Group: "cms-admins", Role: "admin"}, {Group: "writers", Role: "editor"}},
+		DefaultRole: "viewer",
+	})
+
+	tests := []struct {
+		groups []string
+		want   string
+	}{
+		{[]string{"writers", "cms-admins"}, "admin"}, // Mapping order wins.
+		{[]string{"other", "writers"}, "editor"},
+		{[]string{"other"}, "viewer"},
+		{nil, "viewer"},
+	}
+	for _, tt := range tests {
+		if got := svc.roleFor(tt.groups); got != tt.want {
+			t.Errorf("roleFor(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
+
+func TestOIDCService_DomainAllowed(t *testing.T) {
+	_, svc := newOIDCService(t, OIDCConfig{AllowedDomains: []string{"Example.com", "@corp.example"}})
+
+	tests := map[string]bool{
+		"jane@example.com":      true,
+		"jane@EXAMPLE.COM":      true,
+		"jane@corp.example":     true,
+		"jane@sub.example.com":  false,
+		"jane@example.com.evil": false,
+		"example.com":           false,
+	}
+	for email, want := range tests {
+		if got := svc.domainAllowed(email); got != want {
+			t.Errorf("domainAllowed(%q) = %v, want %v", email, got, want)
+		}
+	}
+
+	_, open := newOIDCService(t, OIDCConfig{})
+	if !open.domainAllowed("jane@anywhere.example") {
+		t.Error("domainAllowed() = false without allowed domains")
+	}
+}
+
+func TestOIDCService_ResolveAdminDenied(t *testing.T) {
+	_, svc := newOIDCService(t, OIDCConfig{AllowedDomains: []string{"example.com"}, DefaultRole: "editor"})
+	verified, unverified := true, false
+
+	tests := []struct {
+		name     string
+		identity oidc.Identity
+		want     error
+	}{
+		{"no email", oidc.Identity{Subject: "u1"}, ErrOIDCEmailUnverified},
+		{"unverified", oidc.Identity{Subject: "u1", Email: "jane@example.com", EmailVerified: &unverified}, ErrOIDCEmailUnverified},
+		{"no email_verified claim", oidc.Identity{Subject: "u1", Email: "jane@example.com"}, ErrOIDCEmailUnverified},
+		{"other domain", oidc.Identity{Subject: "u1", Email: "jane@other.example", EmailVerified: &verified}, ErrOIDCDomainNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.resolveAdmin(context.Background(), &tt.identity)
+			var denied *OIDCError
+			if !errors.Is(err, tt.want) || !errors.As(err, &denied) || denied.Email != tt.identity.Email {
+				t.Errorf("resolveAdmin() error = %v, want OIDCError wrapping %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// identityStore stands in for the admins and their linked identities.
+type identityStore struct {
+	admins map[string]*Admin // by email
+	links  map[string]string // admin ID by issuer and subject
+}
+
+func identityKey(issuer, subject string) string { return issuer + " " + subject }
+
+// use makes svc read and write the store instead of the repository.
+func (st *identityStore) use(svc *OIDCService) {
+	byID := func(id string) *Admin {
+		for _, a := range st.admins {
+			if a.ID == id {
+				return a
+			}
+		}
+		return nil
+	}
+	svc.adminByIdentity = func(_ context.Context, issuer, subject string) (*Admin, error) {
+		if id, ok := st.links[identityKey(issuer, subject)]; ok {
+			return byID(id), nil
+		}
+		return nil, pgx.ErrNoRows
+	}
+	svc.adminByEmail = func(_ context.Context, email string) (*Admin, error) {
+		if a, ok := st.admins[email]; ok {
+			return a, nil
+		}
+		return nil, pgx.ErrNoRows
+	}
+	svc.linkIdentity = func(_ context.Context, adminID, issuer, subject string) (bool, error) {
+		if _, ok := st.links[identityKey(issuer, subject)]; ok {
+			return false, nil
+		}
+		for key, id := range st.links {
+			if id == adminID && strings.HasPrefix(key, issuer+" ") {
+				return false, nil
+			}
+		}
+		st.links[identityKey(issuer, subject)] = adminID
+		return true, nil
+	}
+}
+
+func TestOIDCService_ResolveAdminByIdentity(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	verified := true
+	identity := func(subject, email string) *oidc.Identity {
+		return &oidc.Identity{Issuer: issuer, Subject: subject, Email: email, EmailVerified: &verified}
+	}
+
+	_, svc := newOIDCService(t, OIDCConfig{})
+	st := &identityStore{
+		admins: map[string]*Admin{
+			"jane@example.com": {ID: "admin-jane", Email: "jane@example.com", Role: "editor"},
+			"joe@example.com":  {ID: "admin-joe", Email: "joe@example.com", Role: "admin"},
+		},
+		links: map[string]string{},
+	}
+	st.use(svc)
+
+	// The first sign-in matches by email and links the identity.
+	admin, err := svc.resolveAdmin(context.Background(), identity("u-jane", "jane@example.com"))
+	if err != nil || admin.ID != "admin-jane" {
+		t.Fatalf("first sign-in = %v, %v; want admin-jane", admin, err)
+	}
+	if st.links[identityKey(issuer, "u-jane")] != "admin-jane" {
+		t.Errorf("identity not linked: %v", st.links)
+	}
+
+	// Once linked, the subject decides, whatever email the provider sends.
+	admin, err = svc.resolveAdmin(context.Background(), identity("u-jane", "joe@example.com"))
+	if err != nil || admin.ID != "admin-jane" {
+		t.Errorf("sign-in with changed email = %v, %v; want admin-jane", admin, err)
+	}
+
+	// Another subject with the email of a linked admin is refused.
+	_, err = svc.resolveAdmin(context.Background(), identity("u-mallory", "jane@example.com"))
+	var denied *OIDCError
+	if !errors.Is(err, ErrOIDCIdentityConflict) || !errors.As(err, &denied) {
+		t.Errorf("sign-in as linked admin error = %v, want ErrOIDCIdentityConflict", err)
+	}
+
+	// The same subject at another issuer is another identity.
+	other := identity("u-jane", "joe@example.com")
+	other.Issuer = "https://other-idp.example.com"
+	admin, err = svc.resolveAdmin(context.Background(), other)
+	if err != nil || admin.ID != "admin-joe" {
+		t.Errorf("sign-in at other issuer = %v, %v; want admin-joe", admin, err)
+	}
+}
+
+func TestOIDCService_TwoFactor(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	verified := true
+	enabled := time.Now()
+
+	_, svc := newOIDCService(t, OIDCConfig{})
+	st := &identityStore{
+		admins: map[string]*Admin{
+			"jane@example.com": {ID: "admin-jane", Email: "jane@example.com", Role: "editor", TOTPEnabledAt: &enabled},
+		},
+		links: map[string]string{},
+	}
+	st.use(svc)
+
+	// An admin with two-factor authentication is not linked by email.
+	_, err := svc.resolveAdmin(context.Background(), &oidc.Identity{Issuer: issuer, Subject: "u-jane", Email: "jane@example.com", EmailVerified: &verified})
+	if !errors.Is(err, ErrOIDCTwoFactorLink) {
+		t.Errorf("resolveAdmin() error = %v, want ErrOIDCTwoFactorLink", err)
+	}
+	if len(st.links) != 0 {
+		t.Errorf("identity linked: %v", st.links)
+	}
+}
+
+func TestOIDCService_CompleteTwoFactor(t *testing.T) {
+	mock, svc := newOIDCService(t, OIDCConfig{})
+	enabled := time.Now()
+	st := &identityStore{
+		admins: map[string]*Admin{
+			"jane@example.com": {ID: "admin-jane", Email: "jane@example.com", Role: "editor", TOTPEnabledAt: &enabled},
+		},
+		links: map[string]string{identityKey(mock.Issuer(), "u-jane"): "admin-jane"},
+	}
+	st.use(svc)
+	mock.SetClaims(map[string]any{"sub": "u-jane", "email": "jane@example.com", "email_verified": true})
+
+	authURL, flowToken, err := svc.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorizing: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parsing callback URL: %v", err)
+	}
+
+	// A linked admin with two-factor authentication gets a challenge, as
+	// after the password step of Login.
+	result, err := svc.Complete(context.Background(), flowToken, callback.Query().Get("state"), callback.Query().Get("code"))
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if result.ChallengeToken == "" || result.AccessToken != "" || result.RefreshToken != "" {
+		t.Errorf("Complete() = %+v, want a challenge token only", result)
+	}
+	if _, err := parseChallengeToken(result.ChallengeToken, challengeVerify, testSecret); err != nil {
+		t.Errorf("challenge token invalid: %v", err)
+	}
+}
+
+func TestOIDCService_TrustUnverifiedEmail(t *testing.T) {
+	unverified := false
+	_, svc := newOIDCService(t, OIDCConfig{TrustUnverifiedEmail: true})
+	st := &identityStore{
+		admins: map[string]*Admin{"jane@example.com": {ID: "admin-jane", Email: "jane@example.com"}},
+		links:  map[string]string{},
+	}
+	st.use(svc)
+
+	admin, err := svc.resolveAdmin(context.Background(), &oidc.Identity{Subject: "u1", Email: "jane@example.com"})
+	if err != nil || admin.ID != "admin-jane" {
+		t.Errorf("resolveAdmin() without email_verified = %v, %v; want admin-jane", admin, err)
+	}
+
+	_, err = svc.resolveAdmin(context.Background(), &oidc.Identity{Subject: "u1", Email: "jane@example.com", EmailVerified: &unverified})
+	if !errors.Is(err, ErrOIDCEmailUnverified) {
+		t.Errorf("resolveAdmin() with email_verified false error = %v, want ErrOIDCEmailUnverified", err)
+	}
+}
+
+func TestOIDCService_CompleteInvalidState(t *testing.T) {
+	_, svc := newOIDCService(t, OIDCConfig{})
+
+	authURL, flowToken, err := svc.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing auth URL: %v", err)
+	}
+	state := u.Query().Get("state")
+	if state == "" || u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("auth URL %q lacks state or PKCE", authURL)
+	}
+
+	if _, err := svc.Complete(context.Background(), flowToken, "other-state", "code"); !errors.Is(err, ErrOIDCState) {
+		t.Errorf("Complete() with wrong state error = %v, want ErrOIDCState", err)
+	}
+	if _, err := svc.Complete(context.Background(), "not-a-token", state, "code"); !errors.Is(err, ErrOIDCState) {
+		t.Errorf("Complete() with bad flow token error = %v, want ErrOIDCState", err)
+	}
+	if _, err := parseOIDCFlowToken(flowToken, "other-secret"); err == nil {
+		t.Error("flow token accepted with another secret")
+	}
+	if _, err := ValidateAccessToken(flowToken, testSecret); err == nil {
+		t.Error("flow token accepted as access token")
+	}
+
+	// With the right state the unknown code reaches the provider.
+	if _, err := svc.Complete(context.Background(), flowToken, state, "unknown-code"); !errors.Is(err, oidc.ErrProvider) {
+		t.Errorf("Complete() with unknown code error = %v, want ErrProvider", err)
+	}
+}
+
+func TestOIDCHandler_Login(t *testing.T) {
+	mock, svc := newOIDCService(t, OIDCConfig{})
+	h := NewOIDCHandler(svc, nil, false)
+
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodGet, "/admin/api/auth/oidc/login", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	if loc := rec.Header().Get("Location"); !strings.HasPrefix(loc, mock.Issuer()+"/") {
+		t.Errorf("Location = %q, want provider URL", loc)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != oidcFlowCookieName || c.Path != oidcFlowCookiePath || !c.HttpOnly || !c.Secure ||
+		c.SameSite != http.SameSiteLaxMode || c.Value == "" {
+		t.Errorf("flow cookie = %+v", c)
+	}
+}
+
+func TestOIDCHandler_CallbackErrors(t *testing.T) {
+	_, svc := newOIDCService(t, OIDCConfig{})
+	h := NewOIDCHandler(svc, nil, true)
+
+	_, flowToken, err := svc.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+		want   string
+	}{
+		{"denied at provider", "?error=access_denied", flowToken, "denied"},
+		{"no flow cookie", "?state=s&code=c", "", "state"},
+		{"wrong state", "?state=s&code=c", flowToken, "state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/api/auth/oidc/callback"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcFlowCookieName, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.Callback(rec, req)
+
+			if loc := rec.Header().Get("Location"); loc != "/admin/login?sso_error="+tt.want {
+				t.Errorf("Location = %q, want sso_error=%s", loc, tt.want)
+			}
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == refreshCookieName {
+					t.Error("refresh cookie set on failed callback")
+				}
+				if c.Name == oidcFlowCookieName && c.MaxAge >= 0 {
+					t.Error("flow cookie not cleared")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// SuperuserRole is the built-in role with every permission. It cannot be
+// changed or deleted, so there is always a way to manage roles.
+const SuperuserRole = "admin"
+
+// Sentinel errors for role management.
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleInUse    = errors.New("role is assigned to admins")
+	ErrRoleBuiltIn  = errors.New("the admin role cannot be changed")
+)
+
+// resourceActions lists the actions each resource kind supports. Content
+// resources are "content:<type>" or "content:*".
+var resourceActions = map[string][]string{
+	"content":  {"read", "create", "update", "publish", "delete"},
+	"media":    {"read", "create", "delete"},
+	"audit":    {"read"},
+	"schema":   {"refresh"},
+	"api_keys": {"read", "create", "delete"},
+	"roles":    {"read", "manage"},
+	"admins":   {"read", "manage"},
+}
+
+// ownActions are the content actions that can be granted on an admin's own
+// entries only. They all act on a single existing entry.
+var ownActions = []string{"update", "publish", "delete"}
+
+// roleNamePattern matches valid role names. It is the same pattern schema
+// workflows use for the roles they name.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Permission grants an action on a resource. Resource "*" matches every
+// resource and "content:*" every content type; action "*" matches every
+// action. Own limits a content grant to entries the admin created.
+type Permission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Own      bool   `json:"own,omitempty"`
+}
+
+// matches reports whether p covers action on resource.
+func (p Permission) matches(resource, action string) bool {
+	if p.Action != "*" && p.Action != action {
+		return false
+	}
+	if p.Resource == "*" || p.Resource == resource {
+		return true
+	}
+	return p.Resource == "content:*" && strings.HasPrefix(resource, "content:")
+}
+
+// Permissions is the set of permissions of a role.
+type Permissions []Permission
+
+// Check reports whether the permissions allow action on resource, and if so
+// whether only on the admin's own entries.
+func (ps Permissions) Check(resource, action string) (allowed, ownOnly bool) {
+	for _, p := range ps {
+		if !p.matches(resource, action) {
+			continue
+		}
+		if !p.Own {
+			return true, false
+		}
+		allowed, ownOnly = true, true
+	}
+	return allowed, ownOnly
+}
+
+// Covers reports whether ps allow everything other allows, so that an admin
+// with ps could grant other without gaining permissions. Action "*" of other
+// is taken as every action of its resource.
+func (ps Permissions) Covers(other Permissions) bool {
+	for _, q := range other {
+		for _, action := range q.actions() {
+			if !ps.covers(Permission{Resource: q.Resource, Action: action, Own: q.Own}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// covers reports whether a single permission of ps covers q.
+func (ps Permissions) covers(q Permission) bool {
+	for _, p := range ps {
+		if p.matches(q.Resource, q.Action) && (!p.Own || q.Own) {
+			return true
+		}
+	}
+	return false
+}
+
+// actions returns the actions p grants: its action, or those of its
+// resource for "*". Resource "*" has no list, so its "*" is kept.
+func (p Permission) actions() []string {
+	if p.Action != "*" || p.Resource == "*" {
+		return []string{p.Action}
+	}
+	kind, _, _ := strings.Cut(p.Resource, ":")
+	return resourceActions[kind]
+}
+
+// ContentResource returns the resource name of a content type.
+func ContentResource(contentType string) string {
+	return "content:" + contentType
+}
+
+// Role is a named set of permissions.
+type Role struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Permissions Permissions `json:"permissions"`
+}
+
+// RoleValidationError is returned when a role definition is invalid.
+type RoleValidationError struct {
+	Fields []server.FieldError
+}
+
+func (e *RoleValidationError) Error() string {
+	return fmt.Sprintf("role validation failed: %d field error(s)", len(e.Fields))
+}
+
+// validateRole checks a role definition.
+func validateRole(role Role) []server.FieldError {
+	var errs []server.FieldError
+	if !roleNamePattern.MatchString(role.Name) {
+		errs = append(errs, server.FieldError{Field: "name", Message: "must match " + roleNamePattern.String()})
+	}
+	return append(errs, validatePermissions("permissions", role.Permissions)...)
+}
+
+// validatePermissions checks a list of permissions. Errors name the field
+// with the index of the offending permission, e.g. "permissions[2]".
+func validatePermissions(name string, perms Permissions) []server.FieldError {
+	var errs []server.FieldError
+	seen := make(map[Permission]bool, len(perms))
+	for i, p := range perms {
+		field := fmt.Sprintf("%s[%d]", name, i)
+		kind, _, _ := strings.Cut(p.Resource, ":")
+		actions, ok := resourceActions[kind]
+		switch {
+		case p.Resource == "*":
+			if p.Action != "*" {
+				errs = append(errs, server.FieldError{Field: field, Message: "resource '*' requires action '*'"})
+			}
+		case !ok || (kind != "content" && p.Resource != kind) || p.Resource == "content" || p.Resource == "content:":
+			errs = append(errs, server.FieldError{Field: field, Message: fmt.Sprintf("unknown resource '%s'", p.Resource)})
+		case p.Action != "*" && !slices.Contains(actions, p.Action):
+			errs = append(errs, server.FieldError{Field: field, Message: fmt.Sprintf("unknown action '%s' for resource '%s'", p.Action, p.Resource)})
+		}
+		if p.Own && (kind != "content" || !slices.Contains(ownActions, p.Action)) {
+			errs = append(errs, server.FieldError{Field: field, Message: "own only applies to content update, publish and delete"})
+		}
+
+		key := Permission{Resource: p.Resource, Action: p.Action}
+		if seen[key] {
+			errs = append(errs, server.FieldError{Field: field, Message: "duplicate permission"})
+		}
+		seen[key] = true
+	}
+	return errs
+}
+
+// Authorize returns a middleware that only lets a request through if the
+// authenticated admin's permissions allow action on resource. Placeholders of
+// the form {param} in resource are replaced with the request's URL
+// parameters, e.g. "content:{contentType}". If the grant only covers the
+// admin's own entries, that is recorded in the request context for handlers
+// to enforce (see OwnOnlyFromContext). Requests made with a personal access
+// token must also be allowed by its scopes. Denied requests get a 403 JSON
+// error response. Must run after Middleware.
+func Authorize(resource, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, ownOnly := Can(r.Context(), expandResource(resource, r), action)
+			if !allowed {
+				server.Error(w, http.StatusForbidden, "FORBIDDEN", "insufficient permissions", nil)
+				return
+			}
+			if ownOnly {
+				r = r.WithContext(context.WithValue(r.Context(), ContextKeyOwnOnly, true))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Can reports whether the authenticated admin of ctx may perform action on
+// resource, and whether only on their own entries, as Authorize checks it.
+// Handlers use it for checks that depend on the data, such as which content
+// types to include in a listing.
+func Can(ctx context.Context, resource, action string) (allowed, ownOnly bool) {
+	allowed, ownOnly = PermissionsFromContext(ctx).Check(resource, action)
+	if token := PersonalAccessTokenFromContext(ctx); allowed && token != nil {
+		scoped, scopedOwnOnly := token.Scopes.Check(resource, action)
+		allowed, ownOnly = scoped, ownOnly || scopedOwnOnly
+	}
+	return allowed, ownOnly
+}
+
+// placeholderPattern matches {param} placeholders in resource templates.
+var placeholderPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// expandResource replaces {param} placeholders with URL parameters of r.
+func expandResource(resource string, r *http.Request) string {
+	return placeholderPattern.ReplaceAllStringFunc(resource, func(m string) string {
+		return chi.URLParam(r, m[1:len(m)-1])
+	})
+}
+
+// PermissionsFromContext extracts the authenticated admin's permissions from
+// the request context. Returns nil if no admin is authenticated.
+func PermissionsFromContext(ctx context.Context) Permissions {
+	v, _ := ctx.Value(ContextKeyPermissions).(Permissions)
+	return v
+}
+
+// OwnOnlyFromContext reports whether the request was authorized only for
+// entries the admin created.
+func OwnOnlyFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(ContextKeyOwnOnly).(bool)
+	return v
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPermissions_Check(t *testing.T) {
+	author := Permissions{
+		{Resource: "content:*", Action: "read"},
+		{Resource: "content:*", Action: "update", Own: true},
+		{Resource: "content:pages", Action: "update"},
+		{Resource: "media", Action: "read"},
+	}
+	superuser := Permissions{{Resource: "*", Action: "*"}}
+
+	tests := []struct {
+		name        string
+		perms       Permissions
+		resource    string
+		action      string
+		wantAllowed bool
+		wantOwnOnly bool
+	}{
+		{"wildcard content type", author, "content:posts", "read", true, false},
+		{"own only", author, "content:posts", "update", true, true},
+		{"full grant wins over own", author, "content:pages", "update", true, false},
+		{"missing action", author, "content:posts", "delete", false, false},
+		{"content wildcard does not cover media", author, "media", "delete", false, false},
+		{"exact resource", author, "media", "read", true, false},
+		{"superuser", superuser, "roles", "manage", true, false},
+		{"no permissions", nil, "content:posts", "read", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, ownOnly := tt.perms.Check(tt.resource, tt.action)
+			if allowed != tt.wantAllowed || ownOnly != tt.wantOwnOnly {
+				t.Errorf("Check(%q, %q) = (%v, %v), want (%v, %v)",
+					tt.resource, tt.action, allowed, ownOnly, tt.wantAllowed, tt.wantOwnOnly)
+			}
+		})
+	}
+}
+
+func TestPermissions_Covers(t *testing.T) {
+	editor := Permissions{
+		{Resource: "content:*", Action: "read"},
+		{Resource: "content:*", Action: "update"},
+		{Resource: "content:*", Action: "update", Own: true},
+		{Resource: "media", Action: "read"},
+		{Resource: "media", Action: "create"},
+		{Resource: "media", Action: "delete"},
+	}
+
+	tests := []struct {
+		name  string
+		perms Permissions
+		other Permissions
+		want  bool
+	}{
+		{"same", editor, editor, true},
+		{"content type of wildcard", editor, Permissions{{Resource: "content:posts", Action: "update"}}, true},
+		{"own of full grant", editor, Permissions{{Resource: "content:posts", Action: "update", Own: true}}, true},
+		{"full of own grant", Permissions{{Resource: "content:*", Action: "update", Own: true}},
+			Permissions{{Resource: "content:posts", Action: "update"}}, false},
+		{"wildcard action spelled out", editor, Permissions{{Resource: "media", Action: "*"}}, true},
+		{"wildcard action not all held", editor, Permissions{{Resource: "content:*", Action: "*"}}, false},
+		{"wildcard resource", editor, Permissions{{Resource: "*", Action: "*"}}, false},
+		{"other resource", editor, Permissions{{Resource: "admins", Action: "read"}}, false},
+		{"superuser", Permissions{{Resource: "*", Action: "*"}}, Permissions{{Resource: "*", Action: "*"}}, true},
+		{"nothing", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.perms.Covers(tt.other); got != tt.want {
+				t.Errorf("Covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       Role
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			role: Role{Name: "author", Permissions: Permissions{
+				{Resource: "content:*", Action: "read"},
+				{Resource: "content:posts", Action: "delete", Own: true},
+				{Resource: "media", Action: "*"},
+			}},
+		},
+		{
+			name:       "invalid name",
+			role:       Role{Name: "Author"},
+			wantFields: []string{"name"},
+		},
+		{
+			name: "unknown resources",
+			role: Role{Name: "r", Permissions: Permissions{
+				{Resource: "media:foo", Action: "read"},
+				{Resource: "content", Action: "read"},
+				{Resource: "widgets", Action: "read"},
+			}},
+			wantFields: []string{"permissions[0]", "permissions[1]", "permissions[2]"},
+		},
+		{
+			name: "unknown action",
+			role: Role{Name: "r", Permissions: Permissions{
+				{Resource: "media", Action: "publish"},
+			}},
+			wantFields: []string{"permissions[0]"},
+		},
+		{
+			name: "superuser grant needs wildcard action",
+			role: Role{Name: "r", Permissions: Permissions{
+				{Resource: "*", Action: "read"},
+			}},
+			wantFields: []string{"permissions[0]"},
+		},
+		{
+			name: "own outside entry actions",
+			role: Role{Name: "r", Permissions: Permissions{
+				{Resource: "media", Action: "delete", Own: true},
+				{Resource: "content:*", Action: "read", Own: true},
+			}},
+			wantFields: []string{"permissions[0]", "permissions[1]"},
+		},
+		{
+			name: "duplicate",
+			role: Role{Name: "r", Permissions: Permissions{
+				{Resource: "audit", Action: "read"},
+				{Resource: "audit", Action: "read", Own: false},
+			}},
+			wantFields: []string{"permissions[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateRole(tt.role)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("validateRole() = %+v, want errors for %v", errs, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Errorf("error %d field = %q, want %q", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	perms := Permissions{
+		{Resource: "content:posts", Action: "read"},
+		{Resource: "content:posts", Action: "update", Own: true},
+	}
+
+	var gotOwnOnly bool
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ContextKeyPermissions, perms)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Route("/content/{contentType}", func(r chi.Router) {
+		ok := func(w http.ResponseWriter, r *http.Request) {
+			gotOwnOnly = OwnOnlyFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}
+		r.With(Authorize("content:{contentType}", "read")).Get("/", ok)
+		r.With(Authorize("content:{contentType}", "update")).Put("/{id}", ok)
+	})
+
+	tests := []struct {
+		method      string
+		path        string
+		wantStatus  int
+		wantOwnOnly bool
+	}{
+		{http.MethodGet, "/content/posts/", http.StatusOK, false},
+		{http.MethodGet, "/content/pages/", http.StatusForbidden, false},
+		{http.MethodPut, "/content/posts/1", http.StatusOK, true},
+		{http.MethodPut, "/content/pages/1", http.StatusForbidden, false},
+	}
+
+	for _, tt := range tests {
+		gotOwnOnly = false
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, rr.Code, tt.wantStatus)
+		}
+		if gotOwnOnly != tt.wantOwnOnly {
+			t.Errorf("%s %s: own only = %v, want %v", tt.method, tt.path, gotOwnOnly, tt.wantOwnOnly)
+		}
+	}
+}
+
+func TestSaveRole_BuiltIn(t *testing.T) {
+	svc := NewService(nil, testSecret)
+
+	if _, err := svc.SaveRole(context.Background(), Role{Name: SuperuserRole}); err != ErrRoleBuiltIn {
+		t.Errorf("SaveRole(admin) error = %v, want ErrRoleBuiltIn", err)
+	}
+	if err := svc.DeleteRole(context.Background(), SuperuserRole); err != ErrRoleBuiltIn {
+		t.Errorf("DeleteRole(admin) error = %v, want ErrRoleBuiltIn", err)
+	}
+}
@@ -21,16 +21,23 @@ var ErrTokenAlreadyUsed = errors.New("refresh token already used")
 
 // Admin represents an admin user row from the admins table.
 type Admin struct {
-	ID           string
-	Email        string
-	PasswordHash string
-	CreatedAt    time.Time
+	ID           string     `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"`
+	Role         string     `json:"role"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	// TOTPEnabledAt is set while the admin has two-factor authentication
+	// enabled.
+	TOTPEnabledAt *time.Time `json:"two_factor_enabled_at"`
 }
 
 // RefreshToken represents a refresh token row from the refresh_tokens table.
 type RefreshToken struct {
 	ID        string
 	AdminID   string
+	SessionID string
 	TokenHash string
 	ExpiresAt time.Time
 	CreatedAt time.Time
@@ -46,55 +53,111 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// adminColumns is the column list scanned by scanAdmin.
+const adminColumns = `id, email, password_hash, role, created_at, updated_at, disabled_at, totp_enabled_at`
+
+func scanAdmin(row pgx.Row) (*Admin, error) {
+	var a Admin
+	if err := row.Scan(&a.ID, &a.Email, &a.PasswordHash, &a.Role, &a.CreatedAt, &a.UpdatedAt, &a.DisabledAt, &a.TOTPEnabledAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
 // GetAdminByEmail returns the admin with the given email, or an error wrapping
 // pgx.ErrNoRows if no admin exists with that email.
 func (r *Repository) GetAdminByEmail(ctx context.Context, email string) (*Admin, error) {
-	row := r.db.Pool().QueryRow(ctx,
-		`SELECT id, email, password_hash, created_at FROM admins WHERE email = $1`,
+	a, err := scanAdmin(r.db.Pool().QueryRow(ctx,
+		`SELECT `+adminColumns+` FROM admins WHERE email = $1`,
 		email,
-	)
-
-	var a Admin
-	if err := row.Scan(&a.ID, &a.Email, &a.PasswordHash, &a.CreatedAt); err != nil {
+	))
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("admin not found: %w", err)
 		}
 		return nil, fmt.Errorf("querying admin by email: %w", err)
 	}
-	return &a, nil
+	return a, nil
+}
+
+// FindAdminByEmail returns the admin whose email matches the given one
+// ignoring case, preferring an exact match, or an error wrapping
+// pgx.ErrNoRows if there is none.
+func (r *Repository) FindAdminByEmail(ctx context.Context, email string) (*Admin, error) {
+	a, err := scanAdmin(r.db.Pool().QueryRow(ctx,
+		`SELECT `+adminColumns+` FROM admins WHERE lower(email) = lower($1)
+		 ORDER BY email = $1 DESC LIMIT 1`,
+		email,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("admin not found: %w", err)
+		}
+		return nil, fmt.Errorf("finding admin by email: %w", err)
+	}
+	return a, nil
+}
+
+// FindAdminByIdentity returns the admin linked to the single sign-on
+// identity with the given issuer and subject, or an error wrapping
+// pgx.ErrNoRows if none is.
+func (r *Repository) FindAdminByIdentity(ctx context.Context, issuer, subject string) (*Admin, error) {
+	a, err := scanAdmin(r.db.Pool().QueryRow(ctx,
+		`SELECT `+adminColumns+` FROM admins
+		 WHERE id = (SELECT admin_id FROM admin_identities WHERE issuer = $1 AND subject = $2)`,
+		issuer, subject,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("admin not found: %w", err)
+		}
+		return nil, fmt.Errorf("finding admin by identity: %w", err)
+	}
+	return a, nil
+}
+
+// LinkIdentity links an admin to the single sign-on identity with the given
+// issuer and subject. It reports false if the admin is already linked to
+// another identity of the issuer, or the identity to another admin.
+func (r *Repository) LinkIdentity(ctx context.Context, adminID, issuer, subject string) (bool, error) {
+	tag, err := r.db.Pool().Exec(ctx,
+		`INSERT INTO admin_identities (issuer, subject, admin_id) VALUES ($1, $2, $3)
+		 ON CONFLICT DO NOTHING`,
+		issuer, subject, adminID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("linking identity: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
 }
 
 // GetAdminByID returns the admin with the given UUID, or an error wrapping
 // pgx.ErrNoRows if no admin exists with that ID.
 func (r *Repository) GetAdminByID(ctx context.Context, adminID string) (*Admin, error) {
-	row := r.db.Pool().QueryRow(ctx,
-		`SELECT id, email, password_hash, created_at FROM admins WHERE id = $1`,
+	a, err := scanAdmin(r.db.Pool().QueryRow(ctx,
+		`SELECT `+adminColumns+` FROM admins WHERE id = $1`,
 		adminID,
-	)
-
-	var a Admin
-	if err := row.Scan(&a.ID, &a.Email, &a.PasswordHash, &a.CreatedAt); err != nil {
+	))
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("admin not found: %w", err)
 		}
 		return nil, fmt.Errorf("querying admin by id: %w", err)
 	}
-	return &a, nil
+	return a, nil
 }
 
 // CreateAdmin inserts a new admin with the given email and password hash. If an
 // admin with the same email already exists, this is treated as success and the
 // existing admin is returned. This eliminates the TOCTOU race in EnsureAdmin.
 func (r *Repository) CreateAdmin(ctx context.Context, email, passwordHash string) (*Admin, error) {
-	row := r.db.Pool().QueryRow(ctx,
+	a, err := scanAdmin(r.db.Pool().QueryRow(ctx,
 		`INSERT INTO admins (email, password_hash) VALUES ($1, $2)
 		 ON CONFLICT (email) DO NOTHING
-		 RETURNING id, email, password_hash, created_at`,
+		 RETURNING `+adminColumns,
 		email, passwordHash,
-	)
-
-	var a Admin
-	if err := row.Scan(&a.ID, &a.Email, &a.PasswordHash, &a.CreatedAt); err != nil {
+	))
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			// ON CONFLICT DO NOTHING returns no rows — admin already exists.
 			// Fetch the existing admin to return consistent data.
@@ -102,7 +165,7 @@ func (r *Repository) CreateAdmin(ctx context.Context, email, passwordHash string
 		}
 		return nil, fmt.Errorf("creating admin: %w", err)
 	}
-	return &a, nil
+	return a, nil
 }
 
 // CountAdmins returns the total number of admin users in the database.
@@ -114,16 +177,32 @@ func (r *Repository) CountAdmins(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-// CreateRefreshToken stores a new refresh token hash with the given admin ID
-// and expiration time.
-func (r *Repository) CreateRefreshToken(ctx context.Context, adminID, tokenHash string, expiresAt time.Time) error {
-	_, err := r.db.Pool().Exec(ctx,
-		`INSERT INTO refresh_tokens (admin_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
-		adminID, tokenHash, expiresAt,
-	)
+// CreateSession starts a session for the admin from the given client and
+// stores its first refresh token hash with the given expiration time.
+func (r *Repository) CreateSession(ctx context.Context, adminID, tokenHash string, client ClientInfo, expiresAt time.Time) error {
+	tx, err := r.db.Pool().Begin(ctx)
 	if err != nil {
+		return fmt.Errorf("beginning session tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	var sessionID string
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO admin_sessions (admin_id, user_agent, ip) VALUES ($1, $2, $3) RETURNING id`,
+		adminID, client.UserAgent, client.IP,
+	).Scan(&sessionID); err != nil {
+		return fmt.Errorf("creating session: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (admin_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		adminID, sessionID, tokenHash, expiresAt,
+	); err != nil {
 		return fmt.Errorf("creating refresh token: %w", err)
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing session: %w", err)
+	}
 	return nil
 }
 
@@ -131,13 +210,13 @@ func (r *Repository) CreateRefreshToken(ctx context.Context, adminID, tokenHash
 // error wrapping pgx.ErrNoRows if no matching token exists.
 func (r *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
 	row := r.db.Pool().QueryRow(ctx,
-		`SELECT id, admin_id, token_hash, expires_at, created_at
+		`SELECT id, admin_id, session_id, token_hash, expires_at, created_at
 		 FROM refresh_tokens WHERE token_hash = $1`,
 		tokenHash,
 	)
 
 	var t RefreshToken
-	if err := row.Scan(&t.ID, &t.AdminID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt); err != nil {
+	if err := row.Scan(&t.ID, &t.AdminID, &t.SessionID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("refresh token not found: %w", err)
 		}
@@ -146,11 +225,12 @@ func (r *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*Re
 	return &t, nil
 }
 
-// RotateRefreshToken atomically deletes the old refresh token and inserts a new
-// one within a single database transaction. If the old token has already been
-// consumed (0 rows deleted), all tokens for the admin are revoked as a security
-// measure and ErrTokenAlreadyUsed is returned.
-func (r *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash, newTokenHash, adminID string, expiresAt time.Time) error {
+// RotateRefreshToken atomically replaces the old refresh token of a session
+// with a new one within a single database transaction, and records the
+// client the session was used from. If the old token has already been
+// consumed (0 rows deleted), all sessions of the admin are revoked as a
+// security measure and ErrTokenAlreadyUsed is returned.
+func (r *Repository) RotateRefreshToken(ctx context.Context, old *RefreshToken, newTokenHash string, client ClientInfo, expiresAt time.Time) error {
 	tx, err := r.db.Pool().Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning refresh token rotation tx: %w", err)
@@ -160,7 +240,7 @@ func (r *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash, newTo
 	// Delete the old token and verify it existed.
 	tag, err := tx.Exec(ctx,
 		`DELETE FROM refresh_tokens WHERE token_hash = $1 AND admin_id = $2`,
-		oldTokenHash, adminID,
+		old.TokenHash, old.AdminID,
 	)
 	if err != nil {
 		return fmt.Errorf("deleting old refresh token: %w", err)
@@ -170,10 +250,10 @@ func (r *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash, newTo
 		// The old token was already consumed — possible replay attack.
 		// Revoke all sessions for this admin as a security measure.
 		if _, err := tx.Exec(ctx,
-			`DELETE FROM refresh_tokens WHERE admin_id = $1`,
-			adminID,
+			`DELETE FROM admin_sessions WHERE admin_id = $1`,
+			old.AdminID,
 		); err != nil {
-			return fmt.Errorf("revoking all tokens after replay: %w", err)
+			return fmt.Errorf("revoking all sessions after replay: %w", err)
 		}
 		if err := tx.Commit(ctx); err != nil {
 			return fmt.Errorf("committing replay revocation: %w", err)
@@ -183,11 +263,17 @@ func (r *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash, newTo
 
 	// Insert the new token.
 	if _, err := tx.Exec(ctx,
-		`INSERT INTO refresh_tokens (admin_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
-		adminID, newTokenHash, expiresAt,
+		`INSERT INTO refresh_tokens (admin_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		old.AdminID, old.SessionID, newTokenHash, expiresAt,
 	); err != nil {
 		return fmt.Errorf("inserting new refresh token: %w", err)
 	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE admin_sessions SET user_agent = $2, ip = $3, last_used_at = now() WHERE id = $1`,
+		old.SessionID, client.UserAgent, client.IP,
+	); err != nil {
+		return fmt.Errorf("updating session: %w", err)
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("committing refresh token rotation: %w", err)
@@ -195,11 +281,11 @@ func (r *Repository) RotateRefreshToken(ctx context.Context, oldTokenHash, newTo
 	return nil
 }
 
-// DeleteRefreshToken removes the refresh token with the given hash from the
-// database. It is not an error if no matching token exists.
+// DeleteRefreshToken ends the session of the refresh token with the given
+// hash. It is not an error if no matching token exists.
 func (r *Repository) DeleteRefreshToken(ctx context.Context, tokenHash string) error {
 	_, err := r.db.Pool().Exec(ctx,
-		`DELETE FROM refresh_tokens WHERE token_hash = $1`,
+		`DELETE FROM admin_sessions WHERE id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)`,
 		tokenHash,
 	)
 	if err != nil {
@@ -208,28 +294,1204 @@ func (r *Repository) DeleteRefreshToken(ctx context.Context, tokenHash string) e
 	return nil
 }
 
-// DeleteAllForAdmin removes all refresh tokens belonging to the given admin.
-// This is used for security (suspected token reuse) and for implementing a
-// "logout everywhere" feature.
+// DeleteAllForAdmin ends all sessions of the given admin, revoking their
+// refresh tokens. This is used for security (suspected token reuse) and for
+// implementing a "logout everywhere" feature.
 func (r *Repository) DeleteAllForAdmin(ctx context.Context, adminID string) error {
 	_, err := r.db.Pool().Exec(ctx,
-		`DELETE FROM refresh_tokens WHERE admin_id = $1`,
+		`DELETE FROM admin_sessions WHERE admin_id = $1`,
 		adminID,
 	)
 	if err != nil {
-		return fmt.Errorf("deleting all tokens for admin: %w", err)
+		return fmt.Errorf("deleting all sessions for admin: %w", err)
 	}
 	return nil
 }
 
-// DeleteExpiredTokens removes all refresh tokens that have passed their
+// DeleteExpiredTokens removes all sessions whose refresh token has passed its
 // expiration time. This can be called periodically for cleanup.
 func (r *Repository) DeleteExpiredTokens(ctx context.Context) error {
 	_, err := r.db.Pool().Exec(ctx,
-		`DELETE FROM refresh_tokens WHERE expires_at < now()`,
+		`DELETE FROM admin_sessions s WHERE NOT EXISTS (
+			SELECT 1 FROM refresh_tokens t WHERE t.session_id = s.id AND t.expires_at >= now()
+		 )`,
 	)
 	if err != nil {
 		return fmt.Errorf("deleting expired tokens: %w", err)
 	}
 	return nil
 }
+
+// sessionColumns is the column list scanned by scanSession, for admin_sessions
+// joined as s with their refresh token as t.
+const sessionColumns = `s.id, s.admin_id, s.user_agent, s.ip, s.created_at, s.last_used_at, t.expires_at`
+
+func scanSession(row pgx.Row) (*Session, error) {
+	var s Session
+	if err := row.Scan(&s.ID, &s.AdminID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSessions returns the unexpired sessions of an admin, most recently used
+// first.
+func (r *Repository) ListSessions(ctx context.Context, adminID string) ([]Session, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT `+sessionColumns+`
+		 FROM admin_sessions s JOIN refresh_tokens t ON t.session_id = s.id
+		 WHERE s.admin_id = $1 AND t.expires_at >= now()
+		 ORDER BY s.last_used_at DESC`,
+		adminID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying sessions: %w", err)
+	}
+
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		s, err := scanSession(row)
+		if err != nil {
+			return Session{}, err
+		}
+		return *s, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteSession ends a session of an admin, revoking its refresh token.
+// Returns ErrSessionNotFound if the admin has no such session.
+func (r *Repository) DeleteSession(ctx context.Context, adminID, sessionID string) error {
+	tag, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM admin_sessions WHERE id = $1 AND admin_id = $2`,
+		sessionID, adminID,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// apiKeyColumns is the column list scanned by scanAPIKey.
+const apiKeyColumns = `id, name, prefix, content_types, drafts, created_by::text,
+	created_at, revoked_at, last_used_at, request_count`
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.ContentTypes, &k.Drafts, &k.CreatedBy,
+		&k.CreatedAt, &k.RevokedAt, &k.LastUsedAt, &k.RequestCount)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// CreateAPIKey stores a new API key with the given token hash.
+func (r *Repository) CreateAPIKey(ctx context.Context, name, tokenHash, prefix string, contentTypes []string, drafts bool, createdBy string) (*APIKey, error) {
+	k, err := scanAPIKey(r.db.Pool().QueryRow(ctx,
+		`INSERT INTO api_keys (name, token_hash, prefix, content_types, drafts, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+apiKeyColumns,
+		name, tokenHash, prefix, contentTypes, drafts, createdBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating api key: %w", err)
+	}
+	return k, nil
+}
+
+// ListAPIKeys returns all API keys, including revoked ones, newest first.
+func (r *Repository) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying api keys: %w", err)
+	}
+
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (APIKey, error) {
+		k, err := scanAPIKey(row)
+		if err != nil {
+			return APIKey{}, err
+		}
+		return *k, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GetActiveAPIKey looks up an unrevoked API key by its SHA256 token hash.
+// Returns an error wrapping pgx.ErrNoRows if no matching key exists.
+func (r *Repository) GetActiveAPIKey(ctx context.Context, tokenHash string) (*APIKey, error) {
+	k, err := scanAPIKey(r.db.Pool().QueryRow(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys
+		 WHERE token_hash = $1 AND revoked_at IS NULL`,
+		tokenHash,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("api key not found: %w", err)
+		}
+		return nil, fmt.Errorf("querying api key: %w", err)
+	}
+	return k, nil
+}
+
+// RevokeAPIKey marks an API key as revoked. Returns an error wrapping
+// pgx.ErrNoRows if no unrevoked key with the given ID exists.
+func (r *Repository) RevokeAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	k, err := scanAPIKey(r.db.Pool().QueryRow(ctx,
+		`UPDATE api_keys SET revoked_at = now()
+		 WHERE id = $1 AND revoked_at IS NULL
+		 RETURNING `+apiKeyColumns,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("api key not found: %w", err)
+		}
+		return nil, fmt.Errorf("revoking api key: %w", err)
+	}
+	return k, nil
+}
+
+// AddAPIKeyUsage adds count requests to an API key's request count and moves
+// its last use forward to lastUsed.
+func (r *Repository) AddAPIKeyUsage(ctx context.Context, id string, count int64, lastUsed time.Time) error {
+	_, err := r.db.Pool().Exec(ctx,
+		`UPDATE api_keys
+		 SET request_count = request_count + $2,
+		     last_used_at = GREATEST(last_used_at, $3)
+		 WHERE id = $1`,
+		id, count, lastUsed,
+	)
+	if err != nil {
+		return fmt.Errorf("recording api key usage: %w", err)
+	}
+	return nil
+}
+
+// RolePermissions returns the permissions of a role. A role without
+// permissions, or one that does not exist, yields none.
+func (r *Repository) RolePermissions(ctx context.Context, role string) (Permissions, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT resource, action, own FROM role_permissions
+		 WHERE role = $1 ORDER BY resource, action`,
+		role,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying role permissions: %w", err)
+	}
+
+	perms, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Permission, error) {
+		var p Permission
+		err := row.Scan(&p.Resource, &p.Action, &p.Own)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning role permissions: %w", err)
+	}
+	return perms, nil
+}
+
+// ListRoles returns all roles with their permissions, ordered by name.
+func (r *Repository) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT r.name, r.description,
+		        COALESCE(json_agg(json_build_object('resource', p.resource, 'action', p.action, 'own', p.own)
+		                 ORDER BY p.resource, p.action) FILTER (WHERE p.role IS NOT NULL), '[]')
+		 FROM roles r LEFT JOIN role_permissions p ON p.role = r.name
+		 GROUP BY r.name ORDER BY r.name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying roles: %w", err)
+	}
+
+	roles, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Role, error) {
+		var role Role
+		err := row.Scan(&role.Name, &role.Description, &role.Permissions)
+		return role, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning roles: %w", err)
+	}
+	return roles, nil
+}
+
+// SaveRole creates a role or replaces the description and permissions of an
+// existing one in a single transaction. It reports whether the role was
+// created.
+func (r *Repository) SaveRole(ctx context.Context, role Role) (created bool, holders []string, err error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return false, nil, fmt.Errorf("beginning role tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	// xmax is zero for freshly inserted rows.
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO roles (name, description) VALUES ($1, $2)
+		 ON CONFLICT (name) DO UPDATE
+		 SET description = EXCLUDED.description, updated_at = now()
+		 RETURNING xmax = 0`,
+		role.Name, role.Description,
+	).Scan(&created); err != nil {
+		return false, nil, fmt.Errorf("saving role: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role = $1`, role.Name); err != nil {
+		return false, nil, fmt.Errorf("clearing role permissions: %w", err)
+	}
+	for _, p := range role.Permissions {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO role_permissions (role, resource, action, own) VALUES ($1, $2, $3, $4)`,
+			role.Name, p.Resource, p.Action, p.Own,
+		); err != nil {
+			return false, nil, fmt.Errorf("inserting role permission: %w", err)
+		}
+	}
+
+	// Access tokens carry the permissions they were issued with.
+	rows, err := tx.Query(ctx,
+		`UPDATE admins SET tokens_valid_after = date_trunc('second', now()) WHERE role = $1 RETURNING id`,
+		role.Name,
+	)
+	if err != nil {
+		return false, nil, fmt.Errorf("revoking access tokens of role holders: %w", err)
+	}
+	holders, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return false, nil, fmt.Errorf("revoking access tokens of role holders: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, nil, fmt.Errorf("committing role: %w", err)
+	}
+	return created, holders, nil
+}
+
+// DeleteRole deletes a role and its permissions. Returns ErrRoleNotFound if
+// it does not exist and ErrRoleInUse if admins still have it.
+func (r *Repository) DeleteRole(ctx context.Context, name string) error {
+	tag, err := r.db.Pool().Exec(ctx, `DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return ErrRoleInUse
+		}
+		return fmt.Errorf("deleting role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// ListAdmins returns all admins ordered by email.
+func (r *Repository) ListAdmins(ctx context.Context) ([]Admin, error) {
+	rows, err := r.db.Pool().Query(ctx, `SELECT `+adminColumns+` FROM admins ORDER BY email`)
+	if err != nil {
+		return nil, fmt.Errorf("querying admins: %w", err)
+	}
+
+	admins, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Admin, error) {
+		a, err := scanAdmin(row)
+		if err != nil {
+			return Admin{}, err
+		}
+		return *a, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning admins: %w", err)
+	}
+	return admins, nil
+}
+
+// InsertAdmin inserts a new admin. Returns ErrEmailTaken if the email is in
+// use and ErrUnknownRole if the role does not exist.
+func (r *Repository) InsertAdmin(ctx context.Context, email, passwordHash, role string) (*Admin, error) {
+	a, err := scanAdmin(r.db.Pool().QueryRow(ctx,
+		`INSERT INTO admins (email, password_hash, role) VALUES ($1, $2, $3)
+		 RETURNING `+adminColumns,
+		email, passwordHash, role,
+	))
+	if err != nil {
+		return nil, adminConstraintError("inserting admin", err)
+	}
+	return a, nil
+}
+
+// UpdateAdmin sets the email and/or role of an admin; nil values are left
+// unchanged. A new role revokes the admin's access tokens, which carry the
+// old role's permissions, and a new email deletes unused password reset
+// tokens sent to the old one. Returns ErrAdminNotFound, ErrEmailTaken,
+// ErrUnknownRole, or ErrLastSuperuser if the role change would leave no
+// enabled superuser.
+func (r *Repository) UpdateAdmin(ctx context.Context, id string, email, role *string) (*Admin, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning admin update tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if role != nil && *role != SuperuserRole {
+		if err := guardLastSuperuser(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	a, err := scanAdmin(tx.QueryRow(ctx,
+		`UPDATE admins SET email = COALESCE($2, email), role = COALESCE($3, role),
+		     tokens_valid_after = CASE WHEN $3 <> role THEN date_trunc('second', now()) ELSE tokens_valid_after END,
+		     updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+adminColumns,
+		id, email, role,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdminNotFound
+		}
+		return nil, adminConstraintError("updating admin", err)
+	}
+	if email != nil {
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM account_tokens WHERE admin_id = $1 AND used_at IS NULL AND email <> $2`,
+			id, *email,
+		); err != nil {
+			return nil, fmt.Errorf("deleting account tokens: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing admin update: %w", err)
+	}
+	return a, nil
+}
+
+// SetAdminDisabled disables or re-enables an admin. Disabling deletes the
+// admin's unused account tokens. Returns ErrAdminNotFound, or
+// ErrLastSuperuser if disabling would leave no enabled superuser.
+func (r *Repository) SetAdminDisabled(ctx context.Context, id string, disabled bool) (*Admin, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning admin disable tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if disabled {
+		if err := guardLastSuperuser(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	a, err := scanAdmin(tx.QueryRow(ctx,
+		`UPDATE admins
+		 SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, now()) END, updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+adminColumns,
+		id, disabled,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdminNotFound
+		}
+		return nil, fmt.Errorf("setting admin disabled: %w", err)
+	}
+	if disabled {
+		if err := deleteAccountTokens(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing admin disable: %w", err)
+	}
+	return a, nil
+}
+
+// DeleteAdmin deletes an admin and returns it. Returns ErrAdminNotFound,
+// ErrLastSuperuser, or ErrAdminInUse if content entries still reference the
+// admin as their creator or last editor.
+func (r *Repository) DeleteAdmin(ctx context.Context, id string) (*Admin, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning admin delete tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if err := guardLastSuperuser(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	a, err := scanAdmin(tx.QueryRow(ctx,
+		`DELETE FROM admins WHERE id = $1 RETURNING `+adminColumns,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdminNotFound
+		}
+		if database.IsForeignKeyViolation(err) {
+			return nil, ErrAdminInUse
+		}
+		return nil, fmt.Errorf("deleting admin: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing admin delete: %w", err)
+	}
+	return a, nil
+}
+
+// UpdatePassword sets the password hash of an admin and deletes the admin's
+// unused account tokens, so reset links sent before the change stop working.
+func (r *Repository) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning password update tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE admins SET password_hash = $2, updated_at = now() WHERE id = $1`,
+		id, passwordHash,
+	)
+	if err != nil {
+		return fmt.Errorf("updating password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAdminNotFound
+	}
+	if err := deleteAccountTokens(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing password update: %w", err)
+	}
+	return nil
+}
+
+// deleteAccountTokens deletes the unused account tokens (password resets)
+// of an admin. They are issued for the account as it was, so changes to its
+// password, email or status invalidate them.
+func deleteAccountTokens(ctx context.Context, tx pgx.Tx, adminID string) error {
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM account_tokens WHERE admin_id = $1 AND used_at IS NULL`,
+		adminID,
+	); err != nil {
+		return fmt.Errorf("deleting account tokens: %w", err)
+	}
+	return nil
+}
+
+// guardLastSuperuser returns ErrLastSuperuser if the admin is the only
+// enabled superuser. The superuser rows stay locked until tx ends, so
+// concurrent removals cannot both pass the check.
+func guardLastSuperuser(ctx context.Context, tx pgx.Tx, id string) error {
+	rows, err := tx.Query(ctx,
+		`SELECT id::text FROM admins WHERE role = $1 AND disabled_at IS NULL FOR UPDATE`,
+		SuperuserRole,
+	)
+	if err != nil {
+		return fmt.Errorf("locking superusers: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("scanning superusers: %w", err)
+	}
+	if len(ids) == 1 && ids[0] == id {
+		return ErrLastSuperuser
+	}
+	return nil
+}
+
+// adminConstraintError translates constraint violations from writing an admin.
+func adminConstraintError(op string, err error) error {
+	switch {
+	case database.IsUniqueViolation(err):
+		return ErrEmailTaken
+	case database.IsForeignKeyViolation(err):
+		return ErrUnknownRole
+	default:
+		return fmt.Errorf("%s: %w", op, err)
+	}
+}
+
+// inviteColumns is the column list scanned by scanInvite.
+const inviteColumns = `id, email, role, created_by::text, created_at, expires_at`
+
+func scanInvite(row pgx.Row) (*Invite, error) {
+	var i Invite
+	if err := row.Scan(&i.ID, &i.Email, &i.Role, &i.CreatedBy, &i.CreatedAt, &i.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// CreateInvite stores a new invitation, replacing any pending invitation for
+// the same email. Returns ErrUnknownRole if the role does not exist.
+func (r *Repository) CreateInvite(ctx context.Context, tokenHash, email, role, createdBy string, expiresAt time.Time) (*Invite, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning invite tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM account_tokens WHERE kind = $1 AND email = $2 AND used_at IS NULL`,
+		tokenKindInvite, email,
+	); err != nil {
+		return nil, fmt.Errorf("replacing pending invites: %w", err)
+	}
+
+	invite, err := scanInvite(tx.QueryRow(ctx,
+		`INSERT INTO account_tokens (kind, token_hash, email, role, created_by, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+inviteColumns,
+		tokenKindInvite, tokenHash, email, role, createdBy, expiresAt,
+	))
+	if err != nil {
+		return nil, adminConstraintError("creating invite", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing invite: %w", err)
+	}
+	return invite, nil
+}
+
+// ListInvites returns unused, unexpired invitations, newest first.
+func (r *Repository) ListInvites(ctx context.Context) ([]Invite, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT `+inviteColumns+` FROM account_tokens
+		 WHERE kind = $1 AND used_at IS NULL AND expires_at > now()
+		 ORDER BY created_at DESC`,
+		tokenKindInvite,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying invites: %w", err)
+	}
+
+	invites, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Invite, error) {
+		i, err := scanInvite(row)
+		if err != nil {
+			return Invite{}, err
+		}
+		return *i, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning invites: %w", err)
+	}
+	return invites, nil
+}
+
+// DeleteInvite deletes an unused invitation and returns it. Returns
+// ErrInviteNotFound if there is none with the ID.
+func (r *Repository) DeleteInvite(ctx context.Context, id string) (*Invite, error) {
+	invite, err := scanInvite(r.db.Pool().QueryRow(ctx,
+		`DELETE FROM account_tokens WHERE id = $1 AND kind = $2 AND used_at IS NULL
+		 RETURNING `+inviteColumns,
+		id, tokenKindInvite,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, fmt.Errorf("deleting invite: %w", err)
+	}
+	return invite, nil
+}
+
+// AcceptInvite spends an invitation token and creates the invited admin in
+// a single transaction. Returns the admin and the invitation's ID, or
+// ErrInvalidAccountToken if the token is unknown, expired or used, and
+// ErrEmailTaken if an admin with the email was created in the meantime.
+func (r *Repository) AcceptInvite(ctx context.Context, tokenHash, passwordHash string) (*Admin, string, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("beginning invite acceptance tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	var inviteID, email, role string
+	if err := tx.QueryRow(ctx,
+		`UPDATE account_tokens SET used_at = now()
+		 WHERE token_hash = $1 AND kind = $2 AND used_at IS NULL AND expires_at > now()
+		 RETURNING id, email, role`,
+		tokenHash, tokenKindInvite,
+	).Scan(&inviteID, &email, &role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", ErrInvalidAccountToken
+		}
+		return nil, "", fmt.Errorf("spending invite: %w", err)
+	}
+
+	admin, err := scanAdmin(tx.QueryRow(ctx,
+		`INSERT INTO admins (email, password_hash, role) VALUES ($1, $2, $3)
+		 RETURNING `+adminColumns,
+		email, passwordHash, role,
+	))
+	if err != nil {
+		return nil, "", adminConstraintError("creating invited admin", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", fmt.Errorf("committing invite acceptance: %w", err)
+	}
+	return admin, inviteID, nil
+}
+
+// CreatePasswordReset stores a new password reset token for the admin,
+// invalidating the admin's earlier unused ones.
+func (r *Repository) CreatePasswordReset(ctx context.Context, tokenHash, adminID, email string, expiresAt time.Time) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning password reset tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM account_tokens WHERE kind = $1 AND admin_id = $2 AND used_at IS NULL`,
+		tokenKindPasswordReset, adminID,
+	); err != nil {
+		return fmt.Errorf("replacing password resets: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO account_tokens (kind, token_hash, email, admin_id, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		tokenKindPasswordReset, tokenHash, email, adminID, expiresAt,
+	); err != nil {
+		return fmt.Errorf("creating password reset: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing password reset: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword spends a password reset token and sets the admin's password
+// in a single transaction, returning the admin's ID. The admin's other
+// unused tokens are deleted. Returns ErrInvalidAccountToken if the token is
+// unknown, expired or used, was sent to an email the admin no longer has, or
+// the admin has been disabled.
+func (r *Repository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("beginning password reset tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	var adminID string
+	if err := tx.QueryRow(ctx,
+		`UPDATE account_tokens t SET used_at = now()
+		 FROM admins a
+		 WHERE t.token_hash = $1 AND t.kind = $2 AND t.used_at IS NULL AND t.expires_at > now()
+		   AND a.id = t.admin_id AND t.email = a.email
+		 RETURNING t.admin_id::text`,
+		tokenHash, tokenKindPasswordReset,
+	).Scan(&adminID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrInvalidAccountToken
+		}
+		return "", fmt.Errorf("spending password reset: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE admins SET password_hash = $2, updated_at = now()
+		 WHERE id = $1 AND disabled_at IS NULL`,
+		adminID, passwordHash,
+	)
+	if err != nil {
+		return "", fmt.Errorf("resetting password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return "", ErrInvalidAccountToken
+	}
+	if err := deleteAccountTokens(ctx, tx, adminID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("committing password reset: %w", err)
+	}
+	return adminID, nil
+}
+
+// totpState is an admin's TOTP enrollment. Secret is nil if the admin never
+// started enrolling; EnabledAt is nil until enrollment is confirmed.
+type totpState struct {
+	Secret    *string
+	EnabledAt *time.Time
+	LastStep  int64
+}
+
+// GetTOTP returns the admin's TOTP enrollment, or ErrAdminNotFound.
+func (r *Repository) GetTOTP(ctx context.Context, adminID string) (*totpState, error) {
+	var st totpState
+	err := r.db.Pool().QueryRow(ctx,
+		`SELECT totp_secret, totp_enabled_at, totp_last_step FROM admins WHERE id = $1`,
+		adminID,
+	).Scan(&st.Secret, &st.EnabledAt, &st.LastStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdminNotFound
+		}
+		return nil, fmt.Errorf("querying totp state: %w", err)
+	}
+	return &st, nil
+}
+
+// SetPendingTOTP stores a new, unconfirmed TOTP secret for the admin,
+// replacing any earlier pending one. Returns ErrTwoFactorEnabled if the admin
+// already has two-factor authentication enabled.
+func (r *Repository) SetPendingTOTP(ctx context.Context, adminID, secret string) error {
+	tag, err := r.db.Pool().Exec(ctx,
+		`UPDATE admins SET totp_secret = $2, totp_last_step = 0, updated_at = now()
+		 WHERE id = $1 AND totp_enabled_at IS NULL`,
+		adminID, secret,
+	)
+	if err != nil {
+		return fmt.Errorf("storing totp secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// EnableTOTP confirms the admin's pending TOTP secret, recording step as the
+// last used one, and replaces the admin's recovery codes with codeHashes.
+// Returns ErrTwoFactorEnabled if enrollment was confirmed concurrently.
+func (r *Repository) EnableTOTP(ctx context.Context, adminID string, step int64, codeHashes []string) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning totp enable tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE admins SET totp_enabled_at = now(), totp_last_step = $2, updated_at = now()
+		 WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`,
+		adminID, step,
+	)
+	if err != nil {
+		return fmt.Errorf("enabling totp: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTwoFactorEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, tx, adminID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing totp enable: %w", err)
+	}
+	return nil
+}
+
+// DisableTOTP removes the admin's TOTP secret and recovery codes. Returns
+// ErrAdminNotFound if there is no admin with the ID.
+func (r *Repository) DisableTOTP(ctx context.Context, adminID string) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning totp disable tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE admins SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = now()
+		 WHERE id = $1`,
+		adminID,
+	)
+	if err != nil {
+		return fmt.Errorf("disabling totp: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAdminNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return fmt.Errorf("deleting recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing totp disable: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep records step as the admin's last used TOTP step. It reports
+// false if a code of that or a later step was already used, which makes
+// each code single-use even under concurrent logins.
+func (r *Repository) UseTOTPStep(ctx context.Context, adminID string, step int64) (bool, error) {
+	tag, err := r.db.Pool().Exec(ctx,
+		`UPDATE admins SET totp_last_step = $2
+		 WHERE id = $1 AND totp_enabled_at IS NOT NULL AND totp_last_step < $2`,
+		adminID, step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("recording totp step: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode marks the admin's unused recovery code with the given hash
+// as used, reporting whether there was one.
+func (r *Repository) UseRecoveryCode(ctx context.Context, adminID, codeHash string) (bool, error) {
+	tag, err := r.db.Pool().Exec(ctx,
+		`UPDATE admin_recovery_codes SET used_at = now()
+		 WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		adminID, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("using recovery code: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces all of the admin's recovery codes with
+// codeHashes.
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, adminID string, codeHashes []string) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning recovery code tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if err := replaceRecoveryCodes(ctx, tx, adminID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing recovery codes: %w", err)
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes of the
+// admin.
+func (r *Repository) CountRecoveryCodes(ctx context.Context, adminID string) (int, error) {
+	var n int
+	if err := r.db.Pool().QueryRow(ctx,
+		`SELECT count(*) FROM admin_recovery_codes WHERE admin_id = $1 AND used_at IS NULL`,
+		adminID,
+	).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting recovery codes: %w", err)
+	}
+	return n, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, adminID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return fmt.Errorf("deleting recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO admin_recovery_codes (admin_id, code_hash) SELECT $1, unnest($2::text[])`,
+		adminID, codeHashes,
+	); err != nil {
+		return fmt.Errorf("inserting recovery codes: %w", err)
+	}
+	return nil
+}
+
+// GetLoginBlock returns until when logins are refused for the given login
+// failure keys (kind to key), or the zero time if they are not.
+func (r *Repository) GetLoginBlock(ctx context.Context, keys map[string]string) (time.Time, error) {
+	var until *time.Time
+	err := r.db.Pool().QueryRow(ctx,
+		`SELECT max(blocked_until) FROM login_failures
+		 WHERE (kind = 'email' AND key = $1) OR (kind = 'ip' AND key = $2)`,
+		keys[failureKindEmail], keys[failureKindIP],
+	).Scan(&until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("querying login block: %w", err)
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+// RecordLoginFailure counts a failed login for key and returns the number of
+// failures. Earlier failures are forgotten if there was none within window.
+func (r *Repository) RecordLoginFailure(ctx context.Context, kind, key string, window time.Duration) (int, error) {
+	var failures int
+	err := r.db.Pool().QueryRow(ctx,
+		`INSERT INTO login_failures (kind, key, failures, last_failure_at) VALUES ($1, $2, 1, now())
+		 ON CONFLICT (kind, key) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failure_at < now() - $3 * interval '1 second'
+				THEN 1 ELSE login_failures.failures + 1 END,
+			last_failure_at = now()
+		 RETURNING failures`,
+		kind, key, window.Seconds(),
+	).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("recording login failure: %w", err)
+	}
+	return failures, nil
+}
+
+// BlockLogin refuses logins for key until the given time.
+func (r *Repository) BlockLogin(ctx context.Context, kind, key string, until time.Time) error {
+	_, err := r.db.Pool().Exec(ctx,
+		`UPDATE login_failures SET blocked_until = $3 WHERE kind = $1 AND key = $2`,
+		kind, key, until,
+	)
+	if err != nil {
+		return fmt.Errorf("blocking login: %w", err)
+	}
+	return nil
+}
+
+// ClearLoginFailures forgets the failed logins for key, reporting whether
+// there were any.
+func (r *Repository) ClearLoginFailures(ctx context.Context, kind, key string) (bool, error) {
+	tag, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM login_failures WHERE kind = $1 AND key = $2`,
+		kind, key,
+	)
+	if err != nil {
+		return false, fmt.Errorf("clearing login failures: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// signingKeyColumns is the column list for signing_keys queries.
+const signingKeyColumns = `id, algorithm, private_key, created_at, retired_at`
+
+// scanSigningKey scans a single signing key row. The private key is left
+// sealed.
+func scanSigningKey(row pgx.Row) (*SigningKey, error) {
+	var k SigningKey
+	if err := row.Scan(&k.ID, &k.Algorithm, &k.sealed, &k.CreatedAt, &k.RetiredAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListSigningKeys returns the signing keys that are in use or were retired
+// less than retention ago, newest first.
+func (r *Repository) ListSigningKeys(ctx context.Context, retention time.Duration) ([]*SigningKey, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT `+signingKeyColumns+`
+		 FROM signing_keys
+		 WHERE retired_at IS NULL OR retired_at > now() - $1 * interval '1 second'
+		 ORDER BY created_at DESC`,
+		retention.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying signing keys: %w", err)
+	}
+
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*SigningKey, error) {
+		return scanSigningKey(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning signing keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RotateSigningKey stores a new signing key and retires the keys in use
+// before it. Keys retired more than retention ago are deleted.
+func (r *Repository) RotateSigningKey(ctx context.Context, key *SigningKey, retention time.Duration) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning signing key rotation tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE signing_keys SET retired_at = now() WHERE retired_at IS NULL`,
+	); err != nil {
+		return fmt.Errorf("retiring signing keys: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM signing_keys WHERE retired_at < now() - $1 * interval '1 second'`,
+		retention.Seconds(),
+	); err != nil {
+		return fmt.Errorf("deleting retired signing keys: %w", err)
+	}
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO signing_keys (id, algorithm, private_key) VALUES ($1, $2, $3)
+		 RETURNING created_at`,
+		key.ID, key.Algorithm, key.sealed,
+	).Scan(&key.CreatedAt); err != nil {
+		return fmt.Errorf("inserting signing key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing signing key rotation: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken adds an access token to the revocation list until it
+// expires. Tokens that have expired are removed from the list.
+func (r *Repository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if _, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM revoked_tokens WHERE expires_at < now()`,
+	); err != nil {
+		return fmt.Errorf("deleting expired revoked tokens: %w", err)
+	}
+	if _, err := r.db.Pool().Exec(ctx,
+		`INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`,
+		jti, expiresAt,
+	); err != nil {
+		return fmt.Errorf("revoking access token: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessTokens invalidates all access tokens issued to the admin so far.
+// The cutoff is truncated to whole seconds, the precision of the iat claim,
+// so tokens issued right after it stay valid.
+func (r *Repository) RevokeAccessTokens(ctx context.Context, adminID string) error {
+	if _, err := r.db.Pool().Exec(ctx,
+		`UPDATE admins SET tokens_valid_after = date_trunc('second', now()) WHERE id = $1`,
+		adminID,
+	); err != nil {
+		return fmt.Errorf("revoking access tokens of admin: %w", err)
+	}
+	return nil
+}
+
+// AccessTokenRevoked reports whether an access token of the admin, issued at
+// issuedAt, may no longer be used: it is on the revocation list, it was issued
+// before the admin's tokens_valid_after, or the admin was disabled or deleted.
+// An empty jti is only checked against the admin.
+func (r *Repository) AccessTokenRevoked(ctx context.Context, adminID, jti string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := r.db.Pool().QueryRow(ctx,
+		`SELECT NOT EXISTS (
+		     SELECT 1 FROM admins
+		     WHERE id = $1 AND disabled_at IS NULL
+		       AND (tokens_valid_after IS NULL OR tokens_valid_after <= $3)
+		 ) OR EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $2)`,
+		adminID, jti, issuedAt,
+	).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("checking access token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// personalAccessTokenColumns is the column list scanned by
+// scanPersonalAccessToken, for personal_access_tokens as t.
+const personalAccessTokenColumns = `t.id, t.admin_id, t.name, t.prefix, t.scopes, t.expires_at,
+	t.created_at, t.revoked_at, t.last_used_at, t.request_count`
+
+func scanPersonalAccessToken(row pgx.Row, extra ...any) (*PersonalAccessToken, error) {
+	var t PersonalAccessToken
+	dest := append([]any{&t.ID, &t.AdminID, &t.Name, &t.Prefix, &t.Scopes, &t.ExpiresAt,
+		&t.CreatedAt, &t.RevokedAt, &t.LastUsedAt, &t.RequestCount}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreatePersonalAccessToken stores a new personal access token of an admin
+// with the given token hash.
+func (r *Repository) CreatePersonalAccessToken(ctx context.Context, adminID, name, tokenHash, prefix string, scopes Permissions, expiresAt time.Time) (*PersonalAccessToken, error) {
+	t, err := scanPersonalAccessToken(r.db.Pool().QueryRow(ctx,
+		`INSERT INTO personal_access_tokens AS t (admin_id, name, token_hash, prefix, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+personalAccessTokenColumns,
+		adminID, name, tokenHash, prefix, scopes, expiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating personal access token: %w", err)
+	}
+	return t, nil
+}
+
+// ListPersonalAccessTokens returns all personal access tokens of an admin,
+// including revoked and expired ones, newest first.
+func (r *Repository) ListPersonalAccessTokens(ctx context.Context, adminID string) ([]PersonalAccessToken, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens t
+		 WHERE t.admin_id = $1 ORDER BY t.created_at DESC`,
+		adminID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying personal access tokens: %w", err)
+	}
+
+	tokens, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (PersonalAccessToken, error) {
+		t, err := scanPersonalAccessToken(row)
+		if err != nil {
+			return PersonalAccessToken{}, err
+		}
+		return *t, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// GetActivePersonalAccessToken looks up an unrevoked, unexpired personal
+// access token of an enabled admin by its SHA256 token hash, along with the
+// admin's email and role. Returns an error wrapping pgx.ErrNoRows if there is
+// no such token.
+func (r *Repository) GetActivePersonalAccessToken(ctx context.Context, tokenHash string) (token *PersonalAccessToken, email, role string, err error) {
+	token, err = scanPersonalAccessToken(r.db.Pool().QueryRow(ctx,
+		`SELECT `+personalAccessTokenColumns+`, a.email, a.role
+		 FROM personal_access_tokens t JOIN admins a ON a.id = t.admin_id
+		 WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > now()
+		   AND a.disabled_at IS NULL`,
+		tokenHash,
+	), &email, &role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", "", fmt.Errorf("personal access token not found: %w", err)
+		}
+		return nil, "", "", fmt.Errorf("querying personal access token: %w", err)
+	}
+	return token, email, role, nil
+}
+
+// RevokePersonalAccessToken marks a personal access token of an admin as
+// revoked. Returns an error wrapping pgx.ErrNoRows if the admin has no
+// unrevoked token with the given ID.
+func (r *Repository) RevokePersonalAccessToken(ctx context.Context, adminID, id string) (*PersonalAccessToken, error) {
+	t, err := scanPersonalAccessToken(r.db.Pool().QueryRow(ctx,
+		`UPDATE personal_access_tokens AS t SET revoked_at = now()
+		 WHERE t.id = $1 AND t.admin_id = $2 AND t.revoked_at IS NULL
+		 RETURNING `+personalAccessTokenColumns,
+		id, adminID,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("personal access token not found: %w", err)
+		}
+		return nil, fmt.Errorf("revoking personal access token: %w", err)
+	}
+	return t, nil
+}
+
+// AddPersonalAccessTokenUsage adds count requests to a personal access
+// token's request count and moves its last use forward to lastUsed.
+func (r *Repository) AddPersonalAccessTokenUsage(ctx context.Context, id string, count int64, lastUsed time.Time) error {
+	_, err := r.db.Pool().Exec(ctx,
+		`UPDATE personal_access_tokens
+		 SET request_count = request_count + $2,
+		     last_used_at = GREATEST(last_used_at, $3)
+		 WHERE id = $1`,
+		id, count, lastUsed,
+	)
+	if err != nil {
+		return fmt.Errorf("recording personal access token usage: %w", err)
+	}
+	return nil
+}
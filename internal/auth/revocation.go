@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// revocationCacheTTL is how long a revocation check of an access token is
+	// cached. Revocations by other processes take up to this long to apply;
+	// those by this process apply at once.
+	revocationCacheTTL = 5 * time.Second
+
+	// revocationPruneInterval is how often cached checks of expired tokens
+	// are dropped.
+	revocationPruneInterval = time.Minute
+)
+
+// Revocations checks whether access tokens were revoked before they expire,
+// caching the answers of the database for a few seconds.
+type Revocations struct {
+	repo *Repository
+
+	mu       sync.Mutex
+	checks   map[string]revocationCheck
+	prunedAt time.Time
+}
+
+// revocationCheck is a cached revocation check of an access token.
+type revocationCheck struct {
+	adminID   string
+	revoked   bool
+	checkedAt time.Time
+	expiresAt time.Time
+}
+
+// NewRevocations creates a new Revocations.
+func NewRevocations(repo *Repository) *Revocations {
+	return &Revocations{
+		repo:     repo,
+		checks:   make(map[string]revocationCheck),
+		prunedAt: time.Now(),
+	}
+}
+
+// Revoked reports whether the access token with the claims was revoked, was
+// issued before the admin's tokens were revoked, or belongs to an admin who
+// was disabled or deleted.
+func (r *Revocations) Revoked(ctx context.Context, claims *Claims) (bool, error) {
+	key := revocationKey(claims)
+	now := time.Now()
+
+	r.mu.Lock()
+	check, ok := r.checks[key]
+	r.mu.Unlock()
+	if ok && (check.revoked || now.Sub(check.checkedAt) < revocationCacheTTL) {
+		return check.revoked, nil
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := r.repo.AccessTokenRevoked(ctx, claims.AdminID(), claims.ID, issuedAt)
+	if err != nil {
+		return false, err
+	}
+
+	check = revocationCheck{adminID: claims.AdminID(), revoked: revoked, checkedAt: now}
+	if claims.ExpiresAt != nil {
+		check.expiresAt = claims.ExpiresAt.Time
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[key] = check
+	r.pruneLocked(now)
+	return revoked, nil
+}
+
+// markRevoked records that this process revoked the token with the claims.
+func (r *Revocations) markRevoked(claims *Claims) {
+	check := revocationCheck{adminID: claims.AdminID(), revoked: true, checkedAt: time.Now()}
+	if claims.ExpiresAt != nil {
+		check.expiresAt = claims.ExpiresAt.Time
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[revocationKey(claims)] = check
+}
+
+// forgetAdmin drops the cached checks of the admin's tokens, after this
+// process revoked them or changed the admin.
+func (r *Revocations) forgetAdmin(adminID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, check := range r.checks {
+		if check.adminID == adminID {
+			delete(r.checks, key)
+		}
+	}
+}
+
+// pruneLocked drops the checks of expired tokens, at most once per
+// revocationPruneInterval. r.mu must be held.
+func (r *Revocations) pruneLocked(now time.Time) {
+	if now.Sub(r.prunedAt) < revocationPruneInterval {
+		return
+	}
+	for key, check := range r.checks {
+		if check.expiresAt.Before(now) {
+			delete(r.checks, key)
+		}
+	}
+	r.prunedAt = now
+}
+
+// revocationKey returns the cache key of a token: its jti, or its subject
+// and issue time for tokens issued without one.
+func revocationKey(claims *Claims) string {
+	if claims.ID != "" {
+		return claims.ID
+	}
+	var iat int64
+	if claims.IssuedAt != nil {
+		iat = claims.IssuedAt.Unix()
+	}
+	return fmt.Sprintf("%s@%d", claims.AdminID(), iat)
+}
+
+// SetRevocations sets the cache of access token revocations, so revocations
+// by the service apply to it at once.
+func (s *Service) SetRevocations(revocations *Revocations) {
+	s.revocations = revocations
+}
+
+// RevokeAccessToken revokes a raw access token before it expires. Invalid or
+// expired tokens are ignored, as are tokens without a jti claim.
+func (s *Service) RevokeAccessToken(ctx context.Context, accessToken string) error {
+	claims, err := s.keys.ValidateAccessToken(accessToken)
+	if err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	if err := s.repo.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	if s.revocations != nil {
+		s.revocations.markRevoked(claims)
+	}
+	return nil
+}
+
+// revokeAccessTokens invalidates all access tokens issued to the admin so
+// far.
+func (s *Service) revokeAccessTokens(ctx context.Context, adminID string) error {
+	if err := s.repo.RevokeAccessTokens(ctx, adminID); err != nil {
+		return err
+	}
+	s.forgetAdminTokens(adminID)
+	return nil
+}
+
+// forgetAdminTokens drops the cached revocation checks of the admin's tokens.
+func (s *Service) forgetAdminTokens(adminID string) {
+	if s.revocations != nil {
+		s.revocations.forgetAdmin(adminID)
+	}
+}
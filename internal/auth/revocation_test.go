@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testClaims returns the claims of a token issued to adminID, valid for the
+// usual access token lifetime.
+func testClaims(adminID, jti string) *Claims {
+	claims := newAccessClaims(adminID, "a@example.com", "editor", nil)
+	claims.ID = jti
+	return &claims
+}
+
+func TestNewAccessClaims_JTI(t *testing.T) {
+	a := newAccessClaims("id", "a@example.com", "editor", nil)
+	b := newAccessClaims("id", "a@example.com", "editor", nil)
+	if a.ID == "" || a.ID == b.ID {
+		t.Errorf("jti = %q and %q, want distinct non-empty IDs", a.ID, b.ID)
+	}
+}
+
+func TestRevocations_Cache(t *testing.T) {
+	// Without a repository, only cached answers can be given.
+	revocations := NewRevocations(nil)
+	ctx := context.Background()
+
+	revoked := testClaims("admin-1", "jti-revoked")
+	revocations.markRevoked(revoked)
+	if got, err := revocations.Revoked(ctx, revoked); err != nil || !got {
+		t.Errorf("Revoked(marked) = %v, %v; want true", got, err)
+	}
+
+	valid := testClaims("admin-1", "jti-valid")
+	revocations.checks[revocationKey(valid)] = revocationCheck{adminID: "admin-1", checkedAt: time.Now()}
+	if got, err := revocations.Revoked(ctx, valid); err != nil || got {
+		t.Errorf("Revoked(cached valid) = %v, %v; want false", got, err)
+	}
+
+	other := testClaims("admin-2", "jti-other")
+	revocations.markRevoked(other)
+	revocations.forgetAdmin("admin-1")
+	if len(revocations.checks) != 1 {
+		t.Errorf("after forgetAdmin, %d checks cached, want 1", len(revocations.checks))
+	}
+	if _, ok := revocations.checks[revocationKey(other)]; !ok {
+		t.Error("forgetAdmin dropped another admin's check")
+	}
+}
+
+func TestRevocations_Prune(t *testing.T) {
+	revocations := NewRevocations(nil)
+	now := time.Now()
+	revocations.checks["expired"] = revocationCheck{expiresAt: now.Add(-time.Second)}
+	revocations.checks["live"] = revocationCheck{expiresAt: now.Add(time.Minute)}
+
+	revocations.pruneLocked(now)
+	if len(revocations.checks) != 2 {
+		t.Fatal("pruned before revocationPruneInterval passed")
+	}
+	revocations.pruneLocked(now.Add(revocationPruneInterval))
+	if _, ok := revocations.checks["expired"]; ok || len(revocations.checks) != 1 {
+		t.Errorf("checks after prune = %v, want only live", revocations.checks)
+	}
+}
+
+func TestRevocationKey_WithoutJTI(t *testing.T) {
+	claims := testClaims("admin-1", "")
+	claims.IssuedAt = jwt.NewNumericDate(time.Unix(1700000000, 0))
+	if got := revocationKey(claims); got != "admin-1@1700000000" {
+		t.Errorf("revocationKey() = %q, want admin-1@1700000000", got)
+	}
+}
+
+func TestMiddleware_RevokedToken(t *testing.T) {
+	keys := NewHMACKeySet(testSecret)
+	token, err := keys.CreateAccessToken("550e8400-e29b-41d4-a716-446655440000", "a@example.com", "editor", nil)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	claims, err := keys.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+
+	revocations := NewRevocations(nil)
+	revocations.markRevoked(claims)
+	handler := Middleware(keys, revocations, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called with revoked token")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestService_RevokeAccessTokenInvalid(t *testing.T) {
+	// Invalid tokens are ignored without touching the repository.
+	svc := NewService(nil, testSecret)
+	if err := svc.RevokeAccessToken(context.Background(), "not-a-jwt"); err != nil {
+		t.Errorf("RevokeAccessToken(invalid) = %v, want nil", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]string{
+		"":             "",
+		"Bearer abc":   "abc",
+		"bearer abc":   "abc",
+		"Basic abc":    "",
+		"Bearerabc":    "",
+		"Bearer a b c": "a b c",
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/auth/logout", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		if got := bearerToken(req); got != want {
+			t.Errorf("bearerToken(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestRoleChanges_RevokeAccessTokens(t *testing.T) {
+	cached := func(revocations *Revocations, adminIDs ...string) {
+		for _, id := range adminIDs {
+			revocations.checks[id] = revocationCheck{adminID: id, checkedAt: time.Now()}
+		}
+	}
+	svc := NewService(nil, testSecret)
+	revocations := NewRevocations(nil)
+	svc.SetRevocations(revocations)
+	// The repository revokes the tokens of admins whose role changes; the
+	// service must drop its cached answers for them.
+	svc.updateAdmin = func(_ context.Context, id string, email, role *string) (*Admin, error) {
+		return &Admin{ID: id}, nil
+	}
+	svc.saveRole = func(context.Context, Role) (bool, []string, error) {
+		return false, []string{"editor-1", "editor-2"}, nil
+	}
+	ctx := context.Background()
+
+	cached(revocations, "admin-1")
+	email := "new@example.com"
+	if _, err := svc.UpdateAdmin(ctx, "admin-1", UpdateAdminInput{Email: &email}); err != nil {
+		t.Fatalf("UpdateAdmin() error = %v", err)
+	}
+	if _, ok := revocations.checks["admin-1"]; !ok {
+		t.Error("changing the email dropped the admin's cached token checks")
+	}
+
+	role := "viewer"
+	if _, err := svc.UpdateAdmin(ctx, "admin-1", UpdateAdminInput{Role: &role}); err != nil {
+		t.Fatalf("UpdateAdmin() error = %v", err)
+	}
+	if _, ok := revocations.checks["admin-1"]; ok {
+		t.Error("changing the role kept the admin's cached token checks")
+	}
+
+	cached(revocations, "editor-1", "editor-2", "viewer-1")
+	if _, err := svc.SaveRole(ctx, Role{Name: "editor", Permissions: Permissions{{Resource: "media", Action: "read"}}}); err != nil {
+		t.Fatalf("SaveRole() error = %v", err)
+	}
+	if len(revocations.checks) != 1 {
+		t.Errorf("after SaveRole, %d checks cached, want only the other role's", len(revocations.checks))
+	}
+}
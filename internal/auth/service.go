@@ -33,18 +33,41 @@ var (
 // Service provides authentication business logic including password hashing,
 // JWT token creation, and refresh token management.
 type Service struct {
-	repo      *Repository
-	jwtSecret string
+	repo             *Repository
+	jwtSecret        string
+	keys             *KeySet
+	revocations      *Revocations
+	requireTwoFactor bool
+	lockout          LockoutPolicy
+
+	// rolePermissions and adminByID read from the repository for
+	// authorization checks, and updateAdmin and saveRole write to it;
+	// tests replace them.
+	rolePermissions func(ctx context.Context, role string) (Permissions, error)
+	adminByID       func(ctx context.Context, id string) (*Admin, error)
+	updateAdmin     func(ctx context.Context, id string, email, role *string) (*Admin, error)
+	saveRole        func(ctx context.Context, role Role) (bool, []string, error)
 }
 
 // NewService creates a new auth Service with the given repository and JWT signing secret.
 func NewService(repo *Repository, jwtSecret string) *Service {
 	return &Service{
-		repo:      repo,
-		jwtSecret: jwtSecret,
+		repo:            repo,
+		jwtSecret:       jwtSecret,
+		keys:            NewHMACKeySet(jwtSecret),
+		rolePermissions: repo.RolePermissions,
+		adminByID:       repo.GetAdminByID,
+		updateAdmin:     repo.UpdateAdmin,
+		saveRole:        repo.SaveRole,
 	}
 }
 
+// SetKeySet sets the keys access tokens are signed with. By default they are
+// signed with the JWT secret.
+func (s *Service) SetKeySet(keys *KeySet) {
+	s.keys = keys
+}
+
 // EnsureAdmin creates the initial admin user if one with the given email does
 // not yet exist. Uses INSERT ... ON CONFLICT to avoid TOCTOU races between
 // checking and creating.
@@ -86,37 +109,89 @@ func (s *Service) VerifyPassword(hash, password string) (bool, error) {
 	return match, nil
 }
 
-// Login validates the given credentials and, on success, returns the admin's
-// UUID, a signed JWT access token, and a raw refresh token (to be sent to the
-// client in a cookie). The refresh token's SHA256 hash is stored in the database.
-func (s *Service) Login(ctx context.Context, email, password string) (adminID string, accessToken string, refreshToken string, err error) {
+// Login validates the given credentials. For admins without two-factor
+// authentication, the result holds the admin's UUID, a signed JWT access
+// token, and a raw refresh token (to be sent to the client in a cookie) whose
+// SHA256 hash is stored in the database. For admins with two-factor
+// authentication, or who must enroll in it, the result holds a challenge
+// token instead; see CompleteTwoFactorLogin and CompleteTwoFactorSetup.
+// Returns ErrAccountDisabled for correct credentials of a disabled account,
+// and a *LoginBlockedError while failed logins for the email or from the
+// client's IP address block further attempts.
+func (s *Service) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	if err := s.checkLoginBlocked(ctx, email); err != nil {
+		return nil, err
+	}
+
 	admin, err := s.repo.GetAdminByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", "", "", ErrInvalidCredentials
+			return nil, s.loginFailed(ctx, email)
 		}
-		return "", "", "", fmt.Errorf("looking up admin: %w", err)
+		return nil, fmt.Errorf("looking up admin: %w", err)
 	}
 
 	match, err := s.VerifyPassword(admin.PasswordHash, password)
 	if err != nil {
-		return "", "", "", fmt.Errorf("verifying password: %w", err)
+		return nil, fmt.Errorf("verifying password: %w", err)
 	}
 	if !match {
-		return "", "", "", ErrInvalidCredentials
+		return nil, s.loginFailed(ctx, email)
 	}
+	if admin.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if err := s.clearLoginFailures(ctx, email); err != nil {
+		return nil, err
+	}
+	return s.completeLogin(ctx, admin)
+}
 
-	accessToken, err = CreateAccessToken(admin.ID, admin.Email, s.jwtSecret)
-	if err != nil {
-		return "", "", "", err
+// completeLogin finishes the sign-in of an authenticated admin. It starts a
+// session, unless the admin has two-factor authentication or must enroll in
+// it, in which case it returns a challenge token.
+func (s *Service) completeLogin(ctx context.Context, admin *Admin) (*LoginResult, error) {
+	purpose := ""
+	switch {
+	case admin.TOTPEnabledAt != nil:
+		purpose = challengeVerify
+	case s.requireTwoFactor:
+		purpose = challengeSetup
+	default:
+		return s.startSession(ctx, admin)
 	}
 
-	refreshToken, err = s.createRefreshToken(ctx, admin.ID)
+	challenge, err := createChallengeToken(admin.ID, purpose, s.jwtSecret)
 	if err != nil {
-		return "", "", "", err
+		return nil, err
+	}
+	return &LoginResult{
+		AdminID:        admin.ID,
+		ChallengeToken: challenge,
+		SetupRequired:  purpose == challengeSetup,
+	}, nil
+}
+
+// loginFailed records a failed login for email and returns
+// ErrInvalidCredentials.
+func (s *Service) loginFailed(ctx context.Context, email string) error {
+	if err := s.recordLoginFailure(ctx, email); err != nil {
+		return err
 	}
+	return ErrInvalidCredentials
+}
 
-	return admin.ID, accessToken, refreshToken, nil
+// startSession issues an access token and a new refresh token for the admin.
+func (s *Service) startSession(ctx context.Context, admin *Admin) (*LoginResult, error) {
+	accessToken, err := s.createAccessToken(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.createRefreshToken(ctx, admin.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{AdminID: admin.ID, AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
 // Refresh validates the given raw refresh token, atomically rotates it (deletes
@@ -151,7 +226,7 @@ func (s *Service) Refresh(ctx context.Context, oldToken string) (accessToken str
 	expiresAt := time.Now().Add(refreshTokenExpiry)
 
 	// Atomic rotation: delete old + insert new in one transaction.
-	if err := s.repo.RotateRefreshToken(ctx, oldTokenHash, newTokenHash, stored.AdminID, expiresAt); err != nil {
+	if err := s.repo.RotateRefreshToken(ctx, stored, newTokenHash, clientInfoFromContext(ctx), expiresAt); err != nil {
 		if errors.Is(err, ErrTokenAlreadyUsed) {
 			slog.Warn("refresh token replay detected, all sessions revoked",
 				"admin_id", stored.AdminID)
@@ -165,8 +240,12 @@ func (s *Service) Refresh(ctx context.Context, oldToken string) (accessToken str
 	if err != nil {
 		return "", "", fmt.Errorf("looking up admin for refresh: %w", err)
 	}
+	if admin.DisabledAt != nil {
+		// Disabling revokes all sessions; this covers a refresh racing it.
+		return "", "", ErrInvalidToken
+	}
 
-	accessToken, err = CreateAccessToken(admin.ID, admin.Email, s.jwtSecret)
+	accessToken, err = s.createAccessToken(ctx, admin)
 	if err != nil {
 		return "", "", err
 	}
@@ -174,8 +253,8 @@ func (s *Service) Refresh(ctx context.Context, oldToken string) (accessToken str
 	return accessToken, newToken, nil
 }
 
-// Logout deletes the refresh token identified by the given raw token value
-// from the database. It is not an error if the token does not exist.
+// Logout ends the session of the given raw refresh token. It is not an error
+// if the token does not exist.
 func (s *Service) Logout(ctx context.Context, refreshToken string) error {
 	tokenHash := hashToken(refreshToken)
 	if err := s.repo.DeleteRefreshToken(ctx, tokenHash); err != nil {
@@ -184,8 +263,64 @@ func (s *Service) Logout(ctx context.Context, refreshToken string) error {
 	return nil
 }
 
-// createRefreshToken generates a cryptographically random token, stores its
-// SHA256 hash in the database, and returns the raw hex-encoded token.
+// createAccessToken issues an access token carrying the admin's role and its
+// current permissions.
+func (s *Service) createAccessToken(ctx context.Context, admin *Admin) (string, error) {
+	perms, err := s.repo.RolePermissions(ctx, admin.Role)
+	if err != nil {
+		return "", err
+	}
+	return s.keys.CreateAccessToken(admin.ID, admin.Email, admin.Role, perms)
+}
+
+// ListRoles returns all roles with their permissions.
+func (s *Service) ListRoles(ctx context.Context) ([]Role, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []Role{}
+	}
+	return roles, nil
+}
+
+// SaveRole validates and creates or replaces a role, reporting whether it was
+// created. The superuser role cannot be changed. The access tokens of the
+// role's admins are revoked, so they pick up the changed permissions when
+// they next refresh them.
+func (s *Service) SaveRole(ctx context.Context, role Role) (bool, error) {
+	if role.Name == SuperuserRole {
+		return false, ErrRoleBuiltIn
+	}
+	if errs := validateRole(role); len(errs) > 0 {
+		return false, &RoleValidationError{Fields: errs}
+	}
+	if role.Permissions == nil {
+		role.Permissions = Permissions{}
+	}
+	created, holders, err := s.saveRole(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range holders {
+		s.forgetAdminTokens(id)
+	}
+	return created, nil
+}
+
+// DeleteRole deletes a role that no admin has. The superuser role cannot be
+// deleted.
+func (s *Service) DeleteRole(ctx context.Context, name string) error {
+	if name == SuperuserRole {
+		return ErrRoleBuiltIn
+	}
+	return s.repo.DeleteRole(ctx, name)
+}
+
+// createRefreshToken starts a new session from the client in ctx. It
+// generates a cryptographically random token, stores its SHA256 hash in the
+// database, and returns the raw hex-encoded token.
 func (s *Service) createRefreshToken(ctx context.Context, adminID string) (string, error) {
 	raw := make([]byte, refreshTokenBytes)
 	if _, err := rand.Read(raw); err != nil {
@@ -196,7 +331,7 @@ func (s *Service) createRefreshToken(ctx context.Context, adminID string) (strin
 	tokenHash := hashToken(token)
 	expiresAt := time.Now().Add(refreshTokenExpiry)
 
-	if err := s.repo.CreateRefreshToken(ctx, adminID, tokenHash, expiresAt); err != nil {
+	if err := s.repo.CreateSession(ctx, adminID, tokenHash, clientInfoFromContext(ctx), expiresAt); err != nil {
 		return "", err
 	}
 	return token, nil
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxUserAgentLength bounds the user agent stored with a session.
+const maxUserAgentLength = 512
+
+// contextKeyClient is the context key for the ClientInfo of a request that
+// starts or refreshes a session.
+const contextKeyClient contextKey = "client"
+
+// ErrSessionNotFound is returned when an admin has no session with the given
+// ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a login of an admin, kept alive by its rotating refresh token.
+type Session struct {
+	ID         string    `json:"id"`
+	AdminID    string    `json:"admin_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session the request was made from.
+	Current bool `json:"current"`
+}
+
+// ClientInfo describes the client a session is used from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// withClientInfo returns the request context carrying the request's client,
+// which is recorded with the sessions it starts or refreshes.
+func withClientInfo(r *http.Request) context.Context {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+	return context.WithValue(r.Context(), contextKeyClient, ClientInfo{IP: ip, UserAgent: ua})
+}
+
+// clientInfoFromContext returns the client set by withClientInfo, or the zero
+// ClientInfo.
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	v, _ := ctx.Value(contextKeyClient).(ClientInfo)
+	return v
+}
+
+// ListSessions returns the admin's active sessions, most recently used
+// first. The session of currentToken, a raw refresh token, is marked as
+// current.
+func (s *Service) ListSessions(ctx context.Context, adminID, currentToken string) ([]Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = []Session{}
+	}
+
+	currentID, err := s.SessionID(ctx, currentToken)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// SessionID returns the ID of the session of a raw refresh token, or "" if
+// the token is empty or unknown.
+func (s *Service) SessionID(ctx context.Context, refreshToken string) (string, error) {
+	if refreshToken == "" {
+		return "", nil
+	}
+	t, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return t.SessionID, nil
+}
+
+// RevokeSession ends one of the admin's sessions. Its refresh token stops
+// working at once; access tokens issued to it remain valid until they
+// expire.
+func (s *Service) RevokeSession(ctx context.Context, adminID, sessionID string) error {
+	return s.repo.DeleteSession(ctx, adminID, sessionID)
+}
+
+// RevokeAllSessions ends all of the admin's sessions, and invalidates the
+// access tokens issued to them.
+func (s *Service) RevokeAllSessions(ctx context.Context, adminID string) error {
+	if err := s.repo.DeleteAllForAdmin(ctx, adminID); err != nil {
+		return err
+	}
+	return s.revokeAccessTokens(ctx, adminID)
+}
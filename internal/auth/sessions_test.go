@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithClientInfo(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/auth/login", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)")
+
+	got := clientInfoFromContext(withClientInfo(req))
+	want := ClientInfo{IP: "203.0.113.7", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}
+	if got != want {
+		t.Errorf("client = %+v, want %+v", got, want)
+	}
+}
+
+func TestWithClientInfo_Bounds(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/auth/login", nil)
+	req.RemoteAddr = "2001:db8::1" // Already without port, as set by RealIP.
+	req.Header.Set("User-Agent", strings.Repeat("a", 2*maxUserAgentLength))
+
+	got := clientInfoFromContext(withClientInfo(req))
+	if got.IP != "2001:db8::1" {
+		t.Errorf("IP = %q, want 2001:db8::1", got.IP)
+	}
+	if len(got.UserAgent) != maxUserAgentLength {
+		t.Errorf("user agent length = %d, want %d", len(got.UserAgent), maxUserAgentLength)
+	}
+}
+
+func TestClientInfoFromContext_Unset(t *testing.T) {
+	if got := clientInfoFromContext(context.Background()); got != (ClientInfo{}) {
+		t.Errorf("client = %+v, want zero value", got)
+	}
+}
+
+func TestRefreshCookieValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/auth/sessions", nil)
+	if got := refreshCookieValue(req); got != "" {
+		t.Errorf("refreshCookieValue() without cookie = %q, want empty", got)
+	}
+
+	req.AddCookie(&http.Cookie{Name: refreshCookieName, Value: "abc"})
+	if got := refreshCookieValue(req); got != "abc" {
+		t.Errorf("refreshCookieValue() = %q, want abc", got)
+	}
+}
+
+func TestRevokeSession_InvalidID(t *testing.T) {
+	h := NewHandler(NewService(nil, testSecret), nil, true)
+	req := httptest.NewRequest(http.MethodDelete, "/admin/api/auth/sessions/not-a-uuid", nil)
+	rec := httptest.NewRecorder()
+
+	// Without a matching route the {id} parameter is empty.
+	h.RevokeSession(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+const (
+	// personalAccessTokenPrefix starts every raw personal access token, so
+	// tokens are recognisable in CI secrets and distinguishable from JWTs and
+	// API keys.
+	personalAccessTokenPrefix = "mpat_"
+	personalAccessTokenBytes  = 32
+	// personalAccessTokenDisplayLength is how much of the raw token is stored
+	// in clear text to identify it in listings.
+	personalAccessTokenDisplayLength = len(personalAccessTokenPrefix) + 8
+
+	maxTokenNameLength = 100
+
+	// defaultTokenLifetimeDays and maxTokenLifetimeDays bound how long a
+	// personal access token is valid.
+	defaultTokenLifetimeDays = 90
+	maxTokenLifetimeDays     = 365
+)
+
+// Sentinel errors for personal access tokens.
+var (
+	ErrInvalidPersonalAccessToken  = errors.New("invalid, expired or revoked personal access token")
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+)
+
+// PersonalAccessToken is a long-lived token for automating the admin API. It
+// acts as its admin, but only where Scopes also allow it, so it never grants
+// more than the admin's role.
+type PersonalAccessToken struct {
+	ID           string      `json:"id"`
+	AdminID      string      `json:"admin_id"`
+	Name         string      `json:"name"`
+	Prefix       string      `json:"prefix"`
+	Scopes       Permissions `json:"scopes"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	CreatedAt    time.Time   `json:"created_at"`
+	RevokedAt    *time.Time  `json:"revoked_at"`
+	LastUsedAt   *time.Time  `json:"last_used_at"`
+	RequestCount int64       `json:"request_count"`
+}
+
+// TokenValidationError is returned when personal access token input is
+// invalid.
+type TokenValidationError struct {
+	Fields []server.FieldError
+}
+
+func (e *TokenValidationError) Error() string {
+	return fmt.Sprintf("personal access token validation failed: %d field error(s)", len(e.Fields))
+}
+
+// CreateTokenInput holds the settings of a new personal access token.
+// ExpiresInDays defaults to defaultTokenLifetimeDays if zero.
+type CreateTokenInput struct {
+	Name          string
+	Scopes        Permissions
+	ExpiresInDays int
+}
+
+// IsPersonalAccessToken reports whether a raw bearer token is a personal
+// access token rather than a JWT.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+// TokenService manages personal access tokens. Like API key usage, token
+// usage is buffered in memory and written to the database periodically by a
+// background goroutine.
+type TokenService struct {
+	repo         *Repository
+	auditService *audit.Service
+	usage        *usageRecorder
+}
+
+// NewTokenService creates a new TokenService. The audit service is optional.
+// Call Start to begin recording usage, and Shutdown to flush and stop.
+func NewTokenService(repo *Repository, auditService *audit.Service) *TokenService {
+	return &TokenService{
+		repo:         repo,
+		auditService: auditService,
+		usage:        newUsageRecorder("personal access token", repo.AddPersonalAccessTokenUsage),
+	}
+}
+
+// Start begins the background goroutine that writes usage to the database.
+// Must be called once after NewTokenService.
+func (s *TokenService) Start() {
+	s.usage.start()
+}
+
+// Shutdown stops the background goroutine after a final flush. The provided
+// context controls the maximum time to wait.
+func (s *TokenService) Shutdown(ctx context.Context) {
+	s.usage.shutdown(ctx)
+}
+
+// Create validates the input and creates a personal access token for the
+// admin. The raw token is only returned here; only its hash is stored.
+func (s *TokenService) Create(ctx context.Context, adminID string, in CreateTokenInput) (*PersonalAccessToken, string, error) {
+	if in.ExpiresInDays == 0 {
+		in.ExpiresInDays = defaultTokenLifetimeDays
+	}
+	if errs := validateToken(in); len(errs) > 0 {
+		return nil, "", &TokenValidationError{Fields: errs}
+	}
+
+	raw := make([]byte, personalAccessTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("generating personal access token: %w", err)
+	}
+	token := personalAccessTokenPrefix + hex.EncodeToString(raw)
+	expiresAt := time.Now().AddDate(0, 0, in.ExpiresInDays)
+
+	t, err := s.repo.CreatePersonalAccessToken(ctx, adminID, strings.TrimSpace(in.Name), hashToken(token),
+		token[:personalAccessTokenDisplayLength], in.Scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "token.create",
+		ActorID:    adminID,
+		Resource:   "token",
+		ResourceID: t.ID,
+		Payload: map[string]any{
+			"name":       t.Name,
+			"scopes":     t.Scopes,
+			"expires_at": t.ExpiresAt,
+		},
+	})
+	return t, token, nil
+}
+
+// validateToken checks the settings of a new personal access token.
+func validateToken(in CreateTokenInput) []server.FieldError {
+	var errs []server.FieldError
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		errs = append(errs, server.FieldError{Field: "name", Message: "is required"})
+	} else if utf8.RuneCountInString(name) > maxTokenNameLength {
+		errs = append(errs, server.FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", maxTokenNameLength)})
+	}
+
+	if len(in.Scopes) == 0 {
+		errs = append(errs, server.FieldError{Field: "scopes", Message: "must list at least one permission"})
+	}
+	errs = append(errs, validatePermissions("scopes", in.Scopes)...)
+
+	if in.ExpiresInDays < 1 || in.ExpiresInDays > maxTokenLifetimeDays {
+		errs = append(errs, server.FieldError{Field: "expires_in_days", Message: fmt.Sprintf("must be between 1 and %d", maxTokenLifetimeDays)})
+	}
+	return errs
+}
+
+// List returns the admin's personal access tokens, including revoked and
+// expired ones. Returns ErrAdminNotFound if there is no such admin.
+func (s *TokenService) List(ctx context.Context, adminID string) ([]PersonalAccessToken, error) {
+	tokens, err := s.repo.ListPersonalAccessTokens(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		if _, err := s.repo.GetAdminByID(ctx, adminID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrAdminNotFound
+			}
+			return nil, err
+		}
+		tokens = []PersonalAccessToken{}
+	}
+	return tokens, nil
+}
+
+// Revoke revokes one of the admin's personal access tokens on behalf of
+// actorID. Requests made with it fail from then on.
+func (s *TokenService) Revoke(ctx context.Context, adminID, id, actorID string) error {
+	t, err := s.repo.RevokePersonalAccessToken(ctx, adminID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrPersonalAccessTokenNotFound
+		}
+		return err
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "token.revoke",
+		ActorID:    actorID,
+		Resource:   "token",
+		ResourceID: t.ID,
+		Payload:    map[string]any{"name": t.Name, "admin_id": t.AdminID},
+	})
+	return nil
+}
+
+// Authenticate returns the active personal access token matching the raw
+// token, with claims describing its admin as an access token would, and
+// records its use. Returns ErrInvalidPersonalAccessToken if there is none, or
+// if its admin is disabled.
+func (s *TokenService) Authenticate(ctx context.Context, token string) (*PersonalAccessToken, *Claims, error) {
+	if !IsPersonalAccessToken(token) {
+		return nil, nil, ErrInvalidPersonalAccessToken
+	}
+
+	t, email, role, err := s.repo.GetActivePersonalAccessToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrInvalidPersonalAccessToken
+		}
+		return nil, nil, fmt.Errorf("looking up personal access token: %w", err)
+	}
+	perms, err := s.repo.RolePermissions(ctx, role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.usage.record(t.ID, time.Now())
+	return t, &Claims{
+		Email:            email,
+		Role:             role,
+		Permissions:      perms,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: t.AdminID},
+	}, nil
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (s *TokenService) logAudit(ctx context.Context, event audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Log(ctx, event)
+	}
+}
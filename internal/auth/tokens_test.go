@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTokenService_CreateValidation(t *testing.T) {
+	svc := NewTokenService(nil, nil)
+	read := Permissions{{Resource: "content:*", Action: "read"}}
+
+	tests := []struct {
+		name       string
+		in         CreateTokenInput
+		wantFields []string
+	}{
+		{"empty", CreateTokenInput{Name: "  "}, []string{"name", "scopes"}},
+		{"long name", CreateTokenInput{Name: strings.Repeat("x", maxTokenNameLength+1), Scopes: read}, []string{"name"}},
+		{"unknown scope", CreateTokenInput{Name: "ci", Scopes: Permissions{{Resource: "widgets", Action: "read"}}}, []string{"scopes[0]"}},
+		{"too long", CreateTokenInput{Name: "ci", Scopes: read, ExpiresInDays: maxTokenLifetimeDays + 1}, []string{"expires_in_days"}},
+		{"negative lifetime", CreateTokenInput{Name: "ci", Scopes: read, ExpiresInDays: -1}, []string{"expires_in_days"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.Create(context.Background(), "admin-id", tt.in)
+			var valErr *TokenValidationError
+			if !errors.As(err, &valErr) {
+				t.Fatalf("expected *TokenValidationError, got %v", err)
+			}
+			if len(valErr.Fields) != len(tt.wantFields) {
+				t.Fatalf("got field errors %+v, want fields %v", valErr.Fields, tt.wantFields)
+			}
+			for i, f := range tt.wantFields {
+				if valErr.Fields[i].Field != f {
+					t.Errorf("field error %d = %q, want %q", i, valErr.Fields[i].Field, f)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenService_AuthenticateWithoutPrefix(t *testing.T) {
+	// Tokens without the mpat_ prefix are rejected before the repository is
+	// consulted.
+	svc := NewTokenService(nil, nil)
+	for _, token := range []string{"", "mk_abc", "eyJhbGciOiJIUzI1NiJ9.e30.sig"} {
+		if _, _, err := svc.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidPersonalAccessToken) {
+			t.Errorf("Authenticate(%q) error = %v, want ErrInvalidPersonalAccessToken", token, err)
+		}
+	}
+}
+
+func TestMiddleware_JWTWithTokenService(t *testing.T) {
+	// Access tokens are validated as before when personal access tokens are
+	// accepted too.
+	keys := NewHMACKeySet(testSecret)
+	token, err := keys.CreateAccessToken("550e8400-e29b-41d4-a716-446655440000", "a@example.com", "editor", nil)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+
+	handler := Middleware(keys, nil, NewTokenService(nil, nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if PersonalAccessTokenFromContext(r.Context()) != nil {
+			t.Error("personal access token set for JWT request")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// withToken returns a middleware authenticating requests as an admin with
+// perms, using a personal access token limited to scopes.
+func withToken(perms, scopes Permissions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ContextKeyPermissions, perms)
+			ctx = context.WithValue(ctx, ContextKeyPersonalAccessToken, &PersonalAccessToken{ID: "token-id", Scopes: scopes})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func TestAuthorize_TokenScopes(t *testing.T) {
+	superuser := Permissions{{Resource: "*", Action: "*"}}
+	scopes := Permissions{
+		{Resource: "content:posts", Action: "read"},
+		{Resource: "content:posts", Action: "update", Own: true},
+		{Resource: "media", Action: "create"},
+	}
+	editor := Permissions{{Resource: "content:*", Action: "*"}}
+
+	tests := []struct {
+		name        string
+		perms       Permissions
+		resource    string
+		action      string
+		wantStatus  int
+		wantOwnOnly bool
+	}{
+		{"in scope", superuser, "content:posts", "read", http.StatusOK, false},
+		{"own only scope", superuser, "content:posts", "update", http.StatusOK, true},
+		{"outside scope", superuser, "content:posts", "delete", http.StatusForbidden, false},
+		{"other resource", superuser, "admins", "manage", http.StatusForbidden, false},
+		{"scope beyond role", editor, "media", "create", http.StatusForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOwnOnly bool
+			r := chi.NewRouter()
+			r.Use(withToken(tt.perms, scopes))
+			r.With(Authorize(tt.resource, tt.action)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				gotOwnOnly = OwnOnlyFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantStatus || gotOwnOnly != tt.wantOwnOnly {
+				t.Errorf("status = %d, own only = %v; want %d, %v", rec.Code, gotOwnOnly, tt.wantStatus, tt.wantOwnOnly)
+			}
+		})
+	}
+}
+
+func TestHandler_RejectsPersonalAccessToken(t *testing.T) {
+	// Credentials cannot be managed with a personal access token; the
+	// requests are refused before the service is used.
+	h := NewHandler(nil, nil, true)
+	tokens := NewTokenHandler(nil)
+	handlers := map[string]http.HandlerFunc{
+		"change password":      h.ChangePassword,
+		"2fa enroll":           h.TwoFactorEnroll,
+		"2fa enroll confirm":   h.TwoFactorEnrollConfirm,
+		"2fa disable":          h.TwoFactorDisable,
+		"2fa recovery codes":   h.TwoFactorRecoveryCodes,
+		"revoke session":       h.RevokeSession,
+		"revoke all sessions":  h.RevokeAllSessions,
+		"create another token": tokens.Create,
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			withToken(nil, nil)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")))
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestTokenHandler_InvalidID(t *testing.T) {
+	h := NewTokenHandler(nil)
+	r := chi.NewRouter()
+	r.Delete("/auth/tokens/{id}", h.Revoke)
+	r.Delete("/admins/{id}/tokens/{tokenID}", h.AdminRevoke)
+
+	for _, path := range []string{"/auth/tokens/nope", "/admins/nope/tokens/550e8400-e29b-41d4-a716-446655440000", "/admins/550e8400-e29b-41d4-a716-446655440000/tokens/nope"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("DELETE %s status = %d, want %d", path, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 test key of RFC 6238, "12345678901234567890", in
+// base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := totpCode(rfcSecret, totpStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("totpCode: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode at %d = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now)
+	code := func(s int64) string {
+		c, err := totpCode(rfcSecret, s)
+		if err != nil {
+			t.Fatalf("totpCode: %v", err)
+		}
+		return c
+	}
+
+	if got, ok := verifyTOTP(rfcSecret, code(step), now, 0); !ok || got != step {
+		t.Errorf("current code: got step %d, ok %v", got, ok)
+	}
+	if _, ok := verifyTOTP(rfcSecret, code(step-1), now, 0); !ok {
+		t.Error("previous period's code rejected, want accepted within skew")
+	}
+	if _, ok := verifyTOTP(rfcSecret, code(step+1), now, 0); !ok {
+		t.Error("next period's code rejected, want accepted within skew")
+	}
+	if _, ok := verifyTOTP(rfcSecret, code(step-2), now, 0); ok {
+		t.Error("code two periods old accepted")
+	}
+	if _, ok := verifyTOTP(rfcSecret, code(step), now, step); ok {
+		t.Error("already used code accepted")
+	}
+	if _, ok := verifyTOTP(rfcSecret, "12345", now, 0); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("jane@example.com", "ABCDEF")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Mithril%20CMS:jane@example.com?") {
+		t.Errorf("uri = %q, want otpauth label with issuer and email", uri)
+	}
+	for _, param := range []string{"secret=ABCDEF", "issuer=Mithril+CMS", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("uri = %q, missing %s", uri, param)
+		}
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret: %v", err)
+	}
+	if _, err := totpCode(secret, 1); err != nil {
+		t.Errorf("generated secret %q is not usable: %v", secret, err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatalf("newRecoveryCodes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+	seen := make(map[string]bool)
+	for _, c := range codes {
+		if len(c) != 9 || c[4] != '-' {
+			t.Errorf("code %q not formatted as xxxx-xxxx", c)
+		}
+		if isTOTPCode(c) {
+			t.Errorf("code %q mistaken for a TOTP code", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+
+	// Codes match however they are typed.
+	want := hashRecoveryCode(codes[0])
+	for _, typed := range []string{strings.ToUpper(codes[0]), strings.ReplaceAll(codes[0], "-", ""), " " + codes[0]} {
+		if hashRecoveryCode(typed) != want {
+			t.Errorf("hashRecoveryCode(%q) differs from hash of %q", typed, codes[0])
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// challengeTokenExpiry bounds how long after the password step the second
+// factor may be completed.
+const challengeTokenExpiry = 5 * time.Minute
+
+// Purposes of challenge tokens.
+const (
+	challengeVerify = "2fa_verify" // Enter a code of the enrolled authenticator.
+	challengeSetup  = "2fa_setup"  // Enroll first; two-factor authentication is required.
+)
+
+// Sentinel errors for two-factor authentication.
+var (
+	ErrInvalidChallenge     = errors.New("invalid or expired two-factor challenge")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotPending  = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorEnforced    = errors.New("two-factor authentication is required for all admins")
+)
+
+// TwoFactorCodeError is returned when a two-factor code is wrong. It carries
+// the admin the code was entered for, so the failure can be audited.
+type TwoFactorCodeError struct {
+	AdminID string
+}
+
+func (e *TwoFactorCodeError) Error() string { return ErrInvalidTwoFactorCode.Error() }
+
+// Unwrap makes errors.Is(err, ErrInvalidTwoFactorCode) match.
+func (e *TwoFactorCodeError) Unwrap() error { return ErrInvalidTwoFactorCode }
+
+// LoginResult is the outcome of a successful password check. Either the
+// session tokens are set, or ChallengeToken is, and the login must be
+// completed with a second factor.
+type LoginResult struct {
+	AdminID      string
+	AccessToken  string
+	RefreshToken string
+
+	// ChallengeToken authorizes completing the login with a second factor.
+	ChallengeToken string
+	// SetupRequired is set with ChallengeToken if the admin has to enroll
+	// in two-factor authentication before logging in.
+	SetupRequired bool
+	// Method is the second factor used: "totp" or "recovery_code".
+	Method string
+}
+
+// TwoFactorEnrollment is a started enrollment: the secret to add to an
+// authenticator app, directly or as an otpauth:// URI shown as a QR code.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorStatus describes an admin's two-factor authentication.
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// challengeClaims are the claims of a challenge token.
+type challengeClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// SetRequireTwoFactor sets whether all admins must use two-factor
+// authentication. Admins who have not enrolled are asked to at their next
+// login, and cannot disable it.
+func (s *Service) SetRequireTwoFactor(required bool) {
+	s.requireTwoFactor = required
+}
+
+// TwoFactorStatus returns the admin's two-factor status.
+func (s *Service) TwoFactorStatus(ctx context.Context, adminID string) (*TwoFactorStatus, error) {
+	st, err := s.repo.GetTOTP(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: st.EnabledAt != nil, Required: s.requireTwoFactor}
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(ctx, adminID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTwoFactorEnrollment generates a new TOTP secret for the admin. It only
+// takes effect once confirmed with ConfirmTwoFactorEnrollment.
+func (s *Service) BeginTwoFactorEnrollment(ctx context.Context, adminID string) (*TwoFactorEnrollment, error) {
+	admin, err := s.GetAdmin(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPendingTOTP(ctx, adminID, secret); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{Secret: secret, URI: totpURI(admin.Email, secret)}, nil
+}
+
+// ConfirmTwoFactorEnrollment enables two-factor authentication once the admin
+// proves their authenticator works by entering a code. It returns the
+// admin's recovery codes, which are not shown again.
+func (s *Service) ConfirmTwoFactorEnrollment(ctx context.Context, adminID, code string) ([]string, error) {
+	st, err := s.repo.GetTOTP(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if st.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if st.Secret == nil {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	step, ok := verifyTOTP(*st.Secret, code, time.Now(), st.LastStep)
+	if !ok {
+		return nil, &TwoFactorCodeError{AdminID: adminID}
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableTOTP(ctx, adminID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication for the admin after
+// verifying their password. Not allowed while two-factor authentication is
+// required for all admins.
+func (s *Service) DisableTwoFactor(ctx context.Context, adminID, password string) error {
+	if s.requireTwoFactor {
+		return ErrTwoFactorEnforced
+	}
+	admin, err := s.checkPassword(ctx, adminID, password)
+	if err != nil {
+		return err
+	}
+	if admin.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	return s.repo.DisableTOTP(ctx, adminID)
+}
+
+// ResetTwoFactor removes another admin's two-factor authentication, for
+// when they lost their authenticator and recovery codes. If two-factor
+// authentication is required, they enroll again at their next login. by is
+// the admin resetting it, who must be allowed to grant the admin's role;
+// nil for trusted callers.
+func (s *Service) ResetTwoFactor(ctx context.Context, adminID string, by *Grantor) (*Admin, error) {
+	if err := s.authorizeOther(ctx, by, adminID); err != nil {
+		return nil, err
+	}
+	admin, err := s.GetAdmin(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.repo.DisableTOTP(ctx, adminID); err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// RegenerateRecoveryCodes replaces the admin's recovery codes after
+// verifying their password.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, adminID, password string) ([]string, error) {
+	admin, err := s.checkPassword(ctx, adminID, password)
+	if err != nil {
+		return nil, err
+	}
+	if admin.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, adminID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteTwoFactorLogin finishes a login started by Login with a code from
+// the admin's authenticator or one of their recovery codes. Wrong codes
+// count as failed logins, like wrong passwords.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string) (*LoginResult, error) {
+	admin, err := s.challengeAdmin(ctx, challengeToken, challengeVerify)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginBlocked(ctx, admin.Email); err != nil {
+		return nil, err
+	}
+
+	method, err := s.checkSecondFactor(ctx, admin.ID, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if recErr := s.recordLoginFailure(ctx, admin.Email); recErr != nil {
+			return nil, recErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.clearLoginFailures(ctx, admin.Email); err != nil {
+		return nil, err
+	}
+
+	result, err := s.startSession(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	result.Method = method
+	return result, nil
+}
+
+// BeginTwoFactorSetup starts the enrollment of an admin who must enroll
+// before logging in.
+func (s *Service) BeginTwoFactorSetup(ctx context.Context, challengeToken string) (*TwoFactorEnrollment, error) {
+	admin, err := s.challengeAdmin(ctx, challengeToken, challengeSetup)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginTwoFactorEnrollment(ctx, admin.ID)
+}
+
+// CompleteTwoFactorSetup confirms the enrollment started with
+// BeginTwoFactorSetup and finishes the login. It returns the new session and
+// the admin's recovery codes.
+func (s *Service) CompleteTwoFactorSetup(ctx context.Context, challengeToken, code string) (*LoginResult, []string, error) {
+	admin, err := s.challengeAdmin(ctx, challengeToken, challengeSetup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codes, err := s.ConfirmTwoFactorEnrollment(ctx, admin.ID, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := s.startSession(ctx, admin)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.Method = "totp"
+	return result, codes, nil
+}
+
+// checkSecondFactor verifies a TOTP or recovery code of the admin and
+// consumes it, returning which kind it was.
+func (s *Service) checkSecondFactor(ctx context.Context, adminID, code string) (string, error) {
+	if isTOTPCode(code) {
+		st, err := s.repo.GetTOTP(ctx, adminID)
+		if err != nil {
+			return "", err
+		}
+		if st.EnabledAt == nil || st.Secret == nil {
+			return "", ErrInvalidChallenge
+		}
+		step, ok := verifyTOTP(*st.Secret, code, time.Now(), st.LastStep)
+		if ok {
+			// Another login may have used the same code in the meantime.
+			if ok, err = s.repo.UseTOTPStep(ctx, adminID, step); err != nil {
+				return "", err
+			}
+		}
+		if !ok {
+			return "", &TwoFactorCodeError{AdminID: adminID}
+		}
+		return "totp", nil
+	}
+
+	ok, err := s.repo.UseRecoveryCode(ctx, adminID, hashRecoveryCode(code))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", &TwoFactorCodeError{AdminID: adminID}
+	}
+	return "recovery_code", nil
+}
+
+// checkPassword returns the admin after verifying their password, or
+// ErrWrongPassword.
+func (s *Service) checkPassword(ctx context.Context, adminID, password string) (*Admin, error) {
+	admin, err := s.GetAdmin(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	match, err := s.VerifyPassword(admin.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, ErrWrongPassword
+	}
+	return admin, nil
+}
+
+// challengeAdmin validates a challenge token for purpose and returns its
+// admin, who must still be enabled.
+func (s *Service) challengeAdmin(ctx context.Context, challengeToken, purpose string) (*Admin, error) {
+	adminID, err := parseChallengeToken(challengeToken, purpose, s.jwtSecret)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	admin, err := s.GetAdmin(ctx, adminID)
+	if err != nil {
+		if errors.Is(err, ErrAdminNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	if admin.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	return admin, nil
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes.
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	codes, err = newRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes = make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashRecoveryCode(c)
+	}
+	return codes, hashes, nil
+}
+
+// challengeKey derives the key challenge tokens are signed with from the JWT
+// secret. Using a separate key means a challenge token can never pass as an
+// access token.
+func challengeKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("mithril-cms 2fa challenge"))
+	return mac.Sum(nil)
+}
+
+// createChallengeToken creates a short-lived token that lets the admin
+// complete a login with a second factor.
+func createChallengeToken(adminID, purpose, secret string) (string, error) {
+	now := time.Now()
+	claims := challengeClaims{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   adminID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(challengeTokenExpiry)),
+			Issuer:    "mithril-cms",
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(challengeKey(secret))
+	if err != nil {
+		return "", fmt.Errorf("signing challenge token: %w", err)
+	}
+	return signed, nil
+}
+
+// parseChallengeToken validates a challenge token for purpose and returns
+// its admin ID.
+func parseChallengeToken(tokenString, purpose, secret string) (string, error) {
+	var claims challengeClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return challengeKey(secret), nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("parsing challenge token: %w", err)
+	}
+	if !token.Valid || claims.Purpose != purpose || claims.Subject == "" {
+		return "", fmt.Errorf("invalid challenge token claims")
+	}
+	return claims.Subject, nil
+}
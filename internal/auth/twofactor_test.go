@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChallengeToken(t *testing.T) {
+	id := "550e8400-e29b-41d4-a716-446655440000"
+	token, err := createChallengeToken(id, challengeVerify, testSecret)
+	if err != nil {
+		t.Fatalf("createChallengeToken: %v", err)
+	}
+
+	got, err := parseChallengeToken(token, challengeVerify, testSecret)
+	if err != nil || got != id {
+		t.Errorf("parseChallengeToken() = %q, %v; want %q", got, err, id)
+	}
+	if _, err := parseChallengeToken(token, challengeSetup, testSecret); err == nil {
+		t.Error("challenge token accepted for another purpose")
+	}
+	if _, err := parseChallengeToken(token, challengeVerify, "other-secret"); err == nil {
+		t.Error("challenge token accepted with another secret")
+	}
+}
+
+func TestChallengeToken_NotAnAccessToken(t *testing.T) {
+	challenge, err := createChallengeToken("550e8400-e29b-41d4-a716-446655440000", challengeVerify, testSecret)
+	if err != nil {
+		t.Fatalf("createChallengeToken: %v", err)
+	}
+	if _, err := ValidateAccessToken(challenge, testSecret); err == nil {
+		t.Error("challenge token accepted as access token")
+	}
+
+	access, err := CreateAccessToken("550e8400-e29b-41d4-a716-446655440000", "a@example.com", SuperuserRole, nil, testSecret)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	if _, err := parseChallengeToken(access, challengeVerify, testSecret); err == nil {
+		t.Error("access token accepted as challenge token")
+	}
+}
+
+func TestCompleteTwoFactorLogin_InvalidChallenge(t *testing.T) {
+	svc := NewService(nil, testSecret)
+
+	_, err := svc.CompleteTwoFactorLogin(context.Background(), "not-a-token", "123456")
+	if !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("CompleteTwoFactorLogin() error = %v, want ErrInvalidChallenge", err)
+	}
+	_, _, err = svc.CompleteTwoFactorSetup(context.Background(), "not-a-token", "123456")
+	if !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("CompleteTwoFactorSetup() error = %v, want ErrInvalidChallenge", err)
+	}
+}
+
+func TestDisableTwoFactor_Enforced(t *testing.T) {
+	svc := NewService(nil, testSecret)
+	svc.SetRequireTwoFactor(true)
+
+	err := svc.DisableTwoFactor(context.Background(), "550e8400-e29b-41d4-a716-446655440000", "password")
+	if !errors.Is(err, ErrTwoFactorEnforced) {
+		t.Errorf("DisableTwoFactor() error = %v, want ErrTwoFactorEnforced", err)
+	}
+}
+
+func TestResetTwoFactor_Self(t *testing.T) {
+	svc := NewService(nil, testSecret)
+	id := "550e8400-e29b-41d4-a716-446655440000"
+
+	if _, err := svc.ResetTwoFactor(context.Background(), id, &Grantor{AdminID: id}); !errors.Is(err, ErrSelfAction) {
+		t.Errorf("ResetTwoFactor(self) error = %v, want ErrSelfAction", err)
+	}
+}
+
+func TestWriteTwoFactorError(t *testing.T) {
+	h := NewHandler(nil, nil, false)
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{&TwoFactorCodeError{AdminID: "id"}, http.StatusUnauthorized},
+		{ErrInvalidChallenge, http.StatusUnauthorized},
+		{ErrAccountDisabled, http.StatusForbidden},
+		{ErrWrongPassword, http.StatusBadRequest},
+		{ErrTwoFactorEnabled, http.StatusConflict},
+		{ErrTwoFactorNotEnabled, http.StatusConflict},
+		{ErrTwoFactorNotPending, http.StatusConflict},
+		{ErrTwoFactorEnforced, http.StatusConflict},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/auth/2fa/verify", nil)
+		h.writeTwoFactorError(rr, req, tt.err, "login")
+		if rr.Code != tt.wantStatus {
+			t.Errorf("writeTwoFactorError(%v) status = %d, want %d", tt.err, rr.Code, tt.wantStatus)
+		}
+	}
+	if !errors.Is(&TwoFactorCodeError{}, ErrInvalidTwoFactorCode) {
+		t.Error("TwoFactorCodeError does not match ErrInvalidTwoFactorCode")
+	}
+}
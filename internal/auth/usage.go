@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// usageFlushInterval is how often buffered usage of API keys and personal
+// access tokens is written to the database.
+const usageFlushInterval = 30 * time.Second
+
+// keyUsage is the buffered usage of one API key or personal access token.
+type keyUsage struct {
+	count    int64
+	lastUsed time.Time
+}
+
+// usageFlushFunc adds count requests, the last at lastUsed, to the usage of
+// the credential with the given ID.
+type usageFlushFunc func(ctx context.Context, id string, count int64, lastUsed time.Time) error
+
+// usageRecorder buffers the usage of credentials in memory and writes it
+// with its flush function periodically from a background goroutine, so
+// authenticating a request never waits for a write.
+type usageRecorder struct {
+	kind  string // what is used, for log messages
+	flush usageFlushFunc
+
+	mu    sync.Mutex
+	usage map[string]keyUsage
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newUsageRecorder creates a usageRecorder for credentials of the given
+// kind, e.g. "api key". Call start to begin writing usage, and shutdown to
+// flush and stop.
+func newUsageRecorder(kind string, flush usageFlushFunc) *usageRecorder {
+	return &usageRecorder{
+		kind:  kind,
+		flush: flush,
+		usage: make(map[string]keyUsage),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// start begins the background goroutine that writes usage. Must be called
+// once.
+func (u *usageRecorder) start() {
+	go u.loop()
+}
+
+// shutdown stops the background goroutine after a final flush. The provided
+// context controls the maximum time to wait.
+func (u *usageRecorder) shutdown(ctx context.Context) {
+	close(u.stop)
+
+	select {
+	case <-u.done:
+	case <-ctx.Done():
+		slog.Warn(u.kind + " usage flush timed out")
+	}
+}
+
+// record buffers one request made with the credential.
+func (u *usageRecorder) record(id string, at time.Time) {
+	u.mu.Lock()
+	k := u.usage[id]
+	k.count++
+	k.lastUsed = at
+	u.usage[id] = k
+	u.mu.Unlock()
+}
+
+// loop writes buffered usage every usageFlushInterval until shutdown.
+func (u *usageRecorder) loop() {
+	defer close(u.done)
+
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.flushAll()
+		case <-u.stop:
+			u.flushAll()
+			return
+		}
+	}
+}
+
+// flushAll writes buffered usage. Errors are logged and the affected usage
+// is dropped, so a database failure cannot grow the buffer.
+func (u *usageRecorder) flushAll() {
+	u.mu.Lock()
+	usage := u.usage
+	u.usage = make(map[string]keyUsage)
+	u.mu.Unlock()
+
+	ctx := context.Background()
+	for id, k := range usage {
+		if err := u.flush(ctx, id, k.count, k.lastUsed); err != nil {
+			slog.Error("failed to record "+u.kind+" usage", "id", id, "error", err)
+		}
+	}
+}
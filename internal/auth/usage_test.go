@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUsageRecorder(t *testing.T) {
+	flushed := make(map[string]keyUsage)
+	u := newUsageRecorder("test", func(_ context.Context, id string, count int64, lastUsed time.Time) error {
+		flushed[id] = keyUsage{count: count, lastUsed: lastUsed}
+		return nil
+	})
+	first := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	u.record("k1", first)
+	u.record("k1", first.Add(time.Second))
+	u.record("k2", first)
+	u.flushAll()
+
+	if k := flushed["k1"]; k.count != 2 || !k.lastUsed.Equal(first.Add(time.Second)) {
+		t.Errorf("k1 usage = %+v, want 2 requests last used at %v", k, first.Add(time.Second))
+	}
+	if k := flushed["k2"]; k.count != 1 {
+		t.Errorf("k2 usage = %+v, want 1 request", k)
+	}
+	if len(u.usage) != 0 {
+		t.Errorf("%d entries buffered after flush, want 0", len(u.usage))
+	}
+}
+
+func TestUsageRecorder_FlushErrorDropsUsage(t *testing.T) {
+	u := newUsageRecorder("test", func(context.Context, string, int64, time.Time) error {
+		return errors.New("database down")
+	})
+	u.record("k1", time.Now())
+	u.flushAll()
+
+	if len(u.usage) != 0 {
+		t.Errorf("%d entries buffered after a failed flush, want 0", len(u.usage))
+	}
+}
+
+func TestUsageRecorder_ShutdownFlushes(t *testing.T) {
+	var flushed int64
+	u := newUsageRecorder("test", func(_ context.Context, _ string, count int64, _ time.Time) error {
+		flushed += count
+		return nil
+	})
+	u.start()
+	u.record("k1", time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	u.shutdown(ctx)
+	if flushed != 1 {
+		t.Errorf("flushed %d requests on shutdown, want 1", flushed)
+	}
+}
@@ -0,0 +1,330 @@
+package comments
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/auth"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// maxRequestSize is the maximum allowed request body size (64 KiB).
+const maxRequestSize = 64 << 10
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isValidUUID reports whether s is a valid UUID string.
+func isValidUUID(s string) bool {
+	return uuidRegex.MatchString(s)
+}
+
+// Handler provides HTTP handlers for comments and notifications.
+type Handler struct {
+	service *Service
+	owner   OwnerCheck
+}
+
+// NewHandler creates a new comments Handler. owner enforces permissions
+// granted only on the admin's own entries (see auth.OwnOnlyFromContext) for
+// the routes that change comments; it may be nil if no such grants exist.
+func NewHandler(service *Service, owner OwnerCheck) *Handler {
+	return &Handler{service: service, owner: owner}
+}
+
+// commentRequest is the body of create and update requests.
+type commentRequest struct {
+	Body     string `json:"body"`
+	Field    string `json:"field"`
+	ParentID string `json:"parent_id"`
+}
+
+// decodeRequest decodes a comment request body. Returns false if the body is
+// invalid (400 already written).
+func decodeRequest(w http.ResponseWriter, r *http.Request) (commentRequest, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_JSON",
+			"invalid or too-large JSON body", nil)
+		return req, false
+	}
+	return req, true
+}
+
+// entryParams returns the content type and entry ID from the URL. Returns
+// false if the entry ID is invalid (400 already written).
+func entryParams(w http.ResponseWriter, r *http.Request) (contentType, entryID string, ok bool) {
+	entryID = chi.URLParam(r, "id")
+	if !isValidUUID(entryID) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return "", "", false
+	}
+	return chi.URLParam(r, "contentType"), entryID, true
+}
+
+// commentParams is entryParams plus the comment ID.
+func commentParams(w http.ResponseWriter, r *http.Request) (contentType, entryID, commentID string, ok bool) {
+	contentType, entryID, ok = entryParams(w, r)
+	if !ok {
+		return "", "", "", false
+	}
+	commentID = chi.URLParam(r, "commentID")
+	if !isValidUUID(commentID) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "comment id must be a valid UUID", nil)
+		return "", "", "", false
+	}
+	return contentType, entryID, commentID, true
+}
+
+// handleServiceError writes the appropriate error response for service errors.
+func handleServiceError(w http.ResponseWriter, err error) {
+	var valErr *ValidationError
+	switch {
+	case errors.As(err, &valErr):
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", valErr.Fields)
+	case errors.Is(err, ErrEntryNotFound):
+		server.Error(w, http.StatusNotFound, "NOT_FOUND", "entry not found", nil)
+	case errors.Is(err, ErrNotFound):
+		server.Error(w, http.StatusNotFound, "NOT_FOUND", "comment not found", nil)
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrNotEntryOwner):
+		server.Error(w, http.StatusForbidden, "FORBIDDEN", err.Error(), nil)
+	case errors.Is(err, ErrNotThread):
+		server.Error(w, http.StatusConflict, "NOT_A_THREAD", err.Error(), nil)
+	default:
+		slog.Error("comments service error", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"an internal error occurred", nil)
+	}
+}
+
+// checkOwner rejects requests authorized only for the admin's own entries if
+// the entry was created by someone else. It writes an error response and
+// returns false if so.
+func (h *Handler) checkOwner(w http.ResponseWriter, r *http.Request, contentType, entryID string) bool {
+	if !auth.OwnOnlyFromContext(r.Context()) || h.owner == nil {
+		return true
+	}
+	if err := h.owner(r.Context(), contentType, entryID, auth.AdminIDFromContext(r.Context())); err != nil {
+		handleServiceError(w, err)
+		return false
+	}
+	return true
+}
+
+// List handles GET /admin/api/content/{contentType}/{id}/comments. The
+// optional resolved=true|false query parameter filters threads.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	contentType, entryID, ok := entryParams(w, r)
+	if !ok {
+		return
+	}
+
+	var resolved *bool
+	if v := r.URL.Query().Get("resolved"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", "resolved must be 'true' or 'false'", nil)
+			return
+		}
+		resolved = &b
+	}
+
+	threads, err := h.service.List(r.Context(), contentType, entryID, resolved)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, threads)
+}
+
+// Create handles POST /admin/api/content/{contentType}/{id}/comments.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	contentType, entryID, ok := entryParams(w, r)
+	if !ok {
+		return
+	}
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.ParentID != "" && !isValidUUID(req.ParentID) {
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed",
+			[]server.FieldError{{Field: "parent_id", Message: "must be a valid UUID"}})
+		return
+	}
+	if !h.checkOwner(w, r, contentType, entryID) {
+		return
+	}
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	c, err := h.service.Create(r.Context(), contentType, entryID, CreateInput{
+		Body:     req.Body,
+		Field:    req.Field,
+		ParentID: req.ParentID,
+	}, adminID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusCreated, c)
+}
+
+// Update handles PUT /admin/api/content/{contentType}/{id}/comments/{commentID}.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	contentType, entryID, commentID, ok := commentParams(w, r)
+	if !ok {
+		return
+	}
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkOwner(w, r, contentType, entryID) {
+		return
+	}
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	c, err := h.service.Update(r.Context(), contentType, entryID, commentID, req.Body, adminID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, c)
+}
+
+// Delete handles DELETE /admin/api/content/{contentType}/{id}/comments/{commentID}.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	contentType, entryID, commentID, ok := commentParams(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkOwner(w, r, contentType, entryID) {
+		return
+	}
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	if err := h.service.Delete(r.Context(), contentType, entryID, commentID, adminID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// Resolve handles POST /admin/api/content/{contentType}/{id}/comments/{commentID}/resolve.
+func (h *Handler) Resolve(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, true)
+}
+
+// Unresolve handles POST /admin/api/content/{contentType}/{id}/comments/{commentID}/unresolve.
+func (h *Handler) Unresolve(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, false)
+}
+
+func (h *Handler) setResolved(w http.ResponseWriter, r *http.Request, resolved bool) {
+	contentType, entryID, commentID, ok := commentParams(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkOwner(w, r, contentType, entryID) {
+		return
+	}
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	c, err := h.service.SetResolved(r.Context(), contentType, entryID, commentID, adminID, resolved)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, c)
+}
+
+// Notifications handles GET /admin/api/notifications. It returns a paginated
+// list of the authenticated admin's unread notifications, newest first.
+func (h *Handler) Notifications(w http.ResponseWriter, r *http.Request) {
+	page, perPage := parsePagination(r)
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	notes, total, err := h.service.Notifications(r.Context(), adminID, page, perPage)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	totalPages := 0
+	if perPage > 0 {
+		totalPages = (total + perPage - 1) / perPage
+	}
+
+	server.Paginated(w, notes, server.PaginationMeta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// MarkRead handles POST /admin/api/notifications/{id}/read.
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	if err := h.service.MarkRead(r.Context(), adminID, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			server.Error(w, http.StatusNotFound, "NOT_FOUND", "notification not found", nil)
+			return
+		}
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "marked as read"})
+}
+
+// MarkAllRead handles POST /admin/api/notifications/read-all.
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	adminID := auth.AdminIDFromContext(r.Context())
+	n, err := h.service.MarkAllRead(r.Context(), adminID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]int64{"marked": n})
+}
+
+// parsePagination extracts page and per_page query parameters with defaults.
+func parsePagination(r *http.Request) (page, perPage int) {
+	page = 1
+	perPage = 20
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			page = n
+		}
+	}
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			perPage = n
+			if perPage > 100 {
+				perPage = 100
+			}
+		}
+	}
+	return page, perPage
+}
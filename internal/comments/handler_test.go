@@ -0,0 +1,150 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/auth"
+)
+
+func newTestRouter() chi.Router {
+	// The service is nil: these tests cover request validation that happens
+	// before any service call.
+	h := NewHandler(nil, nil)
+	r := chi.NewRouter()
+	r.Route("/content/{contentType}/{id}/comments", func(r chi.Router) {
+		r.Get("/", h.List)
+		r.Post("/", h.Create)
+		r.Put("/{commentID}", h.Update)
+		r.Post("/{commentID}/resolve", h.Resolve)
+	})
+	r.Post("/notifications/{id}/read", h.MarkRead)
+	return r
+}
+
+func TestHandler_InvalidRequests(t *testing.T) {
+	const entry = "/content/posts/550e8400-e29b-41d4-a716-446655440000/comments"
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode string
+	}{
+		{"invalid entry id", http.MethodGet, "/content/posts/nope/comments", "", "INVALID_ID"},
+		{"invalid resolved filter", http.MethodGet, entry + "?resolved=maybe", "", "INVALID_PARAMS"},
+		{"invalid json", http.MethodPost, entry, "{", "INVALID_JSON"},
+		{"invalid parent id", http.MethodPost, entry, `{"body": "hi", "parent_id": "nope"}`, "VALIDATION_ERROR"},
+		{"invalid comment id", http.MethodPut, entry + "/nope", `{"body": "hi"}`, "INVALID_ID"},
+		{"invalid comment id on resolve", http.MethodPost, entry + "/nope/resolve", "", "INVALID_ID"},
+		{"invalid notification id", http.MethodPost, "/notifications/nope/read", "", "INVALID_ID"},
+	}
+
+	r := newTestRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("expected %s, got %s", tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_OwnEntriesOnly(t *testing.T) {
+	const entry = "/content/posts/550e8400-e29b-41d4-a716-446655440000/comments"
+	const comment = entry + "/650e8400-e29b-41d4-a716-446655440000"
+
+	// The service is nil: requests on others' entries are rejected before
+	// any service call.
+	var checked []string
+	h := NewHandler(nil, func(_ context.Context, contentType, entryID, adminID string) error {
+		checked = append(checked, adminID)
+		return ErrNotEntryOwner
+	})
+	r := chi.NewRouter()
+	r.Route("/content/{contentType}/{id}/comments", func(r chi.Router) {
+		r.Post("/", h.Create)
+		r.Put("/{commentID}", h.Update)
+		r.Delete("/{commentID}", h.Delete)
+		r.Post("/{commentID}/resolve", h.Resolve)
+		r.Post("/{commentID}/unresolve", h.Unresolve)
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, entry, `{"body": "hi"}`},
+		{http.MethodPut, comment, `{"body": "hi"}`},
+		{http.MethodDelete, comment, ""},
+		{http.MethodPost, comment + "/resolve", ""},
+		{http.MethodPost, comment + "/unresolve", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			ctx := context.WithValue(req.Context(), auth.ContextKeyAdminID, "author-id")
+			ctx = context.WithValue(ctx, auth.ContextKeyOwnOnly, true)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected 403, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+	if len(checked) != len(tests) || checked[0] != "author-id" {
+		t.Errorf("owner checked for %v, want author-id %d times", checked, len(tests))
+	}
+}
+
+func TestHandleServiceError(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantCode int
+		wantErr  string
+	}{
+		{&ValidationError{}, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{fmt.Errorf("wrapped: %w", ErrEntryNotFound), http.StatusNotFound, "NOT_FOUND"},
+		{ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{ErrForbidden, http.StatusForbidden, "FORBIDDEN"},
+		{fmt.Errorf("wrapped: %w", ErrNotEntryOwner), http.StatusForbidden, "FORBIDDEN"},
+		{ErrNotThread, http.StatusConflict, "NOT_A_THREAD"},
+		{errors.New("boom"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantErr, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleServiceError(w, tt.err)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+			var resp map[string]any
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if code := resp["error"].(map[string]any)["code"]; code != tt.wantErr {
+				t.Errorf("expected %s code, got %v", tt.wantErr, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+// Package comments provides editorial discussion on content entries:
+// threaded comments optionally anchored to a field, resolvable threads,
+// @mentions of admins, and per-admin notifications.
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/database"
+)
+
+// ErrNotFound is returned when a comment or notification does not exist.
+var ErrNotFound = errors.New("comment not found")
+
+// Comment represents a row in the comments table together with the admins it
+// mentions. Top-level comments carry the replies of their thread.
+type Comment struct {
+	ID          string     `json:"id"`
+	ContentType string     `json:"content_type"`
+	EntryID     string     `json:"entry_id"`
+	ParentID    *string    `json:"parent_id"`
+	Field       *string    `json:"field"`
+	Body        string     `json:"body"`
+	AuthorID    *string    `json:"author_id"`
+	Mentions    []string   `json:"mentions"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	ResolvedBy  *string    `json:"resolved_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Replies     []*Comment `json:"replies,omitempty"`
+}
+
+// Notification represents a row in the notifications table, with the body of
+// the comment it refers to.
+type Notification struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	CommentID   string    `json:"comment_id"`
+	ActorID     *string   `json:"actor_id"`
+	ContentType string    `json:"content_type"`
+	EntryID     string    `json:"entry_id"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// notice is a notification to be created for a comment.
+type notice struct {
+	adminID string
+	kind    string
+}
+
+// Repository provides database access for comments and notifications.
+type Repository struct {
+	db *database.DB
+}
+
+// NewRepository creates a new comments Repository.
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// commentColumns is the select list scanned by scanComment.
+const commentColumns = `c.id, c.content_type, c.entry_id::text, c.parent_id::text, c.field, c.body,
+	c.author_id::text,
+	ARRAY(SELECT m.admin_id::text FROM comment_mentions m WHERE m.comment_id = c.id ORDER BY 1),
+	c.resolved_at, c.resolved_by::text, c.created_at, c.updated_at`
+
+func scanComment(row pgx.Row) (*Comment, error) {
+	var c Comment
+	err := row.Scan(&c.ID, &c.ContentType, &c.EntryID, &c.ParentID, &c.Field, &c.Body,
+		&c.AuthorID, &c.Mentions, &c.ResolvedAt, &c.ResolvedBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Get returns the comment with the given ID, or ErrNotFound.
+func (r *Repository) Get(ctx context.Context, id string) (*Comment, error) {
+	c, err := scanComment(r.db.Pool().QueryRow(ctx,
+		`SELECT `+commentColumns+` FROM comments c WHERE c.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("querying comment: %w", err)
+	}
+	return c, nil
+}
+
+// ListForEntry returns all comments on an entry ordered by creation time.
+func (r *Repository) ListForEntry(ctx context.Context, contentType, entryID string) ([]*Comment, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT `+commentColumns+` FROM comments c
+		 WHERE c.content_type = $1 AND c.entry_id = $2
+		 ORDER BY c.created_at, c.id`,
+		contentType, entryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying comments: %w", err)
+	}
+
+	comments, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Comment, error) {
+		return scanComment(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning comments: %w", err)
+	}
+	return comments, nil
+}
+
+// Create inserts a comment with its mentions and notifications in a single
+// transaction, filling in the comment's ID and timestamps.
+func (r *Repository) Create(ctx context.Context, c *Comment, notes []notice) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning comment tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO comments (content_type, entry_id, parent_id, field, body, author_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at, updated_at`,
+		c.ContentType, c.EntryID, c.ParentID, c.Field, c.Body, c.AuthorID,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting comment: %w", err)
+	}
+
+	if err := insertMentions(ctx, tx, c.ID, c.Mentions); err != nil {
+		return err
+	}
+	if err := insertNotifications(ctx, tx, c, notes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing comment: %w", err)
+	}
+	return nil
+}
+
+// UpdateBody replaces a comment's body and mentions and inserts
+// notifications for newly mentioned admins in a single transaction.
+func (r *Repository) UpdateBody(ctx context.Context, c *Comment, notes []notice) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning comment tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	err = tx.QueryRow(ctx,
+		`UPDATE comments SET body = $2, updated_at = now() WHERE id = $1 RETURNING updated_at`,
+		c.ID, c.Body,
+	).Scan(&c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("updating comment: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM comment_mentions WHERE comment_id = $1`, c.ID); err != nil {
+		return fmt.Errorf("deleting comment mentions: %w", err)
+	}
+	if err := insertMentions(ctx, tx, c.ID, c.Mentions); err != nil {
+		return err
+	}
+	if err := insertNotifications(ctx, tx, c, notes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing comment update: %w", err)
+	}
+	return nil
+}
+
+func insertMentions(ctx context.Context, tx pgx.Tx, commentID string, adminIDs []string) error {
+	if len(adminIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx,
+		`INSERT INTO comment_mentions (comment_id, admin_id)
+		 SELECT $1, unnest($2::text[]::uuid[])`,
+		commentID, adminIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting comment mentions: %w", err)
+	}
+	return nil
+}
+
+func insertNotifications(ctx context.Context, tx pgx.Tx, c *Comment, notes []notice) error {
+	for _, n := range notes {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO notifications (admin_id, kind, comment_id, actor_id, content_type, entry_id)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			n.adminID, n.kind, c.ID, c.AuthorID, c.ContentType, c.EntryID,
+		)
+		if err != nil {
+			return fmt.Errorf("inserting notification: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetResolved marks a comment as resolved by adminID, or unresolved if
+// adminID is empty.
+func (r *Repository) SetResolved(ctx context.Context, id, adminID string) error {
+	var sql string
+	var args []any
+	if adminID != "" {
+		sql = `UPDATE comments SET resolved_at = now(), resolved_by = $2 WHERE id = $1`
+		args = []any{id, adminID}
+	} else {
+		sql = `UPDATE comments SET resolved_at = NULL, resolved_by = NULL WHERE id = $1`
+		args = []any{id}
+	}
+
+	tag, err := r.db.Pool().Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("updating comment resolution: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a comment and, through ON DELETE CASCADE, its replies,
+// mentions and notifications.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	tag, err := r.db.Pool().Exec(ctx, `DELETE FROM comments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting comment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteForEntry removes all comments on an entry.
+func (r *Repository) DeleteForEntry(ctx context.Context, contentType, entryID string) error {
+	_, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM comments WHERE content_type = $1 AND entry_id = $2`,
+		contentType, entryID,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting entry comments: %w", err)
+	}
+	return nil
+}
+
+// AdminIDsByEmail returns the IDs of the admins with the given emails,
+// compared case-insensitively. Unknown emails are skipped.
+func (r *Repository) AdminIDsByEmail(ctx context.Context, emails []string) ([]string, error) {
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT id::text FROM admins WHERE lower(email) = ANY($1::text[]) ORDER BY 1`,
+		emails,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying mentioned admins: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("scanning mentioned admins: %w", err)
+	}
+	return ids, nil
+}
+
+// ListUnread returns a page of an admin's unread notifications, newest first,
+// and the total number of unread notifications.
+func (r *Repository) ListUnread(ctx context.Context, adminID string, page, perPage int) ([]*Notification, int, error) {
+	var total int
+	if err := r.db.Pool().QueryRow(ctx,
+		`SELECT COUNT(*) FROM notifications WHERE admin_id = $1 AND read_at IS NULL`,
+		adminID,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting notifications: %w", err)
+	}
+
+	rows, err := r.db.Pool().Query(ctx,
+		`SELECT n.id, n.kind, n.comment_id::text, n.actor_id::text, n.content_type,
+		        n.entry_id::text, c.body, n.created_at
+		 FROM notifications n JOIN comments c ON c.id = n.comment_id
+		 WHERE n.admin_id = $1 AND n.read_at IS NULL
+		 ORDER BY n.created_at DESC, n.id
+		 LIMIT $2 OFFSET $3`,
+		adminID, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querying notifications: %w", err)
+	}
+
+	notes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Notification, error) {
+		var n Notification
+		err := row.Scan(&n.ID, &n.Kind, &n.CommentID, &n.ActorID, &n.ContentType, &n.EntryID, &n.Body, &n.CreatedAt)
+		return &n, err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("scanning notifications: %w", err)
+	}
+	return notes, total, nil
+}
+
+// MarkRead marks one of an admin's notifications as read. It returns
+// ErrNotFound if the notification does not exist or belongs to another admin.
+func (r *Repository) MarkRead(ctx context.Context, adminID, id string) error {
+	tag, err := r.db.Pool().Exec(ctx,
+		`UPDATE notifications SET read_at = COALESCE(read_at, now())
+		 WHERE id = $1 AND admin_id = $2`,
+		id, adminID,
+	)
+	if err != nil {
+		return fmt.Errorf("marking notification read: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all of an admin's notifications as read and returns how
+// many were unread.
+func (r *Repository) MarkAllRead(ctx context.Context, adminID string) (int64, error) {
+	tag, err := r.db.Pool().Exec(ctx,
+		`UPDATE notifications SET read_at = now() WHERE admin_id = $1 AND read_at IS NULL`,
+		adminID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("marking notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,428 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+const (
+	// maxBodyLength is the maximum length of a comment body in bytes.
+	maxBodyLength = 10000
+
+	// maxMentions caps how many admins a single comment can mention.
+	maxMentions = 20
+)
+
+// Notification kinds.
+const (
+	KindMention = "mention"
+	KindReply   = "reply"
+)
+
+var (
+	// ErrEntryNotFound is returned when the commented content type or entry
+	// does not exist.
+	ErrEntryNotFound = errors.New("entry not found")
+
+	// ErrForbidden is returned when an admin edits or deletes a comment they
+	// did not write.
+	ErrForbidden = errors.New("only the author can change this comment")
+
+	// ErrNotEntryOwner is returned when an admin whose permission covers
+	// only their own entries comments on someone else's.
+	ErrNotEntryOwner = errors.New("you may only comment on your own entries")
+
+	// ErrNotThread is returned when resolving a reply instead of the
+	// top-level comment of its thread.
+	ErrNotThread = errors.New("replies cannot be resolved; resolve the top-level comment")
+)
+
+// mentionRegex matches @mentions of admin emails, e.g. "@jane@example.com".
+// The mention must start the text or follow a character that cannot be part
+// of an email address.
+var mentionRegex = regexp.MustCompile(`(?:^|[^\w.%+@-])@([A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+// EntryLookup returns the content type of an existing entry, or an error
+// wrapping ErrEntryNotFound if the content type or entry does not exist. It
+// is supplied by the content package at wiring time so comments does not
+// depend on it.
+type EntryLookup func(ctx context.Context, contentType, entryID string) (schema.ContentType, error)
+
+// OwnerCheck returns an error wrapping ErrNotEntryOwner unless the entry was
+// created by the admin. Like EntryLookup, it is supplied by the content
+// package at wiring time.
+type OwnerCheck func(ctx context.Context, contentType, entryID, adminID string) error
+
+// ValidationError is returned when comment input fails validation.
+type ValidationError struct {
+	Fields []server.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation failed: %d field errors", len(e.Fields))
+}
+
+// CreateInput holds the fields of a new comment.
+type CreateInput struct {
+	Body     string
+	Field    string // Optional field anchor; top-level comments only.
+	ParentID string // Optional comment to reply to.
+}
+
+// Service implements the business logic for comments and notifications.
+type Service struct {
+	repo         *Repository
+	auditService *audit.Service
+	lookup       EntryLookup
+}
+
+// NewService creates a new comments Service. The audit service is optional;
+// if nil, audit events are silently skipped.
+func NewService(repo *Repository, auditService *audit.Service, lookup EntryLookup) *Service {
+	return &Service{
+		repo:         repo,
+		auditService: auditService,
+		lookup:       lookup,
+	}
+}
+
+// logAudit sends an audit event if the audit service is configured.
+func (s *Service) logAudit(ctx context.Context, event audit.Event) {
+	if s.auditService != nil {
+		s.auditService.Log(ctx, event)
+	}
+}
+
+// auditComment logs an audit event for an action on c.
+func (s *Service) auditComment(ctx context.Context, action string, c *Comment, adminID string) {
+	s.logAudit(ctx, audit.Event{
+		Action:     action,
+		ActorID:    adminID,
+		Resource:   "comment",
+		ResourceID: c.ID,
+		Payload: map[string]any{
+			"content_type": c.ContentType,
+			"entry_id":     c.EntryID,
+		},
+	})
+}
+
+// List returns the comment threads on an entry, oldest first, each with its
+// replies. If resolved is non-nil only threads with that resolution state are
+// returned.
+func (s *Service) List(ctx context.Context, contentType, entryID string, resolved *bool) ([]*Comment, error) {
+	if _, err := s.lookup(ctx, contentType, entryID); err != nil {
+		return nil, err
+	}
+
+	all, err := s.repo.ListForEntry(ctx, contentType, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("listing comments: %w", err)
+	}
+	return buildThreads(all, resolved), nil
+}
+
+// buildThreads groups comments (ordered by creation time) into threads and
+// filters the threads by resolution state.
+func buildThreads(all []*Comment, resolved *bool) []*Comment {
+	roots := make(map[string]*Comment)
+	threads := []*Comment{}
+	for _, c := range all {
+		if c.ParentID == nil {
+			roots[c.ID] = c
+			if resolved == nil || *resolved == (c.ResolvedAt != nil) {
+				threads = append(threads, c)
+			}
+		}
+	}
+	for _, c := range all {
+		if c.ParentID != nil {
+			if root, ok := roots[*c.ParentID]; ok {
+				root.Replies = append(root.Replies, c)
+			}
+		}
+	}
+	return threads
+}
+
+// Create adds a comment to an entry. Replies are attached to the top-level
+// comment of the thread they answer. Mentioned admins are notified, as is
+// the author of the thread when someone else replies.
+func (s *Service) Create(ctx context.Context, contentType, entryID string, in CreateInput, adminID string) (*Comment, error) {
+	errs := validateBody(in.Body)
+	if in.Field != "" && in.ParentID != "" {
+		errs = append(errs, server.FieldError{Field: "field", Message: "only top-level comments can be anchored to a field"})
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
+	ct, err := s.lookup(ctx, contentType, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Comment{
+		ContentType: contentType,
+		EntryID:     entryID,
+		Body:        in.Body,
+		AuthorID:    &adminID,
+	}
+
+	if in.Field != "" {
+		if !hasField(ct, in.Field) {
+			return nil, &ValidationError{Fields: []server.FieldError{
+				{Field: "field", Message: fmt.Sprintf("unknown field %q", in.Field)},
+			}}
+		}
+		c.Field = &in.Field
+	}
+
+	var root *Comment
+	if in.ParentID != "" {
+		root, err = s.threadRoot(ctx, contentType, entryID, in.ParentID)
+		if errors.Is(err, ErrNotFound) {
+			return nil, &ValidationError{Fields: []server.FieldError{
+				{Field: "parent_id", Message: "comment not found on this entry"},
+			}}
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.ParentID = &root.ID
+	}
+
+	c.Mentions, err = s.resolveMentions(ctx, c.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []notice
+	for _, id := range c.Mentions {
+		if id != adminID {
+			notes = append(notes, notice{adminID: id, kind: KindMention})
+		}
+	}
+	if root != nil && root.AuthorID != nil && *root.AuthorID != adminID && !contains(c.Mentions, *root.AuthorID) {
+		notes = append(notes, notice{adminID: *root.AuthorID, kind: KindReply})
+	}
+
+	if err := s.repo.Create(ctx, c, notes); err != nil {
+		return nil, fmt.Errorf("creating comment: %w", err)
+	}
+
+	s.auditComment(ctx, "comment.create", c, adminID)
+	return c, nil
+}
+
+// Update replaces the body of a comment written by adminID. Admins newly
+// mentioned by the edit are notified.
+func (s *Service) Update(ctx context.Context, contentType, entryID, id, body, adminID string) (*Comment, error) {
+	if errs := validateBody(body); len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
+	c, err := s.getForEntry(ctx, contentType, entryID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.AuthorID == nil || *c.AuthorID != adminID {
+		return nil, ErrForbidden
+	}
+
+	mentions, err := s.resolveMentions(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	var notes []notice
+	for _, m := range mentions {
+		if m != adminID && !contains(c.Mentions, m) {
+			notes = append(notes, notice{adminID: m, kind: KindMention})
+		}
+	}
+
+	c.Body = body
+	c.Mentions = mentions
+	if err := s.repo.UpdateBody(ctx, c, notes); err != nil {
+		return nil, fmt.Errorf("updating comment: %w", err)
+	}
+
+	s.auditComment(ctx, "comment.update", c, adminID)
+	return c, nil
+}
+
+// Delete removes a comment written by adminID. Deleting a top-level comment
+// removes its replies as well.
+func (s *Service) Delete(ctx context.Context, contentType, entryID, id, adminID string) error {
+	c, err := s.getForEntry(ctx, contentType, entryID, id)
+	if err != nil {
+		return err
+	}
+	if c.AuthorID == nil || *c.AuthorID != adminID {
+		return ErrForbidden
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("deleting comment: %w", err)
+	}
+
+	s.auditComment(ctx, "comment.delete", c, adminID)
+	return nil
+}
+
+// SetResolved resolves or reopens a comment thread. Any admin may do so.
+func (s *Service) SetResolved(ctx context.Context, contentType, entryID, id, adminID string, resolved bool) (*Comment, error) {
+	c, err := s.getForEntry(ctx, contentType, entryID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.ParentID != nil {
+		return nil, ErrNotThread
+	}
+
+	resolvedBy, action := "", "comment.unresolve"
+	if resolved {
+		resolvedBy, action = adminID, "comment.resolve"
+	}
+	if err := s.repo.SetResolved(ctx, id, resolvedBy); err != nil {
+		return nil, fmt.Errorf("updating comment resolution: %w", err)
+	}
+
+	c, err = s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting comment: %w", err)
+	}
+
+	s.auditComment(ctx, action, c, adminID)
+	return c, nil
+}
+
+// DeleteForEntry removes the comments on a deleted entry. Failures are only
+// logged because the entry is already gone.
+func (s *Service) DeleteForEntry(ctx context.Context, contentType, entryID string) {
+	if err := s.repo.DeleteForEntry(ctx, contentType, entryID); err != nil {
+		slog.Warn("failed to delete entry comments", "content_type", contentType, "id", entryID, "error", err)
+	}
+}
+
+// Notifications returns a page of an admin's unread notifications and the
+// total number of unread notifications.
+func (s *Service) Notifications(ctx context.Context, adminID string, page, perPage int) ([]*Notification, int, error) {
+	notes, total, err := s.repo.ListUnread(ctx, adminID, page, perPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing notifications: %w", err)
+	}
+	return notes, total, nil
+}
+
+// MarkRead marks one of an admin's notifications as read.
+func (s *Service) MarkRead(ctx context.Context, adminID, id string) error {
+	return s.repo.MarkRead(ctx, adminID, id)
+}
+
+// MarkAllRead marks all of an admin's notifications as read and returns how
+// many were unread.
+func (s *Service) MarkAllRead(ctx context.Context, adminID string) (int64, error) {
+	return s.repo.MarkAllRead(ctx, adminID)
+}
+
+// getForEntry returns a comment after checking that the entry exists and the
+// comment belongs to it.
+func (s *Service) getForEntry(ctx context.Context, contentType, entryID, id string) (*Comment, error) {
+	if _, err := s.lookup(ctx, contentType, entryID); err != nil {
+		return nil, err
+	}
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.ContentType != contentType || c.EntryID != entryID {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+// threadRoot returns the top-level comment of the thread containing id,
+// which must belong to the given entry.
+func (s *Service) threadRoot(ctx context.Context, contentType, entryID, id string) (*Comment, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.ContentType != contentType || c.EntryID != entryID {
+		return nil, ErrNotFound
+	}
+	if c.ParentID == nil {
+		return c, nil
+	}
+	return s.repo.Get(ctx, *c.ParentID)
+}
+
+// resolveMentions returns the IDs of the admins mentioned in body.
+func (s *Service) resolveMentions(ctx context.Context, body string) ([]string, error) {
+	emails := parseMentions(body)
+	if len(emails) == 0 {
+		return []string{}, nil
+	}
+	ids, err := s.repo.AdminIDsByEmail(ctx, emails)
+	if err != nil {
+		return nil, fmt.Errorf("resolving mentions: %w", err)
+	}
+	return ids, nil
+}
+
+// parseMentions returns the distinct, lowercased emails @mentioned in body,
+// at most maxMentions of them.
+func parseMentions(body string) []string {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, m := range mentionRegex.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(m[1])
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		emails = append(emails, email)
+		if len(emails) == maxMentions {
+			break
+		}
+	}
+	return emails
+}
+
+func validateBody(body string) []server.FieldError {
+	switch {
+	case strings.TrimSpace(body) == "":
+		return []server.FieldError{{Field: "body", Message: "is required"}}
+	case len(body) > maxBodyLength:
+		return []server.FieldError{{Field: "body", Message: fmt.Sprintf("must be at most %d characters", maxBodyLength)}}
+	}
+	return nil
+}
+
+// hasField reports whether ct has a field with the given name.
+func hasField(ct schema.ContentType, name string) bool {
+	for _, f := range ct.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"none", "looks good to me", nil},
+		{"single", "@jane@example.com can you check?", []string{"jane@example.com"}},
+		{"lowercased and deduplicated", "@Jane@Example.com and again @jane@example.com", []string{"jane@example.com"}},
+		{"multiple", "cc @a@x.io, @b.c@y.org.", []string{"a@x.io", "b.c@y.org"}},
+		{"after newline and paren", "first\n(@a@x.io)", []string{"a@x.io"}},
+		{"plain email is not a mention", "mail jane@example.com", nil},
+		{"embedded in a word", "foo@jane@example.com", nil},
+		{"missing domain", "@jane@localhost", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMentions(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMentions_Limit(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < maxMentions+5; i++ {
+		b.WriteString(" @user")
+		b.WriteByte(byte('a' + i))
+		b.WriteString("@example.com")
+	}
+	if got := parseMentions(b.String()); len(got) != maxMentions {
+		t.Errorf("expected %d mentions, got %d", maxMentions, len(got))
+	}
+}
+
+func TestBuildThreads(t *testing.T) {
+	now := time.Now()
+	root1 := &Comment{ID: "r1"}
+	root2 := &Comment{ID: "r2", ResolvedAt: &now}
+	reply1 := &Comment{ID: "c1", ParentID: &root1.ID}
+	reply2 := &Comment{ID: "c2", ParentID: &root2.ID}
+	orphan := &Comment{ID: "c3", ParentID: strPtr("missing")}
+
+	build := func(resolved *bool) []*Comment {
+		for _, c := range []*Comment{root1, root2} {
+			c.Replies = nil
+		}
+		return buildThreads([]*Comment{root1, reply1, root2, reply2, orphan}, resolved)
+	}
+
+	all := build(nil)
+	if len(all) != 2 || all[0] != root1 || all[1] != root2 {
+		t.Fatalf("unexpected threads: %+v", all)
+	}
+	if len(root1.Replies) != 1 || root1.Replies[0] != reply1 {
+		t.Errorf("unexpected replies of r1: %+v", root1.Replies)
+	}
+
+	open := build(boolPtr(false))
+	if len(open) != 1 || open[0] != root1 {
+		t.Errorf("expected only r1 when resolved=false, got %+v", open)
+	}
+	closed := build(boolPtr(true))
+	if len(closed) != 1 || closed[0] != root2 {
+		t.Errorf("expected only r2 when resolved=true, got %+v", closed)
+	}
+
+	if got := buildThreads(nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("expected empty non-nil slice, got %#v", got)
+	}
+}
+
+func TestService_Create_Validation(t *testing.T) {
+	ct := schema.ContentType{
+		Name:   "posts",
+		Fields: []schema.Field{{Name: "title", Type: schema.FieldTypeString}},
+	}
+	lookup := func(ctx context.Context, contentType, entryID string) (schema.ContentType, error) {
+		if contentType != "posts" {
+			return schema.ContentType{}, ErrEntryNotFound
+		}
+		return ct, nil
+	}
+	svc := NewService(nil, nil, lookup)
+	ctx := context.Background()
+	entryID := "550e8400-e29b-41d4-a716-446655440000"
+
+	tests := []struct {
+		name      string
+		in        CreateInput
+		wantField string
+	}{
+		{"empty body", CreateInput{Body: "   "}, "body"},
+		{"too long body", CreateInput{Body: strings.Repeat("x", maxBodyLength+1)}, "body"},
+		{"anchored reply", CreateInput{Body: "ok", Field: "title", ParentID: entryID}, "field"},
+		{"unknown field", CreateInput{Body: "ok", Field: "subtitle"}, "field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(ctx, "posts", entryID, tt.in, "admin-id")
+			var valErr *ValidationError
+			if !errors.As(err, &valErr) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if valErr.Fields[0].Field != tt.wantField {
+				t.Errorf("expected error on %q, got %+v", tt.wantField, valErr.Fields)
+			}
+		})
+	}
+
+	if _, err := svc.Create(ctx, "pages", entryID, CreateInput{Body: "ok"}, "admin-id"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("expected ErrEntryNotFound, got %v", err)
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
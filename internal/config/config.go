@@ -2,9 +2,12 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration values for the Mithril CMS application.
@@ -23,9 +26,15 @@ type Config struct {
 	// MediaDir is the path to the directory for media file storage. Default: ./media
 	MediaDir string
 
-	// JWTSecret is the secret key used for signing JWT access tokens.
+	// JWTSecret is the secret key used for signing JWT access tokens with
+	// HS256, and for encrypting the stored signing keys otherwise.
 	JWTSecret string
 
+	// JWTAlgorithm is the algorithm access tokens are signed with: HS256
+	// (with JWTSecret), or EdDSA or RS256 (with rotatable keys stored in the
+	// database and published as a JWKS). Default: HS256
+	JWTAlgorithm string
+
 	// DevMode enables development features such as auto-applying breaking schema changes
 	// and proxying the admin SPA to the Vite dev server. Default: false.
 	DevMode bool
@@ -35,21 +44,185 @@ type Config struct {
 
 	// AdminPassword is the password for the initial admin user, required on first run.
 	AdminPassword string
+
+	// Require2FA forces all admins to use two-factor authentication. Admins
+	// who have not enrolled must do so at their next login. Default: false
+	Require2FA bool
+
+	// LockoutThreshold is the number of failed logins for an email after
+	// which it is locked for LockoutDuration. 0 disables it. Default: 10
+	LockoutThreshold int
+
+	// LockoutIPThreshold is the number of failed logins from an IP address
+	// after which it is locked for LockoutDuration. 0 disables it.
+	// Default: 100
+	LockoutIPThreshold int
+
+	// LockoutDuration is how long a lock lasts, and how long failed logins
+	// are remembered. Default: 15m
+	LockoutDuration time.Duration
+
+	// LoginDelay is the wait after a failed login for an email, which
+	// doubles with each further failure up to LoginDelayMax. 0 disables
+	// delays. Default: 1s
+	LoginDelay time.Duration
+
+	// LoginDelayMax caps LoginDelay. Default: 30s
+	LoginDelayMax time.Duration
+
+	// RateLimitStore selects where rate limit buckets are kept: "memory"
+	// (per process) or "postgres" (shared across replicas). Default: memory
+	RateLimitStore string
+
+	// RateLimitPublic is the per-IP rate of the public content API, as
+	// "<limit>/<window>" or "off". Default: 300/1m
+	RateLimitPublic string
+
+	// RateLimitPublicAPIKey is the per-API-key rate of the public content API.
+	// Default: 1200/1m
+	RateLimitPublicAPIKey string
+
+	// RateLimitLogin is the per-IP rate of the admin login endpoint. Default: 10/1m
+	RateLimitLogin string
+
+	// TrustedProxies are the IP addresses and CIDR networks of reverse
+	// proxies whose X-Forwarded-For and X-Real-IP headers are trusted. The
+	// headers of other clients are ignored. Default: none
+	TrustedProxies []string
+
+	// PublicURL is the externally visible base URL of the server, used in
+	// links sent by email. Default: http://localhost:<Port>
+	PublicURL string
+
+	// SMTPHost is the SMTP server used to send invitations and password
+	// resets. If empty, emails are only logged in dev mode and cannot be sent
+	// otherwise.
+	SMTPHost string
+
+	// SMTPPort is the SMTP server port. Default: 587
+	SMTPPort int
+
+	// SMTPUsername and SMTPPassword authenticate to the SMTP server. No
+	// authentication is attempted if SMTPUsername is empty.
+	SMTPUsername string
+	SMTPPassword string
+
+	// SMTPFrom is the sender address of outgoing emails. Default: mithril@localhost
+	SMTPFrom string
+
+	// OIDCIssuer is the issuer URL of the OpenID Connect provider admins
+	// can sign in with. Single sign-on is disabled if empty.
+	OIDCIssuer string
+
+	// OIDCClientID and OIDCClientSecret are the credentials of Mithril's
+	// client at the provider.
+	OIDCClientID     string
+	OIDCClientSecret string
+
+	// OIDCScopes are the scopes requested. Default: openid, email, profile
+	OIDCScopes []string
+
+	// OIDCAllowedDomains restricts single sign-on to emails in these
+	// domains. Empty allows all.
+	OIDCAllowedDomains []string
+
+	// OIDCGroupsClaim is the ID token claim listing the user's groups.
+	// Default: groups
+	OIDCGroupsClaim string
+
+	// OIDCRoleMapping maps provider groups to roles, as
+	// "group=role,group=role". The first group the user is in wins.
+	OIDCRoleMapping string
+
+	// OIDCDefaultRole is the role of users in no mapped group. If empty,
+	// such users are not given an account.
+	OIDCDefaultRole string
+
+	// OIDCTrustUnverifiedEmail accepts emails from providers that do not
+	// send the email_verified claim. Default: false
+	OIDCTrustUnverifiedEmail bool
+
+	// AuditRedactFields lists fields whose values are left out of the
+	// changes recorded in the audit log, as "field" or "resource.field".
+	AuditRedactFields []string
+
+	// AuditMaxValueLength truncates longer values in the changes recorded in
+	// the audit log. 0 disables truncation. Default: 1000
+	AuditMaxValueLength int
+
+	// AuditMode selects what happens to audit events when the database is
+	// slow or unavailable: "drop" them when the queue is full, "block"
+	// requests until there is room, or "wal" to keep every event in an
+	// on-disk write-ahead log until it is written. Default: drop
+	AuditMode string
+
+	// AuditWALDir is the directory of the audit write-ahead log in wal mode.
+	// Default: ./audit-wal
+	AuditWALDir string
+
+	// AuditCheckpointFile is a file that signed checkpoints of the audit
+	// log's hash chain are appended to, to detect the chain being rewritten
+	// by someone with database access. It should be on storage they cannot
+	// change. Empty disables checkpoints.
+	AuditCheckpointFile string
+
+	// AuditCheckpointInterval is how often a checkpoint is written, if the
+	// audit log has grown. Default: 1h
+	AuditCheckpointInterval time.Duration
 }
 
 // Load reads configuration from environment variables and returns a Config
 // with sensible defaults applied for optional values.
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		Port:          getEnvInt("MITHRIL_PORT", 8080),
 		DatabaseURL:   getEnv("MITHRIL_DATABASE_URL", ""),
 		SchemaDir:     getEnv("MITHRIL_SCHEMA_DIR", "./schema"),
 		MediaDir:      getEnv("MITHRIL_MEDIA_DIR", "./media"),
 		JWTSecret:     getEnv("MITHRIL_JWT_SECRET", ""),
+		JWTAlgorithm:  getEnv("MITHRIL_JWT_ALGORITHM", "HS256"),
 		DevMode:       getEnvBool("MITHRIL_DEV_MODE", false),
 		AdminEmail:    getEnv("MITHRIL_ADMIN_EMAIL", ""),
 		AdminPassword: getEnv("MITHRIL_ADMIN_PASSWORD", ""),
+		Require2FA:    getEnvBool("MITHRIL_REQUIRE_2FA", false),
+
+		LockoutThreshold:   getEnvInt("MITHRIL_LOCKOUT_THRESHOLD", 10),
+		LockoutIPThreshold: getEnvInt("MITHRIL_LOCKOUT_IP_THRESHOLD", 100),
+		LockoutDuration:    getEnvDuration("MITHRIL_LOCKOUT_DURATION", 15*time.Minute),
+		LoginDelay:         getEnvDuration("MITHRIL_LOGIN_DELAY", time.Second),
+		LoginDelayMax:      getEnvDuration("MITHRIL_LOGIN_DELAY_MAX", 30*time.Second),
+
+		RateLimitStore:        getEnv("MITHRIL_RATE_LIMIT_STORE", "memory"),
+		RateLimitPublic:       getEnv("MITHRIL_RATE_LIMIT_PUBLIC", "300/1m"),
+		RateLimitPublicAPIKey: getEnv("MITHRIL_RATE_LIMIT_PUBLIC_API_KEY", "1200/1m"),
+		RateLimitLogin:        getEnv("MITHRIL_RATE_LIMIT_LOGIN", "10/1m"),
+		TrustedProxies:        getEnvList("MITHRIL_TRUSTED_PROXIES"),
+
+		SMTPHost:     getEnv("MITHRIL_SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("MITHRIL_SMTP_PORT", 587),
+		SMTPUsername: getEnv("MITHRIL_SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("MITHRIL_SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("MITHRIL_SMTP_FROM", "mithril@localhost"),
+
+		OIDCIssuer:               getEnv("MITHRIL_OIDC_ISSUER", ""),
+		OIDCClientID:             getEnv("MITHRIL_OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:         getEnv("MITHRIL_OIDC_CLIENT_SECRET", ""),
+		OIDCScopes:               getEnvList("MITHRIL_OIDC_SCOPES"),
+		OIDCAllowedDomains:       getEnvList("MITHRIL_OIDC_ALLOWED_DOMAINS"),
+		OIDCGroupsClaim:          getEnv("MITHRIL_OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:          getEnv("MITHRIL_OIDC_ROLE_MAPPING", ""),
+		OIDCDefaultRole:          getEnv("MITHRIL_OIDC_DEFAULT_ROLE", ""),
+		OIDCTrustUnverifiedEmail: getEnvBool("MITHRIL_OIDC_TRUST_UNVERIFIED_EMAIL", false),
+
+		AuditRedactFields:       getEnvList("MITHRIL_AUDIT_REDACT_FIELDS"),
+		AuditMaxValueLength:     getEnvInt("MITHRIL_AUDIT_MAX_VALUE_LENGTH", 1000),
+		AuditMode:               getEnv("MITHRIL_AUDIT_MODE", "drop"),
+		AuditWALDir:             getEnv("MITHRIL_AUDIT_WAL_DIR", "./audit-wal"),
+		AuditCheckpointFile:     getEnv("MITHRIL_AUDIT_CHECKPOINT_FILE", ""),
+		AuditCheckpointInterval: getEnvDuration("MITHRIL_AUDIT_CHECKPOINT_INTERVAL", time.Hour),
 	}
+	cfg.PublicURL = strings.TrimRight(getEnv("MITHRIL_PUBLIC_URL", fmt.Sprintf("http://localhost:%d", cfg.Port)), "/")
+	return cfg
 }
 
 // getEnv returns the value of the environment variable named by key,
@@ -61,6 +234,18 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvList returns the comma-separated values of the environment variable
+// named by key, without empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 // getEnvInt returns the value of the environment variable named by key
 // parsed as an integer, or the provided default if the variable is unset,
 // empty, or not a valid integer.
@@ -82,6 +267,30 @@ func getEnvInt(key string, defaultVal int) int {
 	return n
 }
 
+// getEnvDuration returns the value of the environment variable named by key
+// parsed as a duration (e.g. "15m"), or the provided default if the variable
+// is unset, empty, or not a valid non-negative duration.
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("negative duration")
+	}
+	if err != nil {
+		slog.Warn("invalid duration for env var, using default",
+			"key", key,
+			"value", val,
+			"default", defaultVal,
+			"error", err,
+		)
+		return defaultVal
+	}
+	return d
+}
+
 // getEnvBool returns the value of the environment variable named by key
 // parsed as a boolean, or the provided default if the variable is unset,
 // empty, or not a valid boolean.
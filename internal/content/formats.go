@@ -0,0 +1,83 @@
+package content
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// hexColorRegex matches #RGB and #RRGGBB colors.
+var hexColorRegex = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// e164Regex matches an E.164-shaped phone number: a leading +, a non-zero
+// first digit, and at most 15 digits in total. Only the shape is checked; the
+// country code is not looked up and national number lengths are not enforced.
+var e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// phoneSeparators are the formatting characters allowed between phone digits.
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// validateFormat checks a string value against the field's built-in format.
+// It returns nil for fields without a format.
+func validateFormat(f schema.Field, s string) []server.FieldError {
+	var msg string
+	switch f.Format {
+	case schema.FormatEmail:
+		if !isValidEmail(s) {
+			msg = "must be a valid email address"
+		}
+	case schema.FormatURL:
+		msg = checkURL(s, f.URLSchemes())
+	case schema.FormatUUID:
+		if !isValidUUID(s) {
+			msg = "must be a valid UUID"
+		}
+	case schema.FormatHexColor:
+		if !hexColorRegex.MatchString(s) {
+			msg = "must be a hex color (#RGB or #RRGGBB)"
+		}
+	case schema.FormatPhone:
+		if !e164Regex.MatchString(phoneSeparators.Replace(s)) {
+			msg = "must be an E.164-shaped phone number (e.g. +14155550123)"
+		}
+	}
+	if msg == "" {
+		return nil
+	}
+	return []server.FieldError{{Field: f.Name, Message: msg}}
+}
+
+// isValidEmail reports whether s is a bare RFC 5322 address with a dotted
+// domain. Display names ("Jane <jane@example.com>") are rejected.
+func isValidEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	at := strings.LastIndexByte(s, '@')
+	domain := s[at+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// checkURL validates an absolute URL against the allowed schemes and returns
+// an error message, or "" if the URL is acceptable.
+func checkURL(s string, schemes []string) string {
+	if strings.ContainsAny(s, " \t\r\n") {
+		return "must be a valid URL"
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+		return "must be a valid URL"
+	}
+	// url.Parse lowercases the scheme.
+	if !slices.Contains(schemes, u.Scheme) {
+		return fmt.Sprintf("must use one of the URL schemes: %s", strings.Join(schemes, ", "))
+	}
+	return ""
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -106,6 +108,43 @@ func handleServiceError(w http.ResponseWriter, err error) {
 			"Validation failed", valErr.Fields)
 		return
 	}
+	var refErr *schema.ReferencedError
+	if errors.As(err, &refErr) {
+		server.Error(w, http.StatusConflict, "REFERENCED",
+			"entry is still referenced by other entries", server.ReferenceDetails(refErr.References))
+		return
+	}
+	var lockErr *LockedError
+	if errors.As(err, &lockErr) {
+		server.Error(w, http.StatusLocked, "LOCKED",
+			"entry is being edited by another admin", lockDetails(lockErr.Lock))
+		return
+	}
+	var transErr *TransitionError
+	if errors.As(err, &transErr) {
+		if transErr.Forbidden {
+			server.Error(w, http.StatusForbidden, "FORBIDDEN", transErr.Error(), nil)
+		} else {
+			server.Error(w, http.StatusConflict, "INVALID_TRANSITION", transErr.Error(), nil)
+		}
+		return
+	}
+	if errors.Is(err, ErrNoWorkflow) {
+		server.Error(w, http.StatusConflict, "NO_WORKFLOW", err.Error(), nil)
+		return
+	}
+	if errors.Is(err, ErrWorkflowRequired) {
+		server.Error(w, http.StatusConflict, "WORKFLOW_REQUIRED", err.Error(), nil)
+		return
+	}
+	if errors.Is(err, ErrPublishedEdit) {
+		server.Error(w, http.StatusForbidden, "FORBIDDEN", err.Error(), nil)
+		return
+	}
+	if errors.Is(err, ErrNotOwner) {
+		server.Error(w, http.StatusForbidden, "FORBIDDEN", err.Error(), nil)
+		return
+	}
 	if errors.Is(err, ErrNotFound) {
 		server.Error(w, http.StatusNotFound, "NOT_FOUND", "entry not found", nil)
 		return
@@ -115,6 +154,115 @@ func handleServiceError(w http.ResponseWriter, err error) {
 		"an internal error occurred", nil)
 }
 
+// checkOwner enforces permissions granted only on the admin's own entries
+// (see auth.OwnOnlyFromContext). It writes an error response and returns
+// false if the entry was created by someone else.
+func (h *Handler) checkOwner(w http.ResponseWriter, r *http.Request, contentType, id string) bool {
+	if !auth.OwnOnlyFromContext(r.Context()) {
+		return true
+	}
+	if err := h.service.CheckOwner(r.Context(), contentType, id, auth.AdminIDFromContext(r.Context())); err != nil {
+		handleServiceError(w, err)
+		return false
+	}
+	return true
+}
+
+// mayPublish reports whether the admin of r may publish the entry: the
+// publish permission is required, and if it covers only the admin's own
+// entries, the entry must be theirs.
+func (h *Handler) mayPublish(r *http.Request, contentType, id string) bool {
+	allowed, ownOnly := auth.Can(r.Context(), "content:"+contentType, "publish")
+	if !allowed || !ownOnly {
+		return allowed
+	}
+	return h.service.CheckOwner(r.Context(), contentType, id, auth.AdminIDFromContext(r.Context())) == nil
+}
+
+// readable returns a predicate reporting whether the admin of r may read all
+// entries of a content type. Types the admin may only read their own entries
+// of are not readable, since listings of other entries would include others'.
+func readable(r *http.Request) func(contentType string) bool {
+	return func(contentType string) bool {
+		allowed, ownOnly := auth.Can(r.Context(), "content:"+contentType, "read")
+		return allowed && !ownOnly
+	}
+}
+
+// hiddenInverse returns the inverse relation fields of ct whose referencing
+// content type the admin of r may not read. Their values would reveal
+// entries of that type, so they are left out of admin responses.
+func hiddenInverse(r *http.Request, ct schema.ContentType) map[string]bool {
+	canRead := readable(r)
+	var hidden map[string]bool
+	for _, f := range ct.Fields {
+		if !f.IsInverse() || canRead(f.RelatesTo) {
+			continue
+		}
+		if hidden == nil {
+			hidden = make(map[string]bool)
+		}
+		hidden[f.Name] = true
+	}
+	return hidden
+}
+
+// checkHiddenInverse rejects filters and populate requests on hidden inverse
+// fields of ct the same way checkInverseParams rejects fields that are not
+// visible: they are treated as unknown.
+func checkHiddenInverse(ct schema.ContentType, hidden map[string]bool, filters map[string]string, populate []string) error {
+	var errs []server.FieldError
+	for _, f := range ct.Fields {
+		if !hidden[f.Name] {
+			continue
+		}
+		if _, ok := filters[f.Name]; ok {
+			errs = append(errs, server.FieldError{Field: "filter[" + f.Name + "]", Message: "unknown field"})
+		}
+		for _, p := range populate {
+			if p == f.Name {
+				errs = append(errs, server.FieldError{Field: "populate", Message: "unknown field " + f.Name})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// hideInverse removes hidden inverse relation fields from entries.
+func hideInverse(hidden map[string]bool, entries ...map[string]any) {
+	for name := range hidden {
+		for _, e := range entries {
+			delete(e, name)
+		}
+	}
+}
+
+// lockDetails describes the holder of a lock as error details.
+func lockDetails(lock *Lock) []server.FieldError {
+	return []server.FieldError{
+		{Field: "locked_by", Message: lock.AdminEmail},
+		{Field: "expires_at", Message: lock.ExpiresAt.Format(time.RFC3339)},
+	}
+}
+
+// parseTakeover reads the optional takeover=true|false query parameter.
+// Returns false if it is invalid (400 already written).
+func parseTakeover(w http.ResponseWriter, r *http.Request) (takeover, ok bool) {
+	v := r.URL.Query().Get("takeover")
+	if v == "" {
+		return false, true
+	}
+	takeover, err := strconv.ParseBool(v)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", "takeover must be 'true' or 'false'", nil)
+		return false, false
+	}
+	return takeover, true
+}
+
 // --- Admin handlers ---
 
 // AdminList handles GET /admin/api/content/{contentType}.
@@ -130,11 +278,21 @@ func (h *Handler) AdminList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries, total, err := h.service.List(r.Context(), ct.Name, q, false)
+	hidden := hiddenInverse(r, ct)
+	if err := checkHiddenInverse(ct, hidden, q.Filters, q.Populate); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	entries, total, err := h.service.List(r.Context(), ct.Name, q, AdminVisibility)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
+	hideInverse(hidden, entries...)
+	if q.Render == RenderHTML {
+		h.service.RenderRichText(ct.Name, entries)
+	}
 
 	totalPages := 0
 	if q.PerPage > 0 {
@@ -161,11 +319,30 @@ func (h *Handler) AdminGet(w http.ResponseWriter, r *http.Request) {
 		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
 		return
 	}
-	entry, err := h.service.GetByID(r.Context(), ct.Name, id, false)
+	populate, err := ParsePopulate(r, ct)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+	render, err := ParseRender(r)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+	hidden := hiddenInverse(r, ct)
+	if err := checkHiddenInverse(ct, hidden, nil, populate); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	entry, err := h.service.GetByID(r.Context(), ct.Name, id, populate, AdminVisibility)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
+	hideInverse(hidden, entry)
+	if render == RenderHTML {
+		h.service.RenderRichText(ct.Name, []map[string]any{entry})
+	}
 
 	server.JSON(w, http.StatusOK, entry)
 }
@@ -188,11 +365,15 @@ func (h *Handler) AdminCreate(w http.ResponseWriter, r *http.Request) {
 		handleServiceError(w, err)
 		return
 	}
+	hideInverse(hiddenInverse(r, ct), entry)
 
 	server.JSON(w, http.StatusCreated, entry)
 }
 
-// AdminUpdate handles PUT /admin/api/content/{contentType}/{id}.
+// AdminUpdate handles PUT /admin/api/content/{contentType}/{id}. Entries
+// locked by another admin can only be written with ?takeover=true, and
+// published entries of content types with a workflow only by admins who may
+// publish them.
 func (h *Handler) AdminUpdate(w http.ResponseWriter, r *http.Request) {
 	ct, ok := h.lookupSchema(w, r)
 	if !ok {
@@ -204,17 +385,25 @@ func (h *Handler) AdminUpdate(w http.ResponseWriter, r *http.Request) {
 		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
 		return
 	}
+	takeover, ok := parseTakeover(w, r)
+	if !ok {
+		return
+	}
 	data, ok := decodeBody(w, r)
 	if !ok {
 		return
 	}
+	if !h.checkOwner(w, r, ct.Name, id) {
+		return
+	}
 
 	adminID := auth.AdminIDFromContext(r.Context())
-	entry, err := h.service.Update(r.Context(), ct.Name, id, data, adminID)
+	entry, err := h.service.Update(r.Context(), ct.Name, id, data, adminID, takeover, h.mayPublish(r, ct.Name, id))
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
+	hideInverse(hiddenInverse(r, ct), entry)
 
 	server.JSON(w, http.StatusOK, entry)
 }
@@ -231,18 +420,290 @@ func (h *Handler) AdminPublish(w http.ResponseWriter, r *http.Request) {
 		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
 		return
 	}
+	if !h.checkOwner(w, r, ct.Name, id) {
+		return
+	}
 	adminID := auth.AdminIDFromContext(r.Context())
 	entry, err := h.service.Publish(r.Context(), ct.Name, id, adminID)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
+	hideInverse(hiddenInverse(r, ct), entry)
 
 	server.JSON(w, http.StatusOK, entry)
 }
 
+// AdminDelete handles DELETE /admin/api/content/{contentType}/{id}.
+func (h *Handler) AdminDelete(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	if !h.checkOwner(w, r, ct.Name, id) {
+		return
+	}
+	adminID := auth.AdminIDFromContext(r.Context())
+	if err := h.service.Delete(r.Context(), ct.Name, id, adminID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// AdminReferences handles GET /admin/api/content/{contentType}/{id}/references.
+func (h *Handler) AdminReferences(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	refs, err := h.service.EntryReferences(r.Context(), ct.Name, id, readable(r))
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, refs)
+}
+
+// AdminHistory handles GET /admin/api/content/{contentType}/{id}/history. It
+// returns a page of the entry's audit log entries, newest first.
+func (h *Handler) AdminHistory(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	page, perPage, err := ParsePagination(r)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+
+	entries, total, err := h.service.History(r.Context(), ct.Name, id, page, perPage)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.Paginated(w, entries, server.PaginationMeta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	})
+}
+
+// AdminTransition handles POST /admin/api/content/{contentType}/{id}/transition.
+// The body is {"to": "<stage>", "comment": "<optional>"}.
+func (h *Handler) AdminTransition(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	data, ok := decodeBody(w, r)
+	if !ok {
+		return
+	}
+
+	var errs []server.FieldError
+	to, _ := data["to"].(string)
+	if to == "" {
+		errs = append(errs, server.FieldError{Field: "to", Message: "must be a non-empty string"})
+	}
+	comment, isString := data["comment"].(string)
+	if data["comment"] != nil && !isString {
+		errs = append(errs, server.FieldError{Field: "comment", Message: "must be a string"})
+	}
+	if len(errs) > 0 {
+		server.Error(w, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", errs)
+		return
+	}
+
+	if !h.checkOwner(w, r, ct.Name, id) {
+		return
+	}
+	adminID := auth.AdminIDFromContext(r.Context())
+	role := auth.RoleFromContext(r.Context())
+	entry, err := h.service.Transition(r.Context(), ct.Name, id, to, comment, adminID, role, h.mayPublish(r, ct.Name, id))
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	hideInverse(hiddenInverse(r, ct), entry)
+
+	server.JSON(w, http.StatusOK, entry)
+}
+
+// AdminWorkflow handles GET /admin/api/content/{contentType}/{id}/workflow.
+func (h *Handler) AdminWorkflow(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	state, err := h.service.WorkflowState(r.Context(), ct.Name, id, auth.RoleFromContext(r.Context()), h.mayPublish(r, ct.Name, id))
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, state)
+}
+
+// AdminLock handles POST /admin/api/content/{contentType}/{id}/lock. It takes
+// or renews the authenticated admin's edit lock; editors call it periodically
+// as a heartbeat. A lock held by another admin is only taken over with
+// ?takeover=true.
+func (h *Handler) AdminLock(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	takeover, ok := parseTakeover(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.checkOwner(w, r, ct.Name, id) {
+		return
+	}
+	adminID := auth.AdminIDFromContext(r.Context())
+	lock, err := h.service.AcquireLock(r.Context(), ct.Name, id, adminID, takeover)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, lock)
+}
+
+// AdminUnlock handles DELETE /admin/api/content/{contentType}/{id}/lock.
+func (h *Handler) AdminUnlock(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+
+	adminID := auth.AdminIDFromContext(r.Context())
+	if err := h.service.ReleaseLock(r.Context(), ct.Name, id, adminID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, map[string]string{"message": "unlocked"})
+}
+
+// AdminGetLock handles GET /admin/api/content/{contentType}/{id}/lock. The
+// response data is null when the entry is not locked.
+func (h *Handler) AdminGetLock(w http.ResponseWriter, r *http.Request) {
+	ct, ok := h.lookupSchema(w, r)
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
+		return
+	}
+	lock, err := h.service.GetLock(r.Context(), ct.Name, id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, lock)
+}
+
+// ReviewQueue handles GET /admin/api/review-queue. It lists the entries
+// waiting in review stages the authenticated admin's role can act on, in the
+// content types the admin may read. Types the admin may only read their own
+// entries of are left out.
+func (h *Handler) ReviewQueue(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := ParsePagination(r)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+
+	canPublish := func(contentType string) bool {
+		allowed, ownOnly := auth.Can(r.Context(), "content:"+contentType, "publish")
+		return allowed && !ownOnly
+	}
+	items, total, err := h.service.ReviewQueue(r.Context(), auth.RoleFromContext(r.Context()), readable(r), canPublish, page, perPage)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	server.Paginated(w, items, server.PaginationMeta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	})
+}
+
 // --- Public handlers ---
 
+// publicAccess checks that the public API request may read ct: the content
+// type must be public or the request's API key must be scoped to it. It
+// returns the entries the request can see. Returns false if access is denied
+// (404 already written, so private types stay hidden).
+func publicAccess(w http.ResponseWriter, r *http.Request, ct schema.ContentType) (Visibility, bool) {
+	key := auth.APIKeyFromContext(r.Context())
+	if !ct.PublicRead && !key.CanRead(ct.Name) {
+		server.Error(w, http.StatusNotFound, "NOT_FOUND",
+			fmt.Sprintf("content type '%s' not found", ct.Name), nil)
+		return Visibility{}, false
+	}
+	vis := PublicVisibility
+	vis.Drafts = key.CanReadDrafts(ct.Name)
+	return vis, true
+}
+
 // PublicList handles GET /api/{contentType}.
 func (h *Handler) PublicList(w http.ResponseWriter, r *http.Request) {
 	ct, ok := h.lookupSchema(w, r)
@@ -250,9 +711,8 @@ func (h *Handler) PublicList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !ct.PublicRead {
-		server.Error(w, http.StatusNotFound, "NOT_FOUND",
-			fmt.Sprintf("content type '%s' not found", ct.Name), nil)
+	vis, ok := publicAccess(w, r, ct)
+	if !ok {
 		return
 	}
 
@@ -262,11 +722,14 @@ func (h *Handler) PublicList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries, total, err := h.service.List(r.Context(), ct.Name, q, true)
+	entries, total, err := h.service.List(r.Context(), ct.Name, q, vis)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
+	if q.Render == RenderHTML {
+		h.service.RenderRichText(ct.Name, entries)
+	}
 
 	totalPages := 0
 	if q.PerPage > 0 {
@@ -288,9 +751,8 @@ func (h *Handler) PublicGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !ct.PublicRead {
-		server.Error(w, http.StatusNotFound, "NOT_FOUND",
-			fmt.Sprintf("content type '%s' not found", ct.Name), nil)
+	vis, ok := publicAccess(w, r, ct)
+	if !ok {
 		return
 	}
 
@@ -299,11 +761,24 @@ func (h *Handler) PublicGet(w http.ResponseWriter, r *http.Request) {
 		server.Error(w, http.StatusBadRequest, "INVALID_ID", "id must be a valid UUID", nil)
 		return
 	}
-	entry, err := h.service.GetByID(r.Context(), ct.Name, id, true)
+	populate, err := ParsePopulate(r, ct)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+	render, err := ParseRender(r)
+	if err != nil {
+		server.Error(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+	entry, err := h.service.GetByID(r.Context(), ct.Name, id, populate, vis)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
+	if render == RenderHTML {
+		h.service.RenderRichText(ct.Name, []map[string]any{entry})
+	}
 
 	server.JSON(w, http.StatusOK, entry)
 }
@@ -1,7 +1,9 @@
 package content
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/GyroZepelix/mithril-cms/internal/auth"
 	"github.com/GyroZepelix/mithril-cms/internal/schema"
 )
 
@@ -94,6 +97,44 @@ func TestHandler_PublicGet_NonPublicType(t *testing.T) {
 	}
 }
 
+func TestPublicAccess_APIKey(t *testing.T) {
+	posts := schema.ContentType{Name: "posts", PublicRead: true}
+	secrets := schema.ContentType{Name: "secrets"}
+
+	tests := []struct {
+		name    string
+		ct      schema.ContentType
+		key     *auth.APIKey
+		wantOK  bool
+		wantVis Visibility
+	}{
+		{"public without key", posts, nil, true, PublicVisibility},
+		{"private without key", secrets, nil, false, Visibility{}},
+		{"private with scoped key", secrets, &auth.APIKey{ContentTypes: []string{"secrets"}}, true, PublicVisibility},
+		{"private with other key", secrets, &auth.APIKey{ContentTypes: []string{"posts"}, Drafts: true}, false, Visibility{}},
+		{"public with draft key", posts, &auth.APIKey{ContentTypes: []string{"posts"}, Drafts: true}, true, Visibility{Drafts: true, Public: true}},
+		{"public with draft key for other type", posts, &auth.APIKey{ContentTypes: []string{"secrets"}, Drafts: true}, true, PublicVisibility},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/"+tt.ct.Name, nil)
+			if tt.key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), auth.ContextKeyAPIKey, tt.key))
+			}
+			w := httptest.NewRecorder()
+
+			vis, ok := publicAccess(w, req, tt.ct)
+			if ok != tt.wantOK || vis != tt.wantVis {
+				t.Errorf("publicAccess = %+v, %v; want %+v, %v", vis, ok, tt.wantVis, tt.wantOK)
+			}
+			if !ok && w.Code != http.StatusNotFound {
+				t.Errorf("expected 404 when denied, got %d", w.Code)
+			}
+		})
+	}
+}
+
 func TestHandler_DecodeBody_InvalidJSON(t *testing.T) {
 	h := newTestHandler()
 	// We need a real service for AdminCreate, but the body decode happens before
@@ -193,6 +234,47 @@ func TestHandler_AdminGet_InvalidUUID(t *testing.T) {
 	}
 }
 
+func TestHiddenInverse(t *testing.T) {
+	authors := schema.ContentType{
+		Name: "authors",
+		Fields: []schema.Field{
+			{Name: "name", Type: schema.FieldTypeString},
+			{Name: "posts", Type: schema.FieldTypeRelation, RelatesTo: "posts", RelationType: schema.RelationMany, InverseOf: "author"},
+			{Name: "notes", Type: schema.FieldTypeRelation, RelatesTo: "notes", RelationType: schema.RelationMany, InverseOf: "author"},
+		},
+	}
+	perms := auth.Permissions{
+		{Resource: "content:authors", Action: "read"},
+		{Resource: "content:posts", Action: "read"},
+		{Resource: "content:notes", Action: "read", Own: true},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/content/authors", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.ContextKeyPermissions, perms))
+
+	hidden := hiddenInverse(req, authors)
+	if len(hidden) != 1 || !hidden["notes"] {
+		t.Fatalf("hiddenInverse = %v, want only notes", hidden)
+	}
+
+	entry := map[string]any{"name": "Ada", "posts": []string{"p1"}, "notes": []string{"n1"}}
+	hideInverse(hidden, entry)
+	if _, ok := entry["notes"]; ok {
+		t.Error("expected notes to be removed from the entry")
+	}
+	if _, ok := entry["posts"]; !ok {
+		t.Error("expected posts to be kept")
+	}
+
+	err := checkHiddenInverse(authors, hidden, map[string]string{"notes": "n1", "posts": "p1"}, []string{"notes"})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) || len(valErr.Fields) != 2 {
+		t.Fatalf("expected two field errors, got %v", err)
+	}
+	if err := checkHiddenInverse(authors, hidden, map[string]string{"posts": "p1"}, []string{"posts"}); err != nil {
+		t.Errorf("readable inverse field rejected: %v", err)
+	}
+}
+
 func TestHandler_PublicGet_InvalidUUID(t *testing.T) {
 	h := newTestHandler()
 
@@ -216,3 +298,73 @@ func TestHandler_PublicGet_InvalidUUID(t *testing.T) {
 		t.Errorf("expected INVALID_ID code, got %v", errObj["code"])
 	}
 }
+
+func TestHandler_AdminDelete_InvalidUUID(t *testing.T) {
+	h := newTestHandler()
+
+	r := chi.NewRouter()
+	r.Delete("/admin/api/content/{contentType}/{id}", h.AdminDelete)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/api/content/posts/not-a-uuid", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid UUID, got %d", w.Code)
+	}
+}
+
+func TestHandleServiceError_Referenced(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleServiceError(w, &schema.ReferencedError{References: []schema.Reference{
+		{ContentType: "blog_posts", EntryID: "550e8400-e29b-41d4-a716-446655440000", Field: "author"},
+	}})
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	errObj := resp["error"].(map[string]any)
+	if errObj["code"] != "REFERENCED" {
+		t.Errorf("expected REFERENCED code, got %v", errObj["code"])
+	}
+	details := errObj["details"].([]any)
+	if len(details) != 1 {
+		t.Fatalf("expected 1 detail, got %d", len(details))
+	}
+	d := details[0].(map[string]any)
+	if d["field"] != "blog_posts.author" {
+		t.Errorf("expected field blog_posts.author, got %v", d["field"])
+	}
+	if !strings.Contains(d["message"].(string), "550e8400-e29b-41d4-a716-446655440000") {
+		t.Errorf("expected message to contain entry id, got %v", d["message"])
+	}
+}
+
+func TestHandler_AdminHistory_InvalidParams(t *testing.T) {
+	h := newTestHandler()
+
+	r := chi.NewRouter()
+	r.Get("/admin/api/content/{contentType}/{id}/history", h.AdminHistory)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/admin/api/content/nope/550e8400-e29b-41d4-a716-446655440000/history", http.StatusNotFound},
+		{"/admin/api/content/posts/not-a-uuid/history", http.StatusBadRequest},
+		{"/admin/api/content/posts/550e8400-e29b-41d4-a716-446655440000/history?page=0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("GET %s: expected %d, got %d", tt.path, tt.wantStatus, w.Code)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package content
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+)
+
+// History returns a page of an entry's audit log entries, newest first, and
+// their total. Updates, publications and workflow transitions record the
+// changed fields under "changes" in their payload. The history of a deleted
+// entry remains available; ErrNotFound is returned if the content type does
+// not exist, or the entry neither exists nor has history.
+func (s *Service) History(ctx context.Context, contentType, id string, page, perPage int) ([]*audit.AuditEntry, int, error) {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+
+	entries, total := []*audit.AuditEntry{}, 0
+	if s.auditService != nil {
+		var err error
+		entries, total, err = s.auditService.List(ctx, audit.AuditFilters{Resource: ct.Name, ResourceID: id}, page, perPage)
+		if err != nil {
+			return nil, 0, fmt.Errorf("listing %s entry history: %w", contentType, err)
+		}
+	}
+	if total == 0 {
+		if _, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false); err != nil {
+			return nil, 0, fmt.Errorf("listing %s entry history: %w", contentType, err)
+		}
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,37 @@
+package content
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+func TestAuditFields(t *testing.T) {
+	ct := schema.ContentType{
+		Name: "posts",
+		Fields: []schema.Field{
+			{Name: "title", Type: schema.FieldTypeString},
+			{Name: "tags", Type: schema.FieldTypeRelation, RelatesTo: "tags", RelationType: schema.RelationMany},
+		},
+	}
+	entry := map[string]any{
+		"id":           "550e8400-e29b-41d4-a716-446655440000",
+		"status":       "draft",
+		"title":        "Hello",
+		"tags":         []string{"t1"},
+		"updated_at":   time.Now(),
+		"published_at": nil,
+	}
+
+	values := auditFields(ct, entry)
+	if len(values) != 3 || values["title"] != "Hello" || values["status"] != "draft" {
+		t.Errorf("auditFields() = %v, want title, status and published_at", values)
+	}
+	if _, ok := values["published_at"]; !ok {
+		t.Error("auditFields() left out published_at")
+	}
+	if auditFields(ct, nil) != nil {
+		t.Error("auditFields(nil) should return nil")
+	}
+}
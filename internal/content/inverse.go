@@ -0,0 +1,145 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// resolveInverse returns the visible inverse relation fields of ct, keyed by
+// field name. See inverseSources for the visibility rules.
+func (s *Service) resolveInverse(ct schema.ContentType, publishedOnly bool) map[string]referenceSource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return inverseSources(s.schemas, ct, publishedOnly)
+}
+
+// checkInverseParams rejects filters and populate requests on inverse fields
+// that are not visible in the current mode (their referencing content type is
+// not public_read on a public request). Such fields are treated as unknown.
+func checkInverseParams(ct schema.ContentType, inverse map[string]referenceSource, filters map[string]string, populate []string) error {
+	var errs []server.FieldError
+	for _, f := range ct.Fields {
+		if !f.IsInverse() {
+			continue
+		}
+		if _, ok := inverse[f.Name]; ok {
+			continue
+		}
+		if _, ok := filters[f.Name]; ok {
+			errs = append(errs, server.FieldError{Field: "filter[" + f.Name + "]", Message: "unknown field"})
+		}
+		for _, p := range populate {
+			if p == f.Name {
+				errs = append(errs, server.FieldError{Field: "populate", Message: "unknown field " + f.Name})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// attachInverse sets each visible inverse relation field on entries to the
+// list of referencing entry IDs, or to the referencing entries themselves for
+// fields listed in populate. Populated entries are fetched with the same
+// publishedOnly restriction and do not carry inverse fields of their own.
+func (s *Service) attachInverse(ctx context.Context, entries []map[string]any, inverse map[string]referenceSource, populate []string, publishedOnly bool) error {
+	if len(entries) == 0 || len(inverse) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if id, ok := e["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	populated := make(map[string]bool, len(populate))
+	for _, p := range populate {
+		populated[p] = true
+	}
+
+	// Iterate in name order so queries run in a deterministic sequence.
+	names := make([]string, 0, len(inverse))
+	for name := range inverse {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		src := inverse[name]
+		refs, err := s.repo.InverseIDs(ctx, src, ids, publishedOnly)
+		if err != nil {
+			return err
+		}
+
+		if !populated[name] {
+			for _, e := range entries {
+				id, _ := e["id"].(string)
+				list := refs[id]
+				if list == nil {
+					list = []string{}
+				}
+				e[name] = list
+			}
+			continue
+		}
+
+		byID, err := s.fetchReferencing(ctx, src.contentType, refs, publishedOnly)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			id, _ := e["id"].(string)
+			list := make([]map[string]any, 0, len(refs[id]))
+			for _, refID := range refs[id] {
+				if entry, ok := byID[refID]; ok {
+					list = append(list, entry)
+				}
+			}
+			e[name] = list
+		}
+	}
+	return nil
+}
+
+// fetchReferencing loads all entries of contentType named in refs, keyed by ID.
+func (s *Service) fetchReferencing(ctx context.Context, contentType string, refs map[string][]string, publishedOnly bool) (map[string]map[string]any, error) {
+	src, ok := s.getSchema(contentType)
+	if !ok {
+		return nil, fmt.Errorf("content type %q not found", contentType)
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, list := range refs {
+		for _, id := range list {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return map[string]map[string]any{}, nil
+	}
+
+	entries, err := s.repo.GetByIDs(ctx, tableName(src.Name), src.Fields, ids, publishedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("populating %s entries: %w", contentType, err)
+	}
+
+	byID := make(map[string]map[string]any, len(entries))
+	for _, e := range entries {
+		if id, ok := e["id"].(string); ok {
+			byID[id] = e
+		}
+	}
+	return byID, nil
+}
@@ -0,0 +1,123 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+)
+
+// lockTTL is how long an edit lock lasts without a heartbeat. Editors renew
+// their lock by requesting it again before it expires.
+const lockTTL = 2 * time.Minute
+
+// Lock is an editing lease on an entry.
+type Lock struct {
+	AdminID    string    `json:"admin_id"`
+	AdminEmail string    `json:"admin_email"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// LockedError is returned when an entry is locked by another admin.
+type LockedError struct {
+	Lock *Lock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("entry is locked by %s until %s", e.Lock.AdminEmail, e.Lock.ExpiresAt.Format(time.RFC3339))
+}
+
+// AcquireLock takes or renews the edit lock on an entry for adminID. If
+// another admin holds an unexpired lock, a *LockedError is returned unless
+// takeover is set, in which case the lock is taken over and the takeover is
+// audited.
+func (s *Service) AcquireLock(ctx context.Context, contentType, id, adminID string, takeover bool) (*Lock, error) {
+	if _, err := s.Lookup(ctx, contentType, id); err != nil {
+		return nil, err
+	}
+	return s.acquireLock(ctx, contentType, id, adminID, takeover)
+}
+
+func (s *Service) acquireLock(ctx context.Context, contentType, id, adminID string, takeover bool) (*Lock, error) {
+	lock, previous, err := s.repo.AcquireLock(ctx, contentType, id, adminID, lockTTL, takeover)
+	if err != nil {
+		return nil, fmt.Errorf("locking %s entry: %w", contentType, err)
+	}
+	if previous != nil && !takeover {
+		return nil, &LockedError{Lock: previous}
+	}
+
+	if previous != nil {
+		s.logAudit(ctx, audit.Event{
+			Action:     "entry.lock.takeover",
+			ActorID:    adminID,
+			Resource:   contentType,
+			ResourceID: id,
+			Payload: map[string]any{
+				"previous_holder": previous.AdminID,
+				"previous_expiry": previous.ExpiresAt,
+			},
+		})
+	}
+	return lock, nil
+}
+
+// ReleaseLock releases adminID's edit lock on an entry. Releasing an entry
+// that is not locked is not an error; releasing another admin's unexpired
+// lock returns a *LockedError.
+func (s *Service) ReleaseLock(ctx context.Context, contentType, id, adminID string) error {
+	if _, ok := s.getSchema(contentType); !ok {
+		return ErrNotFound
+	}
+
+	holder, err := s.repo.ReleaseLock(ctx, contentType, id, adminID)
+	if err != nil {
+		return fmt.Errorf("unlocking %s entry: %w", contentType, err)
+	}
+	if holder != nil {
+		return &LockedError{Lock: holder}
+	}
+	return nil
+}
+
+// GetLock returns the unexpired edit lock on an entry, or nil if it is not
+// locked.
+func (s *Service) GetLock(ctx context.Context, contentType, id string) (*Lock, error) {
+	if _, err := s.Lookup(ctx, contentType, id); err != nil {
+		return nil, err
+	}
+	lock, err := s.repo.GetLock(ctx, contentType, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s entry lock: %w", contentType, err)
+	}
+	return lock, nil
+}
+
+// checkLock verifies that adminID may write to an entry: the entry must be
+// unlocked or locked by adminID. With takeover, another admin's lock is taken
+// over instead of rejecting the write.
+func (s *Service) checkLock(ctx context.Context, contentType, id, adminID string, takeover bool) error {
+	lock, err := s.repo.GetLock(ctx, contentType, id)
+	if err != nil {
+		return fmt.Errorf("getting %s entry lock: %w", contentType, err)
+	}
+	if lock == nil || lock.AdminID == adminID {
+		return nil
+	}
+	if !takeover {
+		return &LockedError{Lock: lock}
+	}
+	_, err = s.acquireLock(ctx, contentType, id, adminID, true)
+	return err
+}
+
+// deleteLock removes the edit lock of a deleted entry. Failures are only
+// logged: leftover locks expire on their own.
+func (s *Service) deleteLock(ctx context.Context, contentType, id string) {
+	if err := s.repo.DeleteLock(ctx, contentType, id); err != nil {
+		slog.Warn("failed to delete entry lock", "content_type", contentType, "id", id, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package content
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandleServiceError_Locked(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleServiceError(w, &LockedError{Lock: &Lock{
+		AdminID:    "660e8400-e29b-41d4-a716-446655440000",
+		AdminEmail: "jane@example.com",
+		ExpiresAt:  time.Date(2025, 1, 15, 10, 34, 0, 0, time.UTC),
+	}})
+
+	if w.Code != http.StatusLocked {
+		t.Fatalf("expected 423, got %d", w.Code)
+	}
+
+	var resp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details []struct {
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Code != "LOCKED" {
+		t.Errorf("expected LOCKED code, got %s", resp.Error.Code)
+	}
+	if len(resp.Error.Details) != 2 ||
+		resp.Error.Details[0].Field != "locked_by" || resp.Error.Details[0].Message != "jane@example.com" ||
+		resp.Error.Details[1].Field != "expires_at" || resp.Error.Details[1].Message != "2025-01-15T10:34:00Z" {
+		t.Errorf("unexpected details: %+v", resp.Error.Details)
+	}
+}
+
+func TestHandler_Lock_InvalidRequest(t *testing.T) {
+	h := newTestHandler()
+	r := chi.NewRouter()
+	r.Post("/admin/api/content/{contentType}/{id}/lock", h.AdminLock)
+	r.Get("/admin/api/content/{contentType}/{id}/lock", h.AdminGetLock)
+	r.Delete("/admin/api/content/{contentType}/{id}/lock", h.AdminUnlock)
+	r.Put("/admin/api/content/{contentType}/{id}", h.AdminUpdate)
+
+	const id = "550e8400-e29b-41d4-a716-446655440000"
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode string
+	}{
+		{"lock bad id", http.MethodPost, "/admin/api/content/posts/not-a-uuid/lock", "INVALID_ID"},
+		{"get lock bad id", http.MethodGet, "/admin/api/content/posts/not-a-uuid/lock", "INVALID_ID"},
+		{"unlock bad id", http.MethodDelete, "/admin/api/content/posts/not-a-uuid/lock", "INVALID_ID"},
+		{"lock bad takeover", http.MethodPost, "/admin/api/content/posts/" + id + "/lock?takeover=maybe", "INVALID_PARAMS"},
+		{"update bad takeover", http.MethodPut, "/admin/api/content/posts/" + id + "?takeover=maybe", "INVALID_PARAMS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"title": "x"}`))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("expected %s, got %s", tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
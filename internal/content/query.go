@@ -20,6 +20,12 @@ type QueryParams struct {
 	Order   string            // "asc" or "desc"
 	Filters map[string]string // field name -> value
 	Search  string            // full-text search query (Task 8)
+	// Populate lists inverse relation fields whose referencing entries are
+	// embedded in full instead of as IDs.
+	Populate []string
+	// Render selects the output format of richtext fields: "" for the stored
+	// markdown, or RenderHTML.
+	Render string
 }
 
 // systemSortColumns are columns that exist on every content table and are
@@ -34,49 +40,64 @@ var systemSortColumns = map[string]bool{
 	"updated_by":   true,
 }
 
-// ParseQueryParams extracts and validates query parameters from the request
-// URL against the given content type schema.
-func ParseQueryParams(r *http.Request, ct schema.ContentType) (QueryParams, error) {
-	q := QueryParams{
-		Page:    1,
-		PerPage: 20,
-		Sort:    "created_at",
-		Order:   "desc",
-		Filters: make(map[string]string),
-	}
-
+// ParsePagination extracts the page (default 1) and per_page (default 20,
+// capped at 100) query parameters.
+func ParsePagination(r *http.Request) (page, perPage int, err error) {
+	page, perPage = 1, 20
 	query := r.URL.Query()
 
-	// Parse page.
 	if v := query.Get("page"); v != "" {
-		page, err := strconv.Atoi(v)
+		page, err = strconv.Atoi(v)
 		if err != nil || page < 1 {
-			return q, fmt.Errorf("page must be a positive integer")
+			return 1, 20, fmt.Errorf("page must be a positive integer")
 		}
-		q.Page = page
 	}
 
-	// Parse per_page.
 	if v := query.Get("per_page"); v != "" {
-		perPage, err := strconv.Atoi(v)
+		perPage, err = strconv.Atoi(v)
 		if err != nil || perPage < 1 {
-			return q, fmt.Errorf("per_page must be a positive integer")
+			return 1, 20, fmt.Errorf("per_page must be a positive integer")
 		}
 		if perPage > 100 {
 			perPage = 100
 		}
-		q.PerPage = perPage
 	}
 
+	return page, perPage, nil
+}
+
+// ParseQueryParams extracts and validates query parameters from the request
+// URL against the given content type schema.
+func ParseQueryParams(r *http.Request, ct schema.ContentType) (QueryParams, error) {
+	q := QueryParams{
+		Page:    1,
+		PerPage: 20,
+		Sort:    "created_at",
+		Order:   "desc",
+		Filters: make(map[string]string),
+	}
+
+	query := r.URL.Query()
+
+	page, perPage, err := ParsePagination(r)
+	if err != nil {
+		return q, err
+	}
+	q.Page, q.PerPage = page, perPage
+
 	// Build field name lookup for validation.
 	fieldNames := make(map[string]bool, len(ct.Fields))
+	inverseFields := make(map[string]bool)
 	for _, f := range ct.Fields {
 		fieldNames[f.Name] = true
+		if f.IsInverse() {
+			inverseFields[f.Name] = true
+		}
 	}
 
-	// Parse sort.
+	// Parse sort. Inverse relations have no column to sort by.
 	if v := query.Get("sort"); v != "" {
-		if !fieldNames[v] && !systemSortColumns[v] {
+		if (!fieldNames[v] || inverseFields[v]) && !systemSortColumns[v] {
 			return q, fmt.Errorf("invalid sort field: %s", v)
 		}
 		q.Sort = v
@@ -101,12 +122,69 @@ func ParseQueryParams(r *http.Request, ct schema.ContentType) (QueryParams, erro
 			return q, fmt.Errorf("invalid filter field: %s", fieldName)
 		}
 		if len(values) > 0 {
+			// Inverse relation filters match entries referenced by the given entry.
+			if inverseFields[fieldName] && !isValidUUID(values[0]) {
+				return q, fmt.Errorf("filter[%s] must be a valid UUID", fieldName)
+			}
 			q.Filters[fieldName] = values[0]
 		}
 	}
 
+	populate, err := ParsePopulate(r, ct)
+	if err != nil {
+		return q, err
+	}
+	q.Populate = populate
+
+	render, err := ParseRender(r)
+	if err != nil {
+		return q, err
+	}
+	q.Render = render
+
 	// Parse search query (captured here, implemented in Task 8).
 	q.Search = query.Get("q")
 
 	return q, nil
 }
+
+// ParsePopulate parses the comma-separated "populate" query parameter. Only
+// inverse relation fields can be populated.
+func ParsePopulate(r *http.Request, ct schema.ContentType) ([]string, error) {
+	v := r.URL.Query().Get("populate")
+	if v == "" {
+		return nil, nil
+	}
+
+	inverseFields := make(map[string]bool)
+	for _, f := range ct.Fields {
+		if f.IsInverse() {
+			inverseFields[f.Name] = true
+		}
+	}
+
+	var populate []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if !inverseFields[name] {
+			return nil, fmt.Errorf("populate is only supported on inverse relation fields: %s", name)
+		}
+		seen[name] = true
+		populate = append(populate, name)
+	}
+	return populate, nil
+}
+
+// ParseRender parses the "render" query parameter, which selects the output
+// format of richtext fields.
+func ParseRender(r *http.Request) (string, error) {
+	v := r.URL.Query().Get("render")
+	if v != "" && v != RenderHTML {
+		return "", fmt.Errorf("render must be '%s'", RenderHTML)
+	}
+	return v, nil
+}
@@ -140,3 +140,59 @@ func TestParseQueryParams_Search(t *testing.T) {
 		t.Errorf("search: got %q, want 'hello world'", q.Search)
 	}
 }
+
+var inverseCT = schema.ContentType{
+	Name: "authors",
+	Fields: []schema.Field{
+		{Name: "name", Type: schema.FieldTypeString},
+		{Name: "posts", Type: schema.FieldTypeRelation, RelatesTo: "posts", InverseOf: "author"},
+	},
+}
+
+func TestParseQueryParams_InverseField(t *testing.T) {
+	t.Run("sort rejected", func(t *testing.T) {
+		if _, err := ParseQueryParams(newRequest("sort=posts"), inverseCT); err == nil {
+			t.Error("expected error sorting by inverse field")
+		}
+	})
+
+	t.Run("filter requires uuid", func(t *testing.T) {
+		if _, err := ParseQueryParams(newRequest("filter[posts]=abc"), inverseCT); err == nil {
+			t.Error("expected error for non-UUID inverse filter")
+		}
+		q, err := ParseQueryParams(newRequest("filter[posts]=550e8400-e29b-41d4-a716-446655440000"), inverseCT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Filters["posts"] != "550e8400-e29b-41d4-a716-446655440000" {
+			t.Errorf("posts filter: got %q", q.Filters["posts"])
+		}
+	})
+
+	t.Run("populate", func(t *testing.T) {
+		q, err := ParseQueryParams(newRequest("populate=posts,posts"), inverseCT)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(q.Populate) != 1 || q.Populate[0] != "posts" {
+			t.Errorf("populate: got %v, want [posts]", q.Populate)
+		}
+		if _, err := ParseQueryParams(newRequest("populate=name"), inverseCT); err == nil {
+			t.Error("expected error populating a non-inverse field")
+		}
+	})
+}
+
+func TestParseQueryParams_Render(t *testing.T) {
+	q, err := ParseQueryParams(newRequest("render=html"), testCT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Render != RenderHTML {
+		t.Errorf("render: got %q, want %q", q.Render, RenderHTML)
+	}
+
+	if _, err := ParseQueryParams(newRequest("render=pdf"), testCT); err == nil {
+		t.Error("expected error for unsupported render format")
+	}
+}
@@ -0,0 +1,186 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+const (
+	// maxBlockingReferencesPerField caps how many referencing entries are
+	// reported per field when a delete is blocked, keeping 409 responses small.
+	maxBlockingReferencesPerField = 10
+
+	// maxListedReferencesPerField caps how many referencing entries are
+	// returned per field by the references endpoints.
+	maxListedReferencesPerField = 100
+)
+
+// referenceSource describes where references from one schema field are stored:
+// the content table column for media/relation-one fields, or the junction
+// table for relation-many fields.
+type referenceSource struct {
+	contentType string
+	field       string
+	table       string
+	idColumn    string
+	refColumn   string
+}
+
+// fromClause returns a FROM clause that joins the reference storage to the
+// referencing content table (aliased "s"), together with the qualified
+// expression for the column holding the referenced record's ID. For
+// media/relation-one fields both are the content table itself.
+func (src referenceSource) fromClause() (from, refExpr string) {
+	srcTable := tableName(src.contentType)
+	if src.table == srcTable {
+		return fmt.Sprintf("FROM %s s", schema.QuoteIdent(srcTable)),
+			"s." + schema.QuoteIdent(src.refColumn)
+	}
+	return fmt.Sprintf("FROM %s r JOIN %s s ON s.%s = r.%s",
+			schema.QuoteIdent(src.table), schema.QuoteIdent(srcTable),
+			schema.QuoteIdent("id"), schema.QuoteIdent(src.idColumn)),
+		"r." + schema.QuoteIdent(src.refColumn)
+}
+
+// sourceFor returns where the references held by field f of content type
+// ctName are stored.
+func sourceFor(ctName string, f schema.Field) referenceSource {
+	if f.Type == schema.FieldTypeRelation && f.RelationType == schema.RelationMany {
+		return referenceSource{
+			contentType: ctName,
+			field:       f.Name,
+			table:       schema.JunctionTableName(ctName, f.Name),
+			idColumn:    "source_id",
+			refColumn:   "target_id",
+		}
+	}
+	return referenceSource{
+		contentType: ctName,
+		field:       f.Name,
+		table:       tableName(ctName),
+		idColumn:    "id",
+		refColumn:   f.Name,
+	}
+}
+
+// referenceSources returns the storage locations of all fields across the
+// given schemas for which match returns true, sorted by content type name and
+// then field order for deterministic output. Inverse relations are never
+// returned since they hold no references of their own.
+func referenceSources(schemas map[string]schema.ContentType, match func(schema.Field) bool) []referenceSource {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sources []referenceSource
+	for _, name := range names {
+		for _, f := range schemas[name].Fields {
+			if f.IsInverse() || !match(f) {
+				continue
+			}
+			sources = append(sources, sourceFor(name, f))
+		}
+	}
+	return sources
+}
+
+// inverseSources resolves the inverse relation fields of ct to the storage of
+// their forward fields, keyed by inverse field name. When publicOnly is true,
+// inverse fields whose referencing content type is not public_read are left
+// out so that non-public entries are never exposed through public responses.
+func inverseSources(schemas map[string]schema.ContentType, ct schema.ContentType, publicOnly bool) map[string]referenceSource {
+	sources := make(map[string]referenceSource)
+	for _, f := range ct.Fields {
+		if !f.IsInverse() {
+			continue
+		}
+		src, ok := schemas[f.RelatesTo]
+		if !ok || (publicOnly && !src.PublicRead) {
+			continue
+		}
+		for _, forward := range src.Fields {
+			if forward.Name == f.InverseOf {
+				sources[f.Name] = sourceFor(src.Name, forward)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// findReferences lists the entries that reference targetID through any field
+// matched by match, returning at most limit entries per field.
+func (s *Service) findReferences(ctx context.Context, targetID string, limit int, match func(schema.Field) bool) ([]schema.Reference, error) {
+	s.mu.RLock()
+	sources := referenceSources(s.schemas, match)
+	s.mu.RUnlock()
+
+	refs := []schema.Reference{}
+	for _, src := range sources {
+		ids, err := s.repo.ReferencingIDs(ctx, src.table, src.idColumn, src.refColumn, targetID, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			refs = append(refs, schema.Reference{ContentType: src.contentType, EntryID: id, Field: src.field})
+		}
+	}
+	return refs, nil
+}
+
+// MediaReferences lists the entries that reference a media file. When
+// blockingOnly is true, only references through media fields with
+// on_delete: restrict are returned, i.e. those that prevent deleting it.
+func (s *Service) MediaReferences(ctx context.Context, mediaID string, blockingOnly bool) ([]schema.Reference, error) {
+	limit := maxListedReferencesPerField
+	if blockingOnly {
+		limit = maxBlockingReferencesPerField
+	}
+	return s.findReferences(ctx, mediaID, limit, func(f schema.Field) bool {
+		return f.Type == schema.FieldTypeMedia &&
+			(!blockingOnly || f.EffectiveOnDelete() == schema.OnDeleteRestrict)
+	})
+}
+
+// EntryReferences lists every entry, across all content types that canRead
+// allows, that references the given entry through a relation field
+// (including relation-many junction tables).
+func (s *Service) EntryReferences(ctx context.Context, contentType, id string, canRead func(contentType string) bool) ([]schema.Reference, error) {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if _, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false); err != nil {
+		return nil, fmt.Errorf("getting %s entry: %w", contentType, err)
+	}
+
+	refs, err := s.findReferences(ctx, id, maxListedReferencesPerField, func(f schema.Field) bool {
+		return f.Type == schema.FieldTypeRelation && f.RelatesTo == ct.Name
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing references to %s entry: %w", contentType, err)
+	}
+	readable := refs[:0]
+	for _, ref := range refs {
+		if canRead(ref.ContentType) {
+			readable = append(readable, ref)
+		}
+	}
+	return readable, nil
+}
+
+// blockingEntryReferences lists the entries that prevent an entry of
+// contentType from being deleted, i.e. those referencing it through a
+// relation field with on_delete: restrict.
+func (s *Service) blockingEntryReferences(ctx context.Context, contentType, id string) ([]schema.Reference, error) {
+	return s.findReferences(ctx, id, maxBlockingReferencesPerField, func(f schema.Field) bool {
+		return f.Type == schema.FieldTypeRelation && f.RelatesTo == contentType &&
+			f.EffectiveOnDelete() == schema.OnDeleteRestrict
+	})
+}
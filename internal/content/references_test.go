@@ -0,0 +1,147 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+func TestReferenceSources(t *testing.T) {
+	schemas := map[string]schema.ContentType{
+		"posts": {
+			Name: "posts",
+			Fields: []schema.Field{
+				{Name: "title", Type: schema.FieldTypeString},
+				{Name: "author", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationOne, OnDelete: schema.OnDeleteRestrict},
+				{Name: "editors", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationMany, OnDelete: schema.OnDeleteRestrict},
+				{Name: "reviewer", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationOne},
+			},
+		},
+		"books": {
+			Name: "books",
+			Fields: []schema.Field{
+				{Name: "writer", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationOne, OnDelete: schema.OnDeleteRestrict},
+			},
+		},
+	}
+
+	sources := referenceSources(schemas, func(f schema.Field) bool {
+		return f.Type == schema.FieldTypeRelation && f.RelatesTo == "authors" &&
+			f.EffectiveOnDelete() == schema.OnDeleteRestrict
+	})
+
+	want := []referenceSource{
+		{contentType: "books", field: "writer", table: "ct_books", idColumn: "id", refColumn: "writer"},
+		{contentType: "posts", field: "author", table: "ct_posts", idColumn: "id", refColumn: "author"},
+		{contentType: "posts", field: "editors", table: "ct_posts_editors_rel", idColumn: "source_id", refColumn: "target_id"},
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("expected %d sources, got %d: %+v", len(want), len(sources), sources)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Errorf("sources[%d] = %+v, want %+v", i, sources[i], want[i])
+		}
+	}
+}
+
+func inverseTestSchemas() map[string]schema.ContentType {
+	return map[string]schema.ContentType{
+		"authors": {
+			Name:       "authors",
+			PublicRead: true,
+			Fields: []schema.Field{
+				{Name: "name", Type: schema.FieldTypeString},
+				{Name: "posts", Type: schema.FieldTypeRelation, RelatesTo: "posts", InverseOf: "author"},
+				{Name: "edited", Type: schema.FieldTypeRelation, RelatesTo: "posts", InverseOf: "editors"},
+				{Name: "notes", Type: schema.FieldTypeRelation, RelatesTo: "notes", InverseOf: "author"},
+			},
+		},
+		"posts": {
+			Name:       "posts",
+			PublicRead: true,
+			Fields: []schema.Field{
+				{Name: "author", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationOne},
+				{Name: "editors", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationMany},
+			},
+		},
+		"notes": {
+			Name:       "notes",
+			PublicRead: false,
+			Fields: []schema.Field{
+				{Name: "author", Type: schema.FieldTypeRelation, RelatesTo: "authors", RelationType: schema.RelationOne},
+			},
+		},
+	}
+}
+
+func TestInverseSources(t *testing.T) {
+	schemas := inverseTestSchemas()
+
+	admin := inverseSources(schemas, schemas["authors"], false)
+	if len(admin) != 3 {
+		t.Fatalf("expected 3 inverse sources, got %d: %+v", len(admin), admin)
+	}
+	want := referenceSource{contentType: "posts", field: "author", table: "ct_posts", idColumn: "id", refColumn: "author"}
+	if admin["posts"] != want {
+		t.Errorf("posts = %+v, want %+v", admin["posts"], want)
+	}
+	want = referenceSource{contentType: "posts", field: "editors", table: "ct_posts_editors_rel", idColumn: "source_id", refColumn: "target_id"}
+	if admin["edited"] != want {
+		t.Errorf("edited = %+v, want %+v", admin["edited"], want)
+	}
+
+	// Inverse fields backed by non-public content types are hidden publicly.
+	public := inverseSources(schemas, schemas["authors"], true)
+	if _, ok := public["notes"]; ok {
+		t.Error("expected notes to be hidden from public responses")
+	}
+	if len(public) != 2 {
+		t.Errorf("expected 2 public inverse sources, got %d", len(public))
+	}
+}
+
+func TestReferenceSources_SkipsInverseFields(t *testing.T) {
+	schemas := inverseTestSchemas()
+	sources := referenceSources(schemas, func(f schema.Field) bool {
+		return f.Type == schema.FieldTypeRelation && f.RelatesTo == "posts"
+	})
+	if len(sources) != 0 {
+		t.Errorf("inverse fields must not be reported as references, got %+v", sources)
+	}
+}
+
+func TestCheckInverseParams(t *testing.T) {
+	schemas := inverseTestSchemas()
+	ct := schemas["authors"]
+	public := inverseSources(schemas, ct, true)
+
+	if err := checkInverseParams(ct, public, map[string]string{"posts": "x"}, []string{"edited"}); err != nil {
+		t.Errorf("expected visible inverse fields to be accepted, got %v", err)
+	}
+
+	err := checkInverseParams(ct, public, map[string]string{"notes": "x"}, []string{"notes"})
+	valErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	if len(valErr.Fields) != 2 {
+		t.Errorf("expected 2 field errors, got %v", valErr.Fields)
+	}
+}
+
+func TestInverseFilterClause(t *testing.T) {
+	one := referenceSource{contentType: "posts", field: "author", table: "ct_posts", idColumn: "id", refColumn: "author"}
+	got := inverseFilterClause(one, 2, true)
+	want := `"id" IN (SELECT s."author" FROM "ct_posts" s WHERE s."id" = $2 AND s."status" = 'published')`
+	if got != want {
+		t.Errorf("inverseFilterClause(one) =\n%s\nwant\n%s", got, want)
+	}
+
+	many := referenceSource{contentType: "posts", field: "editors", table: "ct_posts_editors_rel", idColumn: "source_id", refColumn: "target_id"}
+	got = inverseFilterClause(many, 1, false)
+	want = `"id" IN (SELECT r."target_id" FROM "ct_posts_editors_rel" r JOIN "ct_posts" s ON s."id" = r."source_id" WHERE s."id" = $1)`
+	if got != want {
+		t.Errorf("inverseFilterClause(many) =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -29,11 +30,12 @@ func NewRepository(db *database.DB) *Repository {
 }
 
 // allColumns returns the list of all columns to SELECT for a content type:
-// id, status, user-defined fields (excluding many-relations), then system columns.
+// id, status, user-defined fields (excluding many-relations and inverse
+// relations, which have no column), then system columns.
 func allColumns(fields []schema.Field) []string {
 	cols := []string{"id", "status"}
 	for _, f := range fields {
-		if f.Type == schema.FieldTypeRelation && f.RelationType == schema.RelationMany {
+		if !f.HasColumn() {
 			continue
 		}
 		cols = append(cols, f.Name)
@@ -87,8 +89,10 @@ func normalizeRows(rows []map[string]any) []map[string]any {
 	return rows
 }
 
-// List retrieves a paginated list of content entries with optional filtering and sorting.
-func (r *Repository) List(ctx context.Context, tableName string, fields []schema.Field, q QueryParams, publishedOnly bool) ([]map[string]any, int, error) {
+// List retrieves a paginated list of content entries with optional filtering
+// and sorting. Filters on inverse relation fields are resolved through the
+// matching entry in inverse, selecting entries referenced by the given ID.
+func (r *Repository) List(ctx context.Context, tableName string, fields []schema.Field, q QueryParams, inverse map[string]referenceSource, publishedOnly, inversePublishedOnly bool) ([]map[string]any, int, error) {
 	cols := allColumns(fields)
 	qTable := schema.QuoteIdent(tableName)
 
@@ -111,7 +115,11 @@ func (r *Repository) List(ctx context.Context, tableName string, fields []schema
 	sort.Strings(filterKeys)
 
 	for _, field := range filterKeys {
-		whereParts = append(whereParts, fmt.Sprintf("%s = $%d", schema.QuoteIdent(field), argIdx))
+		if src, ok := inverse[field]; ok {
+			whereParts = append(whereParts, inverseFilterClause(src, argIdx, inversePublishedOnly))
+		} else {
+			whereParts = append(whereParts, fmt.Sprintf("%s = $%d", schema.QuoteIdent(field), argIdx))
+		}
 		args = append(args, q.Filters[field])
 		argIdx++
 	}
@@ -188,6 +196,75 @@ func (r *Repository) List(ctx context.Context, tableName string, fields []schema
 	return normalizeRows(entries), total, nil
 }
 
+// inverseFilterClause returns a WHERE condition matching entries that are
+// referenced by the entry whose ID is bound to placeholder argIdx through the
+// forward field described by src.
+func inverseFilterClause(src referenceSource, argIdx int, publishedOnly bool) string {
+	from, refExpr := src.fromClause()
+	cond := fmt.Sprintf("s.%s = $%d", schema.QuoteIdent("id"), argIdx)
+	if publishedOnly {
+		cond += fmt.Sprintf(" AND s.%s = 'published'", schema.QuoteIdent("status"))
+	}
+	return fmt.Sprintf("%s IN (SELECT %s %s WHERE %s)", schema.QuoteIdent("id"), refExpr, from, cond)
+}
+
+// InverseIDs returns, for each of targetIDs, the IDs of the entries that
+// reference it through the forward field described by src, ordered by the
+// referencing entries' creation time. Targets without references are absent
+// from the map.
+func (r *Repository) InverseIDs(ctx context.Context, src referenceSource, targetIDs []string, publishedOnly bool) (map[string][]string, error) {
+	from, refExpr := src.fromClause()
+	cond := fmt.Sprintf("%s = ANY($1::text[]::uuid[])", refExpr)
+	if publishedOnly {
+		cond += fmt.Sprintf(" AND s.%s = 'published'", schema.QuoteIdent("status"))
+	}
+	sql := fmt.Sprintf("SELECT %s::text, s.%s::text %s WHERE %s ORDER BY s.%s, s.%s",
+		refExpr, schema.QuoteIdent("id"), from, cond,
+		schema.QuoteIdent("created_at"), schema.QuoteIdent("id"))
+
+	rows, err := r.db.Pool().Query(ctx, sql, targetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("querying inverse relation %s.%s: %w", src.contentType, src.field, err)
+	}
+	defer rows.Close()
+
+	result := make(map[string][]string)
+	for rows.Next() {
+		var target, source string
+		if err := rows.Scan(&target, &source); err != nil {
+			return nil, fmt.Errorf("scanning inverse relation %s.%s: %w", src.contentType, src.field, err)
+		}
+		result[target] = append(result[target], source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating inverse relation %s.%s: %w", src.contentType, src.field, err)
+	}
+	return result, nil
+}
+
+// GetByIDs retrieves the content entries with the given UUIDs. Missing IDs
+// (or unpublished ones when publishedOnly is true) are silently skipped.
+func (r *Repository) GetByIDs(ctx context.Context, tableName string, fields []schema.Field, ids []string, publishedOnly bool) ([]map[string]any, error) {
+	whereClause := fmt.Sprintf("WHERE %s = ANY($1::text[]::uuid[])", schema.QuoteIdent("id"))
+	if publishedOnly {
+		whereClause += fmt.Sprintf(" AND %s = 'published'", schema.QuoteIdent("status"))
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s %s",
+		quotedColumns(allColumns(fields)), schema.QuoteIdent(tableName), whereClause)
+
+	rows, err := r.db.Pool().Query(ctx, sql, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToMap)
+	if err != nil {
+		return nil, fmt.Errorf("scanning entries: %w", err)
+	}
+	return normalizeRows(entries), nil
+}
+
 // GetByID retrieves a single content entry by UUID.
 func (r *Repository) GetByID(ctx context.Context, tableName string, fields []schema.Field, id string, publishedOnly bool) (map[string]any, error) {
 	cols := allColumns(fields)
@@ -231,7 +308,7 @@ func (r *Repository) Insert(ctx context.Context, tableName string, fields []sche
 	argIdx := 1
 
 	for _, f := range fields {
-		if f.Type == schema.FieldTypeRelation && f.RelationType == schema.RelationMany {
+		if !f.HasColumn() {
 			continue
 		}
 		val, ok := data[f.Name]
@@ -272,16 +349,67 @@ func (r *Repository) Insert(ctx context.Context, tableName string, fields []sche
 	return normalizeRow(entry), nil
 }
 
-// Update modifies an existing content entry and returns the full updated row.
-func (r *Repository) Update(ctx context.Context, tableName string, fields []schema.Field, id string, data map[string]any, adminID string) (map[string]any, error) {
+// Update modifies an existing content entry. The stored entry is read and
+// locked in the same transaction, including the target IDs of its
+// relation-many fields, and passed to check before anything is written; an
+// error from check aborts the update and is returned unchanged. It returns
+// the entry as it was before the update and the full updated row.
+func (r *Repository) Update(ctx context.Context, tableName, ctName string, fields []schema.Field, id string, data map[string]any, adminID string, check func(existing map[string]any) error) (before, after map[string]any, err error) {
 	qTable := schema.QuoteIdent(tableName)
+	returnCols := allColumns(fields)
+
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("beginning update tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 FOR UPDATE",
+		quotedColumns(returnCols), qTable, schema.QuoteIdent("id")), id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying entry: %w", err)
+	}
+	existing, err := pgx.CollectOneRow(rows, pgx.RowToMap)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("scanning entry: %w", err)
+	}
+	before = normalizeRow(existing)
+
+	for _, f := range fields {
+		if f.Type != schema.FieldTypeRelation || f.RelationType != schema.RelationMany || f.IsInverse() {
+			continue
+		}
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s::text FROM %s WHERE %s = $1 ORDER BY 1",
+			schema.QuoteIdent("target_id"),
+			schema.QuoteIdent(schema.JunctionTableName(ctName, f.Name)),
+			schema.QuoteIdent("source_id"),
+		), id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("querying relation %s: %w", f.Name, err)
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, nil, fmt.Errorf("scanning relation %s: %w", f.Name, err)
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		before[f.Name] = ids
+	}
+
+	if err := check(before); err != nil {
+		return nil, nil, err
+	}
 
 	var setParts []string
 	var args []any
 	argIdx := 1
 
 	for _, f := range fields {
-		if f.Type == schema.FieldTypeRelation && f.RelationType == schema.RelationMany {
+		if !f.HasColumn() {
 			continue
 		}
 		val, ok := data[f.Name]
@@ -302,8 +430,6 @@ func (r *Repository) Update(ctx context.Context, tableName string, fields []sche
 	// ID for WHERE clause.
 	args = append(args, id)
 
-	returnCols := allColumns(fields)
-
 	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d RETURNING %s",
 		qTable,
 		strings.Join(setParts, ", "),
@@ -312,21 +438,22 @@ func (r *Repository) Update(ctx context.Context, tableName string, fields []sche
 		quotedColumns(returnCols),
 	)
 
-	rows, err := r.db.Pool().Query(ctx, sql, args...)
+	rows, err = tx.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("updating entry: %w", err)
+		return nil, nil, fmt.Errorf("updating entry: %w", err)
 	}
-	defer rows.Close()
-
 	entry, err := pgx.CollectOneRow(rows, pgx.RowToMap)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
+			return nil, nil, ErrNotFound
 		}
-		return nil, fmt.Errorf("scanning updated entry: %w", err)
+		return nil, nil, fmt.Errorf("scanning updated entry: %w", err)
 	}
 
-	return normalizeRow(entry), nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("committing update: %w", err)
+	}
+	return before, normalizeRow(entry), nil
 }
 
 // Publish sets an entry's status to 'published' and published_at to now().
@@ -360,3 +487,308 @@ func (r *Repository) Publish(ctx context.Context, tableName string, fields []sch
 
 	return normalizeRow(entry), nil
 }
+
+// Delete removes a content entry by UUID. Returns ErrNotFound if no row was
+// deleted. Foreign key violations from ON DELETE RESTRICT references are
+// returned wrapped so callers can detect them with database.IsForeignKeyViolation.
+func (r *Repository) Delete(ctx context.Context, tableName, id string) error {
+	sql := fmt.Sprintf("DELETE FROM %s WHERE %s = $1",
+		schema.QuoteIdent(tableName), schema.QuoteIdent("id"))
+
+	tag, err := r.db.Pool().Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("deleting entry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreatedBy returns the ID of the admin who created the entry, or nil if that
+// admin has been deleted. Returns ErrNotFound if the entry does not exist.
+func (r *Repository) CreatedBy(ctx context.Context, tableName, id string) (*string, error) {
+	sql := fmt.Sprintf("SELECT %s::text FROM %s WHERE %s = $1",
+		schema.QuoteIdent("created_by"), schema.QuoteIdent(tableName), schema.QuoteIdent("id"))
+
+	var createdBy *string
+	if err := r.db.Pool().QueryRow(ctx, sql, id).Scan(&createdBy); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting entry creator: %w", err)
+	}
+	return createdBy, nil
+}
+
+// ReferencingIDs returns up to limit values of idColumn from tableName for
+// rows whose refColumn equals targetID, ordered for stable output. It is used
+// to list the entries that point at a record through a media column, a
+// relation-one column (idColumn "id"), or a junction table (idColumn
+// "source_id", refColumn "target_id").
+func (r *Repository) ReferencingIDs(ctx context.Context, tableName, idColumn, refColumn, targetID string, limit int) ([]string, error) {
+	sql := fmt.Sprintf("SELECT %s::text FROM %s WHERE %s = $1 ORDER BY 1 LIMIT %d",
+		schema.QuoteIdent(idColumn),
+		schema.QuoteIdent(tableName),
+		schema.QuoteIdent(refColumn),
+		limit,
+	)
+
+	rows, err := r.db.Pool().Query(ctx, sql, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("querying references in %s: %w", tableName, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("scanning references in %s: %w", tableName, err)
+	}
+	return ids, nil
+}
+
+// WorkflowState returns the stored workflow stage of an entry, or nil if the
+// entry has none (it is in its workflow's initial stage).
+func (r *Repository) WorkflowState(ctx context.Context, ctName, id string) (*stageRow, error) {
+	row := r.db.Pool().QueryRow(ctx,
+		`SELECT entry_id::text, stage, updated_by::text, updated_at
+		 FROM workflow_states WHERE content_type = $1 AND entry_id = $2`,
+		ctName, id,
+	)
+
+	var s stageRow
+	if err := row.Scan(&s.EntryID, &s.Stage, &s.UpdatedBy, &s.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("querying workflow state: %w", err)
+	}
+	return &s, nil
+}
+
+// Transition moves an entry from one workflow stage to another in a single
+// transaction. The move only succeeds if the entry is still in stage from;
+// otherwise errStageChanged is returned. fromInitial indicates that from is
+// the workflow's initial stage, which entries without a stored stage are in.
+// A non-empty status ("published" or "draft") is written to the entry as
+// well, setting published_at when publishing.
+func (r *Repository) Transition(ctx context.Context, tableName, ctName, id, from, to string, fromInitial bool, status, adminID string) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transition tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	var sql string
+	if fromInitial {
+		sql = `INSERT INTO workflow_states (content_type, entry_id, stage, updated_by)
+		       VALUES ($1, $2, $4, $5)
+		       ON CONFLICT (content_type, entry_id) DO UPDATE
+		       SET stage = EXCLUDED.stage, updated_by = EXCLUDED.updated_by, updated_at = now()
+		       WHERE workflow_states.stage = $3`
+	} else {
+		sql = `UPDATE workflow_states SET stage = $4, updated_by = $5, updated_at = now()
+		       WHERE content_type = $1 AND entry_id = $2 AND stage = $3`
+	}
+	tag, err := tx.Exec(ctx, sql, ctName, id, from, to, adminID)
+	if err != nil {
+		return fmt.Errorf("updating workflow state: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errStageChanged
+	}
+
+	if status != "" {
+		set := fmt.Sprintf("%s = $3", schema.QuoteIdent("status"))
+		if status == "published" {
+			set += fmt.Sprintf(", %s = now()", schema.QuoteIdent("published_at"))
+		}
+		sql := fmt.Sprintf("UPDATE %s SET %s, %s = $2, %s = now() WHERE %s = $1",
+			schema.QuoteIdent(tableName),
+			set,
+			schema.QuoteIdent("updated_by"),
+			schema.QuoteIdent("updated_at"),
+			schema.QuoteIdent("id"),
+		)
+		tag, err := tx.Exec(ctx, sql, id, adminID, status)
+		if err != nil {
+			return fmt.Errorf("updating entry status: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transition: %w", err)
+	}
+	return nil
+}
+
+// WorkflowQueue returns the workflow states of existing entries of a content
+// type that are in one of the given stages.
+func (r *Repository) WorkflowQueue(ctx context.Context, tableName, ctName string, stages []string) ([]stageRow, error) {
+	sql := fmt.Sprintf(`SELECT w.entry_id::text, w.stage, w.updated_by::text, w.updated_at
+		FROM workflow_states w JOIN %s e ON e.%s = w.entry_id
+		WHERE w.content_type = $1 AND w.stage = ANY($2::text[])`,
+		schema.QuoteIdent(tableName), schema.QuoteIdent("id"))
+
+	rows, err := r.db.Pool().Query(ctx, sql, ctName, stages)
+	if err != nil {
+		return nil, fmt.Errorf("querying workflow queue: %w", err)
+	}
+
+	states, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (stageRow, error) {
+		var s stageRow
+		err := row.Scan(&s.EntryID, &s.Stage, &s.UpdatedBy, &s.UpdatedAt)
+		return s, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning workflow queue: %w", err)
+	}
+	return states, nil
+}
+
+// DeleteWorkflowState removes the stored workflow stage of an entry. It is
+// not an error if none exists.
+func (r *Repository) DeleteWorkflowState(ctx context.Context, ctName, id string) error {
+	_, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM workflow_states WHERE content_type = $1 AND entry_id = $2`,
+		ctName, id,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting workflow state: %w", err)
+	}
+	return nil
+}
+
+// lockColumns selects a Lock from entry_locks l joined with admins a.
+const lockColumns = `l.admin_id::text, a.email, l.acquired_at, l.expires_at`
+
+func scanLock(row pgx.Row) (*Lock, error) {
+	var l Lock
+	if err := row.Scan(&l.AdminID, &l.AdminEmail, &l.AcquiredAt, &l.ExpiresAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetLock returns the unexpired edit lock on an entry, or nil if none exists.
+func (r *Repository) GetLock(ctx context.Context, ctName, id string) (*Lock, error) {
+	lock, err := scanLock(r.db.Pool().QueryRow(ctx,
+		`SELECT `+lockColumns+`
+		 FROM entry_locks l JOIN admins a ON a.id = l.admin_id
+		 WHERE l.content_type = $1 AND l.entry_id = $2 AND l.expires_at > now()`,
+		ctName, id,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("querying entry lock: %w", err)
+	}
+	return lock, nil
+}
+
+// AcquireLock takes or renews adminID's edit lock on an entry for ttl.
+// Renewing keeps the original acquired_at. previous is the unexpired lock of
+// another admin that was in place, if any; in that case the lock is only
+// replaced when takeover is set, and lock is nil otherwise. Expired locks are
+// purged as a side effect.
+func (r *Repository) AcquireLock(ctx context.Context, ctName, id, adminID string, ttl time.Duration, takeover bool) (lock, previous *Lock, err error) {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("beginning lock tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is harmless
+
+	if _, err := tx.Exec(ctx, `DELETE FROM entry_locks WHERE expires_at <= now()`); err != nil {
+		return nil, nil, fmt.Errorf("purging expired locks: %w", err)
+	}
+
+	current, err := scanLock(tx.QueryRow(ctx,
+		`SELECT `+lockColumns+`
+		 FROM entry_locks l JOIN admins a ON a.id = l.admin_id
+		 WHERE l.content_type = $1 AND l.entry_id = $2
+		 FOR UPDATE OF l`,
+		ctName, id,
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying entry lock: %w", err)
+	}
+	if current != nil && current.AdminID != adminID {
+		previous = current
+		if !takeover {
+			return nil, previous, nil
+		}
+	}
+
+	// The conflict guard covers a lock inserted concurrently after the
+	// SELECT above found none.
+	lock, err = scanLock(tx.QueryRow(ctx,
+		`WITH l AS (
+		     INSERT INTO entry_locks (content_type, entry_id, admin_id, expires_at)
+		     VALUES ($1, $2, $3, now() + $4 * interval '1 millisecond')
+		     ON CONFLICT (content_type, entry_id) DO UPDATE
+		     SET admin_id = EXCLUDED.admin_id,
+		         acquired_at = CASE WHEN entry_locks.admin_id = EXCLUDED.admin_id
+		                            THEN entry_locks.acquired_at ELSE now() END,
+		         expires_at = EXCLUDED.expires_at
+		     WHERE entry_locks.admin_id = EXCLUDED.admin_id OR $5
+		     RETURNING admin_id, acquired_at, expires_at
+		 )
+		 SELECT `+lockColumns+` FROM l JOIN admins a ON a.id = l.admin_id`,
+		ctName, id, adminID, ttl.Milliseconds(), takeover,
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("acquiring entry lock: %w", err)
+	}
+	if lock == nil {
+		// Lost the race to another admin; report their lock.
+		held, err := scanLock(tx.QueryRow(ctx,
+			`SELECT `+lockColumns+`
+			 FROM entry_locks l JOIN admins a ON a.id = l.admin_id
+			 WHERE l.content_type = $1 AND l.entry_id = $2`,
+			ctName, id,
+		))
+		if err != nil {
+			return nil, nil, fmt.Errorf("querying entry lock: %w", err)
+		}
+		return nil, held, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("committing lock: %w", err)
+	}
+	return lock, previous, nil
+}
+
+// ReleaseLock deletes adminID's edit lock on an entry, along with any expired
+// lock. If another admin holds an unexpired lock it is left in place and
+// returned.
+func (r *Repository) ReleaseLock(ctx context.Context, ctName, id, adminID string) (*Lock, error) {
+	tag, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM entry_locks
+		 WHERE content_type = $1 AND entry_id = $2
+		   AND (admin_id = $3 OR expires_at <= now())`,
+		ctName, id, adminID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("releasing entry lock: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil, nil
+	}
+	return r.GetLock(ctx, ctName, id)
+}
+
+// DeleteLock removes any edit lock on an entry.
+func (r *Repository) DeleteLock(ctx context.Context, ctName, id string) error {
+	_, err := r.db.Pool().Exec(ctx,
+		`DELETE FROM entry_locks WHERE content_type = $1 AND entry_id = $2`,
+		ctName, id,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting entry lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package content
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/richtext"
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+// renderCacheSize is the maximum number of rendered richtext values kept in
+// memory.
+const renderCacheSize = 4096
+
+// RenderHTML is the only supported value of the render query parameter.
+const RenderHTML = "html"
+
+// fieldPolicy returns the sanitization policy of a richtext field.
+func fieldPolicy(f schema.Field) *richtext.Policy {
+	if f.Sanitize == nil {
+		return richtext.NewPolicy(nil, nil)
+	}
+	return richtext.NewPolicy(f.Sanitize.AllowedTags, f.Sanitize.AllowedAttributes)
+}
+
+// sanitizeRichText applies each richtext field's HTML policy to the values
+// in data. Non-string values are left for validation to reject.
+func sanitizeRichText(ct schema.ContentType, data map[string]any) {
+	for _, f := range ct.Fields {
+		if f.Type != schema.FieldTypeRichText {
+			continue
+		}
+		if s, ok := data[f.Name].(string); ok {
+			data[f.Name] = fieldPolicy(f).SanitizeMarkdown(s)
+		}
+	}
+}
+
+// RenderRichText replaces the markdown in each entry's richtext fields with
+// sanitized HTML. Rendered values are cached per entry and field, keyed by
+// the entry's updated_at, so an edit invalidates them.
+func (s *Service) RenderRichText(contentType string, entries []map[string]any) {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return
+	}
+
+	var fields []schema.Field
+	for _, f := range ct.Fields {
+		if f.Type == schema.FieldTypeRichText {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	for _, entry := range entries {
+		version, cacheable := entry["updated_at"].(time.Time)
+		for _, f := range fields {
+			src, ok := entry[f.Name].(string)
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("%s/%v/%s", ct.Name, entry["id"], f.Name)
+			if cacheable {
+				if html, hit := s.renderCache.Get(key, version); hit {
+					entry[f.Name] = html
+					continue
+				}
+			}
+			html := fieldPolicy(f).Sanitize(richtext.RenderMarkdown(src))
+			if cacheable {
+				s.renderCache.Put(key, version, html)
+			}
+			entry[f.Name] = html
+		}
+	}
+}
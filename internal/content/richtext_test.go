@@ -0,0 +1,92 @@
+package content
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+var richtextCT = schema.ContentType{
+	Name: "posts",
+	Fields: []schema.Field{
+		{Name: "title", Type: schema.FieldTypeString},
+		{Name: "body", Type: schema.FieldTypeRichText},
+		{Name: "summary", Type: schema.FieldTypeRichText, Sanitize: &schema.SanitizePolicy{
+			AllowedTags: []string{"p", "em"},
+		}},
+	},
+}
+
+func TestSanitizeRichText(t *testing.T) {
+	data := map[string]any{
+		"title":   "<script>kept: not richtext</script>",
+		"body":    "Hi <script>alert(1)</script><a href=\"javascript:x\" onclick=\"y\">link</a>\n\n```\n<script>example</script>\n```",
+		"summary": "<p><strong>bold</strong> <em>em</em></p>",
+	}
+	sanitizeRichText(richtextCT, data)
+
+	if data["title"] != "<script>kept: not richtext</script>" {
+		t.Errorf("title changed: %q", data["title"])
+	}
+	if want := "Hi <a>link</a>\n\n```\n<script>example</script>\n```"; data["body"] != want {
+		t.Errorf("body = %q, want %q", data["body"], want)
+	}
+	if want := "<p>bold <em>em</em></p>"; data["summary"] != want {
+		t.Errorf("summary = %q, want %q", data["summary"], want)
+	}
+
+	// Non-string values are left for validation.
+	bad := map[string]any{"body": int64(1)}
+	sanitizeRichText(richtextCT, bad)
+	if bad["body"] != int64(1) {
+		t.Errorf("non-string body changed: %v", bad["body"])
+	}
+}
+
+func TestService_RenderRichText(t *testing.T) {
+	s := NewService(nil, map[string]schema.ContentType{"posts": richtextCT}, nil)
+	updated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entry := map[string]any{
+		"id":         "550e8400-e29b-41d4-a716-446655440000",
+		"title":      "*not rendered*",
+		"body":       "# Hi\n\n*there* <img src=x onerror=alert(1)>",
+		"summary":    "**bold** *em*",
+		"updated_at": updated,
+	}
+	s.RenderRichText("posts", []map[string]any{entry})
+
+	if entry["title"] != "*not rendered*" {
+		t.Errorf("title changed: %q", entry["title"])
+	}
+	if want := "<h1>Hi</h1>\n<p><em>there</em> <img src=\"x\"></p>\n"; entry["body"] != want {
+		t.Errorf("body = %q, want %q", entry["body"], want)
+	}
+	// The field policy also applies to rendered output.
+	if want := "<p>bold <em>em</em></p>\n"; entry["summary"] != want {
+		t.Errorf("summary = %q, want %q", entry["summary"], want)
+	}
+
+	// Same updated_at: served from the cache even though the source differs.
+	again := map[string]any{"id": entry["id"], "body": "changed", "updated_at": updated}
+	s.RenderRichText("posts", []map[string]any{again})
+	if again["body"] != entry["body"] {
+		t.Errorf("expected cached render, got %q", again["body"])
+	}
+
+	// A newer updated_at renders again.
+	edited := map[string]any{"id": entry["id"], "body": "changed", "updated_at": updated.Add(time.Second)}
+	s.RenderRichText("posts", []map[string]any{edited})
+	if edited["body"] != "<p>changed</p>\n" {
+		t.Errorf("expected fresh render, got %q", edited["body"])
+	}
+
+	// A schema refresh drops cached renders.
+	s.UpdateSchemas(map[string]schema.ContentType{"posts": richtextCT})
+	stale := map[string]any{"id": entry["id"], "body": "refreshed", "updated_at": updated.Add(time.Second)}
+	s.RenderRichText("posts", []map[string]any{stale})
+	if stale["body"] != "<p>refreshed</p>\n" {
+		t.Errorf("expected render after schema refresh, got %q", stale["body"])
+	}
+}
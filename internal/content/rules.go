@@ -0,0 +1,254 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// compareOperatorText maps compare operators to the phrase used in default
+// error messages ("must be <phrase> <other>").
+var compareOperatorText = map[schema.CompareOperator]string{
+	schema.CompareEq:  "equal to",
+	schema.CompareNe:  "different from",
+	schema.CompareLt:  "before",
+	schema.CompareLte: "on or before",
+	schema.CompareGt:  "after",
+	schema.CompareGte: "on or after",
+}
+
+// compareNumberText is used instead of compareOperatorText for numeric fields.
+var compareNumberText = map[schema.CompareOperator]string{
+	schema.CompareEq:  "equal to",
+	schema.CompareNe:  "different from",
+	schema.CompareLt:  "less than",
+	schema.CompareLte: "at most",
+	schema.CompareGt:  "greater than",
+	schema.CompareGte: "at least",
+}
+
+// ValidateRules evaluates the content type's cross-field rules against the
+// complete entry values. On update, data must already be merged with the
+// stored entry (see mergeForRules) so that rules see the resulting entry.
+// Rules whose operands fail per-field validation are skipped; those errors
+// are reported by ValidateEntry.
+func ValidateRules(ct schema.ContentType, data map[string]any) []server.FieldError {
+	if len(ct.Rules) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]schema.Field, len(ct.Fields))
+	for _, f := range ct.Fields {
+		fields[f.Name] = f
+	}
+
+	var errs []server.FieldError
+	for _, r := range ct.Rules {
+		switch r.Type {
+		case schema.RuleRequiredIf:
+			when := fields[r.When]
+			if !ruleTriggered(when, data[r.When], r.Equals) || isSet(data[r.Field]) {
+				continue
+			}
+			msg := r.Message
+			if msg == "" {
+				if r.Equals != nil {
+					msg = fmt.Sprintf("is required when %s is %v", r.When, r.Equals)
+				} else {
+					msg = fmt.Sprintf("is required when %s is set", r.When)
+				}
+			}
+			errs = append(errs, server.FieldError{Field: r.Field, Message: msg})
+
+		case schema.RuleCompare:
+			a, aOK := normalizeRuleValue(fields[r.Field], data[r.Field])
+			b, bOK := normalizeRuleValue(fields[r.Other], data[r.Other])
+			if !aOK || !bOK {
+				continue // Missing or invalid operands are not compared.
+			}
+			if compareHolds(r.Operator, a, b) {
+				continue
+			}
+			msg := r.Message
+			if msg == "" {
+				phrase := compareOperatorText[r.Operator]
+				if _, isNum := a.(float64); isNum {
+					phrase = compareNumberText[r.Operator]
+				}
+				msg = fmt.Sprintf("must be %s %s", phrase, r.Other)
+			}
+			errs = append(errs, server.FieldError{Field: r.Field, Message: msg})
+
+		case schema.RuleAtLeastOne:
+			satisfied := false
+			for _, name := range r.Fields {
+				if isSet(data[name]) {
+					satisfied = true
+					break
+				}
+			}
+			if satisfied {
+				continue
+			}
+			msg := r.Message
+			if msg == "" {
+				msg = fmt.Sprintf("at least one of %s is required", strings.Join(r.Fields, ", "))
+			}
+			for _, name := range r.Fields {
+				errs = append(errs, server.FieldError{Field: name, Message: msg})
+			}
+		}
+	}
+	return errs
+}
+
+// mergeForRules overlays the submitted update data on the stored entry, which
+// includes the target IDs of its relation-many fields, so rules are evaluated
+// against the entry as it will be after the update.
+func mergeForRules(existing, data map[string]any) map[string]any {
+	merged := make(map[string]any, len(existing)+len(data))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range data {
+		merged[k] = v
+	}
+	return merged
+}
+
+// isSet reports whether a value counts as provided: not null, not an empty
+// string or list, and not false.
+func isSet(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case string:
+		return val != ""
+	case bool:
+		return val
+	case []any:
+		return len(val) > 0
+	case []string:
+		return len(val) > 0
+	default:
+		return true
+	}
+}
+
+// ruleTriggered reports whether a required_if rule applies given the value of
+// its when field. Without an equals value the rule applies whenever the
+// field is set.
+func ruleTriggered(when schema.Field, value, equals any) bool {
+	if equals == nil {
+		return isSet(value)
+	}
+	got, ok := normalizeRuleValue(when, value)
+	if !ok {
+		return false
+	}
+	want, ok := normalizeRuleValue(when, equals)
+	if !ok {
+		return false
+	}
+	return got == want
+}
+
+// normalizeRuleValue converts a field value from either a request body or a
+// stored row into a comparable form: float64 for numbers, "YYYY-MM-DD" for
+// dates, "HH:MM:SS" for times, and the value itself for strings and booleans.
+// Returns false for missing values and values of the wrong type.
+func normalizeRuleValue(f schema.Field, v any) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+	switch f.Type {
+	case schema.FieldTypeInt, schema.FieldTypeFloat:
+		n, ok := toFloat64(v)
+		return n, ok
+	case schema.FieldTypeBoolean:
+		b, ok := v.(bool)
+		return b, ok
+	case schema.FieldTypeDate:
+		switch d := v.(type) {
+		case time.Time:
+			return d.Format("2006-01-02"), true
+		case string:
+			t, err := time.Parse("2006-01-02", d)
+			if err != nil {
+				return nil, false
+			}
+			return t.Format("2006-01-02"), true
+		}
+		return nil, false
+	case schema.FieldTypeTime:
+		switch t := v.(type) {
+		case pgtype.Time:
+			if !t.Valid {
+				return nil, false
+			}
+			d := time.Duration(t.Microseconds) * time.Microsecond
+			return time.Time{}.Add(d).Format("15:04:05"), true
+		case string:
+			parsed, err := time.Parse("15:04:05", t)
+			if err != nil {
+				if parsed, err = time.Parse("15:04", t); err != nil {
+					return nil, false
+				}
+			}
+			return parsed.Format("15:04:05"), true
+		}
+		return nil, false
+	default:
+		s, ok := v.(string)
+		return s, ok
+	}
+}
+
+// compareHolds evaluates "a <op> b" for normalized values of the same kind.
+// Dates and times are normalized to fixed-width strings, so they compare
+// correctly as strings.
+func compareHolds(op schema.CompareOperator, a, b any) bool {
+	var cmp int
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return true
+		}
+		switch {
+		case av < bv:
+			cmp = -1
+		case av > bv:
+			cmp = 1
+		}
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return true
+		}
+		cmp = strings.Compare(av, bv)
+	default:
+		return true
+	}
+
+	switch op {
+	case schema.CompareEq:
+		return cmp == 0
+	case schema.CompareNe:
+		return cmp != 0
+	case schema.CompareLt:
+		return cmp < 0
+	case schema.CompareLte:
+		return cmp <= 0
+	case schema.CompareGt:
+		return cmp > 0
+	case schema.CompareGte:
+		return cmp >= 0
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package content
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+var rulesCT = schema.ContentType{
+	Name: "events",
+	Fields: []schema.Field{
+		{Name: "featured", Type: schema.FieldTypeBoolean},
+		{Name: "cta_url", Type: schema.FieldTypeString},
+		{Name: "start_date", Type: schema.FieldTypeDate},
+		{Name: "end_date", Type: schema.FieldTypeDate},
+		{Name: "min_seats", Type: schema.FieldTypeInt},
+		{Name: "max_seats", Type: schema.FieldTypeInt},
+		{Name: "image", Type: schema.FieldTypeMedia},
+		{Name: "video", Type: schema.FieldTypeString},
+	},
+	Rules: []schema.Rule{
+		{Type: schema.RuleRequiredIf, Field: "cta_url", When: "featured", Equals: true},
+		{Type: schema.RuleCompare, Field: "end_date", Operator: schema.CompareGt, Other: "start_date"},
+		{Type: schema.RuleCompare, Field: "max_seats", Operator: schema.CompareGte, Other: "min_seats"},
+		{Type: schema.RuleAtLeastOne, Fields: []string{"image", "video"}},
+	},
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       map[string]any
+		wantFields []string
+	}{
+		{"all satisfied", map[string]any{"featured": true, "cta_url": "https://x", "start_date": "2024-01-01", "end_date": "2024-01-02", "video": "v"}, nil},
+		{"required_if triggered", map[string]any{"featured": true, "video": "v"}, []string{"cta_url"}},
+		{"required_if empty string", map[string]any{"featured": true, "cta_url": "", "video": "v"}, []string{"cta_url"}},
+		{"required_if not triggered", map[string]any{"featured": false, "video": "v"}, nil},
+		{"compare dates fails", map[string]any{"start_date": "2024-01-02", "end_date": "2024-01-02", "video": "v"}, []string{"end_date"}},
+		{"compare skipped when operand missing", map[string]any{"end_date": "2024-01-02", "video": "v"}, nil},
+		{"compare skipped when operand invalid", map[string]any{"start_date": "bad", "end_date": "2024-01-02", "video": "v"}, nil},
+		{"compare numbers", map[string]any{"min_seats": int64(10), "max_seats": int64(5), "video": "v"}, []string{"max_seats"}},
+		{"at_least_one missing", map[string]any{}, []string{"image", "video"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateRules(rulesCT, tt.data)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.wantFields), len(errs), errs)
+			}
+			for i, f := range tt.wantFields {
+				if errs[i].Field != f {
+					t.Errorf("errs[%d].Field = %q, want %q", i, errs[i].Field, f)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRules_Messages(t *testing.T) {
+	errs := ValidateRules(rulesCT, map[string]any{
+		"featured":   true,
+		"start_date": "2024-02-01",
+		"end_date":   "2024-01-01",
+		"min_seats":  int64(3),
+		"max_seats":  int64(1),
+		"image":      "550e8400-e29b-41d4-a716-446655440000",
+	})
+	want := map[string]string{
+		"cta_url":   "is required when featured is true",
+		"end_date":  "must be after start_date",
+		"max_seats": "must be at least min_seats",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for _, e := range errs {
+		if want[e.Field] != e.Message {
+			t.Errorf("%s: message = %q, want %q", e.Field, e.Message, want[e.Field])
+		}
+	}
+
+	custom := rulesCT
+	custom.Rules = []schema.Rule{{Type: schema.RuleAtLeastOne, Fields: []string{"image", "video"}, Message: "add media"}}
+	errs = ValidateRules(custom, map[string]any{})
+	if len(errs) != 2 || errs[0].Message != "add media" {
+		t.Errorf("expected custom message on both fields, got %v", errs)
+	}
+}
+
+func TestValidateRules_MergedUpdate(t *testing.T) {
+	// Stored values come back from pgx as native types; a partial update
+	// changing only end_date must be compared against the stored start_date.
+	existing := map[string]any{
+		"start_date": time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		"end_date":   time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+		"min_seats":  int32(10),
+		"video":      "v",
+	}
+
+	errs := ValidateRules(rulesCT, mergeForRules(existing, map[string]any{"end_date": "2024-03-09"}))
+	if len(errs) != 1 || errs[0].Field != "end_date" {
+		t.Errorf("expected end_date error, got %v", errs)
+	}
+
+	errs = ValidateRules(rulesCT, mergeForRules(existing, map[string]any{"max_seats": int64(12)}))
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	// Clearing the only media value on update violates at_least_one.
+	errs = ValidateRules(rulesCT, mergeForRules(existing, map[string]any{"video": nil}))
+	if len(errs) != 2 {
+		t.Errorf("expected at_least_one errors, got %v", errs)
+	}
+}
+
+func TestMergeForRules_RelationMany(t *testing.T) {
+	// The stored entry carries the target IDs of relation-many fields, which
+	// an update that does not send them must keep.
+	existing := map[string]any{"title": "a", "tags": []string{"t1", "t2"}}
+
+	merged := mergeForRules(existing, map[string]any{"title": "b"})
+	if tags, _ := merged["tags"].([]string); len(tags) != 2 {
+		t.Errorf("expected stored tags to be kept, got %v", merged["tags"])
+	}
+
+	merged = mergeForRules(existing, map[string]any{"tags": []any{}})
+	if tags, _ := merged["tags"].([]any); tags == nil || len(tags) != 0 {
+		t.Errorf("expected submitted tags to replace stored ones, got %v", merged["tags"])
+	}
+}
+
+func TestNormalizeRuleValue_Time(t *testing.T) {
+	f := schema.Field{Name: "t", Type: schema.FieldTypeTime}
+
+	got, ok := normalizeRuleValue(f, "09:30")
+	if !ok || got != "09:30:00" {
+		t.Errorf("normalize(\"09:30\") = %v, %v", got, ok)
+	}
+	stored := pgtype.Time{Microseconds: int64((9*time.Hour + 30*time.Minute) / time.Microsecond), Valid: true}
+	got, ok = normalizeRuleValue(f, stored)
+	if !ok || got != "09:30:00" {
+		t.Errorf("normalize(pgtype.Time) = %v, %v", got, ok)
+	}
+}
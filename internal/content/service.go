@@ -2,10 +2,13 @@ package content
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/database"
+	"github.com/GyroZepelix/mithril-cms/internal/richtext"
 	"github.com/GyroZepelix/mithril-cms/internal/schema"
 	"github.com/GyroZepelix/mithril-cms/internal/server"
 )
@@ -16,8 +19,14 @@ type Service struct {
 	mu           sync.RWMutex
 	schemas      map[string]schema.ContentType
 	auditService *audit.Service
+	renderCache  *richtext.Cache
+	deleteHooks  []DeleteHook
 }
 
+// DeleteHook is called after an entry has been deleted, so that other
+// packages can remove data attached to it.
+type DeleteHook func(ctx context.Context, contentType, id string)
+
 // NewService creates a new content Service. The audit service is optional;
 // if nil, audit events are silently skipped.
 func NewService(repo *Repository, schemas map[string]schema.ContentType, auditService *audit.Service) *Service {
@@ -25,6 +34,7 @@ func NewService(repo *Repository, schemas map[string]schema.ContentType, auditSe
 		repo:         repo,
 		schemas:      schemas,
 		auditService: auditService,
+		renderCache:  richtext.NewCache(renderCacheSize),
 	}
 }
 
@@ -34,6 +44,21 @@ func (s *Service) UpdateSchemas(schemas map[string]schema.ContentType) {
 	s.mu.Lock()
 	s.schemas = schemas
 	s.mu.Unlock()
+
+	// Sanitization policies may have changed.
+	s.renderCache.Purge()
+}
+
+// OnDelete registers a hook to run after each entry deletion. Hooks must be
+// registered before the service handles requests.
+func (s *Service) OnDelete(hook DeleteHook) {
+	s.deleteHooks = append(s.deleteHooks, hook)
+}
+
+// HasContentType reports whether a content type with the given name exists.
+func (s *Service) HasContentType(name string) bool {
+	_, ok := s.getSchema(name)
+	return ok
 }
 
 // getSchema safely retrieves a schema by name with read locking.
@@ -51,6 +76,41 @@ func (s *Service) logAudit(ctx context.Context, event audit.Event) {
 	}
 }
 
+// logChange sends an audit event recording the entry's field changes from
+// before to after under "changes" in its payload, if the audit service is
+// configured.
+func (s *Service) logChange(ctx context.Context, ct schema.ContentType, event audit.Event, before, after map[string]any) {
+	if s.auditService == nil {
+		return
+	}
+	if changes := s.auditService.Diff(ct.Name, auditFields(ct, before), auditFields(ct, after)); changes != nil {
+		if event.Payload == nil {
+			event.Payload = make(map[string]any, 1)
+		}
+		event.Payload["changes"] = changes
+	}
+	s.auditService.Log(ctx, event)
+}
+
+// auditFields returns the values of an entry that audit events record
+// changes of: its stored fields, status and published_at. Bookkeeping
+// columns such as updated_at change with every write and are left out.
+func auditFields(ct schema.ContentType, entry map[string]any) map[string]any {
+	if entry == nil {
+		return nil
+	}
+	values := map[string]any{
+		"status":       entry["status"],
+		"published_at": entry["published_at"],
+	}
+	for _, f := range ct.Fields {
+		if f.HasColumn() {
+			values[f.Name] = entry[f.Name]
+		}
+	}
+	return values
+}
+
 // tableName returns the PostgreSQL table name for a content type.
 func tableName(ctName string) string {
 	return "ct_" + ctName
@@ -65,36 +125,105 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("validation failed: %d field errors", len(e.Fields))
 }
 
+// Visibility selects the entries a read can see.
+type Visibility struct {
+	// Drafts includes unpublished entries of the requested content type.
+	Drafts bool
+	// Public restricts inverse relations to public content types and their
+	// published entries.
+	Public bool
+}
+
+// Visibilities of admin reads and anonymous public API reads.
+var (
+	AdminVisibility  = Visibility{Drafts: true}
+	PublicVisibility = Visibility{Public: true}
+)
+
 // List retrieves a paginated list of content entries.
-func (s *Service) List(ctx context.Context, contentType string, q QueryParams, publishedOnly bool) ([]map[string]any, int, error) {
+func (s *Service) List(ctx context.Context, contentType string, q QueryParams, vis Visibility) ([]map[string]any, int, error) {
 	ct, ok := s.getSchema(contentType)
 	if !ok {
 		return nil, 0, ErrNotFound
 	}
 
-	entries, total, err := s.repo.List(ctx, tableName(ct.Name), ct.Fields, q, publishedOnly)
+	inverse := s.resolveInverse(ct, vis.Public)
+	if err := checkInverseParams(ct, inverse, q.Filters, q.Populate); err != nil {
+		return nil, 0, err
+	}
+
+	entries, total, err := s.repo.List(ctx, tableName(ct.Name), ct.Fields, q, inverse, !vis.Drafts, vis.Public)
 	if err != nil {
 		return nil, 0, fmt.Errorf("listing %s entries: %w", contentType, err)
 	}
 
+	if err := s.attachInverse(ctx, entries, inverse, q.Populate, vis.Public); err != nil {
+		return nil, 0, fmt.Errorf("listing %s entries: %w", contentType, err)
+	}
+
 	return entries, total, nil
 }
 
-// GetByID retrieves a single content entry by ID.
-func (s *Service) GetByID(ctx context.Context, contentType, id string, publishedOnly bool) (map[string]any, error) {
+// GetByID retrieves a single content entry by ID. Inverse relation fields
+// listed in populate are embedded as full entries instead of IDs.
+func (s *Service) GetByID(ctx context.Context, contentType, id string, populate []string, vis Visibility) (map[string]any, error) {
 	ct, ok := s.getSchema(contentType)
 	if !ok {
 		return nil, ErrNotFound
 	}
 
-	entry, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, publishedOnly)
+	inverse := s.resolveInverse(ct, vis.Public)
+	if err := checkInverseParams(ct, inverse, nil, populate); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, !vis.Drafts)
 	if err != nil {
 		return nil, fmt.Errorf("getting %s entry: %w", contentType, err)
 	}
 
+	if err := s.attachInverse(ctx, []map[string]any{entry}, inverse, populate, vis.Public); err != nil {
+		return nil, fmt.Errorf("getting %s entry: %w", contentType, err)
+	}
+
 	return entry, nil
 }
 
+// Lookup returns the content type of an existing entry, or ErrNotFound if
+// the content type or entry does not exist.
+func (s *Service) Lookup(ctx context.Context, contentType, id string) (schema.ContentType, error) {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return schema.ContentType{}, ErrNotFound
+	}
+	if _, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false); err != nil {
+		return schema.ContentType{}, fmt.Errorf("looking up %s entry: %w", contentType, err)
+	}
+	return ct, nil
+}
+
+// ErrNotOwner is returned by CheckOwner when the admin did not create the
+// entry.
+var ErrNotOwner = errors.New("only the entry's creator may do this")
+
+// CheckOwner returns ErrNotOwner unless the entry was created by the admin,
+// or ErrNotFound if the content type or entry does not exist. It enforces
+// permissions granted only on an admin's own entries.
+func (s *Service) CheckOwner(ctx context.Context, contentType, id, adminID string) error {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return ErrNotFound
+	}
+	createdBy, err := s.repo.CreatedBy(ctx, tableName(ct.Name), id)
+	if err != nil {
+		return err
+	}
+	if createdBy == nil || *createdBy != adminID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
 // Create validates and inserts a new content entry as a draft.
 func (s *Service) Create(ctx context.Context, contentType string, data map[string]any, adminID string) (map[string]any, error) {
 	ct, ok := s.getSchema(contentType)
@@ -102,7 +231,10 @@ func (s *Service) Create(ctx context.Context, contentType string, data map[strin
 		return nil, ErrNotFound
 	}
 
-	if errs := ValidateEntry(ct, data, false); len(errs) > 0 {
+	sanitizeRichText(ct, data)
+	errs := ValidateEntry(ct, data, false)
+	errs = append(errs, ValidateRules(ct, data)...)
+	if len(errs) > 0 {
 		return nil, &ValidationError{Fields: errs}
 	}
 
@@ -110,6 +242,9 @@ func (s *Service) Create(ctx context.Context, contentType string, data map[strin
 	if err != nil {
 		return nil, fmt.Errorf("creating %s entry: %w", contentType, err)
 	}
+	if err := s.attachInverse(ctx, []map[string]any{entry}, s.resolveInverse(ct, false), nil, false); err != nil {
+		return nil, fmt.Errorf("creating %s entry: %w", contentType, err)
+	}
 
 	if id, ok := entry["id"].(string); ok {
 		s.logAudit(ctx, audit.Event{
@@ -123,50 +258,124 @@ func (s *Service) Create(ctx context.Context, contentType string, data map[strin
 	return entry, nil
 }
 
-// Update validates and updates an existing content entry.
-func (s *Service) Update(ctx context.Context, contentType, id string, data map[string]any, adminID string) (map[string]any, error) {
+// Update validates and updates an existing content entry. Writes to an entry
+// locked by another admin fail with a *LockedError unless takeover is set.
+// Published entries of content types with a workflow can only be updated
+// with canPublish (see checkPublishedEdit).
+func (s *Service) Update(ctx context.Context, contentType, id string, data map[string]any, adminID string, takeover, canPublish bool) (map[string]any, error) {
 	ct, ok := s.getSchema(contentType)
 	if !ok {
 		return nil, ErrNotFound
 	}
-
-	if errs := ValidateEntry(ct, data, true); len(errs) > 0 {
-		return nil, &ValidationError{Fields: errs}
+	if err := s.checkLock(ctx, ct.Name, id, adminID, takeover); err != nil {
+		return nil, err
 	}
 
-	entry, err := s.repo.Update(ctx, tableName(ct.Name), ct.Fields, id, data, adminID)
+	sanitizeRichText(ct, data)
+	fieldErrs := ValidateEntry(ct, data, true)
+
+	// Rules apply to the entry as a whole, so a partial update is checked
+	// against the stored values it does not change. The stored entry stays
+	// locked until the update is written, so a concurrent update cannot
+	// change the values the rules were checked against.
+	existing, entry, err := s.repo.Update(ctx, tableName(ct.Name), ct.Name, ct.Fields, id, data, adminID, func(existing map[string]any) error {
+		if err := checkPublishedEdit(ct, existing, canPublish); err != nil {
+			return err
+		}
+		errs := append(fieldErrs, ValidateRules(ct, mergeForRules(existing, data))...)
+		if len(errs) > 0 {
+			return &ValidationError{Fields: errs}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("updating %s entry: %w", contentType, err)
 	}
+	if err := s.attachInverse(ctx, []map[string]any{entry}, s.resolveInverse(ct, false), nil, false); err != nil {
+		return nil, fmt.Errorf("updating %s entry: %w", contentType, err)
+	}
 
-	s.logAudit(ctx, audit.Event{
+	s.logChange(ctx, ct, audit.Event{
 		Action:     "entry.update",
 		ActorID:    adminID,
 		Resource:   contentType,
 		ResourceID: id,
-	})
+	}, existing, entry)
 
 	return entry, nil
 }
 
-// Publish sets an entry's status to 'published'.
+// Publish sets an entry's status to 'published'. Entries of content types
+// with a workflow can only be published through a transition, so
+// ErrWorkflowRequired is returned for them.
 func (s *Service) Publish(ctx context.Context, contentType, id, adminID string) (map[string]any, error) {
 	ct, ok := s.getSchema(contentType)
 	if !ok {
 		return nil, ErrNotFound
 	}
+	if ct.Workflow != nil {
+		return nil, ErrWorkflowRequired
+	}
+
+	var existing map[string]any
+	if s.auditService != nil {
+		var err error
+		existing, err = s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false)
+		if err != nil {
+			return nil, fmt.Errorf("publishing %s entry: %w", contentType, err)
+		}
+	}
 
 	entry, err := s.repo.Publish(ctx, tableName(ct.Name), ct.Fields, id, adminID)
 	if err != nil {
 		return nil, fmt.Errorf("publishing %s entry: %w", contentType, err)
 	}
+	if err := s.attachInverse(ctx, []map[string]any{entry}, s.resolveInverse(ct, false), nil, false); err != nil {
+		return nil, fmt.Errorf("publishing %s entry: %w", contentType, err)
+	}
 
-	s.logAudit(ctx, audit.Event{
+	s.logChange(ctx, ct, audit.Event{
 		Action:     "entry.publish",
 		ActorID:    adminID,
 		Resource:   contentType,
 		ResourceID: id,
-	})
+	}, existing, entry)
 
 	return entry, nil
 }
+
+// Delete permanently removes a content entry. Entries referencing it are
+// handled according to each referencing field's on_delete action; if any
+// on_delete: restrict reference exists the delete is rejected with a
+// *schema.ReferencedError listing the blocking entries.
+func (s *Service) Delete(ctx context.Context, contentType, id, adminID string) error {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if err := s.repo.Delete(ctx, tableName(ct.Name), id); err != nil {
+		if database.IsForeignKeyViolation(err) {
+			refs, refErr := s.blockingEntryReferences(ctx, ct.Name, id)
+			if refErr != nil {
+				return fmt.Errorf("listing references to %s entry: %w", contentType, refErr)
+			}
+			return &schema.ReferencedError{References: refs}
+		}
+		return fmt.Errorf("deleting %s entry: %w", contentType, err)
+	}
+	s.deleteWorkflowState(ctx, ct, id)
+	s.deleteLock(ctx, contentType, id)
+	for _, hook := range s.deleteHooks {
+		hook(ctx, contentType, id)
+	}
+
+	s.logAudit(ctx, audit.Event{
+		Action:     "entry.delete",
+		ActorID:    adminID,
+		Resource:   contentType,
+		ResourceID: id,
+	})
+
+	return nil
+}
@@ -45,6 +45,17 @@ func ValidateEntry(ct schema.ContentType, data map[string]any, isUpdate bool) []
 
 	// Validate each schema field.
 	for _, f := range ct.Fields {
+		// Inverse relations are computed from the referencing side.
+		if f.IsInverse() {
+			if _, present := data[f.Name]; present {
+				errs = append(errs, server.FieldError{
+					Field:   f.Name,
+					Message: fmt.Sprintf("is read-only (inverse of %s.%s)", f.RelatesTo, f.InverseOf),
+				})
+			}
+			continue
+		}
+
 		// Skip many-to-many relations (handled separately, not direct columns).
 		if f.Type == schema.FieldTypeRelation && f.RelationType == schema.RelationMany {
 			continue
@@ -83,6 +94,7 @@ func validateFieldValue(f schema.Field, val any) []server.FieldError {
 			return []server.FieldError{{Field: f.Name, Message: "must be a string"}}
 		}
 		errs = append(errs, validateStringConstraints(f, s)...)
+		errs = append(errs, validateFormat(f, s)...)
 
 	case schema.FieldTypeInt:
 		n, ok := toFloat64(val)
@@ -231,6 +243,8 @@ func toFloat64(v any) (float64, bool) {
 		return float64(n), true
 	case int64:
 		return float64(n), true
+	case int32:
+		return float64(n), true
 	case json.Number:
 		f, err := n.Float64()
 		return f, err == nil
@@ -1,6 +1,7 @@
 package content
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/GyroZepelix/mithril-cms/internal/schema"
@@ -147,6 +148,69 @@ func TestValidateEntry_StringConstraints(t *testing.T) {
 	}
 }
 
+func TestValidateEntry_StringFormats(t *testing.T) {
+	ct := schema.ContentType{
+		Name: "test",
+		Fields: []schema.Field{
+			{Name: "email", Type: schema.FieldTypeString, Format: schema.FormatEmail},
+			{Name: "website", Type: schema.FieldTypeString, Format: schema.FormatURL},
+			{Name: "link", Type: schema.FieldTypeString, Format: schema.FormatURL, AllowedSchemes: []string{"https", "mailto"}},
+			{Name: "ref", Type: schema.FieldTypeString, Format: schema.FormatUUID},
+			{Name: "color", Type: schema.FieldTypeString, Format: schema.FormatHexColor},
+			{Name: "phone", Type: schema.FieldTypeString, Format: schema.FormatPhone},
+		},
+	}
+
+	tests := []struct {
+		field   string
+		value   string
+		wantErr string
+	}{
+		{"email", "jane@example.com", ""},
+		{"email", "jane.doe+news@mail.example.co.uk", ""},
+		{"email", "Jane <jane@example.com>", "must be a valid email address"},
+		{"email", "jane@localhost", "must be a valid email address"},
+		{"email", "jane@@example.com", "must be a valid email address"},
+		{"email", "jane", "must be a valid email address"},
+		{"website", "https://example.com/path?q=1", ""},
+		{"website", "HTTP://example.com", ""},
+		{"website", "ftp://example.com", "must use one of the URL schemes: http, https"},
+		{"website", "example.com", "must be a valid URL"},
+		{"website", "https://", "must be a valid URL"},
+		{"website", "https://exa mple.com", "must be a valid URL"},
+		{"link", "mailto:jane@example.com", ""},
+		{"link", "http://example.com", "must use one of the URL schemes: https, mailto"},
+		{"ref", "550e8400-e29b-41d4-a716-446655440000", ""},
+		{"ref", "550e8400e29b41d4a716446655440000", "must be a valid UUID"},
+		{"color", "#fff", ""},
+		{"color", "#1A2b3C", ""},
+		{"color", "1a2b3c", "must be a hex color (#RGB or #RRGGBB)"},
+		{"color", "#12345", "must be a hex color (#RGB or #RRGGBB)"},
+		{"phone", "+14155550123", ""},
+		{"phone", "+44 20 7946-0958", ""},
+		{"phone", "+1 (415) 555.0123", ""},
+		{"phone", "4155550123", "must be an E.164-shaped phone number (e.g. +14155550123)"},
+		{"phone", "+0123456", "must be an E.164-shaped phone number (e.g. +14155550123)"},
+		{"phone", "+1234567890123456", "must be an E.164-shaped phone number (e.g. +14155550123)"},
+		{"phone", "+1 415 CALL NOW", "must be an E.164-shaped phone number (e.g. +14155550123)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+"/"+tt.value, func(t *testing.T) {
+			errs := ValidateEntry(ct, map[string]any{tt.field: tt.value}, true)
+			if tt.wantErr == "" {
+				if len(errs) != 0 {
+					t.Errorf("expected no errors, got %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Message != tt.wantErr {
+				t.Errorf("expected %q, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
 func TestValidateEntry_NumericConstraints(t *testing.T) {
 	ct := schema.ContentType{
 		Name: "test",
@@ -280,3 +344,25 @@ func TestValidateEntry_MultipleErrors(t *testing.T) {
 		t.Errorf("expected 2 errors, got %d: %v", len(errs), errs)
 	}
 }
+
+func TestValidateEntry_InverseFieldReadOnly(t *testing.T) {
+	ct := schema.ContentType{
+		Name: "authors",
+		Fields: []schema.Field{
+			{Name: "name", Type: schema.FieldTypeString},
+			{Name: "posts", Type: schema.FieldTypeRelation, RelatesTo: "blog_posts", InverseOf: "author"},
+		},
+	}
+
+	if errs := ValidateEntry(ct, map[string]any{"name": "Ada"}, false); len(errs) != 0 {
+		t.Errorf("expected no errors without inverse field, got %v", errs)
+	}
+
+	errs := ValidateEntry(ct, map[string]any{"posts": []any{"550e8400-e29b-41d4-a716-446655440000"}}, true)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Field != "posts" || !strings.Contains(errs[0].Message, "read-only") {
+		t.Errorf("unexpected error: %+v", errs[0])
+	}
+}
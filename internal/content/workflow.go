@@ -0,0 +1,376 @@
+package content
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+	"github.com/GyroZepelix/mithril-cms/internal/server"
+)
+
+// maxTransitionCommentLength caps the length of a transition comment stored
+// in the audit log.
+const maxTransitionCommentLength = 2000
+
+var (
+	// ErrNoWorkflow is returned for workflow operations on a content type
+	// that does not define a workflow.
+	ErrNoWorkflow = errors.New("content type has no workflow")
+
+	// ErrWorkflowRequired is returned when an entry of a content type with a
+	// workflow is published directly instead of through a transition.
+	ErrWorkflowRequired = errors.New("entries of this content type are published through workflow transitions")
+
+	// ErrPublishedEdit is returned when an entry of a content type with a
+	// workflow is updated while it is published by an admin who may not
+	// publish it. Such edits would go live without passing review.
+	ErrPublishedEdit = errors.New("editing a published entry requires the publish permission")
+
+	// errStageChanged is returned by Repository.Transition when the entry is
+	// no longer in the expected stage.
+	errStageChanged = errors.New("workflow stage changed")
+)
+
+// TransitionError is returned when a workflow transition is rejected.
+type TransitionError struct {
+	From string
+	To   string
+
+	// Forbidden is set when the transition exists but the admin's role may
+	// not perform it.
+	Forbidden bool
+
+	// Stale is set when another admin moved the entry concurrently.
+	Stale bool
+
+	// Publish is set with Forbidden when the transition enters a published
+	// stage and the admin may not publish entries.
+	Publish bool
+}
+
+func (e *TransitionError) Error() string {
+	switch {
+	case e.Forbidden && e.Publish:
+		return fmt.Sprintf("moving entries to %q publishes them, which requires the publish permission", e.To)
+	case e.Forbidden:
+		return fmt.Sprintf("your role may not move entries from %q to %q", e.From, e.To)
+	case e.Stale:
+		return fmt.Sprintf("entry is no longer in stage %q", e.From)
+	default:
+		return fmt.Sprintf("no transition from %q to %q", e.From, e.To)
+	}
+}
+
+// WorkflowState describes an entry's position in its content type's workflow.
+type WorkflowState struct {
+	Stage       string                `json:"stage"`
+	Published   bool                  `json:"published"`
+	UpdatedBy   *string               `json:"updated_by"`
+	UpdatedAt   *time.Time            `json:"updated_at"`
+	Transitions []AvailableTransition `json:"transitions"`
+}
+
+// AvailableTransition is an outgoing transition from an entry's current
+// stage, and whether the requesting admin's role may perform it.
+type AvailableTransition struct {
+	To      string `json:"to"`
+	Allowed bool   `json:"allowed"`
+}
+
+// QueueItem is an entry waiting in a review stage the requesting admin can
+// move it out of.
+type QueueItem struct {
+	ContentType string         `json:"content_type"`
+	EntryID     string         `json:"entry_id"`
+	Stage       string         `json:"stage"`
+	UpdatedBy   *string        `json:"updated_by"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Transitions []string       `json:"transitions"`
+	Entry       map[string]any `json:"entry"`
+}
+
+// stageRow is a row of the workflow_states table.
+type stageRow struct {
+	EntryID   string
+	Stage     string
+	UpdatedBy *string
+	UpdatedAt time.Time
+}
+
+// checkPublishedEdit returns ErrPublishedEdit if updating the stored entry
+// existing would change published content of a content type with a
+// workflow without canPublish. Edits to published entries reach the public
+// API immediately, so they need the same permission as publishing them.
+func checkPublishedEdit(ct schema.ContentType, existing map[string]any, canPublish bool) error {
+	if ct.Workflow == nil || canPublish || existing["status"] != "published" {
+		return nil
+	}
+	return ErrPublishedEdit
+}
+
+// Transition moves an entry to another workflow stage. The transition must be
+// defined by the content type's workflow and allow the admin's role. Entering
+// a published stage publishes the entry, which requires canPublish, and
+// leaving one unpublishes it. The transition is recorded in the audit log
+// together with the optional comment.
+func (s *Service) Transition(ctx context.Context, contentType, id, to, comment, adminID, role string, canPublish bool) (map[string]any, error) {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	wf := ct.Workflow
+	if wf == nil {
+		return nil, ErrNoWorkflow
+	}
+
+	var errs []server.FieldError
+	target, ok := wf.Stage(to)
+	if !ok {
+		errs = append(errs, server.FieldError{Field: "to", Message: fmt.Sprintf("unknown stage %q", to)})
+	}
+	if len(comment) > maxTransitionCommentLength {
+		errs = append(errs, server.FieldError{Field: "comment", Message: fmt.Sprintf("must be at most %d characters", maxTransitionCommentLength)})
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+	if target.Published && !canPublish {
+		return nil, &TransitionError{To: to, Forbidden: true, Publish: true}
+	}
+
+	existing, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false)
+	if err != nil {
+		return nil, fmt.Errorf("transitioning %s entry: %w", contentType, err)
+	}
+	state, err := s.repo.WorkflowState(ctx, ct.Name, id)
+	if err != nil {
+		return nil, fmt.Errorf("transitioning %s entry: %w", contentType, err)
+	}
+	from := wf.InitialStage()
+	if state != nil {
+		from = state.Stage
+	}
+
+	t, ok := wf.Transition(from, to)
+	if !ok {
+		return nil, &TransitionError{From: from, To: to}
+	}
+	if !t.Allows(role) {
+		return nil, &TransitionError{From: from, To: to, Forbidden: true}
+	}
+
+	source, _ := wf.Stage(from)
+	status := ""
+	switch {
+	case target.Published && !source.Published:
+		status = "published"
+	case !target.Published && source.Published:
+		status = "draft"
+	}
+
+	err = s.repo.Transition(ctx, tableName(ct.Name), ct.Name, id, from, to, from == wf.InitialStage(), status, adminID)
+	if errors.Is(err, errStageChanged) {
+		return nil, &TransitionError{From: from, To: to, Stale: true}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transitioning %s entry: %w", contentType, err)
+	}
+
+	entry, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false)
+	if err != nil {
+		return nil, fmt.Errorf("transitioning %s entry: %w", contentType, err)
+	}
+	if err := s.attachInverse(ctx, []map[string]any{entry}, s.resolveInverse(ct, false), nil, false); err != nil {
+		return nil, fmt.Errorf("transitioning %s entry: %w", contentType, err)
+	}
+
+	payload := map[string]any{"from": from, "to": to}
+	if comment != "" {
+		payload["comment"] = comment
+	}
+	s.logChange(ctx, ct, audit.Event{
+		Action:     "entry.transition",
+		ActorID:    adminID,
+		Resource:   contentType,
+		ResourceID: id,
+		Payload:    payload,
+	}, existing, entry)
+
+	return entry, nil
+}
+
+// WorkflowState returns an entry's current workflow stage and the transitions
+// out of it, marking those the given role may perform. Transitions into
+// published stages are only allowed with canPublish.
+func (s *Service) WorkflowState(ctx context.Context, contentType, id, role string, canPublish bool) (*WorkflowState, error) {
+	ct, ok := s.getSchema(contentType)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	wf := ct.Workflow
+	if wf == nil {
+		return nil, ErrNoWorkflow
+	}
+
+	if _, err := s.repo.GetByID(ctx, tableName(ct.Name), ct.Fields, id, false); err != nil {
+		return nil, fmt.Errorf("getting %s workflow state: %w", contentType, err)
+	}
+	row, err := s.repo.WorkflowState(ctx, ct.Name, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s workflow state: %w", contentType, err)
+	}
+
+	state := &WorkflowState{Stage: wf.InitialStage(), Transitions: []AvailableTransition{}}
+	if row != nil {
+		state.Stage = row.Stage
+		state.UpdatedBy = row.UpdatedBy
+		state.UpdatedAt = &row.UpdatedAt
+	}
+	if st, ok := wf.Stage(state.Stage); ok {
+		state.Published = st.Published
+	}
+	for _, t := range wf.Transitions {
+		if t.From == state.Stage {
+			allowed := t.Allows(role) && (canPublish || !publishes(wf, t))
+			state.Transitions = append(state.Transitions, AvailableTransition{To: t.To, Allowed: allowed})
+		}
+	}
+
+	return state, nil
+}
+
+// ReviewQueue returns the entries, across all content types with a workflow
+// that canRead allows, that wait in a review stage the given role can move
+// them out of. Review stages are those that are neither the initial stage
+// nor published. Transitions into published stages count only for content
+// types canPublish allows. Entries are ordered by the time they entered
+// their stage, oldest first.
+func (s *Service) ReviewQueue(ctx context.Context, role string, canRead, canPublish func(contentType string) bool, page, perPage int) ([]QueueItem, int, error) {
+	s.mu.RLock()
+	schemas := make([]schema.ContentType, 0, len(s.schemas))
+	for _, ct := range s.schemas {
+		if ct.Workflow != nil && canRead(ct.Name) {
+			schemas = append(schemas, ct)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+
+	var items []QueueItem
+	for _, ct := range schemas {
+		allowed := reviewTransitions(ct.Workflow, role, canPublish(ct.Name))
+		if len(allowed) == 0 {
+			continue
+		}
+		stages := make([]string, 0, len(allowed))
+		for stage := range allowed {
+			stages = append(stages, stage)
+		}
+
+		rows, err := s.repo.WorkflowQueue(ctx, tableName(ct.Name), ct.Name, stages)
+		if err != nil {
+			return nil, 0, fmt.Errorf("listing %s review queue: %w", ct.Name, err)
+		}
+		for _, row := range rows {
+			items = append(items, QueueItem{
+				ContentType: ct.Name,
+				EntryID:     row.EntryID,
+				Stage:       row.Stage,
+				UpdatedBy:   row.UpdatedBy,
+				UpdatedAt:   row.UpdatedAt,
+				Transitions: allowed[row.Stage],
+			})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].UpdatedAt.Before(items[j].UpdatedAt)
+	})
+
+	total := len(items)
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	items = items[start:end]
+
+	if err := s.attachQueueEntries(ctx, items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// reviewTransitions maps each review stage of wf to the stages role may move
+// entries to from there, leaving out published stages unless canPublish.
+// Stages role cannot leave are omitted.
+func reviewTransitions(wf *schema.Workflow, role string, canPublish bool) map[string][]string {
+	initial := wf.InitialStage()
+	out := make(map[string][]string)
+	for _, t := range wf.Transitions {
+		if t.From == initial || !t.Allows(role) || (!canPublish && publishes(wf, t)) {
+			continue
+		}
+		if st, ok := wf.Stage(t.From); !ok || st.Published {
+			continue
+		}
+		out[t.From] = append(out[t.From], t.To)
+	}
+	return out
+}
+
+// publishes reports whether t enters a published stage of wf.
+func publishes(wf *schema.Workflow, t schema.WorkflowTransition) bool {
+	st, ok := wf.Stage(t.To)
+	return ok && st.Published
+}
+
+// attachQueueEntries loads the entries referenced by items, one query per
+// content type.
+func (s *Service) attachQueueEntries(ctx context.Context, items []QueueItem) error {
+	byType := make(map[string][]string)
+	for _, item := range items {
+		byType[item.ContentType] = append(byType[item.ContentType], item.EntryID)
+	}
+
+	entries := make(map[string]map[string]any, len(items))
+	for name, ids := range byType {
+		ct, ok := s.getSchema(name)
+		if !ok {
+			continue
+		}
+		rows, err := s.repo.GetByIDs(ctx, tableName(ct.Name), ct.Fields, ids, false)
+		if err != nil {
+			return fmt.Errorf("loading %s review queue entries: %w", name, err)
+		}
+		for _, row := range rows {
+			if id, ok := row["id"].(string); ok {
+				entries[name+"/"+id] = row
+			}
+		}
+	}
+
+	for i := range items {
+		items[i].Entry = entries[items[i].ContentType+"/"+items[i].EntryID]
+	}
+	return nil
+}
+
+// deleteWorkflowState removes the workflow stage of a deleted entry. Failures
+// are only logged: the entry is already gone and stale rows are never read.
+func (s *Service) deleteWorkflowState(ctx context.Context, ct schema.ContentType, id string) {
+	if ct.Workflow == nil {
+		return
+	}
+	if err := s.repo.DeleteWorkflowState(ctx, ct.Name, id); err != nil {
+		slog.Warn("failed to delete workflow state", "content_type", ct.Name, "id", id, "error", err)
+	}
+}
@@ -0,0 +1,293 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/auth"
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
+)
+
+func testWorkflow() *schema.Workflow {
+	return &schema.Workflow{
+		Stages: []schema.WorkflowStage{
+			{Name: "draft"},
+			{Name: "in_review"},
+			{Name: "approved"},
+			{Name: "published", Published: true},
+		},
+		Transitions: []schema.WorkflowTransition{
+			{From: "draft", To: "in_review", Roles: []string{"author", "editor"}},
+			{From: "in_review", To: "approved", Roles: []string{"editor"}},
+			{From: "in_review", To: "draft", Roles: []string{"editor"}},
+			{From: "approved", To: "published", Roles: []string{"editor", "admin"}},
+			{From: "published", To: "draft", Roles: []string{"admin"}},
+		},
+	}
+}
+
+func TestReviewTransitions(t *testing.T) {
+	wf := testWorkflow()
+
+	tests := []struct {
+		name       string
+		role       string
+		canPublish bool
+		want       map[string][]string
+	}{
+		// Initial and published stages are never review stages.
+		{"author", "author", true, map[string][]string{}},
+		{"editor", "editor", true, map[string][]string{
+			"in_review": {"approved", "draft"},
+			"approved":  {"published"},
+		}},
+		{"editor without publish", "editor", false, map[string][]string{
+			"in_review": {"approved", "draft"},
+		}},
+		{"admin", "admin", true, map[string][]string{"approved": {"published"}}},
+		{"viewer", "viewer", true, map[string][]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reviewTransitions(wf, tt.role, tt.canPublish)
+			for _, to := range got {
+				sort.Strings(to)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reviewTransitions(%q, %v) = %v, want %v", tt.role, tt.canPublish, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Transition_Rejected(t *testing.T) {
+	svc := NewService(nil, map[string]schema.ContentType{
+		"posts": {Name: "posts", Workflow: testWorkflow()},
+		"pages": {Name: "pages"},
+	}, nil)
+	ctx := context.Background()
+	id := "550e8400-e29b-41d4-a716-446655440000"
+
+	if _, err := svc.Transition(ctx, "pages", id, "published", "", "admin-id", "admin", true); !errors.Is(err, ErrNoWorkflow) {
+		t.Errorf("expected ErrNoWorkflow, got %v", err)
+	}
+
+	// Publishing is refused before the entry is looked up.
+	_, err := svc.Transition(ctx, "posts", id, "published", "", "admin-id", "editor", false)
+	var transErr *TransitionError
+	if !errors.As(err, &transErr) || !transErr.Forbidden || !transErr.Publish {
+		t.Errorf("expected a forbidden publish TransitionError, got %v", err)
+	}
+
+	_, err = svc.Transition(ctx, "posts", id, "archived", strings.Repeat("x", maxTransitionCommentLength+1), "admin-id", "admin", true)
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(valErr.Fields) != 2 || valErr.Fields[0].Field != "to" || valErr.Fields[1].Field != "comment" {
+		t.Errorf("unexpected field errors: %+v", valErr.Fields)
+	}
+}
+
+func TestService_Publish_WorkflowRequired(t *testing.T) {
+	svc := NewService(nil, map[string]schema.ContentType{
+		"posts": {Name: "posts", Workflow: testWorkflow()},
+	}, nil)
+
+	_, err := svc.Publish(context.Background(), "posts", "550e8400-e29b-41d4-a716-446655440000", "admin-id")
+	if !errors.Is(err, ErrWorkflowRequired) {
+		t.Errorf("expected ErrWorkflowRequired, got %v", err)
+	}
+}
+
+func TestCheckPublishedEdit(t *testing.T) {
+	// Public reads only return published entries, so an edit of a published
+	// entry is what the public API serves next. An editor without the
+	// publish permission must not be able to change it, while drafts stay
+	// editable.
+	posts := schema.ContentType{Name: "posts", Workflow: testWorkflow()}
+	pages := schema.ContentType{Name: "pages"}
+	published := map[string]any{"status": "published", "title": "Reviewed"}
+	draft := map[string]any{"status": "draft", "title": "Work in progress"}
+
+	tests := []struct {
+		name       string
+		ct         schema.ContentType
+		existing   map[string]any
+		canPublish bool
+		want       error
+	}{
+		{"editor on published entry", posts, published, false, ErrPublishedEdit},
+		{"editor on draft", posts, draft, false, nil},
+		{"publisher on published entry", posts, published, true, nil},
+		{"no workflow", pages, published, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPublishedEdit(tt.ct, tt.existing, tt.canPublish); !errors.Is(err, tt.want) {
+				t.Errorf("checkPublishedEdit = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleServiceError_Workflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantErr  string
+	}{
+		{"forbidden", &TransitionError{From: "in_review", To: "approved", Forbidden: true}, http.StatusForbidden, "FORBIDDEN"},
+		{"publish forbidden", &TransitionError{To: "published", Forbidden: true, Publish: true}, http.StatusForbidden, "FORBIDDEN"},
+		{"undefined", &TransitionError{From: "draft", To: "published"}, http.StatusConflict, "INVALID_TRANSITION"},
+		{"stale", &TransitionError{From: "draft", To: "in_review", Stale: true}, http.StatusConflict, "INVALID_TRANSITION"},
+		{"no workflow", ErrNoWorkflow, http.StatusConflict, "NO_WORKFLOW"},
+		{"workflow required", ErrWorkflowRequired, http.StatusConflict, "WORKFLOW_REQUIRED"},
+		{"published edit", fmt.Errorf("updating posts entry: %w", ErrPublishedEdit), http.StatusForbidden, "FORBIDDEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleServiceError(w, tt.err)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+			var resp map[string]any
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			errObj := resp["error"].(map[string]any)
+			if errObj["code"] != tt.wantErr {
+				t.Errorf("expected %s code, got %v", tt.wantErr, errObj["code"])
+			}
+		})
+	}
+}
+
+func TestHandler_ReviewQueue_WithoutReadAccess(t *testing.T) {
+	schemas := map[string]schema.ContentType{
+		"posts": {Name: "posts", Workflow: testWorkflow()},
+		"pages": {Name: "pages", Workflow: testWorkflow()},
+	}
+	// Without a repository, querying any content type's queue would panic.
+	h := NewHandler(NewService(nil, schemas, nil), schemas)
+
+	tests := []struct {
+		name  string
+		perms auth.Permissions
+	}{
+		{"no content permissions", auth.Permissions{{Resource: "media", Action: "read"}}},
+		{"update without read", auth.Permissions{{Resource: "content:*", Action: "update"}}},
+		{"own entries only", auth.Permissions{{Resource: "content:*", Action: "read", Own: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/api/review-queue", nil)
+			ctx := context.WithValue(req.Context(), auth.ContextKeyRole, "editor")
+			ctx = context.WithValue(ctx, auth.ContextKeyPermissions, tt.perms)
+			w := httptest.NewRecorder()
+			h.ReviewQueue(w, req.WithContext(ctx))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data []QueueItem `json:"data"`
+				Meta struct {
+					Total int `json:"total"`
+				} `json:"meta"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data) != 0 || resp.Meta.Total != 0 {
+				t.Errorf("expected an empty queue, got %d items (total %d)", len(resp.Data), resp.Meta.Total)
+			}
+		})
+	}
+}
+
+func TestHandler_AdminTransition_RequiresPublish(t *testing.T) {
+	schemas := map[string]schema.ContentType{
+		"posts": {Name: "posts", Workflow: testWorkflow()},
+	}
+	// Without a repository, a transition that got past the check would panic.
+	h := NewHandler(NewService(nil, schemas, nil), schemas)
+	r := chi.NewRouter()
+	r.Post("/admin/api/content/{contentType}/{id}/transition", h.AdminTransition)
+
+	tests := []struct {
+		name  string
+		perms auth.Permissions
+	}{
+		{"update only", auth.Permissions{{Resource: "content:posts", Action: "update"}}},
+		{"publish for another type", auth.Permissions{
+			{Resource: "content:posts", Action: "update"},
+			{Resource: "content:pages", Action: "publish"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost,
+				"/admin/api/content/posts/550e8400-e29b-41d4-a716-446655440000/transition",
+				strings.NewReader(`{"to": "published"}`))
+			ctx := context.WithValue(req.Context(), auth.ContextKeyRole, "editor")
+			ctx = context.WithValue(ctx, auth.ContextKeyPermissions, tt.perms)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), "publish permission") {
+				t.Errorf("expected the publish permission error, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_AdminTransition_InvalidBody(t *testing.T) {
+	h := newTestHandler()
+	r := chi.NewRouter()
+	r.Post("/admin/api/content/{contentType}/{id}/transition", h.AdminTransition)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing to", `{"comment": "looks good"}`},
+		{"non-string to", `{"to": 1}`},
+		{"non-string comment", `{"to": "approved", "comment": 5}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost,
+				"/admin/api/content/posts/550e8400-e29b-41d4-a716-446655440000/transition",
+				strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "VALIDATION_ERROR") {
+				t.Errorf("expected VALIDATION_ERROR, got %s", w.Body.String())
+			}
+		})
+	}
+}
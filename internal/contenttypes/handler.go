@@ -20,34 +20,59 @@ import (
 
 // FieldResponse represents a single field in the content type introspection response.
 type FieldResponse struct {
-	Name         string              `json:"name"`
-	Type         schema.FieldType    `json:"type"`
-	Required     bool                `json:"required"`
-	Unique       bool                `json:"unique"`
-	Searchable   bool                `json:"searchable"`
-	MinLength    *int                `json:"min_length,omitempty"`
-	MaxLength    *int                `json:"max_length,omitempty"`
-	Min          *float64            `json:"min,omitempty"`
-	Max          *float64            `json:"max,omitempty"`
-	Regex        string              `json:"regex,omitempty"`
-	Values       []string            `json:"values,omitempty"`
-	RelatesTo    string              `json:"relates_to,omitempty"`
-	RelationType schema.RelationType `json:"relation_type,omitempty"`
+	Name           string              `json:"name"`
+	Type           schema.FieldType    `json:"type"`
+	Required       bool                `json:"required"`
+	Unique         bool                `json:"unique"`
+	Searchable     bool                `json:"searchable"`
+	MinLength      *int                `json:"min_length,omitempty"`
+	MaxLength      *int                `json:"max_length,omitempty"`
+	Min            *float64            `json:"min,omitempty"`
+	Max            *float64            `json:"max,omitempty"`
+	Regex          string              `json:"regex,omitempty"`
+	Format         schema.StringFormat `json:"format,omitempty"`
+	AllowedSchemes []string            `json:"allowed_schemes,omitempty"`
+	Values         []string            `json:"values,omitempty"`
+	RelatesTo      string              `json:"relates_to,omitempty"`
+	RelationType   schema.RelationType `json:"relation_type,omitempty"`
+	InverseOf      string              `json:"inverse_of,omitempty"`
 }
 
 // ContentTypeResponse represents a content type in the introspection API response.
 type ContentTypeResponse struct {
-	Name        string          `json:"name"`
-	DisplayName string          `json:"display_name"`
-	PublicRead  bool            `json:"public_read"`
-	Fields      []FieldResponse `json:"fields"`
-	EntryCount  int             `json:"entry_count"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"display_name"`
+	PublicRead  bool              `json:"public_read"`
+	Fields      []FieldResponse   `json:"fields"`
+	Workflow    *WorkflowResponse `json:"workflow,omitempty"`
+	EntryCount  int               `json:"entry_count"`
+}
+
+// WorkflowResponse is the JSON representation of a content type's workflow.
+type WorkflowResponse struct {
+	Initial     string                       `json:"initial"`
+	Stages      []WorkflowStageResponse      `json:"stages"`
+	Transitions []WorkflowTransitionResponse `json:"transitions"`
+}
+
+// WorkflowStageResponse is the JSON representation of a workflow stage.
+type WorkflowStageResponse struct {
+	Name      string `json:"name"`
+	Published bool   `json:"published"`
+}
+
+// WorkflowTransitionResponse is the JSON representation of a workflow
+// transition.
+type WorkflowTransitionResponse struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Roles []string `json:"roles"`
 }
 
 // Handler provides HTTP handlers for content type introspection.
 type Handler struct {
-	pool *pgxpool.Pool
-	mu   sync.RWMutex
+	pool    *pgxpool.Pool
+	mu      sync.RWMutex
 	schemas map[string]schema.ContentType
 }
 
@@ -155,24 +180,36 @@ func (h *Handler) countEntries(ctx context.Context, name string) (int, error) {
 	return count, nil
 }
 
+// urlSchemes returns the schemes accepted by a url-formatted field, so the
+// admin UI can validate input without knowing the server defaults.
+func urlSchemes(f schema.Field) []string {
+	if f.Format != schema.FormatURL {
+		return nil
+	}
+	return f.URLSchemes()
+}
+
 // buildResponse converts a schema.ContentType and entry count into the API response type.
 func buildResponse(ct schema.ContentType, entryCount int) ContentTypeResponse {
 	fields := make([]FieldResponse, len(ct.Fields))
 	for i, f := range ct.Fields {
 		fields[i] = FieldResponse{
-			Name:         f.Name,
-			Type:         f.Type,
-			Required:     f.Required,
-			Unique:       f.Unique,
-			Searchable:   f.Searchable,
-			MinLength:    f.MinLength,
-			MaxLength:    f.MaxLength,
-			Min:          f.Min,
-			Max:          f.Max,
-			Regex:        f.Regex,
-			Values:       f.Values,
-			RelatesTo:    f.RelatesTo,
-			RelationType: f.RelationType,
+			Name:           f.Name,
+			Type:           f.Type,
+			Required:       f.Required,
+			Unique:         f.Unique,
+			Searchable:     f.Searchable,
+			MinLength:      f.MinLength,
+			MaxLength:      f.MaxLength,
+			Min:            f.Min,
+			Max:            f.Max,
+			Regex:          f.Regex,
+			Format:         f.Format,
+			AllowedSchemes: urlSchemes(f),
+			Values:         f.Values,
+			RelatesTo:      f.RelatesTo,
+			RelationType:   f.RelationType,
+			InverseOf:      f.InverseOf,
 		}
 	}
 
@@ -181,6 +218,28 @@ func buildResponse(ct schema.ContentType, entryCount int) ContentTypeResponse {
 		DisplayName: ct.DisplayName,
 		PublicRead:  ct.PublicRead,
 		Fields:      fields,
+		Workflow:    workflowResponse(ct.Workflow),
 		EntryCount:  entryCount,
 	}
 }
+
+// workflowResponse converts a workflow definition to its JSON representation,
+// returning nil if the content type has no workflow.
+func workflowResponse(wf *schema.Workflow) *WorkflowResponse {
+	if wf == nil {
+		return nil
+	}
+
+	resp := &WorkflowResponse{
+		Initial:     wf.InitialStage(),
+		Stages:      make([]WorkflowStageResponse, len(wf.Stages)),
+		Transitions: make([]WorkflowTransitionResponse, len(wf.Transitions)),
+	}
+	for i, st := range wf.Stages {
+		resp.Stages[i] = WorkflowStageResponse{Name: st.Name, Published: st.Published}
+	}
+	for i, t := range wf.Transitions {
+		resp.Transitions[i] = WorkflowTransitionResponse{From: t.From, To: t.To, Roles: t.Roles}
+	}
+	return resp
+}
@@ -1,6 +1,7 @@
 package contenttypes
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/GyroZepelix/mithril-cms/internal/schema"
@@ -47,6 +48,22 @@ func TestBuildResponse(t *testing.T) {
 				RelatesTo:    "categories",
 				RelationType: schema.RelationOne,
 			},
+			{
+				Name:   "website",
+				Type:   schema.FieldTypeString,
+				Format: schema.FormatURL,
+			},
+			{
+				Name:           "contact",
+				Type:           schema.FieldTypeString,
+				Format:         schema.FormatURL,
+				AllowedSchemes: []string{"mailto"},
+			},
+			{
+				Name:   "email",
+				Type:   schema.FieldTypeString,
+				Format: schema.FormatEmail,
+			},
 		},
 	}
 
@@ -64,8 +81,8 @@ func TestBuildResponse(t *testing.T) {
 	if resp.EntryCount != 42 {
 		t.Errorf("EntryCount = %d, want %d", resp.EntryCount, 42)
 	}
-	if len(resp.Fields) != 5 {
-		t.Fatalf("len(Fields) = %d, want %d", len(resp.Fields), 5)
+	if len(resp.Fields) != 8 {
+		t.Fatalf("len(Fields) = %d, want %d", len(resp.Fields), 8)
 	}
 
 	// Verify first field (title) preserves all attributes.
@@ -115,6 +132,21 @@ func TestBuildResponse(t *testing.T) {
 	if rel.RelationType != schema.RelationOne {
 		t.Errorf("Fields[4].RelationType = %q, want %q", rel.RelationType, schema.RelationOne)
 	}
+
+	// Verify string formats; url fields expose the effective scheme allowlist.
+	website := resp.Fields[5]
+	if website.Format != schema.FormatURL {
+		t.Errorf("Fields[5].Format = %q, want %q", website.Format, schema.FormatURL)
+	}
+	if strings.Join(website.AllowedSchemes, ",") != "http,https" {
+		t.Errorf("Fields[5].AllowedSchemes = %v, want [http https]", website.AllowedSchemes)
+	}
+	if contact := resp.Fields[6]; strings.Join(contact.AllowedSchemes, ",") != "mailto" {
+		t.Errorf("Fields[6].AllowedSchemes = %v, want [mailto]", contact.AllowedSchemes)
+	}
+	if email := resp.Fields[7]; email.Format != schema.FormatEmail || email.AllowedSchemes != nil {
+		t.Errorf("Fields[7] = %+v, want email format without schemes", email)
+	}
 }
 
 func TestBuildResponseEmptyFields(t *testing.T) {
@@ -134,6 +166,40 @@ func TestBuildResponseEmptyFields(t *testing.T) {
 	}
 }
 
+func TestBuildResponseWorkflow(t *testing.T) {
+	ct := schema.ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields:      []schema.Field{{Name: "title", Type: schema.FieldTypeString}},
+		Workflow: &schema.Workflow{
+			Stages: []schema.WorkflowStage{{Name: "draft"}, {Name: "live", Published: true}},
+			Transitions: []schema.WorkflowTransition{
+				{From: "draft", To: "live", Roles: []string{"editor"}},
+			},
+		},
+	}
+
+	resp := buildResponse(ct, 0)
+
+	wf := resp.Workflow
+	if wf == nil {
+		t.Fatal("expected workflow in response")
+	}
+	if wf.Initial != "draft" {
+		t.Errorf("Initial = %q, want %q", wf.Initial, "draft")
+	}
+	if len(wf.Stages) != 2 || !wf.Stages[1].Published {
+		t.Errorf("unexpected stages: %+v", wf.Stages)
+	}
+	if len(wf.Transitions) != 1 || wf.Transitions[0].Roles[0] != "editor" {
+		t.Errorf("unexpected transitions: %+v", wf.Transitions)
+	}
+
+	if buildResponse(schema.ContentType{Name: "plain"}, 0).Workflow != nil {
+		t.Error("expected nil workflow for content type without one")
+	}
+}
+
 func TestGetSchemasSorted(t *testing.T) {
 	schemas := map[string]schema.ContentType{
 		"zebras":   {Name: "zebras", DisplayName: "Zebras"},
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL SQLSTATEs for integrity constraint violations.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// IsForeignKeyViolation reports whether err (or any error it wraps) is a
+// PostgreSQL foreign key violation, e.g. deleting a row that is still
+// referenced by an ON DELETE RESTRICT constraint.
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// IsUniqueViolation reports whether err (or any error it wraps) is a
+// PostgreSQL unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// IsDataError reports whether err (or any error it wraps) is a PostgreSQL
+// data exception or integrity constraint violation (SQLSTATE classes 22 and
+// 23), i.e. the statement's values were rejected and retrying it cannot
+// succeed.
+func IsDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23"))
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	fk := &pgconn.PgError{Code: "23503"}
+	unique := &pgconn.PgError{Code: "23505"}
+
+	if !IsForeignKeyViolation(fk) {
+		t.Error("expected FK violation to be detected")
+	}
+	if !IsForeignKeyViolation(fmt.Errorf("deleting entry: %w", fk)) {
+		t.Error("expected wrapped FK violation to be detected")
+	}
+	if IsForeignKeyViolation(unique) {
+		t.Error("unique violation must not be reported as FK violation")
+	}
+	if IsForeignKeyViolation(errors.New("boom")) || IsForeignKeyViolation(nil) {
+		t.Error("non-pg errors must not be reported as FK violation")
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	unique := &pgconn.PgError{Code: "23505"}
+
+	if !IsUniqueViolation(fmt.Errorf("creating admin: %w", unique)) {
+		t.Error("expected wrapped unique violation to be detected")
+	}
+	if IsUniqueViolation(&pgconn.PgError{Code: "23503"}) || IsUniqueViolation(nil) {
+		t.Error("other errors must not be reported as unique violation")
+	}
+}
@@ -0,0 +1,52 @@
+// Package mail delivers transactional emails such as admin invitations and
+// password resets.
+package mail
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// Message is a plain-text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// errHeaderInjection is returned for messages whose recipient or subject
+// contain line breaks.
+var errHeaderInjection = errors.New("mail: line break in header value")
+
+// validate rejects messages that would inject extra headers.
+func (m Message) validate() error {
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return errHeaderInjection
+	}
+	return nil
+}
+
+// LogSender is a Sender that only logs messages. It is meant for development,
+// where the links in invitations and password resets can be copied from the
+// log.
+type LogSender struct{}
+
+// Send implements Sender.
+func (LogSender) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	slog.Info("email (not sent, log-only delivery)",
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body,
+	)
+	return nil
+}
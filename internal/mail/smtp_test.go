@@ -0,0 +1,183 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal SMTP server that accepts a single message and
+// records the session.
+type fakeSMTPServer struct {
+	ln       net.Listener
+	from     string
+	rcpt     []string
+	data     string
+	done     chan struct{}
+	rejectTo string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTPServer{ln: ln, done: make(chan struct{})}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 HELP")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			to := line[len("RCPT TO:"):]
+			if s.rejectTo != "" && strings.Contains(to, s.rejectTo) {
+				reply("550 no such user")
+				continue
+			}
+			s.rcpt = append(s.rcpt, to)
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.data = b.String()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("500 unknown command")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+	sender := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: srv.port(), From: "cms@example.com"})
+
+	err := sender.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "Willkommen bei Mithril",
+		Body:    "Hallo Jürgen,\n\nfollow this link:\nhttp://localhost:8080/admin/accept-invite?token=abc\n",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-srv.done
+
+	if srv.from != "<cms@example.com>" {
+		t.Errorf("MAIL FROM = %q, want <cms@example.com>", srv.from)
+	}
+	if len(srv.rcpt) != 1 || srv.rcpt[0] != "<jane@example.com>" {
+		t.Errorf("RCPT TO = %v, want [<jane@example.com>]", srv.rcpt)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(srv.data))
+	if err != nil {
+		t.Fatalf("parsing delivered message: %v", err)
+	}
+	if got := m.Header.Get("To"); got != "jane@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "Willkommen bei Mithril" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if got := m.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q", got)
+	}
+	body, _ := io.ReadAll(m.Body)
+	if !strings.Contains(string(body), "Hallo J=C3=BCrgen,") {
+		t.Errorf("body not quoted-printable encoded: %q", body)
+	}
+	if !strings.Contains(string(body), "accept-invite?token=3Dabc") {
+		t.Errorf("body missing link: %q", body)
+	}
+}
+
+func TestSMTPSender_RecipientRejected(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+	srv.rejectTo = "nobody@example.com"
+	sender := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: srv.port(), From: "cms@example.com"})
+
+	err := sender.Send(context.Background(), Message{To: "nobody@example.com", Subject: "Hi", Body: "x"})
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO") {
+		t.Errorf("Send() error = %v, want RCPT TO error", err)
+	}
+}
+
+func TestSMTPSender_ConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	sender := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: port, From: "cms@example.com"})
+	if err := sender.Send(ctx, Message{To: "jane@example.com", Subject: "Hi", Body: "x"}); err == nil {
+		t.Error("Send() to closed port succeeded, want error")
+	}
+}
+
+func TestMessage_HeaderInjection(t *testing.T) {
+	msgs := []Message{
+		{To: "jane@example.com\r\nBcc: evil@example.com", Subject: "Hi"},
+		{To: "jane@example.com", Subject: "Hi\nBcc: evil@example.com"},
+	}
+	for _, msg := range msgs {
+		if err := (LogSender{}).Send(context.Background(), msg); err == nil {
+			t.Errorf("Send(%q, %q) succeeded, want error", msg.To, msg.Subject)
+		}
+		if err := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: 1}).Send(context.Background(), msg); err != errHeaderInjection {
+			t.Errorf("SMTP Send(%q, %q) error = %v, want errHeaderInjection", msg.To, msg.Subject, err)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/GyroZepelix/mithril-cms/internal/auth"
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
 	"github.com/GyroZepelix/mithril-cms/internal/server"
 )
 
@@ -110,6 +111,12 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 			server.Error(w, http.StatusNotFound, "NOT_FOUND", "media not found", nil)
 			return
 		}
+		var refErr *schema.ReferencedError
+		if errors.As(err, &refErr) {
+			server.Error(w, http.StatusConflict, "REFERENCED",
+				"media is still referenced by content entries", server.ReferenceDetails(refErr.References))
+			return
+		}
 		slog.Error("media delete failed", "error", err)
 		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR",
 			"an internal error occurred", nil)
@@ -119,6 +126,30 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	server.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
 
+// References handles GET /admin/api/media/{id}/references.
+func (h *Handler) References(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !isValidUUID(id) {
+		server.Error(w, http.StatusBadRequest, "INVALID_ID",
+			"id must be a valid UUID", nil)
+		return
+	}
+
+	refs, err := h.service.References(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			server.Error(w, http.StatusNotFound, "NOT_FOUND", "media not found", nil)
+			return
+		}
+		slog.Error("media references lookup failed", "error", err)
+		server.Error(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"an internal error occurred", nil)
+		return
+	}
+
+	server.JSON(w, http.StatusOK, refs)
+}
+
 // Serve handles GET /media/{filename}.
 func (h *Handler) Serve(w http.ResponseWriter, r *http.Request) {
 	filename := chi.URLParam(r, "filename")
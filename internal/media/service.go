@@ -20,6 +20,8 @@ import (
 	"github.com/disintegration/imaging"
 
 	"github.com/GyroZepelix/mithril-cms/internal/audit"
+	"github.com/GyroZepelix/mithril-cms/internal/database"
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
 )
 
 const (
@@ -74,27 +76,59 @@ var imageVariants = []imageVariant{
 	{Name: "lg", MaxWidth: 1920},
 }
 
+// ReferenceFinder lists the content entries that reference a media record.
+// When blockingOnly is true, only references that block deletion (media
+// fields with on_delete: restrict) are returned. It is supplied by the
+// content package at wiring time so media does not depend on it.
+type ReferenceFinder func(ctx context.Context, mediaID string, blockingOnly bool) ([]schema.Reference, error)
+
 // Service implements the business logic for media upload, processing, and deletion.
 type Service struct {
-	repo         *Repository
-	storage      *LocalStorage
-	auditService *audit.Service
+	repo           *Repository
+	storage        *LocalStorage
+	auditService   *audit.Service
+	findReferences ReferenceFinder
 }
 
 // NewService creates a new media Service. The audit service is optional;
-// if nil, audit events are silently skipped.
-func NewService(repo *Repository, storage *LocalStorage, auditService *audit.Service) *Service {
+// if nil, audit events are silently skipped. The reference finder is also
+// optional; if nil, blocked deletes are reported without the referencing
+// entries.
+func NewService(repo *Repository, storage *LocalStorage, auditService *audit.Service, findReferences ReferenceFinder) *Service {
 	return &Service{
-		repo:         repo,
-		storage:      storage,
-		auditService: auditService,
+		repo:           repo,
+		storage:        storage,
+		auditService:   auditService,
+		findReferences: findReferences,
+	}
+}
+
+// logChange sends an audit event recording the media record's field changes
+// from before to after (nil for uploads and deletes) under "changes" in its
+// payload, if the audit service is configured.
+func (s *Service) logChange(ctx context.Context, event audit.Event, before, after *Media) {
+	if s.auditService == nil {
+		return
 	}
+	if changes := s.auditService.Diff(event.Resource, auditFields(before), auditFields(after)); changes != nil {
+		event.Payload = map[string]any{"changes": changes}
+	}
+	s.auditService.Log(ctx, event)
 }
 
-// logAudit sends an audit event if the audit service is configured.
-func (s *Service) logAudit(ctx context.Context, event audit.Event) {
-	if s.auditService != nil {
-		s.auditService.Log(ctx, event)
+// auditFields returns the values of a media record that audit events record
+// changes of.
+func auditFields(m *Media) map[string]any {
+	if m == nil {
+		return nil
+	}
+	return map[string]any{
+		"filename":      m.Filename,
+		"original_name": m.OriginalName,
+		"mime_type":     m.MimeType,
+		"size":          m.Size,
+		"width":         m.Width,
+		"height":        m.Height,
 	}
 }
 
@@ -207,12 +241,12 @@ func (s *Service) Upload(ctx context.Context, fh *multipart.FileHeader, adminID
 		return nil, fmt.Errorf("creating media record: %w", err)
 	}
 
-	s.logAudit(ctx, audit.Event{
+	s.logChange(ctx, audit.Event{
 		Action:     "media.upload",
 		ActorID:    adminID,
 		Resource:   "media",
 		ResourceID: m.ID,
-	})
+	}, nil, m)
 
 	return m, nil
 }
@@ -302,24 +336,57 @@ func (s *Service) Delete(ctx context.Context, id, adminID string) error {
 		return err
 	}
 
-	// Delete from database first.
+	// Delete from database first. A foreign key violation means a content
+	// entry references this record through an on_delete: restrict field.
 	if err := s.repo.Delete(ctx, id); err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return s.referencedError(ctx, id)
+		}
 		return err
 	}
 
 	// Clean up files (best-effort, log failures).
 	s.cleanupFiles(m.Filename, m.Variants)
 
-	s.logAudit(ctx, audit.Event{
+	s.logChange(ctx, audit.Event{
 		Action:     "media.delete",
 		ActorID:    adminID,
 		Resource:   "media",
 		ResourceID: id,
-	})
+	}, m, nil)
 
 	return nil
 }
 
+// referencedError builds a *schema.ReferencedError for a blocked delete, listing the
+// referencing entries when a reference finder is configured.
+func (s *Service) referencedError(ctx context.Context, id string) error {
+	if s.findReferences == nil {
+		return &schema.ReferencedError{}
+	}
+	refs, err := s.findReferences(ctx, id, true)
+	if err != nil {
+		return fmt.Errorf("listing references to media: %w", err)
+	}
+	return &schema.ReferencedError{References: refs}
+}
+
+// References lists the content entries that reference a media record.
+// Returns ErrNotFound if the record does not exist.
+func (s *Service) References(ctx context.Context, id string) ([]schema.Reference, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if s.findReferences == nil {
+		return []schema.Reference{}, nil
+	}
+	refs, err := s.findReferences(ctx, id, false)
+	if err != nil {
+		return nil, fmt.Errorf("listing references to media: %w", err)
+	}
+	return refs, nil
+}
+
 // List retrieves a paginated list of media records.
 func (s *Service) List(ctx context.Context, page, perPage int) ([]*Media, int, error) {
 	return s.repo.List(ctx, page, perPage)
@@ -1,8 +1,12 @@
 package media
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"testing"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
 )
 
 func TestGenerateUUID(t *testing.T) {
@@ -313,3 +317,49 @@ func TestSanitizeFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestReferencedError(t *testing.T) {
+	t.Run("without finder", func(t *testing.T) {
+		s := &Service{}
+		err := s.referencedError(context.Background(), "id")
+		var refErr *schema.ReferencedError
+		if !errors.As(err, &refErr) {
+			t.Fatalf("expected *schema.ReferencedError, got %T: %v", err, err)
+		}
+		if len(refErr.References) != 0 {
+			t.Errorf("expected no references, got %v", refErr.References)
+		}
+	})
+
+	t.Run("with finder", func(t *testing.T) {
+		want := []schema.Reference{{ContentType: "authors", EntryID: "e1", Field: "avatar"}}
+		s := &Service{findReferences: func(ctx context.Context, mediaID string, blockingOnly bool) ([]schema.Reference, error) {
+			if !blockingOnly {
+				t.Error("delete should only ask for blocking references")
+			}
+			if mediaID != "m1" {
+				t.Errorf("finder called with %q, want m1", mediaID)
+			}
+			return want, nil
+		}}
+		err := s.referencedError(context.Background(), "m1")
+		var refErr *schema.ReferencedError
+		if !errors.As(err, &refErr) {
+			t.Fatalf("expected *schema.ReferencedError, got %T: %v", err, err)
+		}
+		if len(refErr.References) != 1 || refErr.References[0] != want[0] {
+			t.Errorf("References = %v, want %v", refErr.References, want)
+		}
+	})
+
+	t.Run("finder error", func(t *testing.T) {
+		s := &Service{findReferences: func(ctx context.Context, mediaID string, blockingOnly bool) ([]schema.Reference, error) {
+			return nil, errors.New("db down")
+		}}
+		err := s.referencedError(context.Background(), "m1")
+		var refErr *schema.ReferencedError
+		if errors.As(err, &refErr) {
+			t.Fatal("finder failure should not be reported as ReferencedError")
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"log/slog"
+	"math/big"
+)
+
+// jsonWebKeySet is a JWK set (RFC 7517) as served at the jwks_uri.
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// jsonWebKey holds the members of RSA and EC public keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKeys returns the set's signing keys by key ID. Keys that are not for
+// signing or cannot be decoded are skipped.
+func (s jsonWebKeySet) publicKeys() map[string]any {
+	keys := make(map[string]any, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, ok := k.publicKey()
+		if !ok {
+			slog.Warn("skipping unsupported oidc signing key", "kid", k.Kid, "kty", k.Kty)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys
+}
+
+func (k jsonWebKey) publicKey() (any, bool) {
+	switch k.Kty {
+	case "RSA":
+		n, ok1 := decodeBigInt(k.N)
+		e, ok2 := decodeBigInt(k.E)
+		if !ok1 || !ok2 || !e.IsInt64() {
+			return nil, false
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, true
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, false
+		}
+		x, ok1 := decodeBigInt(k.X)
+		y, ok2 := decodeBigInt(k.Y)
+		if !ok1 || !ok2 || !curve.IsOnCurve(x, y) {
+			return nil, false
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, true
+	}
+	return nil, false
+}
+
+func decodeBigInt(s string) (*big.Int, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(b), true
+}
@@ -0,0 +1,216 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:8080/admin/api/auth/oidc/callback"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *Provider) {
+	t.Helper()
+	mock := oidctest.NewProvider()
+	t.Cleanup(mock.Close)
+	return mock, NewProvider(Config{
+		Issuer:       mock.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  redirectURL,
+	})
+}
+
+// authorize follows the authorization URL to the mock provider and returns
+// the code and state it redirects back with.
+func authorize(t *testing.T, p *Provider, state, nonce, verifier string) (code, gotState string) {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parsing redirect: %v", err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestProvider_CodeFlow(t *testing.T) {
+	mock, p := newProvider(t)
+	mock.SetClaims(map[string]any{
+		"sub":            "u-42",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane",
+		"groups":         []string{"cms-editors", "staff"},
+	})
+
+	code, state := authorize(t, p, "state-1", "nonce-1", "verifier-with-enough-entropy")
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	id, err := p.Exchange(context.Background(), code, "verifier-with-enough-entropy", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if id.Issuer != mock.Issuer() || id.Subject != "u-42" || id.Email != "jane@example.com" || id.Name != "Jane" {
+		t.Errorf("identity = %+v", id)
+	}
+	if id.EmailVerified == nil || !*id.EmailVerified {
+		t.Errorf("EmailVerified = %v, want true", id.EmailVerified)
+	}
+	if len(id.Groups) != 2 || id.Groups[0] != "cms-editors" {
+		t.Errorf("Groups = %v", id.Groups)
+	}
+}
+
+func TestProvider_WrongVerifier(t *testing.T) {
+	_, p := newProvider(t)
+	code, _ := authorize(t, p, "s", "n", "the-right-verifier")
+
+	_, err := p.Exchange(context.Background(), code, "a-different-verifier", "n")
+	if !errors.Is(err, ErrProvider) {
+		t.Errorf("Exchange() error = %v, want ErrProvider", err)
+	}
+}
+
+func TestProvider_NonceMismatch(t *testing.T) {
+	_, p := newProvider(t)
+	code, _ := authorize(t, p, "s", "nonce-from-login", "verifier")
+
+	_, err := p.Exchange(context.Background(), code, "verifier", "another-nonce")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Exchange() error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestProvider_CodeSingleUse(t *testing.T) {
+	_, p := newProvider(t)
+	code, _ := authorize(t, p, "s", "n", "verifier")
+
+	if _, err := p.Exchange(context.Background(), code, "verifier", "n"); err != nil {
+		t.Fatalf("first Exchange: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), code, "verifier", "n"); err == nil {
+		t.Error("second Exchange with the same code succeeded")
+	}
+}
+
+func TestProvider_IssuerMismatch(t *testing.T) {
+	mock := oidctest.NewProvider()
+	defer mock.Close()
+
+	// The same server under another name: discovery succeeds but reports
+	// the issuer the mock knows itself as.
+	issuer := strings.Replace(mock.Issuer(), "127.0.0.1", "localhost", 1)
+	p := NewProvider(Config{Issuer: issuer, ClientID: oidctest.ClientID})
+	_, err := p.AuthCodeURL(context.Background(), "s", "n", "v")
+	if !errors.Is(err, ErrProvider) || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("AuthCodeURL() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestProvider_DiscoveryNotFound(t *testing.T) {
+	mock := oidctest.NewProvider()
+	defer mock.Close()
+
+	p := NewProvider(Config{Issuer: mock.Issuer() + "/tenant", ClientID: oidctest.ClientID})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); !errors.Is(err, ErrProvider) {
+		t.Errorf("AuthCodeURL() error = %v, want ErrProvider", err)
+	}
+}
+
+func TestProvider_Unreachable(t *testing.T) {
+	mock := oidctest.NewProvider()
+	issuer := mock.Issuer()
+	mock.Close()
+
+	p := NewProvider(Config{Issuer: issuer, ClientID: oidctest.ClientID})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); !errors.Is(err, ErrProvider) {
+		t.Errorf("AuthCodeURL() error = %v, want ErrProvider", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	mock, p := newProvider(t)
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   mock.Issuer(),
+			"aud":   oidctest.ClientID,
+			"sub":   "u-1",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": "n",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"expired", func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"foreign azp", func(c jwt.MapClaims) {
+			c["aud"] = []string{oidctest.ClientID, "other"}
+			c["azp"] = "other"
+		}},
+		{"missing sub", func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			_, err := verifyToken(t, p, mock.SignIDToken(claims), "n")
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("verify() error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+
+	t.Run("hmac signed", func(t *testing.T) {
+		tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte(oidctest.ClientSecret))
+		if _, err := verifyToken(t, p, tok, "n"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("verify() error = %v, want ErrInvalidIDToken", err)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		if _, err := verifyToken(t, p, mock.SignIDToken(valid()), "n"); err != nil {
+			t.Errorf("verify() error = %v", err)
+		}
+	})
+}
+
+// verifyToken verifies a raw ID token as Exchange does.
+func verifyToken(t *testing.T, p *Provider, rawToken, nonce string) (*Identity, error) {
+	t.Helper()
+	meta, err := p.discover(context.Background())
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	return p.verify(context.Background(), meta, rawToken, nonce)
+}
@@ -0,0 +1,87 @@
+package richtext
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a size-bounded LRU cache of rendered HTML. Each entry is stored
+// with a version (the entry's updated_at), and a lookup only hits if the
+// version matches, so edits invalidate stale renders without explicit
+// eviction.
+type Cache struct {
+	mu    sync.Mutex
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	version time.Time
+	html    string
+}
+
+// NewCache creates a Cache holding at most max entries.
+func NewCache(max int) *Cache {
+	return &Cache{
+		max:   max,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the HTML cached for key if it was stored with version.
+func (c *Cache) Get(key string, version time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.version.Equal(version) {
+		return "", false
+	}
+	c.ll.MoveToFront(el)
+	return e.html, true
+}
+
+// Put stores html for key at version, replacing any other version and
+// evicting the least recently used entry if the cache is full.
+func (c *Cache) Put(key string, version time.Time, html string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.version, e.html = version, html
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, version: version, html: html})
+	for c.ll.Len() > c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Purge removes all entries, e.g. after a schema refresh changed a policy.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
@@ -0,0 +1,69 @@
+package richtext
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCache_Versioning(t *testing.T) {
+	c := NewCache(10)
+	v1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	v2 := v1.Add(time.Second)
+
+	if _, ok := c.Get("k", v1); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	c.Put("k", v1, "one")
+	if got, ok := c.Get("k", v1); !ok || got != "one" {
+		t.Errorf("Get(v1) = %q, %v; want one, true", got, ok)
+	}
+	if _, ok := c.Get("k", v2); ok {
+		t.Error("expected miss for a newer version")
+	}
+
+	c.Put("k", v2, "two")
+	if got, ok := c.Get("k", v2); !ok || got != "two" {
+		t.Errorf("Get(v2) = %q, %v; want two, true", got, ok)
+	}
+	if _, ok := c.Get("k", v1); ok {
+		t.Error("expected stale version to be replaced")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(2)
+	v := time.Now()
+
+	c.Put("a", v, "a")
+	c.Put("b", v, "b")
+	c.Get("a", v) // a is now more recent than b.
+	c.Put("c", v, "c")
+
+	if _, ok := c.Get("b", v); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k, v); !ok {
+			t.Errorf("expected %s to be cached", k)
+		}
+	}
+}
+
+func TestCache_Purge(t *testing.T) {
+	c := NewCache(10)
+	v := time.Now()
+	for i := range 5 {
+		c.Put(fmt.Sprint(i), v, "x")
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len() after Purge = %d, want 0", c.Len())
+	}
+	if _, ok := c.Get("1", v); ok {
+		t.Error("expected miss after Purge")
+	}
+}
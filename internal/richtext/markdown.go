@@ -0,0 +1,768 @@
+package richtext
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SanitizeMarkdown sanitizes the HTML embedded in markdown source. Code
+// spans and fenced code blocks are left untouched: markdown renderers
+// display their content as text, so sanitizing would only corrupt examples.
+func (p *Policy) SanitizeMarkdown(src string) string {
+	var b, text strings.Builder
+	flush := func() {
+		b.WriteString(p.sanitizeOutsideCodeSpans(text.String()))
+		text.Reset()
+	}
+
+	var fenceChar byte
+	var fenceLen int
+	for _, line := range strings.SplitAfter(src, "\n") {
+		if fenceLen > 0 {
+			b.WriteString(line)
+			if isClosingFence(line, fenceChar, fenceLen) {
+				fenceLen = 0
+			}
+			continue
+		}
+		if c, n, _, ok := openingFence(strings.TrimRight(line, "\r\n")); ok {
+			flush()
+			b.WriteString(line)
+			fenceChar, fenceLen = c, n
+			continue
+		}
+		text.WriteString(line)
+	}
+	flush()
+
+	return b.String()
+}
+
+// sanitizeOutsideCodeSpans sanitizes s except for backtick code spans.
+func (p *Policy) sanitizeOutsideCodeSpans(s string) string {
+	var b strings.Builder
+	for {
+		start, end := nextCodeSpan(s)
+		if start < 0 {
+			b.WriteString(p.Sanitize(s))
+			return b.String()
+		}
+		b.WriteString(p.Sanitize(s[:start]))
+		b.WriteString(s[start:end])
+		s = s[end:]
+	}
+}
+
+// nextCodeSpan returns the byte range of the first complete code span in s,
+// or -1, -1 if there is none.
+func nextCodeSpan(s string) (int, int) {
+	i := 0
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], '`')
+		if j < 0 {
+			return -1, -1
+		}
+		start := i + j
+		if start > 0 && s[start-1] == '\\' {
+			i = start + 1
+			continue
+		}
+		n := runLength(s, start, '`')
+		if end := closingBackticks(s, start+n, n); end >= 0 {
+			return start, end + n
+		}
+		i = start + n
+	}
+	return -1, -1
+}
+
+// closingBackticks returns the index of the next run of exactly n backticks
+// at or after i, or -1.
+func closingBackticks(s string, i, n int) int {
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], '`')
+		if j < 0 {
+			return -1
+		}
+		k := i + j
+		m := runLength(s, k, '`')
+		if m == n {
+			return k
+		}
+		i = k + m
+	}
+	return -1
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// RenderMarkdown converts markdown to HTML. It supports the commonly used
+// CommonMark subset: ATX and setext headings, paragraphs, emphasis, strong,
+// strikethrough (~~), code spans, fenced and indented code blocks, block
+// quotes, nested bullet and ordered lists, thematic breaks, inline and
+// autolinks, images, hard line breaks, and raw HTML. Reference-style links
+// and tables are not supported.
+//
+// Raw HTML is passed through, so the result must be sanitized before it is
+// served.
+func RenderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), false)
+	return b.String()
+}
+
+var (
+	atxHeadingRegex    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	thematicBreakRegex = regexp.MustCompile(`^ {0,3}(?:(?:-[ ]*){3,}|(?:\*[ ]*){3,}|(?:_[ ]*){3,})$`)
+	setextH1Regex      = regexp.MustCompile(`^ {0,3}=+[ ]*$`)
+	setextH2Regex      = regexp.MustCompile(`^ {0,3}-+[ ]*$`)
+	bulletItemRegex    = regexp.MustCompile(`^( {0,3})([-*+])( {1,4}|$)`)
+	orderedItemRegex   = regexp.MustCompile(`^( {0,3})([0-9]{1,9})([.)])( {1,4}|$)`)
+	htmlBlockRegex     = regexp.MustCompile(`^ {0,3}</?(?i:address|article|aside|blockquote|details|div|dl|fieldset|figcaption|figure|footer|h[1-6]|header|hr|li|main|nav|ol|p|pre|section|summary|table|tbody|td|tfoot|th|thead|tr|ul)(?:[ >/]|$)`)
+	htmlCommentRegex   = regexp.MustCompile(`^ {0,3}<!--`)
+	blockquoteRegex    = regexp.MustCompile(`^ {0,3}> ?`)
+)
+
+// renderBlocks renders block-level markdown. In tight mode (list items
+// without blank lines between them) paragraphs are not wrapped in <p>.
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	i := 0
+	for i < len(lines) {
+		line := lines[i]
+
+		switch {
+		case isBlank(line):
+			i++
+
+		case isFenceStart(line):
+			i = renderFencedCode(b, lines, i)
+
+		case atxHeadingRegex.MatchString(line):
+			m := atxHeadingRegex.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderInline(strings.TrimSpace(m[2])) + "</h" + level + ">\n")
+			i++
+
+		case thematicBreakRegex.MatchString(line):
+			b.WriteString("<hr />\n")
+			i++
+
+		case strings.HasPrefix(line, "    "):
+			i = renderIndentedCode(b, lines, i)
+
+		case blockquoteRegex.MatchString(line):
+			var inner []string
+			for i < len(lines) && !isBlank(lines[i]) {
+				if loc := blockquoteRegex.FindStringIndex(lines[i]); loc != nil {
+					inner = append(inner, lines[i][loc[1]:])
+				} else if startsBlock(lines[i]) {
+					break
+				} else {
+					inner = append(inner, lines[i]) // Lazy continuation.
+				}
+				i++
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, inner, false)
+			b.WriteString("</blockquote>\n")
+
+		case isListItem(line):
+			i = renderList(b, lines, i)
+
+		case htmlBlockRegex.MatchString(line) || htmlCommentRegex.MatchString(line):
+			for i < len(lines) && !isBlank(lines[i]) {
+				b.WriteString(lines[i])
+				b.WriteByte('\n')
+				i++
+			}
+
+		default:
+			i = renderParagraph(b, lines, i, tight)
+		}
+	}
+}
+
+// renderParagraph renders the paragraph (or setext heading) starting at
+// lines[i] and returns the index of the first line after it.
+func renderParagraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	var para []string
+	for i < len(lines) && !isBlank(lines[i]) {
+		if len(para) > 0 {
+			switch {
+			case setextH1Regex.MatchString(lines[i]):
+				b.WriteString("<h1>" + renderInline(strings.Join(para, "\n")) + "</h1>\n")
+				return i + 1
+			case setextH2Regex.MatchString(lines[i]):
+				b.WriteString("<h2>" + renderInline(strings.Join(para, "\n")) + "</h2>\n")
+				return i + 1
+			case startsBlock(lines[i]):
+				goto done
+			}
+		}
+		para = append(para, strings.TrimLeft(lines[i], " "))
+		i++
+	}
+done:
+	text := renderInline(strings.TrimRight(strings.Join(para, "\n"), " "))
+	if tight {
+		b.WriteString(text + "\n")
+	} else {
+		b.WriteString("<p>" + text + "</p>\n")
+	}
+	return i
+}
+
+// startsBlock reports whether line interrupts a paragraph.
+func startsBlock(line string) bool {
+	if isFenceStart(line) || atxHeadingRegex.MatchString(line) ||
+		thematicBreakRegex.MatchString(line) || blockquoteRegex.MatchString(line) ||
+		htmlBlockRegex.MatchString(line) {
+		return true
+	}
+	if m := bulletItemRegex.FindStringSubmatch(line); m != nil {
+		return m[3] != "" // An empty item cannot interrupt a paragraph.
+	}
+	if m := orderedItemRegex.FindStringSubmatch(line); m != nil {
+		return m[2] == "1" && m[4] != ""
+	}
+	return false
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// openingFence parses a code fence opening line: up to three spaces of
+// indentation, then at least three backticks or tildes and an optional info
+// string.
+func openingFence(line string) (c byte, n int, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return 0, 0, "", false
+	}
+	c = trimmed[0]
+	if c != '`' && c != '~' {
+		return 0, 0, "", false
+	}
+	n = runLength(trimmed, 0, c)
+	if n < 3 {
+		return 0, 0, "", false
+	}
+	info = strings.TrimSpace(trimmed[n:])
+	if c == '`' && strings.ContainsRune(info, '`') {
+		return 0, 0, "", false
+	}
+	return c, n, info, true
+}
+
+func isFenceStart(line string) bool {
+	_, _, _, ok := openingFence(line)
+	return ok
+}
+
+func isClosingFence(line string, c byte, n int) bool {
+	trimmed := strings.TrimLeft(strings.TrimRight(line, "\r\n"), " ")
+	if len(strings.TrimRight(line, "\r\n"))-len(trimmed) > 3 {
+		return false
+	}
+	m := runLength(trimmed, 0, c)
+	return m >= n && strings.TrimSpace(trimmed[m:]) == ""
+}
+
+// renderFencedCode renders the fenced code block starting at lines[i].
+func renderFencedCode(b *strings.Builder, lines []string, i int) int {
+	c, n, info, _ := openingFence(lines[i])
+	i++
+
+	var code []string
+	for i < len(lines) && !isClosingFence(lines[i], c, n) {
+		code = append(code, lines[i])
+		i++
+	}
+	if i < len(lines) {
+		i++ // Closing fence.
+	}
+
+	b.WriteString("<pre><code")
+	if lang, _, _ := strings.Cut(info, " "); lang != "" {
+		b.WriteString(` class="language-` + html.EscapeString(unescapeBackslashes(lang)) + `"`)
+	}
+	b.WriteString(">")
+	for _, l := range code {
+		b.WriteString(html.EscapeString(l) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderIndentedCode renders the indented code block starting at lines[i].
+func renderIndentedCode(b *strings.Builder, lines []string, i int) int {
+	var code []string
+	for i < len(lines) && (isBlank(lines[i]) || strings.HasPrefix(lines[i], "    ")) {
+		if isBlank(lines[i]) {
+			code = append(code, "")
+		} else {
+			code = append(code, lines[i][4:])
+		}
+		i++
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+
+	b.WriteString("<pre><code>")
+	for _, l := range code {
+		b.WriteString(html.EscapeString(l) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// listMarker describes the marker of a list item line.
+type listMarker struct {
+	ordered bool
+	char    byte // '-', '*', '+' for bullets; '.' or ')' for ordered lists.
+	start   int
+	width   int // Indentation of the item content.
+	empty   bool
+}
+
+func parseListMarker(line string) (listMarker, bool) {
+	if m := bulletItemRegex.FindStringSubmatch(line); m != nil && !thematicBreakRegex.MatchString(line) {
+		return listMarker{
+			char:  m[2][0],
+			width: len(m[1]) + 1 + max(len(m[3]), 1),
+			empty: m[3] == "",
+		}, true
+	}
+	if m := orderedItemRegex.FindStringSubmatch(line); m != nil {
+		start, _ := strconv.Atoi(m[2])
+		return listMarker{
+			ordered: true,
+			char:    m[3][0],
+			start:   start,
+			width:   len(m[1]) + len(m[2]) + 1 + max(len(m[4]), 1),
+			empty:   m[4] == "",
+		}, true
+	}
+	return listMarker{}, false
+}
+
+func isListItem(line string) bool {
+	_, ok := parseListMarker(line)
+	return ok
+}
+
+// renderList renders the list starting at lines[i] and returns the index of
+// the first line after it.
+func renderList(b *strings.Builder, lines []string, i int) int {
+	first, _ := parseListMarker(lines[i])
+
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		marker, ok := parseListMarker(lines[i])
+		if !ok || marker.ordered != first.ordered || marker.char != first.char {
+			break
+		}
+
+		item := []string{""}
+		if !marker.empty {
+			item[0] = lines[i][marker.width:]
+		}
+		i++
+
+		blank := false
+		for i < len(lines) {
+			line := lines[i]
+			switch {
+			case isBlank(line):
+				blank = true
+				item = append(item, "")
+				i++
+				continue
+			case leadingSpaces(line) >= marker.width:
+				if blank {
+					loose = true
+				}
+				item = append(item, line[marker.width:])
+			case !blank && !startsBlock(line) && !isListItem(line):
+				item = append(item, line) // Lazy continuation.
+			default:
+				goto endItem
+			}
+			blank = false
+			i++
+		}
+	endItem:
+		// Trailing blank lines separate items; a following item makes the list loose.
+		for len(item) > 1 && item[len(item)-1] == "" {
+			item = item[:len(item)-1]
+		}
+		if blank && i < len(lines) {
+			if next, ok := parseListMarker(lines[i]); ok && next.ordered == first.ordered && next.char == first.char {
+				loose = true
+			}
+		}
+		items = append(items, item)
+	}
+
+	if first.ordered {
+		if first.start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(first.start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+	for _, item := range items {
+		var inner strings.Builder
+		renderBlocks(&inner, item, !loose)
+		b.WriteString("<li>" + strings.TrimSuffix(inner.String(), "\n") + "</li>\n")
+	}
+	if first.ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+var (
+	autolinkRegex = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailRegex    = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	entityRegex   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+)
+
+// renderInline renders inline markdown within a block.
+func renderInline(s string) string {
+	var out []byte
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			out = append(out, "<br />\n"...)
+			i += 2
+
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			out = append(out, html.EscapeString(s[i+1:i+2])...)
+			i += 2
+
+		case c == '\n':
+			// Two or more trailing spaces make a hard line break.
+			trimmed := strings.TrimRight(string(out), " ")
+			if len(out)-len(trimmed) >= 2 {
+				out = append([]byte(trimmed), "<br />\n"...)
+			} else {
+				out = append([]byte(trimmed), '\n')
+			}
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			end := closingBackticks(s, i+n, n)
+			if end < 0 {
+				out = append(out, s[i:i+n]...)
+				i += n
+				continue
+			}
+			code := strings.ReplaceAll(s[i+n:end], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			out = append(out, "<code>"+html.EscapeString(code)+"</code>"...)
+			i = end + n
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if text, dest, title, n, ok := parseLink(s[i+1:]); ok {
+				// Attributes are written in sorted order, as the sanitizer does.
+				out = append(out, `<img alt="`+html.EscapeString(plainText(text))+`" src="`+html.EscapeString(dest)+`"`...)
+				if title != "" {
+					out = append(out, ` title="`+html.EscapeString(title)+`"`...)
+				}
+				out = append(out, " />"...)
+				i += 1 + n
+				continue
+			}
+			out = append(out, '!')
+			i++
+
+		case c == '[':
+			if text, dest, title, n, ok := parseLink(s[i:]); ok {
+				out = append(out, `<a href="`+html.EscapeString(dest)+`"`...)
+				if title != "" {
+					out = append(out, ` title="`+html.EscapeString(title)+`"`...)
+				}
+				out = append(out, ">"+renderInline(text)+"</a>"...)
+				i += n
+				continue
+			}
+			out = append(out, '[')
+			i++
+
+		case c == '<':
+			if m := autolinkRegex.FindStringSubmatch(s[i:]); m != nil {
+				out = append(out, `<a href="`+html.EscapeString(m[1])+`">`+html.EscapeString(m[1])+"</a>"...)
+				i += len(m[0])
+				continue
+			}
+			if m := emailRegex.FindStringSubmatch(s[i:]); m != nil {
+				out = append(out, `<a href="mailto:`+html.EscapeString(m[1])+`">`+html.EscapeString(m[1])+"</a>"...)
+				i += len(m[0])
+				continue
+			}
+			if _, n, ok := parseTag(s[i:]); ok {
+				out = append(out, s[i:i+n]...) // Raw inline HTML.
+				i += n
+				continue
+			}
+			out = append(out, "&lt;"...)
+			i++
+
+		case c == '&':
+			if m := entityRegex.FindString(s[i:]); m != "" {
+				out = append(out, m...)
+				i += len(m)
+				continue
+			}
+			out = append(out, "&amp;"...)
+			i++
+
+		case c == '*' || c == '_' || c == '~':
+			if html, n, ok := renderEmphasis(s, i); ok {
+				out = append(out, html...)
+				i += n
+				continue
+			}
+			n := runLength(s, i, c)
+			out = append(out, s[i:i+n]...)
+			i += n
+
+		default:
+			out = append(out, html.EscapeString(s[i:i+1])...)
+			i++
+		}
+	}
+	return string(out)
+}
+
+// renderEmphasis renders the emphasis, strong emphasis or strikethrough that
+// opens at s[i], returning the HTML and the number of bytes consumed.
+func renderEmphasis(s string, i int) (string, int, bool) {
+	c := s[i]
+	run := runLength(s, i, c)
+
+	type form struct {
+		delim string
+		tag   string
+	}
+	var forms []form
+	switch {
+	case c == '~':
+		if run != 2 {
+			return "", 0, false
+		}
+		forms = []form{{"~~", "del"}}
+	case run >= 3:
+		forms = []form{{strings.Repeat(string(c), 3), ""}, {strings.Repeat(string(c), 2), "strong"}, {string(c), "em"}}
+	case run == 2:
+		forms = []form{{strings.Repeat(string(c), 2), "strong"}, {string(c), "em"}}
+	default:
+		forms = []form{{string(c), "em"}}
+	}
+
+	for _, f := range forms {
+		open := i + len(f.delim)
+		if open >= len(s) || isUnicodeSpace(s[open]) {
+			continue
+		}
+		if c == '_' && i > 0 && isWordByte(s[i-1]) {
+			return "", 0, false // Intraword underscores are literal (snake_case).
+		}
+		end := findClosingDelim(s, open, f.delim, c)
+		if end < 0 {
+			continue
+		}
+		inner := renderInline(s[open:end])
+		n := end + len(f.delim) - i
+		if f.tag == "" {
+			return "<em><strong>" + inner + "</strong></em>", n, true
+		}
+		return "<" + f.tag + ">" + inner + "</" + f.tag + ">", n, true
+	}
+	return "", 0, false
+}
+
+// findClosingDelim finds the closing delimiter for emphasis opened at open.
+// The closer must follow a non-space character. A run of the same length
+// closes; a longer run of three or more closes with its last characters (as
+// in "*a **b***"); shorter or two-character runs belong to nested emphasis
+// and are skipped. Code spans are skipped too.
+func findClosingDelim(s string, open int, delim string, c byte) int {
+	for j := open; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			n := runLength(s, j, '`')
+			if end := closingBackticks(s, j+n, n); end >= 0 {
+				j = end + n - 1
+			} else {
+				j += n - 1
+			}
+			continue
+		case c:
+		default:
+			continue
+		}
+
+		run := runLength(s, j, c)
+		closes := j > open && !isUnicodeSpace(s[j-1]) &&
+			(run == len(delim) || (run >= 3 && run > len(delim)))
+		if closes && c == '_' && j+run < len(s) && isWordByte(s[j+run]) {
+			closes = false
+		}
+		if closes {
+			return j + run - len(delim)
+		}
+		j += run - 1
+	}
+	return -1
+}
+
+// parseLink parses an inline link "[text](dest "title")" at the start of s
+// and returns the number of bytes consumed.
+func parseLink(s string) (text, dest, title string, n int, ok bool) {
+	depth := 0
+	closeBracket := -1
+	for j := 1; j < len(s) && closeBracket < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			m := runLength(s, j, '`')
+			if end := closingBackticks(s, j+m, m); end >= 0 {
+				j = end + m - 1
+			} else {
+				j += m - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				closeBracket = j
+			}
+			depth--
+		}
+	}
+	if closeBracket < 0 || closeBracket+1 >= len(s) || s[closeBracket+1] != '(' {
+		return "", "", "", 0, false
+	}
+	text = s[1:closeBracket]
+
+	j := closeBracket + 2
+	j = skipSpaces(s, j)
+	if j < len(s) && s[j] == '<' {
+		end := strings.IndexAny(s[j+1:], ">\n")
+		if end < 0 || s[j+1+end] != '>' {
+			return "", "", "", 0, false
+		}
+		dest = s[j+1 : j+1+end]
+		j += end + 2
+	} else {
+		start := j
+		parens := 0
+		for j < len(s) && !isUnicodeSpace(s[j]) {
+			if s[j] == '\\' && j+1 < len(s) {
+				j += 2
+				continue
+			}
+			if s[j] == '(' {
+				parens++
+			} else if s[j] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+			j++
+		}
+		dest = s[start:j]
+	}
+
+	j = skipSpaces(s, j)
+	if j < len(s) && (s[j] == '"' || s[j] == '\'' || s[j] == '(') {
+		closer := s[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		end := strings.IndexByte(s[j+1:], closer)
+		if end < 0 {
+			return "", "", "", 0, false
+		}
+		title = unescapeBackslashes(s[j+1 : j+1+end])
+		j = skipSpaces(s, j+end+2)
+	}
+	if j >= len(s) || s[j] != ')' {
+		return "", "", "", 0, false
+	}
+	return text, unescapeBackslashes(dest), title, j + 1, true
+}
+
+func skipSpaces(s string, j int) int {
+	for j < len(s) && isUnicodeSpace(s[j]) {
+		j++
+	}
+	return j
+}
+
+// plainText strips markdown emphasis and code markers, for image alt text.
+func plainText(s string) string {
+	return strings.NewReplacer("*", "", "_", "", "`", "", "~~", "").Replace(unescapeBackslashes(s))
+}
+
+// unescapeBackslashes removes backslashes before ASCII punctuation.
+func unescapeBackslashes(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isUnicodeSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordByte(c byte) bool {
+	return isASCIILetter(c) || (c >= '0' && c <= '9') || c >= 0x80
+}
@@ -0,0 +1,61 @@
+package richtext
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"paragraph", "Hello\nworld", "<p>Hello\nworld</p>\n"},
+		{"paragraphs", "a\n\n\nb", "<p>a</p>\n<p>b</p>\n"},
+		{"atx headings", "# One\n### Three ###", "<h1>One</h1>\n<h3>Three</h3>\n"},
+		{"setext headings", "One\n===\nTwo\n---", "<h1>One</h1>\n<h2>Two</h2>\n"},
+		{"emphasis", "*a* _b_ **c** __d__ ***e*** ~~f~~", "<p><em>a</em> <em>b</em> <strong>c</strong> <strong>d</strong> <em><strong>e</strong></em> <del>f</del></p>\n"},
+		{"nested emphasis", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"unmatched delimiters", "2 * 3 * 4 and **open", "<p>2 * 3 * 4 and **open</p>\n"},
+		{"code span", "use `a < b` and `` x`y ``", "<p>use <code>a &lt; b</code> and <code>x`y</code></p>\n"},
+		{"escapes", `\*not em\* 1 \< 2`, "<p>*not em* 1 &lt; 2</p>\n"},
+		{"entities", "&copy; & &amp; &#169; &bogus", "<p>&copy; &amp; &amp; &#169; &amp;bogus</p>\n"},
+		{"hard breaks", "a  \nb\\\nc", "<p>a<br />\nb<br />\nc</p>\n"},
+		{"link", `[a *b*](https://x.com "T")`, `<p><a href="https://x.com" title="T">a <em>b</em></a></p>` + "\n"},
+		{"link with parens", "[w](https://en.wikipedia.org/wiki/Go_(language))", `<p><a href="https://en.wikipedia.org/wiki/Go_(language)">w</a></p>` + "\n"},
+		{"image", `![alt *text*](/a.png)`, `<p><img alt="alt text" src="/a.png" /></p>` + "\n"},
+		{"not a link", "[a] (b)", "<p>[a] (b)</p>\n"},
+		{"autolinks", "<https://x.com> <a@b.co>", `<p><a href="https://x.com">https://x.com</a> <a href="mailto:a@b.co">a@b.co</a></p>` + "\n"},
+		{"inline html", "a <span>b</span>", "<p>a <span>b</span></p>\n"},
+		{"html block", "<div>\n*raw*\n</div>\n\n*md*", "<div>\n*raw*\n</div>\n<p><em>md</em></p>\n"},
+		{"thematic break", "a\n\n* * *\n\nb", "<p>a</p>\n<hr />\n<p>b</p>\n"},
+		{"fenced code", "```go\nif a < b {}\n```", `<pre><code class="language-go">if a &lt; b {}` + "\n</code></pre>\n"},
+		{"unclosed fence", "~~~\ncode", "<pre><code>code\n</code></pre>\n"},
+		{"indented code", "    a\n\n    b", "<pre><code>a\n\nb\n</code></pre>\n"},
+		{"blockquote", "> a\nlazy\n> # h", "<blockquote>\n<p>a\nlazy</p>\n<h1>h</h1>\n</blockquote>\n"},
+		{"tight list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"loose list", "- a\n\n- b", "<ul>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ul>\n"},
+		{"ordered list start", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"nested list", "1. a\n   - b\n2. c", "<ol>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n<li>c</li>\n</ol>\n"},
+		{"list marker change", "- a\n+ b", "<ul>\n<li>a</li>\n</ul>\n<ul>\n<li>b</li>\n</ul>\n"},
+		{"list interrupts paragraph", "text\n- a", "<p>text</p>\n<ul>\n<li>a</li>\n</ul>\n"},
+		{"number does not interrupt", "in\n2019. a year", "<p>in\n2019. a year</p>\n"},
+		{"crlf", "a\r\nb", "<p>a\nb</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.in); got != tt.want {
+				t.Errorf("RenderMarkdown(%q)\n got: %q\nwant: %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown_DefaultPolicyKeepsOutput(t *testing.T) {
+	// Everything the renderer emits must survive the default policy as is.
+	src := "# H\n\n*a* **b** ~~c~~ `d` [e](/e \"t\") ![f](/f.png \"t\")  \nx <https://x.com/?a=b>\n\n> q\n\n3. l\n\n```go\nx\n```\n\n---\n"
+	rendered := RenderMarkdown(src)
+	if got := NewPolicy(nil, nil).Sanitize(rendered); got != rendered {
+		t.Errorf("default policy changed rendered output\n got: %q\nwant: %q", got, rendered)
+	}
+}
@@ -0,0 +1,399 @@
+// Package richtext sanitizes and renders the markdown stored in richtext
+// content fields. Sanitization removes HTML that is not on a per-field
+// allowlist while leaving markdown syntax untouched, so it can run on the
+// stored source as well as on rendered HTML.
+package richtext
+
+import (
+	"html"
+	"sort"
+	"strings"
+)
+
+// DefaultTags are the tags allowed when a field does not configure its own
+// allowlist. They cover everything RenderMarkdown emits.
+var DefaultTags = []string{
+	"a", "b", "blockquote", "br", "code", "del", "em", "h1", "h2", "h3", "h4",
+	"h5", "h6", "hr", "i", "img", "li", "ol", "p", "pre", "s", "span", "strong",
+	"sub", "sup", "table", "tbody", "td", "th", "thead", "tr", "u", "ul",
+}
+
+// DefaultAttributes are the attributes allowed per tag when a field does not
+// configure its own.
+var DefaultAttributes = map[string][]string{
+	"a":    {"href", "title"},
+	"code": {"class"},
+	"img":  {"src", "alt", "title", "width", "height"},
+	"ol":   {"start"},
+	"td":   {"align", "colspan", "rowspan"},
+	"th":   {"align", "colspan", "rowspan"},
+}
+
+// unsafeTags can never be allowed: they execute script, load other
+// documents, or change how the surrounding page is interpreted.
+var unsafeTags = map[string]bool{
+	"applet": true, "base": true, "button": true, "embed": true, "form": true,
+	"frame": true, "frameset": true, "iframe": true, "input": true, "link": true,
+	"math": true, "meta": true, "noscript": true, "object": true, "script": true,
+	"select": true, "style": true, "svg": true, "template": true, "textarea": true,
+}
+
+// dropContentTags are removed together with everything up to their closing
+// tag, because their content is not meant to be displayed as text.
+var dropContentTags = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true,
+	"object": true, "script": true, "style": true, "template": true,
+	"textarea": true, "title": true, "xmp": true,
+}
+
+// urlAttributes hold URLs and are checked against safeSchemes.
+var urlAttributes = map[string]bool{
+	"action": true, "background": true, "cite": true, "formaction": true,
+	"href": true, "longdesc": true, "poster": true, "src": true,
+}
+
+// safeSchemes are the URL schemes allowed in urlAttributes. URLs without a
+// scheme (relative URLs and fragments) are always allowed.
+var safeSchemes = map[string]bool{
+	"http": true, "https": true, "mailto": true, "tel": true,
+}
+
+// UnsafeTag reports whether a tag can never be put on an allowlist.
+func UnsafeTag(name string) bool {
+	return unsafeTags[strings.ToLower(name)]
+}
+
+// UnsafeAttribute reports whether an attribute can never be put on an
+// allowlist: event handlers, inline styles, and inline documents.
+func UnsafeAttribute(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "on") || name == "style" || name == "srcdoc"
+}
+
+// Policy is an allowlist of HTML tags and, per tag, attributes.
+type Policy struct {
+	tags map[string]map[string]bool
+}
+
+// NewPolicy builds a policy from tag and attribute allowlists. A nil tags
+// slice selects DefaultTags and a nil attrs map selects DefaultAttributes;
+// empty non-nil values allow nothing. Unsafe tags and attributes are ignored
+// even if listed.
+func NewPolicy(tags []string, attrs map[string][]string) *Policy {
+	if tags == nil {
+		tags = DefaultTags
+	}
+	if attrs == nil {
+		attrs = DefaultAttributes
+	}
+
+	p := &Policy{tags: make(map[string]map[string]bool, len(tags))}
+	for _, t := range tags {
+		t = strings.ToLower(t)
+		if UnsafeTag(t) {
+			continue
+		}
+		allowed := make(map[string]bool)
+		for _, a := range attrs[t] {
+			if !UnsafeAttribute(a) {
+				allowed[strings.ToLower(a)] = true
+			}
+		}
+		p.tags[t] = allowed
+	}
+	return p
+}
+
+// Sanitize removes comments, disallowed tags, and disallowed or unsafe
+// attributes from s. Text outside of tags, including markdown syntax, is
+// left as is. The content of disallowed tags is kept, except for tags such
+// as script and style whose content is dropped as well.
+func (p *Policy) Sanitize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:lt])
+		s = s[lt:]
+
+		// Comments, doctypes, CDATA sections and processing instructions.
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				break
+			}
+			s = s[4+end+3:]
+			continue
+		}
+		if strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?") {
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				b.WriteString("&lt;")
+				s = s[1:]
+				continue
+			}
+			s = s[end+1:]
+			continue
+		}
+
+		// Markdown autolinks (<https://example.com>, <jane@example.com>)
+		// are kept for the renderer. As HTML they are unknown elements
+		// (the name contains ":" or "@"), harmless unless a browser would
+		// read an unsafe attribute from the rest of the URL.
+		m := autolinkRegex.FindString(s)
+		if m == "" {
+			m = emailRegex.FindString(s)
+		}
+		if m != "" && harmlessAutolink(m) {
+			b.WriteString(m)
+			s = s[len(m):]
+			continue
+		}
+
+		t, n, ok := parseTag(s)
+		if !ok {
+			// A "<" that does not start a complete tag. Escape it if a
+			// browser would start a tag here, so it cannot combine with
+			// later input, or if it is followed by markup that may be
+			// removed, as in "<<x>img ...>".
+			if len(s) > 1 && (isASCIILetter(s[1]) || s[1] == '/' || s[1] == '<') {
+				b.WriteString("&lt;")
+			} else {
+				b.WriteByte('<')
+			}
+			s = s[1:]
+			continue
+		}
+		s = s[n:]
+
+		if !t.closing && dropContentTags[t.name] && !t.selfClosing {
+			s = skipElement(s, t.name)
+			continue
+		}
+		allowed, ok := p.tags[t.name]
+		if !ok {
+			continue
+		}
+		writeTag(&b, t, allowed)
+	}
+
+	return b.String()
+}
+
+// harmlessAutolink reports whether an autolink, parsed as an HTML tag, is
+// an unknown element without unsafe attributes. Email autolinks may contain
+// "/", so <img/src/onerror=x@example.com> is an img element.
+func harmlessAutolink(m string) bool {
+	t, _, ok := parseTag(m)
+	if !ok || !strings.ContainsAny(t.name, ":@") {
+		return false
+	}
+	for _, a := range t.attrs {
+		if UnsafeAttribute(a.name) {
+			return false
+		}
+	}
+	return true
+}
+
+// tag is a parsed HTML start or end tag.
+type tag struct {
+	name        string
+	attrs       []attr
+	closing     bool
+	selfClosing bool
+}
+
+type attr struct {
+	name  string
+	value string // Entity-decoded.
+	bare  bool   // No value (e.g. <input disabled>).
+}
+
+// parseTag parses the tag at the start of s (which begins with "<") the way
+// an HTML tokenizer would, and returns the number of bytes consumed. ok is
+// false if s does not start with a complete tag.
+func parseTag(s string) (t tag, n int, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	if i >= len(s) || !isASCIILetter(s[i]) {
+		return t, 0, false
+	}
+
+	start := i
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	t.name = lowerASCII(s[start:i])
+
+	for {
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == '/') {
+			if s[i] == '/' && i+1 < len(s) && s[i+1] == '>' {
+				t.selfClosing = true
+			}
+			i++
+		}
+		if i >= len(s) {
+			return t, 0, false
+		}
+		if s[i] == '>' {
+			return t, i + 1, true
+		}
+
+		// Attribute name. A leading "=" belongs to the name, as in browsers.
+		start = i
+		i++
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' && s[i] != '=' {
+			i++
+		}
+		a := attr{name: lowerASCII(s[start:i]), bare: true}
+
+		j := i
+		for j < len(s) && isHTMLSpace(s[j]) {
+			j++
+		}
+		if j < len(s) && s[j] == '=' {
+			i = j + 1
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i >= len(s) {
+				return t, 0, false
+			}
+			switch q := s[i]; q {
+			case '"', '\'':
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return t, 0, false
+				}
+				a.value = s[i+1 : i+1+end]
+				i += end + 2
+			default:
+				start = i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				a.value = s[start:i]
+			}
+			a.value = html.UnescapeString(a.value)
+			a.bare = false
+		}
+		t.attrs = append(t.attrs, a)
+	}
+}
+
+// writeTag writes t with only the allowed, safe attributes. Values are
+// re-quoted and escaped.
+func writeTag(b *strings.Builder, t tag, allowed map[string]bool) {
+	b.WriteByte('<')
+	if t.closing {
+		b.WriteByte('/')
+		b.WriteString(t.name)
+		b.WriteByte('>')
+		return
+	}
+	b.WriteString(t.name)
+
+	seen := make(map[string]bool, len(t.attrs))
+	names := make([]string, 0, len(t.attrs))
+	values := make(map[string]attr, len(t.attrs))
+	for _, a := range t.attrs {
+		if seen[a.name] || !allowed[a.name] || UnsafeAttribute(a.name) {
+			continue
+		}
+		seen[a.name] = true // The first occurrence wins, as in browsers.
+		if urlAttributes[a.name] && !safeURL(a.value) {
+			continue
+		}
+		names = append(names, a.name)
+		values[a.name] = a
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a := values[name]
+		b.WriteByte(' ')
+		b.WriteString(name)
+		if !a.bare {
+			b.WriteString(`="`)
+			b.WriteString(html.EscapeString(a.value))
+			b.WriteByte('"')
+		}
+	}
+	if t.selfClosing {
+		b.WriteString(" /")
+	}
+	b.WriteByte('>')
+}
+
+// skipElement returns s after the closing tag of the named element, or ""
+// if it is never closed.
+func skipElement(s, name string) string {
+	lower := lowerASCII(s)
+	for {
+		i := strings.Index(lower, "</"+name)
+		if i < 0 {
+			return ""
+		}
+		after := i + 2 + len(name)
+		if after < len(s) && !isHTMLSpace(s[after]) && s[after] != '>' && s[after] != '/' {
+			// A longer tag name, e.g. </scripts>.
+			lower, s = lower[after:], s[after:]
+			continue
+		}
+		end := strings.IndexByte(s[after:], '>')
+		if end < 0 {
+			return ""
+		}
+		return s[after+end+1:]
+	}
+}
+
+// safeURL reports whether u is relative or uses a safe scheme. Browsers
+// ignore whitespace and control characters inside schemes, so they are
+// removed before the check.
+func safeURL(u string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+
+	colon := strings.IndexByte(cleaned, ':')
+	if colon < 0 {
+		return true
+	}
+	if i := strings.IndexAny(cleaned, "/?#"); i >= 0 && i < colon {
+		return true // The colon is in the path, query or fragment.
+	}
+	return safeSchemes[strings.ToLower(cleaned[:colon])]
+}
+
+// lowerASCII lowercases the ASCII letters in s, as browsers do for tag and
+// attribute names. Unlike strings.ToLower, it keeps byte offsets.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
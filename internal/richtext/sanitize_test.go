@@ -0,0 +1,368 @@
+package richtext
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Sanitize(t *testing.T) {
+	p := NewPolicy(nil, nil)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "a < b && c > d", "a < b && c > d"},
+		{"allowed tag", "<strong>hi</strong>", "<strong>hi</strong>"},
+		{"uppercase tag", "<STRONG>hi</STRONG>", "<strong>hi</strong>"},
+		{"disallowed tag keeps content", "<marquee>hi</marquee>", "hi"},
+		{"script dropped with content", "a<script>alert(1)</script>b", "ab"},
+		{"script case and spacing", "a<SCRIPT type=x>alert(1)</SCRIPT >b", "ab"},
+		{"unclosed script drops rest", "a<script>alert(1)", "a"},
+		{"style dropped", "<style>p{}</style>x", "x"},
+		{"longer tag name not a closer", "<script>x</scripts>y</script>z", "z"},
+		{"comment removed", "a<!-- hidden -->b", "ab"},
+		{"event handler removed", `<img src="/a.png" onerror="alert(1)">`, `<img src="/a.png">`},
+		{"unquoted attributes", `<img src=/a.png alt=hi>`, `<img alt="hi" src="/a.png">`},
+		{"disallowed attribute removed", `<a href="/x" target="_blank">x</a>`, `<a href="/x">x</a>`},
+		{"javascript url removed", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"obfuscated javascript url removed", `<a href="jav&#x09;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"uppercase scheme removed", `<a href=" JAVASCRIPT:alert(1)">x</a>`, `<a>x</a>`},
+		{"data url removed", `<img src="data:text/html;base64,xx">`, `<img>`},
+		{"relative url with colon kept", `<a href="/wiki/a:b">x</a>`, `<a href="/wiki/a:b">x</a>`},
+		{"mailto kept", `<a href="mailto:a@b.co">x</a>`, `<a href="mailto:a@b.co">x</a>`},
+		{"value escaped", `<a title='"><script>'>x</a>`, `<a title="&#34;&gt;&lt;script&gt;">x</a>`},
+		{"duplicate attribute first wins", `<a href="/a" href="javascript:x">x</a>`, `<a href="/a">x</a>`},
+		{"self closing", "<br/>", "<br />"},
+		{"unterminated tag escaped", "x <img src=x onerror=alert(1)", "x &lt;img src=x onerror=alert(1)"},
+		{"unterminated quote escaped", `<a href="x>y`, `&lt;a href="x>y`},
+		{"markup joined after removal", "<<x>img src=x onerror=alert(1)>", "&lt;img src=x onerror=alert(1)>"},
+		{"non-ascii before closing tag", "<script>İİ</SCRIPT>x", "x"},
+		{"url autolink kept", "<https://example.com/a?b=c>", "<https://example.com/a?b=c>"},
+		{"email autolink kept", "<jane@example.com>", "<jane@example.com>"},
+		{"autolink lookalike with handler", "<x:/onclick=alert(1)>", ""},
+		{"autolink lookalike with style", "<x:/style=position:fixed>", ""},
+		{"namespaced tag removed", "<x:y onclick=alert(1)>z", "z"},
+		{"doctype removed", "<!DOCTYPE html>x", "x"},
+		{"markdown untouched", "> quote\n- item\n**bold** & `code`", "> quote\n- item\n**bold** & `code`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got: %q\nwant: %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// xssVectors are the OWASP XSS filter evasion cheat sheet vectors that
+// apply to an HTML sanitizer, with the default policy's output.
+var xssVectors = []struct {
+	name string
+	in   string
+	want string
+}{
+	{"script src", `<SCRIPT SRC=https://xss.rocks/xss.js></SCRIPT>`, ``},
+	{"polyglot", `javascript:/*--></title></style></textarea></script></xmp><svg/onload='+/"/+/onmouseover=1/+/[*/[]/+alert(1)//'>`, `javascript:/*-->`},
+	{"img javascript url", `<IMG SRC="javascript:alert('XSS');">`, `<img>`},
+	{"no quotes no semicolon", `<IMG SRC=javascript:alert('XSS')>`, `<img>`},
+	{"case insensitive scheme", `<IMG SRC=JaVaScRiPt:alert('XSS')>`, `<img>`},
+	{"html entities", `<IMG SRC=javascript:alert(&quot;XSS&quot;)>`, `<img>`},
+	{"grave accent obfuscation", "<IMG SRC=`javascript:alert(\"RSnake says, 'XSS'\")`>", `<img>`},
+	{"malformed a tag", `<a onmouseover="alert(document.cookie)">xxs link</a>`, `<a>xxs link</a>`},
+	{"malformed a tag unquoted", `<a onmouseover=alert(document.cookie)>xxs link</a>`, `<a>xxs link</a>`},
+	{"malformed img tag", `<IMG """><SCRIPT>alert("XSS")</SCRIPT>"\>`, `<img>"\>`},
+	{"fromcharcode", `<IMG SRC=javascript:alert(String.fromCharCode(88,83,83))>`, `<img>`},
+	{"default src with handler", `<IMG SRC=# onmouseover="alert('xxs')">`, `<img src="#">`},
+	{"empty src with handler", `<IMG SRC= onmouseover="alert('xxs')">`, `<img src="onmouseover=&#34;alert(&#39;xxs&#39;)&#34;">`},
+	{"no src", `<IMG onmouseover="alert('xxs')">`, `<img>`},
+	{"on error alert", `<IMG SRC=/ onerror="alert(String.fromCharCode(88,83,83))"></img>`, `<img src="/"></img>`},
+	{"encoded handler", `<img src=x onerror="&#0000106&#0000097&#0000118&#0000097&#0000115&#0000099&#0000114&#0000105&#0000112&#0000116&#0000058&#0000097&#0000108&#0000101&#0000114&#0000116&#0000040&#0000039&#0000088&#0000083&#0000083&#0000039&#0000041">`, `<img src="x">`},
+	{"decimal entities", `<IMG SRC=&#106;&#97;&#118;&#97;&#115;&#99;&#114;&#105;&#112;&#116;&#58;&#97;&#108;&#101;&#114;&#116;&#40;&#39;&#88;&#83;&#83;&#39;&#41;>`, `<img>`},
+	{"padded decimal entities", `<IMG SRC=&#0000106&#0000097&#0000118&#0000097&#0000115&#0000099&#0000114&#0000105&#0000112&#0000116&#0000058&#0000097&#0000108&#0000101&#0000114&#0000116&#0000040&#0000039&#0000088&#0000083&#0000083&#0000039&#0000041>`, `<img>`},
+	{"hex entities", `<IMG SRC=&#x6A&#x61&#x76&#x61&#x73&#x63&#x72&#x69&#x70&#x74&#x3A&#x61&#x6C&#x65&#x72&#x74&#x28&#x27&#x58&#x53&#x53&#x27&#x29>`, `<img>`},
+	{"embedded tab", "<IMG SRC=\"jav\tascript:alert('XSS');\">", `<img>`},
+	{"encoded tab", `<IMG SRC="jav&#x09;ascript:alert('XSS');">`, `<img>`},
+	{"encoded newline", `<IMG SRC="jav&#x0A;ascript:alert('XSS');">`, `<img>`},
+	{"encoded carriage return", `<IMG SRC="jav&#x0D;ascript:alert('XSS');">`, `<img>`},
+	{"null byte", "<IMG SRC=java\x00script:alert(\"XSS\")>", `<img>`},
+	{"spaces and meta chars", `<IMG SRC=" &#14;  javascript:alert('XSS');">`, `<img>`},
+	{"named colon entity", `<a href="jAvAsCrIpT&colon;alert(1)">x</a>`, `<a>x</a>`},
+	{"named tab entity", `<a href="java&Tab;script:alert(1)">x</a>`, `<a>x</a>`},
+	{"non alpha non digit", `<SCRIPT/XSS SRC="http://xss.rocks/xss.js"></SCRIPT>`, ``},
+	{"non alpha handler", "<BODY onload!#$%&()*~+-_.,:;?@[/|\\]^`=alert(\"XSS\")>", ``},
+	{"slash separator", `<SCRIPT/SRC="http://xss.rocks/xss.js"></SCRIPT>`, ``},
+	{"extraneous open brackets", `<<SCRIPT>alert("XSS");//\<</SCRIPT>`, `&lt;`},
+	{"no closing script tag", `<SCRIPT SRC=http://xss.rocks/xss.js?< B >`, ``},
+	{"protocol resolution", `<SCRIPT SRC=//xss.rocks/.j>`, ``},
+	{"half open img", "<IMG SRC=\"`<javascript:alert>`('XSS')\"", "&lt;IMG SRC=\"`<javascript:alert>`('XSS')\""},
+	{"double open angle brackets", `<iframe src=http://xss.rocks/scriptlet.html <`, `&lt;iframe src=http://xss.rocks/scriptlet.html <`},
+	{"escaping title", `</TITLE><SCRIPT>alert("XSS");</SCRIPT>`, ``},
+	{"input image", `<INPUT TYPE="IMAGE" SRC="javascript:alert('XSS');">`, ``},
+	{"body background", `<BODY BACKGROUND="javascript:alert('XSS')">`, ``},
+	{"img dynsrc", `<IMG DYNSRC="javascript:alert('XSS')">`, `<img>`},
+	{"img lowsrc", `<IMG LOWSRC="javascript:alert('XSS')">`, `<img>`},
+	{"list style image", `<STYLE>li {list-style-image: url("javascript:alert('XSS')");}</STYLE><UL><LI>XSS</br>`, `<ul><li>XSS</br>`},
+	{"svg onload", `<svg/onload=alert('XSS')>`, ``},
+	{"bgsound", `<BGSOUND SRC="javascript:alert('XSS');">`, ``},
+	{"br javascript include", `<BR SIZE="&{alert('XSS')}">`, `<br>`},
+	{"stylesheet link", `<LINK REL="stylesheet" HREF="javascript:alert('XSS');">`, ``},
+	{"meta refresh", `<META HTTP-EQUIV="refresh" CONTENT="0;url=javascript:alert('XSS');">`, ``},
+	{"iframe", `<IFRAME SRC="javascript:alert('XSS');"></IFRAME>`, ``},
+	{"frameset", `<FRAMESET><FRAME SRC="javascript:alert('XSS');"></FRAMESET>`, ``},
+	{"table background", `<TABLE BACKGROUND="javascript:alert('XSS')">`, `<table>`},
+	{"td background", `<TABLE><TD BACKGROUND="javascript:alert('XSS')">`, `<table><td>`},
+	{"div background image", `<DIV STYLE="background-image: url(javascript:alert('XSS'))">`, ``},
+	{"div expression", `<DIV STYLE="width: expression(alert('XSS'));">`, ``},
+	{"img style expression", `<IMG STYLE="xss:expr/*XSS*/ession(alert('XSS'))">`, `<img>`},
+	{"anonymous html style", `<XSS STYLE="xss:expression(alert('XSS'))">`, ``},
+	{"downlevel hidden comment", `<!--[if gte IE 4]><SCRIPT>alert('XSS');</SCRIPT><![endif]-->`, ``},
+	{"base tag", `<BASE HREF="javascript:alert('XSS');//">`, ``},
+	{"object tag", `<OBJECT TYPE="text/x-scriptlet" DATA="http://xss.rocks/scriptlet.html"></OBJECT>`, ``},
+	{"embed svg", `<EMBED SRC="data:image/svg+xml;base64,PHN2ZyB4bWxuczpzdmc9Imh0dH" type="image/svg+xml" AllowScriptAccess="always"></EMBED>`, ``},
+	{"quoted gt in attribute", `<SCRIPT a=">" SRC="httx://xss.rocks/xss.js"></SCRIPT>`, ``},
+	{"quoted gt in nameless attribute", `<SCRIPT =">" SRC="httx://xss.rocks/xss.js"></SCRIPT>`, ``},
+	{"quoted gt in quoted name", `<SCRIPT "a='>'" SRC="httx://xss.rocks/xss.js"></SCRIPT>`, ``},
+	{"document write split", `<SCRIPT>document.write("<SCRI");</SCRIPT>PT SRC="httx://xss.rocks/xss.js"></SCRIPT>`, `PT SRC="httx://xss.rocks/xss.js">`},
+	{"vbscript url", `<a href="vbscript:msgbox('XSS')">x</a>`, `<a>x</a>`},
+	{"livescript url", `<IMG SRC="livescript:[code]">`, `<img>`},
+	{"utf-7 charset", `<HEAD><META HTTP-EQUIV="CONTENT-TYPE" CONTENT="text/html; charset=UTF-7"> </HEAD>+ADw-SCRIPT+AD4-alert('XSS');+ADw-/SCRIPT+AD4-`, ` +ADw-SCRIPT+AD4-alert('XSS');+ADw-/SCRIPT+AD4-`},
+	{"newlines in tag", "<IMG\nSRC\n=\n\"\njavascript:alert('XSS')\"\n>", `<img>`},
+	{"handler after quoted value", `<img src="x"onerror="alert(1)">`, `<img src="x">`},
+	{"email autolink lookalike", "<img/src/onerror=alert`1`//@x.co>", `<img src>`},
+	{"url autolink lookalike", `<https://x.co/a="x"onclick=alert(1)>`, ``},
+}
+
+func TestPolicy_SanitizeXSSVectors(t *testing.T) {
+	p := NewPolicy(nil, nil)
+	for _, tt := range xssVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Sanitize(tt.in)
+			if got != tt.want {
+				t.Errorf("Sanitize(%q)\n got: %q\nwant: %q", tt.in, got, tt.want)
+			}
+			if err := checkSafeHTML(p, got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestNewPolicy_Custom(t *testing.T) {
+	p := NewPolicy([]string{"p", "a", "script"}, map[string][]string{"a": {"href", "onclick", "rel"}})
+
+	got := p.Sanitize(`<p><a href="/x" rel="nofollow" onclick="x" title="t">x</a><em>y</em></p><script>z</script>`)
+	want := `<p><a href="/x" rel="nofollow">x</a>y</p>`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	none := NewPolicy([]string{}, nil)
+	if got := none.Sanitize("<p>text</p>"); got != "text" {
+		t.Errorf("empty allowlist: got %q, want %q", got, "text")
+	}
+}
+
+func TestPolicy_SanitizeMarkdown(t *testing.T) {
+	p := NewPolicy(nil, nil)
+
+	in := "Intro <script>alert(1)</script>and `<script>` span.\n\n" +
+		"```html\n<script>alert(1)</script>\n```\n\n" +
+		"~~~~\n```\n<b onclick=x>\n~~~~\n" +
+		"<img src=x onerror=alert(1)>"
+	want := "Intro and `<script>` span.\n\n" +
+		"```html\n<script>alert(1)</script>\n```\n\n" +
+		"~~~~\n```\n<b onclick=x>\n~~~~\n" +
+		`<img src="x">`
+
+	if got := p.SanitizeMarkdown(in); got != want {
+		t.Errorf("SanitizeMarkdown()\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestUnsafeNames(t *testing.T) {
+	for _, tag := range []string{"script", "IFRAME", "svg", "style"} {
+		if !UnsafeTag(tag) {
+			t.Errorf("UnsafeTag(%q) = false, want true", tag)
+		}
+	}
+	if UnsafeTag("p") {
+		t.Error(`UnsafeTag("p") = true, want false`)
+	}
+	for _, a := range []string{"onclick", "OnError", "style", "srcdoc"} {
+		if !UnsafeAttribute(a) {
+			t.Errorf("UnsafeAttribute(%q) = false, want true", a)
+		}
+	}
+	if UnsafeAttribute("href") {
+		t.Error(`UnsafeAttribute("href") = true, want false`)
+	}
+}
+
+func FuzzSanitize(f *testing.F) {
+	for _, tt := range xssVectors {
+		f.Add(tt.in)
+	}
+	f.Add("<https://example.com/a?b=c> <jane@example.com> **bold** `<b>`")
+	f.Add("<a href=\"/x\" title='t'>x</a><img src=/a.png alt=hi/><br/>")
+	f.Add("[x](javascript:alert(1)) ![y](/y.png \"t\") <x:/onclick=alert(1)>")
+
+	p := NewPolicy(nil, nil)
+	f.Fuzz(func(t *testing.T, in string) {
+		for _, out := range []string{p.Sanitize(in), p.Sanitize(RenderMarkdown(in))} {
+			if again := p.Sanitize(out); again != out {
+				t.Fatalf("Sanitize is not stable\n  in: %q\n out: %q\nagain: %q", in, out, again)
+			}
+			if err := checkSafeHTML(p, out); err != nil {
+				t.Fatalf("unsafe output: %v\n in: %q\nout: %q", err, in, out)
+			}
+		}
+	})
+}
+
+// checkSafeHTML tokenizes s the way a browser does and reports the first
+// tag, attribute or URL the policy does not allow. Unknown elements whose
+// name contains ":" or "@", as markdown autolinks parse, are allowed with
+// harmless attributes.
+func checkSafeHTML(p *Policy, s string) error {
+	for {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 || lt == len(s)-1 {
+			return nil
+		}
+		s = s[lt:]
+
+		switch c := s[1]; {
+		case c == '!' || c == '?':
+			return fmt.Errorf("markup declaration at %q", s)
+		case c == '/' && (len(s) < 3 || !isASCIILetter(s[2])):
+			s = s[1:]
+			continue
+		case c != '/' && !isASCIILetter(c):
+			s = s[1:]
+			continue
+		}
+
+		t, n := browserTag(s)
+		if n < 0 {
+			return fmt.Errorf("unterminated tag at %q", s)
+		}
+		s = s[n:]
+
+		allowed, ok := p.tags[t.name]
+		if !ok {
+			if !strings.ContainsAny(t.name, ":@") {
+				return fmt.Errorf("disallowed tag %q", t.name)
+			}
+			allowed = nil
+		}
+		seen := map[string]bool{}
+		for _, a := range t.attrs {
+			if seen[a.name] {
+				continue // Browsers ignore duplicates.
+			}
+			seen[a.name] = true
+			switch {
+			case strings.HasPrefix(a.name, "on") || a.name == "style" || a.name == "srcdoc":
+				return fmt.Errorf("unsafe attribute %q on %q", a.name, t.name)
+			case allowed != nil && !allowed[a.name]:
+				return fmt.Errorf("disallowed attribute %q on %q", a.name, t.name)
+			case allowed != nil && browserURLAttributes[a.name] && scriptURL(a.value):
+				return fmt.Errorf("script URL %q in %q", a.value, a.name)
+			}
+		}
+	}
+}
+
+// browserTag tokenizes the tag at the start of s following the HTML
+// tokenizer states from "tag open" on. It returns the bytes consumed, or -1
+// if the input ends inside the tag.
+func browserTag(s string) (t tag, n int) {
+	i := 1
+	if s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	t.name = strings.ToLower(s[1:i])
+	if t.closing {
+		t.name = t.name[1:]
+	}
+
+	for i < len(s) {
+		// Before attribute name.
+		switch {
+		case isHTMLSpace(s[i]) || s[i] == '/':
+			i++
+			continue
+		case s[i] == '>':
+			return t, i + 1
+		}
+		start := i
+		i++ // A leading "=" is part of the name.
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' && s[i] != '=' {
+			i++
+		}
+		a := attr{name: strings.ToLower(s[start:i])}
+		// After attribute name.
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			switch {
+			case i >= len(s):
+				return t, -1
+			case s[i] == '"' || s[i] == '\'':
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return t, -1
+				}
+				a.value = s[i+1 : i+1+end]
+				i += end + 2
+			case s[i] != '>':
+				start := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				a.value = s[start:i]
+			}
+		}
+		a.value = html.UnescapeString(a.value)
+		t.attrs = append(t.attrs, a)
+	}
+	return t, -1
+}
+
+// browserURLAttributes are the attributes browsers load or navigate to.
+var browserURLAttributes = map[string]bool{
+	"action": true, "background": true, "cite": true, "data": true, "dynsrc": true,
+	"formaction": true, "href": true, "longdesc": true, "lowsrc": true,
+	"poster": true, "src": true,
+}
+
+// scriptURL reports whether a browser would run u as script or load it as
+// an inline document.
+func scriptURL(u string) bool {
+	u = strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u))
+	for _, scheme := range []string{"javascript:", "vbscript:", "data:"} {
+		if strings.HasPrefix(u, scheme) {
+			return true
+		}
+	}
+	return false
+}
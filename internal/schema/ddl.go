@@ -69,17 +69,46 @@ func fieldSQLDefault(f Field) string {
 // or an empty string if there are none. The tableName parameter is used to
 // construct named CHECK constraints for enum fields.
 func fieldSQLConstraints(f Field, tableName string) string {
-	switch f.Type {
-	case FieldTypeMedia:
-		return fmt.Sprintf("REFERENCES %s(%s) ON DELETE SET NULL", quoteIdent("media"), quoteIdent("id"))
-	case FieldTypeRelation:
-		if f.RelationType == RelationOne {
-			return fmt.Sprintf("REFERENCES %s(%s) ON DELETE SET NULL", quoteIdent("ct_"+f.RelatesTo), quoteIdent("id"))
-		}
-		return ""
-	default:
+	if !f.IsForeignKey() {
 		return ""
 	}
+	return fmt.Sprintf("REFERENCES %s(%s) ON DELETE %s",
+		quoteIdent(fkTargetTable(f)), quoteIdent("id"), f.EffectiveOnDelete().SQL())
+}
+
+// fkTargetTable returns the table referenced by a media or relation field.
+func fkTargetTable(f Field) string {
+	if f.Type == FieldTypeMedia {
+		return "media"
+	}
+	return "ct_" + f.RelatesTo
+}
+
+// fkConstraintName returns the name PostgreSQL assigns to an unnamed foreign
+// key constraint on tableName(column), i.e. "{table}_{column}_fkey". Like
+// PostgreSQL's makeObjectName, the longer of the two parts is truncated
+// until the name fits in 63 bytes. Names are ASCII-only (enforced by the
+// validator), so byte-wise truncation matches the server's behaviour.
+func fkConstraintName(tableName, column string) string {
+	const maxIdentLen = 63
+	const label = "fkey"
+
+	avail := maxIdentLen - len(label) - 2 // Two "_" separators.
+	n1, n2 := len(tableName), len(column)
+	for n1+n2 > avail {
+		if n1 > n2 {
+			n1--
+		} else {
+			n2--
+		}
+	}
+	return tableName[:n1] + "_" + column[:n2] + "_" + label
+}
+
+// JunctionTableName returns the junction table name for a many-to-many
+// relation field on the given content type.
+func JunctionTableName(ctName, fieldName string) string {
+	return fmt.Sprintf("ct_%s_%s_rel", ctName, fieldName)
 }
 
 // enumCheckConstraint returns a named CHECK constraint clause for an enum field.
@@ -275,7 +304,7 @@ func generateSearchTrigger(tableName string, searchableFields []string) string {
 
 // generateJunctionTable generates a junction table for a many-to-many relation.
 func generateJunctionTable(sourceName string, f Field) string {
-	junctionTable := fmt.Sprintf("ct_%s_%s_rel", sourceName, f.Name)
+	junctionTable := JunctionTableName(sourceName, f.Name)
 	sourceTable := "ct_" + sourceName
 	targetTable := "ct_" + f.RelatesTo
 
@@ -283,8 +312,8 @@ func generateJunctionTable(sourceName string, f Field) string {
 	b.WriteString(fmt.Sprintf("\nCREATE TABLE %s (\n", quoteIdent(junctionTable)))
 	b.WriteString(fmt.Sprintf("    %s UUID NOT NULL REFERENCES %s(%s) ON DELETE CASCADE,\n",
 		quoteIdent("source_id"), quoteIdent(sourceTable), quoteIdent("id")))
-	b.WriteString(fmt.Sprintf("    %s UUID NOT NULL REFERENCES %s(%s) ON DELETE CASCADE,\n",
-		quoteIdent("target_id"), quoteIdent(targetTable), quoteIdent("id")))
+	b.WriteString(fmt.Sprintf("    %s UUID NOT NULL REFERENCES %s(%s) ON DELETE %s,\n",
+		quoteIdent("target_id"), quoteIdent(targetTable), quoteIdent("id"), f.EffectiveOnDelete().SQL()))
 	b.WriteString(fmt.Sprintf("    PRIMARY KEY (%s, %s)\n", quoteIdent("source_id"), quoteIdent("target_id")))
 	b.WriteString(");\n")
 
@@ -300,7 +329,7 @@ func GenerateDropTable(ct ContentType) string {
 	// Drop junction tables first (they reference the main table).
 	for _, f := range ct.Fields {
 		if f.Type == FieldTypeRelation && f.RelationType == RelationMany {
-			junctionTable := fmt.Sprintf("ct_%s_%s_rel", ct.Name, f.Name)
+			junctionTable := JunctionTableName(ct.Name, f.Name)
 			b.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", quoteIdent(junctionTable)))
 		}
 	}
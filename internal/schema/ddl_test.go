@@ -88,6 +88,9 @@ func TestFieldSQLConstraints(t *testing.T) {
 		{"media", Field{Type: FieldTypeMedia}, `REFERENCES "media"("id") ON DELETE SET NULL`},
 		{"relation_one", Field{Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationOne},
 			`REFERENCES "ct_authors"("id") ON DELETE SET NULL`},
+		{"media_restrict", Field{Type: FieldTypeMedia, OnDelete: OnDeleteRestrict}, `REFERENCES "media"("id") ON DELETE RESTRICT`},
+		{"relation_one_cascade", Field{Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationOne, OnDelete: OnDeleteCascade},
+			`REFERENCES "ct_authors"("id") ON DELETE CASCADE`},
 		{"string", Field{Type: FieldTypeString}, ""},
 		{"relation_many", Field{Type: FieldTypeRelation, RelationType: RelationMany}, ""},
 	}
@@ -101,6 +104,48 @@ func TestFieldSQLConstraints(t *testing.T) {
 	}
 }
 
+func TestGenerateJunctionTable_OnDelete(t *testing.T) {
+	f := Field{Name: "tags", Type: FieldTypeRelation, RelatesTo: "tags", RelationType: RelationMany}
+	got := generateJunctionTable("posts", f)
+	assertContains(t, got, `"target_id" UUID NOT NULL REFERENCES "ct_tags"("id") ON DELETE CASCADE`)
+
+	f.OnDelete = OnDeleteRestrict
+	got = generateJunctionTable("posts", f)
+	assertContains(t, got, `"source_id" UUID NOT NULL REFERENCES "ct_posts"("id") ON DELETE CASCADE`)
+	assertContains(t, got, `"target_id" UUID NOT NULL REFERENCES "ct_tags"("id") ON DELETE RESTRICT`)
+}
+
+func TestGenerateCreateTable_InverseFieldHasNoColumn(t *testing.T) {
+	ct := ContentType{
+		Name: "authors",
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeString},
+			{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "author"},
+		},
+	}
+	got := GenerateCreateTable(ct)
+	assertNotContains(t, got, `"posts"`)
+	assertNotContains(t, got, "_rel")
+}
+
+func TestFKConstraintName(t *testing.T) {
+	if got := fkConstraintName("ct_posts", "author"); got != "ct_posts_author_fkey" {
+		t.Errorf("fkConstraintName() = %q, want %q", got, "ct_posts_author_fkey")
+	}
+
+	// Long names are truncated PostgreSQL-style: the longer part loses
+	// characters first until the whole name fits in 63 bytes.
+	table := "ct_" + strings.Repeat("a", 57)
+	got := fkConstraintName(table, "cover_image")
+	if len(got) != 63 {
+		t.Fatalf("expected 63-byte name, got %d (%q)", len(got), got)
+	}
+	want := "ct_" + strings.Repeat("a", 43) + "_cover_image_fkey"
+	if got != want {
+		t.Errorf("fkConstraintName() = %q, want %q", got, want)
+	}
+}
+
 func TestEnumCheckConstraint(t *testing.T) {
 	f := Field{Name: "category", Type: FieldTypeEnum, Values: []string{"tech", "design"}}
 	got := enumCheckConstraint("ct_posts", f)
@@ -65,19 +65,25 @@ func DiffSchema(loaded ContentType, existing *ContentType) []Change {
 
 	var changes []Change
 
+	// Inverse relations are virtual and have no storage, so they never
+	// produce changes. A field switching between inverse and stored is
+	// diffed as a removal plus an addition.
+	loadedStored := storedFields(loaded.Fields)
+	existingStored := storedFields(existing.Fields)
+
 	// Build field maps for comparison.
-	existingFields := make(map[string]Field, len(existing.Fields))
-	for _, f := range existing.Fields {
+	existingFields := make(map[string]Field, len(existingStored))
+	for _, f := range existingStored {
 		existingFields[f.Name] = f
 	}
 
-	loadedFields := make(map[string]Field, len(loaded.Fields))
-	for _, f := range loaded.Fields {
+	loadedFields := make(map[string]Field, len(loadedStored))
+	for _, f := range loadedStored {
 		loadedFields[f.Name] = f
 	}
 
 	// Detect new fields (in loaded but not in existing).
-	for _, f := range loaded.Fields {
+	for _, f := range loadedStored {
 		if _, exists := existingFields[f.Name]; exists {
 			continue
 		}
@@ -150,14 +156,14 @@ func DiffSchema(loaded ContentType, existing *ContentType) []Change {
 	}
 
 	// Detect removed fields (in existing but not in loaded).
-	for _, f := range existing.Fields {
+	for _, f := range existingStored {
 		if _, exists := loadedFields[f.Name]; exists {
 			continue
 		}
 
 		if f.Type == FieldTypeRelation && f.RelationType == RelationMany {
 			// Drop junction table.
-			junctionTable := fmt.Sprintf("ct_%s_%s_rel", loaded.Name, f.Name)
+			junctionTable := JunctionTableName(loaded.Name, f.Name)
 			changes = append(changes, Change{
 				Type:   ChangeDropColumn,
 				Table:  tableName,
@@ -189,7 +195,7 @@ func DiffSchema(loaded ContentType, existing *ContentType) []Change {
 
 	// Detect type changes, nullability changes, and enum value changes for
 	// fields that exist in both loaded and existing.
-	for _, lf := range loaded.Fields {
+	for _, lf := range loadedStored {
 		ef, exists := existingFields[lf.Name]
 		if !exists {
 			continue
@@ -284,6 +290,13 @@ func DiffSchema(loaded ContentType, existing *ContentType) []Change {
 				Detail: fmt.Sprintf("drop unique index on %s.%s", tableName, lf.Name),
 			})
 		}
+
+		// Check on_delete changes. Only compared when the reference itself is
+		// unchanged; type and target changes are reported above.
+		if lf.Type == ef.Type && lf.RelationType == ef.RelationType && lf.RelatesTo == ef.RelatesTo &&
+			lf.EffectiveOnDelete() != ef.EffectiveOnDelete() {
+			changes = append(changes, diffOnDelete(loaded.Name, ef, lf)...)
+		}
 	}
 
 	// Check if searchable fields changed -- may need trigger update.
@@ -323,6 +336,18 @@ func DiffSchema(loaded ContentType, existing *ContentType) []Change {
 	return changes
 }
 
+// storedFields returns the fields that have storage (a column or a junction
+// table), i.e. all fields except inverse relations.
+func storedFields(fields []Field) []Field {
+	stored := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if !f.IsInverse() {
+			stored = append(stored, f)
+		}
+	}
+	return stored
+}
+
 // diffEnumValues generates changes to update enum CHECK constraints when the
 // allowed values change but the field type remains enum. Instead of ALTER
 // COLUMN TYPE (which cannot carry an inline CHECK), we drop the old named
@@ -386,3 +411,47 @@ func diffEnumValues(tableName string, existing, loaded Field) []Change {
 	}
 }
 
+// diffOnDelete generates changes to replace the foreign key constraint of a
+// media or relation field when its on_delete action changes. PostgreSQL
+// cannot alter the action of an existing constraint, so the constraint is
+// dropped and re-created under the same name. For relation-many fields the
+// affected constraint is the target_id FK on the junction table.
+//
+// Both changes are safe: existing rows already satisfy the reference, so
+// only the behaviour of future deletes changes.
+func diffOnDelete(ctName string, existing, loaded Field) []Change {
+	table, column := "ct_"+ctName, loaded.Name
+	if loaded.Type == FieldTypeRelation && loaded.RelationType == RelationMany {
+		table, column = JunctionTableName(ctName, loaded.Name), "target_id"
+	}
+	constraintName := fkConstraintName(table, column)
+
+	dropSQL := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;",
+		quoteIdent(table), quoteIdent(constraintName))
+	addSQL := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(%s) ON DELETE %s;",
+		quoteIdent(table),
+		quoteIdent(constraintName),
+		quoteIdent(column),
+		quoteIdent(fkTargetTable(loaded)),
+		quoteIdent("id"),
+		loaded.EffectiveOnDelete().SQL())
+
+	return []Change{
+		{
+			Type:   ChangeDropConstraint,
+			Table:  table,
+			Column: column,
+			SQL:    dropSQL,
+			Safe:   true,
+			Detail: fmt.Sprintf("drop foreign key %s on %s.%s for on_delete change", constraintName, table, column),
+		},
+		{
+			Type:   ChangeAddConstraint,
+			Table:  table,
+			Column: column,
+			SQL:    addSQL,
+			Safe:   true,
+			Detail: fmt.Sprintf("change on_delete of %s.%s from %s to %s", table, column, existing.EffectiveOnDelete(), loaded.EffectiveOnDelete()),
+		},
+	}
+}
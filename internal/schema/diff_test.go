@@ -847,6 +847,135 @@ func TestDiffSchema_RemoveEnumField_DropsConstraint(t *testing.T) {
 	assertContains(t, dropCols[0].SQL, `DROP COLUMN "category"`)
 }
 
+func TestDiffSchema_OnDeleteChange(t *testing.T) {
+	existing := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields: []Field{
+			{Name: "author", Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationOne},
+		},
+	}
+	loaded := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields: []Field{
+			{Name: "author", Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationOne, OnDelete: OnDeleteRestrict},
+		},
+	}
+
+	changes := DiffSchema(loaded, &existing)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+
+	drops := filterByType(changes, ChangeDropConstraint)
+	adds := filterByType(changes, ChangeAddConstraint)
+	if len(drops) != 1 || len(adds) != 1 {
+		t.Fatalf("expected 1 drop and 1 add constraint, got %d and %d", len(drops), len(adds))
+	}
+	for _, c := range changes {
+		if !c.Safe {
+			t.Errorf("on_delete change should be safe: %+v", c)
+		}
+	}
+	assertContains(t, drops[0].SQL, `DROP CONSTRAINT IF EXISTS "ct_posts_author_fkey"`)
+	assertContains(t, adds[0].SQL, `ADD CONSTRAINT "ct_posts_author_fkey" FOREIGN KEY ("author") REFERENCES "ct_authors"("id") ON DELETE RESTRICT`)
+}
+
+func TestDiffSchema_OnDeleteExplicitDefault_NoChange(t *testing.T) {
+	existing := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields:      []Field{{Name: "cover", Type: FieldTypeMedia}},
+	}
+	loaded := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields:      []Field{{Name: "cover", Type: FieldTypeMedia, OnDelete: OnDeleteSetNull}},
+	}
+
+	if changes := DiffSchema(loaded, &existing); len(changes) != 0 {
+		t.Errorf("expected no changes when on_delete spells out the default, got %+v", changes)
+	}
+}
+
+func TestDiffSchema_OnDeleteChange_ManyRelation(t *testing.T) {
+	existing := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields: []Field{
+			{Name: "tags", Type: FieldTypeRelation, RelatesTo: "tags", RelationType: RelationMany},
+		},
+	}
+	loaded := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields: []Field{
+			{Name: "tags", Type: FieldTypeRelation, RelatesTo: "tags", RelationType: RelationMany, OnDelete: OnDeleteRestrict},
+		},
+	}
+
+	changes := DiffSchema(loaded, &existing)
+	adds := filterByType(changes, ChangeAddConstraint)
+	if len(adds) != 1 {
+		t.Fatalf("expected 1 AddConstraint, got %d", len(adds))
+	}
+	if adds[0].Table != "ct_posts_tags_rel" {
+		t.Errorf("expected junction table, got %q", adds[0].Table)
+	}
+	assertContains(t, adds[0].SQL, `"ct_posts_tags_rel_target_id_fkey" FOREIGN KEY ("target_id") REFERENCES "ct_tags"("id") ON DELETE RESTRICT`)
+}
+
+func TestDiffSchema_InverseField_NoChange(t *testing.T) {
+	existing := ContentType{
+		Name:        "authors",
+		DisplayName: "Authors",
+		Fields:      []Field{{Name: "name", Type: FieldTypeString}},
+	}
+	loaded := ContentType{
+		Name:        "authors",
+		DisplayName: "Authors",
+		Fields: []Field{
+			{Name: "name", Type: FieldTypeString},
+			{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "author"},
+		},
+	}
+
+	if changes := DiffSchema(loaded, &existing); len(changes) != 0 {
+		t.Errorf("adding an inverse field should produce no changes, got %+v", changes)
+	}
+	if changes := DiffSchema(existing, &loaded); len(changes) != 0 {
+		t.Errorf("removing an inverse field should produce no changes, got %+v", changes)
+	}
+}
+
+func TestDiffSchema_InverseToStoredField(t *testing.T) {
+	existing := ContentType{
+		Name:        "authors",
+		DisplayName: "Authors",
+		Fields: []Field{
+			{Name: "featured", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "author"},
+		},
+	}
+	loaded := ContentType{
+		Name:        "authors",
+		DisplayName: "Authors",
+		Fields: []Field{
+			{Name: "featured", Type: FieldTypeRelation, RelatesTo: "posts", RelationType: RelationOne},
+		},
+	}
+
+	changes := DiffSchema(loaded, &existing)
+	adds := filterByType(changes, ChangeAddColumn)
+	if len(adds) != 1 {
+		t.Fatalf("expected 1 AddColumn, got %d: %+v", len(adds), changes)
+	}
+	assertContains(t, adds[0].SQL, `ADD COLUMN "featured" UUID`)
+	if len(filterByType(changes, ChangeAlterColumn)) != 0 {
+		t.Error("inverse to stored should not produce ALTER COLUMN")
+	}
+}
+
 // ----- Helpers -----
 
 func filterByType(changes []Change, ct ChangeType) []Change {
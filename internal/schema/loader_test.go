@@ -1278,6 +1278,460 @@ func TestValidateSchemas_RequiredRelationManyField_NotRejected(t *testing.T) {
 	}
 }
 
+// ----- on_delete -----
+
+func TestLoadSchemas_OnDelete(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "authors.yaml", `
+name: authors
+display_name: Authors
+fields:
+  - name: name
+    type: string
+`)
+	writeYAML(t, dir, "posts.yaml", `
+name: posts
+display_name: Posts
+fields:
+  - name: author
+    type: relation
+    relates_to: authors
+    relation_type: one
+    required: true
+    on_delete: restrict
+  - name: cover
+    type: media
+    on_delete: cascade
+`)
+
+	schemas, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatalf("LoadSchemas() error: %v", err)
+	}
+
+	var posts ContentType
+	for _, ct := range schemas {
+		if ct.Name == "posts" {
+			posts = ct
+		}
+	}
+	if got := posts.Fields[0].OnDelete; got != OnDeleteRestrict {
+		t.Errorf("author on_delete = %q, want %q", got, OnDeleteRestrict)
+	}
+	if got := posts.Fields[1].OnDelete; got != OnDeleteCascade {
+		t.Errorf("cover on_delete = %q, want %q", got, OnDeleteCascade)
+	}
+}
+
+func TestValidateSchemas_OnDelete(t *testing.T) {
+	authors := ContentType{
+		Name:        "authors",
+		DisplayName: "Authors",
+		Fields:      []Field{{Name: "name", Type: FieldTypeString}},
+	}
+
+	tests := []struct {
+		name    string
+		field   Field
+		wantErr string
+	}{
+		{"invalid value", Field{Name: "cover", Type: FieldTypeMedia, OnDelete: "nullify"}, "on_delete must be one of"},
+		{"non-reference field", Field{Name: "title", Type: FieldTypeString, OnDelete: OnDeleteCascade}, "on_delete is only valid on media and relation types"},
+		{"set_null on many", Field{Name: "authors", Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationMany, OnDelete: OnDeleteSetNull}, `on_delete "set_null" is not valid`},
+		{"required with explicit set_null", Field{Name: "cover", Type: FieldTypeMedia, Required: true, OnDelete: OnDeleteSetNull}, "required is not supported on media/relation fields"},
+		{"required with restrict", Field{Name: "cover", Type: FieldTypeMedia, Required: true, OnDelete: OnDeleteRestrict}, ""},
+		{"required relation with cascade", Field{Name: "author", Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationOne, Required: true, OnDelete: OnDeleteCascade}, ""},
+		{"many with restrict", Field{Name: "authors", Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationMany, OnDelete: OnDeleteRestrict}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemas := []ContentType{authors, {
+				Name:        "posts",
+				DisplayName: "Posts",
+				Fields:      []Field{tt.field},
+			}}
+			err := ValidateSchemas(schemas)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid, got: %v", err)
+				}
+				return
+			}
+			requireValidationError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// ----- inverse_of -----
+
+func TestValidateSchemas_InverseOf(t *testing.T) {
+	posts := ContentType{
+		Name:        "posts",
+		DisplayName: "Posts",
+		Fields: []Field{
+			{Name: "title", Type: FieldTypeString},
+			{Name: "author", Type: FieldTypeRelation, RelatesTo: "authors", RelationType: RelationOne},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		field   Field
+		wantErr string
+	}{
+		{"valid", Field{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "author"}, ""},
+		{"unknown forward field", Field{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "writer"}, `inverse_of references unknown field "writer"`},
+		{"forward not a relation", Field{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "title"}, `inverse_of field posts.title must be a relation with relates_to "authors"`},
+		{"relation_type set", Field{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "author", RelationType: RelationMany}, "relation_type must not be set on inverse relations"},
+		{"required", Field{Name: "posts", Type: FieldTypeRelation, RelatesTo: "posts", InverseOf: "author", Required: true}, "not valid on inverse relations"},
+		{"non-relation", Field{Name: "posts", Type: FieldTypeString, InverseOf: "author"}, "inverse_of is only valid on relation type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authors := ContentType{
+				Name:        "authors",
+				DisplayName: "Authors",
+				Fields:      []Field{{Name: "name", Type: FieldTypeString}, tt.field},
+			}
+			err := ValidateSchemas([]ContentType{authors, posts})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid, got: %v", err)
+				}
+				return
+			}
+			requireValidationError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// ----- rules -----
+
+func TestLoadSchemas_Rules(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "events.yaml", `
+name: events
+display_name: Events
+fields:
+  - name: featured
+    type: boolean
+  - name: cta_url
+    type: string
+  - name: start_date
+    type: date
+  - name: end_date
+    type: date
+  - name: image
+    type: media
+  - name: video
+    type: string
+rules:
+  - type: required_if
+    field: cta_url
+    when: featured
+    equals: true
+  - type: compare
+    field: end_date
+    operator: gt
+    other: start_date
+  - type: at_least_one
+    fields: [image, video]
+    message: add an image or a video
+`)
+
+	schemas, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatalf("LoadSchemas() error: %v", err)
+	}
+	rules := schemas[0].Rules
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+	if rules[0].Type != RuleRequiredIf || rules[0].Equals != true {
+		t.Errorf("unexpected required_if rule: %+v", rules[0])
+	}
+	if rules[1].Operator != CompareGt || rules[1].Other != "start_date" {
+		t.Errorf("unexpected compare rule: %+v", rules[1])
+	}
+	if len(rules[2].Fields) != 2 || rules[2].Message != "add an image or a video" {
+		t.Errorf("unexpected at_least_one rule: %+v", rules[2])
+	}
+}
+
+func TestValidateSchemas_Rules(t *testing.T) {
+	fields := []Field{
+		{Name: "featured", Type: FieldTypeBoolean},
+		{Name: "cta_url", Type: FieldTypeString},
+		{Name: "kind", Type: FieldTypeEnum, Values: []string{"talk", "workshop"}},
+		{Name: "start_date", Type: FieldTypeDate},
+		{Name: "end_date", Type: FieldTypeDate},
+		{Name: "seats", Type: FieldTypeInt},
+		{Name: "image", Type: FieldTypeMedia},
+		{Name: "video", Type: FieldTypeString},
+		{Name: "speakers", Type: FieldTypeRelation, RelatesTo: "events", RelationType: RelationMany},
+	}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{"required_if valid", Rule{Type: RuleRequiredIf, Field: "cta_url", When: "featured", Equals: true}, ""},
+		{"required_if without equals", Rule{Type: RuleRequiredIf, Field: "cta_url", When: "video"}, ""},
+		{"required_if enum equals", Rule{Type: RuleRequiredIf, Field: "cta_url", When: "kind", Equals: "workshop"}, ""},
+		{"required_if unknown enum value", Rule{Type: RuleRequiredIf, Field: "cta_url", When: "kind", Equals: "panel"}, "is not one of the values"},
+		{"required_if equals type mismatch", Rule{Type: RuleRequiredIf, Field: "cta_url", When: "featured", Equals: "yes"}, "does not match the type"},
+		{"required_if unknown field", Rule{Type: RuleRequiredIf, Field: "nope", When: "featured"}, `field references unknown field "nope"`},
+		{"required_if missing when", Rule{Type: RuleRequiredIf, Field: "cta_url"}, "when is required"},
+		{"compare valid", Rule{Type: RuleCompare, Field: "end_date", Operator: CompareGt, Other: "start_date"}, ""},
+		{"compare bad operator", Rule{Type: RuleCompare, Field: "end_date", Operator: "after", Other: "start_date"}, "operator must be one of"},
+		{"compare mismatched types", Rule{Type: RuleCompare, Field: "seats", Operator: CompareGt, Other: "start_date"}, "cannot compare"},
+		{"compare text ordering", Rule{Type: RuleCompare, Field: "cta_url", Operator: CompareLt, Other: "video"}, "only support the eq and ne operators"},
+		{"compare text equality", Rule{Type: RuleCompare, Field: "cta_url", Operator: CompareNe, Other: "video"}, ""},
+		{"at_least_one valid", Rule{Type: RuleAtLeastOne, Fields: []string{"image", "video"}}, ""},
+		{"at_least_one single field", Rule{Type: RuleAtLeastOne, Fields: []string{"image"}}, "at least two fields"},
+		{"at_least_one relation many", Rule{Type: RuleAtLeastOne, Fields: []string{"image", "speakers"}}, "relation-many and inverse fields cannot be used"},
+		{"unknown type", Rule{Type: "forbidden_if"}, "invalid rule type"},
+		{"foreign attribute", Rule{Type: RuleAtLeastOne, Fields: []string{"image", "video"}, Operator: CompareEq}, "operator and other are only valid on compare rules"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchemas([]ContentType{{
+				Name:        "events",
+				DisplayName: "Events",
+				Fields:      fields,
+				Rules:       []Rule{tt.rule},
+			}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid, got: %v", err)
+				}
+				return
+			}
+			requireValidationError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// ----- format -----
+
+func TestValidateSchemas_Format(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   Field
+		wantErr string
+	}{
+		{"email", Field{Name: "f", Type: FieldTypeString, Format: FormatEmail}, ""},
+		{"url with schemes", Field{Name: "f", Type: FieldTypeString, Format: FormatURL, AllowedSchemes: []string{"https", "mailto"}}, ""},
+		{"unknown format", Field{Name: "f", Type: FieldTypeString, Format: "ipv4"}, "invalid format"},
+		{"format on text", Field{Name: "f", Type: FieldTypeText, Format: FormatEmail}, "format is only valid on string type"},
+		{"schemes without url format", Field{Name: "f", Type: FieldTypeString, Format: FormatEmail, AllowedSchemes: []string{"https"}}, "allowed_schemes is only valid with format url"},
+		{"uppercase scheme", Field{Name: "f", Type: FieldTypeString, Format: FormatURL, AllowedSchemes: []string{"HTTPS"}}, "must be a lowercase URL scheme"},
+		{"scheme with separator", Field{Name: "f", Type: FieldTypeString, Format: FormatURL, AllowedSchemes: []string{"https://"}}, "must be a lowercase URL scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchemas([]ContentType{{
+				Name:        "links",
+				DisplayName: "Links",
+				Fields:      []Field{tt.field},
+			}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid, got: %v", err)
+				}
+				return
+			}
+			requireValidationError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// ----- sanitize -----
+
+func TestValidateSchemas_Sanitize(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   Field
+		wantErr string
+	}{
+		{"default policy", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{}}, ""},
+		{"custom policy", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{
+			AllowedTags:       []string{"p", "a"},
+			AllowedAttributes: map[string][]string{"a": {"href", "rel"}},
+		}}, ""},
+		{"attributes with default tags", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{
+			AllowedAttributes: map[string][]string{"a": {"href"}},
+		}}, ""},
+		{"not richtext", Field{Name: "body", Type: FieldTypeText, Sanitize: &SanitizePolicy{}}, "sanitize is only valid on richtext type"},
+		{"unsafe tag", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{AllowedTags: []string{"p", "script"}}}, `tag "script" cannot be allowed`},
+		{"invalid tag name", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{AllowedTags: []string{"<p>"}}}, "must be a lowercase HTML tag name"},
+		{"event handler", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{
+			AllowedTags:       []string{"a"},
+			AllowedAttributes: map[string][]string{"a": {"onclick"}},
+		}}, `attribute "onclick" cannot be allowed`},
+		{"style attribute", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{
+			AllowedAttributes: map[string][]string{"p": {"style"}},
+		}}, `attribute "style" cannot be allowed`},
+		{"attributes for unlisted tag", Field{Name: "body", Type: FieldTypeRichText, Sanitize: &SanitizePolicy{
+			AllowedTags:       []string{"p"},
+			AllowedAttributes: map[string][]string{"a": {"href"}},
+		}}, `tag "a" is not in allowed_tags`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchemas([]ContentType{{
+				Name:        "posts",
+				DisplayName: "Posts",
+				Fields:      []Field{tt.field},
+			}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid, got: %v", err)
+				}
+				return
+			}
+			requireValidationError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadSchemas_Sanitize(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "posts.yaml", `
+name: posts
+display_name: Posts
+fields:
+  - name: body
+    type: richtext
+    sanitize:
+      allowed_tags: [p, a]
+      allowed_attributes:
+        a: [href]
+  - name: plain
+    type: richtext
+    sanitize:
+      allowed_tags: []
+`)
+
+	schemas, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatalf("LoadSchemas() error: %v", err)
+	}
+	body := schemas[0].Fields[0].Sanitize
+	if body == nil || len(body.AllowedTags) != 2 || body.AllowedAttributes["a"][0] != "href" {
+		t.Errorf("unexpected sanitize policy: %+v", body)
+	}
+	plain := schemas[0].Fields[1].Sanitize
+	if plain == nil || plain.AllowedTags == nil || len(plain.AllowedTags) != 0 {
+		t.Errorf("expected empty non-nil allowed_tags, got %+v", plain)
+	}
+}
+
+// ----- workflow -----
+
+func TestLoadSchemas_Workflow(t *testing.T) {
+	dir := t.TempDir()
+	writeYAML(t, dir, "posts.yaml", `
+name: posts
+display_name: Posts
+fields:
+  - name: title
+    type: string
+workflow:
+  stages:
+    - name: draft
+    - name: in_review
+    - name: approved
+    - name: published
+      published: true
+  transitions:
+    - from: draft
+      to: in_review
+      roles: [author, editor]
+    - from: in_review
+      to: approved
+      roles: [editor]
+    - from: approved
+      to: published
+      roles: [editor, admin]
+`)
+
+	schemas, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatalf("LoadSchemas() error: %v", err)
+	}
+	wf := schemas[0].Workflow
+	if wf == nil {
+		t.Fatal("expected workflow to be loaded")
+	}
+	if got := wf.InitialStage(); got != "draft" {
+		t.Errorf("InitialStage() = %q, want %q", got, "draft")
+	}
+	if st, ok := wf.Stage("published"); !ok || !st.Published {
+		t.Errorf("expected published stage, got %+v (found %v)", st, ok)
+	}
+	tr, ok := wf.Transition("draft", "in_review")
+	if !ok || !tr.Allows("author") || tr.Allows("viewer") {
+		t.Errorf("unexpected draft -> in_review transition: %+v (found %v)", tr, ok)
+	}
+	if _, ok := wf.Transition("draft", "published"); ok {
+		t.Error("expected no draft -> published transition")
+	}
+}
+
+func TestValidateSchemas_Workflow(t *testing.T) {
+	stages := []WorkflowStage{{Name: "draft"}, {Name: "review"}, {Name: "live", Published: true}}
+	valid := []WorkflowTransition{
+		{From: "draft", To: "review", Roles: []string{"author"}},
+		{From: "review", To: "live", Roles: []string{"editor"}},
+	}
+
+	tests := []struct {
+		name     string
+		workflow Workflow
+		wantErr  string
+	}{
+		{"valid", Workflow{Stages: stages, Transitions: valid}, ""},
+		{"explicit initial", Workflow{Initial: "review", Stages: stages, Transitions: valid}, ""},
+		{"single stage", Workflow{Stages: stages[:1]}, "at least two stages"},
+		{"bad stage name", Workflow{Stages: []WorkflowStage{{Name: "Draft"}, {Name: "live"}}}, "name must match"},
+		{"duplicate stage", Workflow{Stages: []WorkflowStage{{Name: "draft"}, {Name: "draft"}}}, `duplicate stage "draft"`},
+		{"unknown initial", Workflow{Initial: "nope", Stages: stages}, `initial stage "nope" does not exist`},
+		{"published initial", Workflow{Initial: "live", Stages: stages}, "must not be a published stage"},
+		{"unknown from", Workflow{Stages: stages, Transitions: []WorkflowTransition{{From: "nope", To: "live", Roles: []string{"editor"}}}}, `from references unknown stage "nope"`},
+		{"unknown to", Workflow{Stages: stages, Transitions: []WorkflowTransition{{From: "draft", To: "nope", Roles: []string{"editor"}}}}, `to references unknown stage "nope"`},
+		{"self transition", Workflow{Stages: stages, Transitions: []WorkflowTransition{{From: "draft", To: "draft", Roles: []string{"editor"}}}}, "from and to must differ"},
+		{"no roles", Workflow{Stages: stages, Transitions: []WorkflowTransition{{From: "draft", To: "review"}}}, "at least one role is required"},
+		{"bad role", Workflow{Stages: stages, Transitions: []WorkflowTransition{{From: "draft", To: "review", Roles: []string{"Editor"}}}}, `role "Editor" must match`},
+		{"duplicate transition", Workflow{Stages: stages, Transitions: append(valid, valid[0])}, "duplicate transition"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := tt.workflow
+			err := ValidateSchemas([]ContentType{{
+				Name:        "posts",
+				DisplayName: "Posts",
+				Fields:      []Field{{Name: "title", Type: FieldTypeString}},
+				Workflow:    &wf,
+			}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid, got: %v", err)
+				}
+				return
+			}
+			requireValidationError(t, err, tt.wantErr)
+		})
+	}
+}
+
 // ----- Helpers -----
 
 // requireValidationError asserts that err is a *ValidationError containing
@@ -0,0 +1,21 @@
+package schema
+
+import "fmt"
+
+// Reference identifies a content entry that points at another record (an
+// entry or a media file) through one of its media or relation fields.
+type Reference struct {
+	ContentType string `json:"content_type"`
+	EntryID     string `json:"entry_id"`
+	Field       string `json:"field"`
+}
+
+// ReferencedError is returned when a delete is rejected because content
+// entries still reference the record through fields with on_delete: restrict.
+type ReferencedError struct {
+	References []Reference
+}
+
+func (e *ReferencedError) Error() string {
+	return fmt.Sprintf("record is referenced by %d entries", len(e.References))
+}
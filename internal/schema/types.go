@@ -46,6 +46,66 @@ const (
 	RelationMany RelationType = "many"
 )
 
+// StringFormat is a built-in value format for string fields, validated with
+// a dedicated parser instead of a hand-written regex.
+type StringFormat string
+
+// Supported string formats.
+const (
+	FormatEmail    StringFormat = "email"
+	FormatURL      StringFormat = "url"
+	FormatUUID     StringFormat = "uuid"
+	FormatHexColor StringFormat = "hex_color"
+	FormatPhone    StringFormat = "phone"
+)
+
+// validStringFormats is the set of all supported string formats.
+var validStringFormats = map[StringFormat]bool{
+	FormatEmail:    true,
+	FormatURL:      true,
+	FormatUUID:     true,
+	FormatHexColor: true,
+	FormatPhone:    true,
+}
+
+// DefaultURLSchemes are the schemes accepted by url-formatted fields that do
+// not set allowed_schemes.
+var DefaultURLSchemes = []string{"http", "https"}
+
+// OnDeleteAction controls what happens to a referencing entry when the record
+// it points at (a media file or a related entry) is deleted.
+type OnDeleteAction string
+
+// Supported on-delete actions.
+const (
+	// OnDeleteRestrict blocks the delete while any entry still references the record.
+	OnDeleteRestrict OnDeleteAction = "restrict"
+	// OnDeleteSetNull clears the referencing column. Not valid on relation-many fields.
+	OnDeleteSetNull OnDeleteAction = "set_null"
+	// OnDeleteCascade deletes the referencing entry (or junction row) as well.
+	OnDeleteCascade OnDeleteAction = "cascade"
+)
+
+// validOnDeleteActions is the set of all supported on-delete actions.
+var validOnDeleteActions = map[OnDeleteAction]bool{
+	OnDeleteRestrict: true,
+	OnDeleteSetNull:  true,
+	OnDeleteCascade:  true,
+}
+
+// SQL returns the SQL keyword sequence for the action, as used in an
+// ON DELETE clause.
+func (a OnDeleteAction) SQL() string {
+	switch a {
+	case OnDeleteRestrict:
+		return "RESTRICT"
+	case OnDeleteCascade:
+		return "CASCADE"
+	default:
+		return "SET NULL"
+	}
+}
+
 // ContentType represents a parsed YAML content type schema definition.
 type ContentType struct {
 	// Name is the internal identifier (snake_case), used in table names and API routes.
@@ -60,6 +120,14 @@ type ContentType struct {
 	// Fields defines the list of fields for this content type.
 	Fields []Field `yaml:"fields"`
 
+	// Rules defines cross-field validation rules evaluated on create and update.
+	Rules []Rule `yaml:"rules,omitempty"`
+
+	// Workflow optionally defines editorial stages entries move through
+	// before publication. When set, entries can only be published by a
+	// workflow transition.
+	Workflow *Workflow `yaml:"workflow,omitempty"`
+
 	// SchemaHash is the SHA256 hex digest of the raw YAML file bytes.
 	// It is computed after loading and is not deserialized from YAML.
 	SchemaHash string `yaml:"-"`
@@ -99,6 +167,18 @@ type Field struct {
 	// Regex is a Go regular expression pattern for validation. Only valid on string type.
 	Regex string `yaml:"regex,omitempty"`
 
+	// Format is a built-in value format (email, url, uuid, hex_color, phone).
+	// Only valid on string type.
+	Format StringFormat `yaml:"format,omitempty"`
+
+	// AllowedSchemes restricts the URL schemes accepted by url-formatted
+	// fields. Empty means DefaultURLSchemes. Only valid with format url.
+	AllowedSchemes []string `yaml:"allowed_schemes,omitempty"`
+
+	// Sanitize configures the HTML allowlist applied to richtext values on
+	// write. Nil means the default policy. Only valid on richtext type.
+	Sanitize *SanitizePolicy `yaml:"sanitize,omitempty"`
+
 	// Values is the list of allowed values for enum fields.
 	Values []string `yaml:"values,omitempty"`
 
@@ -107,4 +187,220 @@ type Field struct {
 
 	// RelationType is the cardinality of the relation (one or many).
 	RelationType RelationType `yaml:"relation_type,omitempty"`
+
+	// OnDelete controls what happens when the referenced record is deleted.
+	// Only valid on media and relation fields. Empty means the default for the
+	// field kind (see EffectiveOnDelete).
+	OnDelete OnDeleteAction `yaml:"on_delete,omitempty"`
+
+	// InverseOf makes a relation field virtual: it names the relation field on
+	// the relates_to content type that points back at this type, and the field
+	// exposes the IDs of the entries referencing this one. Inverse fields have
+	// no column, are read-only, and must not set relation_type.
+	InverseOf string `yaml:"inverse_of,omitempty"`
+}
+
+// URLSchemes returns the schemes accepted by a url-formatted field.
+func (f Field) URLSchemes() []string {
+	if len(f.AllowedSchemes) > 0 {
+		return f.AllowedSchemes
+	}
+	return DefaultURLSchemes
+}
+
+// IsInverse reports whether the field is a virtual inverse relation.
+func (f Field) IsInverse() bool {
+	return f.Type == FieldTypeRelation && f.InverseOf != ""
+}
+
+// HasColumn reports whether the field is stored as a column on the content
+// table. Relation-many fields live in junction tables and inverse relations
+// are computed from the referencing side.
+func (f Field) HasColumn() bool {
+	if f.Type != FieldTypeRelation {
+		return true
+	}
+	return f.RelationType == RelationOne && !f.IsInverse()
+}
+
+// IsForeignKey reports whether the field is stored as a UUID column with a
+// foreign key on the content table itself (media and relation-one fields).
+func (f Field) IsForeignKey() bool {
+	return f.Type == FieldTypeMedia || (f.Type == FieldTypeRelation && f.RelationType == RelationOne && !f.IsInverse())
+}
+
+// EffectiveOnDelete returns the on-delete action that applies to the field,
+// filling in the default when none is configured: set_null for media and
+// relation-one fields, cascade for relation-many junction rows. Returns an
+// empty action for fields that do not reference other records.
+func (f Field) EffectiveOnDelete() OnDeleteAction {
+	if f.OnDelete != "" {
+		return f.OnDelete
+	}
+	if f.IsForeignKey() {
+		return OnDeleteSetNull
+	}
+	if f.Type == FieldTypeRelation && f.RelationType == RelationMany && !f.IsInverse() {
+		return OnDeleteCascade
+	}
+	return ""
+}
+
+// SanitizePolicy is the per-field HTML allowlist for richtext fields.
+type SanitizePolicy struct {
+	// AllowedTags lists the HTML tags kept in the field. Omitted means the
+	// default set; an empty list removes all HTML.
+	AllowedTags []string `yaml:"allowed_tags"`
+
+	// AllowedAttributes lists, per tag, the attributes kept on it. Omitted
+	// means the default attributes for the allowed tags.
+	AllowedAttributes map[string][]string `yaml:"allowed_attributes"`
+}
+
+// RuleType is the kind of a cross-field validation rule.
+type RuleType string
+
+// Supported rule types.
+const (
+	// RuleRequiredIf makes Field required when the When field matches Equals
+	// (or, without Equals, when the When field is set).
+	RuleRequiredIf RuleType = "required_if"
+	// RuleCompare requires Field to compare to Other using Operator.
+	RuleCompare RuleType = "compare"
+	// RuleAtLeastOne requires at least one of Fields to be set.
+	RuleAtLeastOne RuleType = "at_least_one"
+)
+
+// validRuleTypes is the set of all supported rule types.
+var validRuleTypes = map[RuleType]bool{
+	RuleRequiredIf: true,
+	RuleCompare:    true,
+	RuleAtLeastOne: true,
+}
+
+// CompareOperator is the comparison applied by a compare rule.
+type CompareOperator string
+
+// Supported compare operators. The rule passes when "Field <op> Other" holds.
+const (
+	CompareEq  CompareOperator = "eq"
+	CompareNe  CompareOperator = "ne"
+	CompareLt  CompareOperator = "lt"
+	CompareLte CompareOperator = "lte"
+	CompareGt  CompareOperator = "gt"
+	CompareGte CompareOperator = "gte"
+)
+
+// validCompareOperators is the set of all supported compare operators.
+var validCompareOperators = map[CompareOperator]bool{
+	CompareEq:  true,
+	CompareNe:  true,
+	CompareLt:  true,
+	CompareLte: true,
+	CompareGt:  true,
+	CompareGte: true,
+}
+
+// Rule is a declarative cross-field validation rule. Which attributes apply
+// depends on Type.
+type Rule struct {
+	// Type selects the rule kind (required_if, compare, at_least_one).
+	Type RuleType `yaml:"type"`
+
+	// Field is the field the rule constrains (required_if, compare).
+	Field string `yaml:"field,omitempty"`
+
+	// When is the field whose value triggers a required_if rule.
+	When string `yaml:"when,omitempty"`
+
+	// Equals is the value of When that triggers a required_if rule. If
+	// omitted, the rule triggers whenever When is set (non-null, non-empty
+	// and not false).
+	Equals any `yaml:"equals,omitempty"`
+
+	// Operator is the comparison for compare rules (eq, ne, lt, lte, gt, gte).
+	Operator CompareOperator `yaml:"operator,omitempty"`
+
+	// Other is the field Field is compared against in compare rules.
+	Other string `yaml:"other,omitempty"`
+
+	// Fields lists the candidate fields for at_least_one rules.
+	Fields []string `yaml:"fields,omitempty"`
+
+	// Message optionally overrides the default error message.
+	Message string `yaml:"message,omitempty"`
+}
+
+// Workflow defines the editorial stages of a content type and which roles may
+// move entries between them.
+type Workflow struct {
+	// Initial is the stage new entries start in. Defaults to the first stage.
+	Initial string `yaml:"initial,omitempty"`
+
+	// Stages lists the workflow stages in display order.
+	Stages []WorkflowStage `yaml:"stages"`
+
+	// Transitions lists the allowed stage changes. Any change not listed is
+	// rejected.
+	Transitions []WorkflowTransition `yaml:"transitions"`
+}
+
+// WorkflowStage is a named step of a workflow.
+type WorkflowStage struct {
+	// Name identifies the stage (snake_case).
+	Name string `yaml:"name"`
+
+	// Published marks stages whose entries are publicly visible. Entering
+	// such a stage publishes the entry; leaving it unpublishes it.
+	Published bool `yaml:"published,omitempty"`
+}
+
+// WorkflowTransition allows admins with one of Roles to move an entry from
+// one stage to another.
+type WorkflowTransition struct {
+	From  string   `yaml:"from"`
+	To    string   `yaml:"to"`
+	Roles []string `yaml:"roles"`
+}
+
+// InitialStage returns the stage new entries start in.
+func (w *Workflow) InitialStage() string {
+	if w.Initial != "" {
+		return w.Initial
+	}
+	if len(w.Stages) > 0 {
+		return w.Stages[0].Name
+	}
+	return ""
+}
+
+// Stage returns the named stage and whether it exists.
+func (w *Workflow) Stage(name string) (WorkflowStage, bool) {
+	for _, st := range w.Stages {
+		if st.Name == name {
+			return st, true
+		}
+	}
+	return WorkflowStage{}, false
+}
+
+// Transition returns the transition from one stage to another and whether it
+// exists.
+func (w *Workflow) Transition(from, to string) (WorkflowTransition, bool) {
+	for _, t := range w.Transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return WorkflowTransition{}, false
+}
+
+// Allows reports whether an admin with the given role may perform t.
+func (t WorkflowTransition) Allows(role string) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
@@ -3,13 +3,22 @@ package schema
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/GyroZepelix/mithril-cms/internal/richtext"
 )
 
 // namePattern matches valid content type and field names: lowercase letter
 // followed by lowercase letters, digits, or underscores.
 var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
 
+// htmlNamePattern matches a lowercase HTML tag or attribute name.
+var htmlNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// schemePattern matches a lowercase URL scheme (RFC 3986 section 3.1).
+var schemePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
 // sqlReservedWords is a set of SQL keywords that must not be used as content
 // type names because they would collide with SQL syntax in generated DDL.
 var sqlReservedWords = map[string]bool{
@@ -97,11 +106,11 @@ var numericFieldTypes = map[FieldType]bool{
 // between content types (e.g., relation targets). It returns a multi-error
 // listing ALL validation problems found, or nil if all schemas are valid.
 func ValidateSchemas(schemas []ContentType) error {
-	// Build a set of known content type names for relation target validation.
-	knownTypes := make(map[string]bool, len(schemas))
+	// Index content types by name for relation target and inverse validation.
+	knownTypes := make(map[string]ContentType, len(schemas))
 	for _, ct := range schemas {
 		if ct.Name != "" {
-			knownTypes[ct.Name] = true
+			knownTypes[ct.Name] = ct
 		}
 	}
 
@@ -153,9 +162,9 @@ const maxContentTypeNameLength = 59
 const maxFieldNameLength = 63
 
 // validateContentType validates a single content type and returns a list of
-// validation error messages. It receives the set of all known content type
-// names for relation target validation.
-func validateContentType(ct ContentType, knownTypes map[string]bool) []string {
+// validation error messages. It receives all known content types, keyed by
+// name, for relation target and inverse_of validation.
+func validateContentType(ct ContentType, knownTypes map[string]ContentType) []string {
 	var problems []string
 
 	// Validate content type name.
@@ -263,6 +272,34 @@ func validateContentType(ct ContentType, knownTypes map[string]bool) []string {
 			}
 		}
 
+		// Validate format and allowed_schemes: only on string type.
+		if f.Format != "" {
+			if f.Type != FieldTypeString {
+				problems = append(problems, fmt.Sprintf("%s: format is only valid on string type", prefix))
+			} else if !validStringFormats[f.Format] {
+				problems = append(problems, fmt.Sprintf("%s: invalid format %q (must be email, url, uuid, hex_color, or phone)", prefix, f.Format))
+			}
+		}
+		if len(f.AllowedSchemes) > 0 {
+			if f.Format != FormatURL {
+				problems = append(problems, fmt.Sprintf("%s: allowed_schemes is only valid with format url", prefix))
+			}
+			for j, scheme := range f.AllowedSchemes {
+				if !schemePattern.MatchString(scheme) {
+					problems = append(problems, fmt.Sprintf("%s: allowed_schemes[%d] %q must be a lowercase URL scheme", prefix, j, scheme))
+				}
+			}
+		}
+
+		// Validate sanitize: only on richtext type.
+		if f.Sanitize != nil {
+			if f.Type != FieldTypeRichText {
+				problems = append(problems, fmt.Sprintf("%s: sanitize is only valid on richtext type", prefix))
+			} else {
+				problems = append(problems, validateSanitizePolicy(prefix, f.Sanitize)...)
+			}
+		}
+
 		// Validate values: only valid on enum type.
 		if len(f.Values) > 0 && f.Type != FieldTypeEnum {
 			problems = append(problems, fmt.Sprintf("%s: values is only valid on enum type", prefix))
@@ -275,6 +312,9 @@ func validateContentType(ct ContentType, knownTypes map[string]bool) []string {
 		if f.RelationType != "" && f.Type != FieldTypeRelation {
 			problems = append(problems, fmt.Sprintf("%s: relation_type is only valid on relation type", prefix))
 		}
+		if f.InverseOf != "" && f.Type != FieldTypeRelation {
+			problems = append(problems, fmt.Sprintf("%s: inverse_of is only valid on relation type", prefix))
+		}
 
 		// Validate enum fields: must have non-empty values list.
 		if f.Type == FieldTypeEnum {
@@ -294,27 +334,311 @@ func validateContentType(ct ContentType, knownTypes map[string]bool) []string {
 		}
 
 		// Validate relation fields: must have relates_to and valid relation_type.
+		// Inverse relations take their cardinality from the forward field.
 		if f.Type == FieldTypeRelation {
+			target, targetKnown := knownTypes[f.RelatesTo]
 			if f.RelatesTo == "" {
 				problems = append(problems, fmt.Sprintf("%s: relation field must have relates_to", prefix))
-			} else if !knownTypes[f.RelatesTo] {
+			} else if !targetKnown {
 				problems = append(problems, fmt.Sprintf("%s: relates_to references unknown content type %q", prefix, f.RelatesTo))
 			}
-			if f.RelationType != RelationOne && f.RelationType != RelationMany {
+			if f.IsInverse() {
+				if targetKnown {
+					problems = append(problems, validateInverseField(prefix, ct.Name, f, target)...)
+				}
+			} else if f.RelationType != RelationOne && f.RelationType != RelationMany {
 				problems = append(problems, fmt.Sprintf("%s: relation field must have relation_type of \"one\" or \"many\", got %q", prefix, f.RelationType))
 			}
 		}
 
-		// Validate that media and relation-one fields are not required.
-		// These fields use ON DELETE SET NULL in the DDL, which conflicts
-		// with a NOT NULL constraint.
-		if f.Required {
-			if f.Type == FieldTypeMedia {
-				problems = append(problems, fmt.Sprintf("field '%s': required is not supported on media/relation fields (ON DELETE SET NULL would conflict with NOT NULL constraint)", f.Name))
-			} else if f.Type == FieldTypeRelation && f.RelationType == RelationOne {
-				problems = append(problems, fmt.Sprintf("field '%s': required is not supported on media/relation fields (ON DELETE SET NULL would conflict with NOT NULL constraint)", f.Name))
+		// Validate on_delete: only valid on media and relation types.
+		if f.OnDelete != "" {
+			if f.Type != FieldTypeMedia && f.Type != FieldTypeRelation {
+				problems = append(problems, fmt.Sprintf("%s: on_delete is only valid on media and relation types", prefix))
+			} else if !validOnDeleteActions[f.OnDelete] {
+				problems = append(problems, fmt.Sprintf("%s: on_delete must be one of \"restrict\", \"set_null\", \"cascade\", got %q", prefix, f.OnDelete))
+			} else if f.OnDelete == OnDeleteSetNull && f.Type == FieldTypeRelation && f.RelationType == RelationMany {
+				problems = append(problems, fmt.Sprintf("%s: on_delete \"set_null\" is not valid on relation_type \"many\" (junction rows cannot be nulled)", prefix))
+			}
+		}
+
+		// Validate that media and relation-one fields using ON DELETE SET NULL
+		// (the default) are not required, since clearing the column would
+		// violate the NOT NULL constraint. Use on_delete: restrict or cascade
+		// for required references.
+		if f.Required && f.IsForeignKey() && f.EffectiveOnDelete() == OnDeleteSetNull {
+			problems = append(problems, fmt.Sprintf("field '%s': required is not supported on media/relation fields with on_delete set_null (ON DELETE SET NULL would conflict with NOT NULL constraint); use on_delete restrict or cascade", f.Name))
+		}
+	}
+
+	problems = append(problems, validateRules(ct)...)
+	if ct.Workflow != nil {
+		problems = append(problems, validateWorkflow(ct.Workflow)...)
+	}
+
+	return problems
+}
+
+// compareKind returns the comparison category of a field type for compare
+// rules: "number", "date", "time" or "string". Fields of other types cannot
+// be compared and yield "".
+func compareKind(t FieldType) string {
+	switch t {
+	case FieldTypeInt, FieldTypeFloat:
+		return "number"
+	case FieldTypeDate:
+		return "date"
+	case FieldTypeTime:
+		return "time"
+	case FieldTypeString, FieldTypeText, FieldTypeRichText, FieldTypeEnum:
+		return "string"
+	default:
+		return ""
+	}
+}
+
+// equalsMatchesType reports whether a required_if equals value (as decoded
+// from YAML) has a type compatible with field f.
+func equalsMatchesType(f Field, v any) bool {
+	switch v.(type) {
+	case bool:
+		return f.Type == FieldTypeBoolean
+	case int, int64, float64:
+		return numericFieldTypes[f.Type]
+	case string:
+		return f.Type != FieldTypeBoolean && !numericFieldTypes[f.Type] && f.Type != FieldTypeJSON
+	default:
+		return false
+	}
+}
+
+// validateRules validates the cross-field rules of a content type. Rules may
+// only reference fields stored as columns, since relation-many and inverse
+// fields are not part of the entry payload.
+func validateRules(ct ContentType) []string {
+	var problems []string
+
+	fields := make(map[string]Field, len(ct.Fields))
+	for _, f := range ct.Fields {
+		if f.HasColumn() {
+			fields[f.Name] = f
+		}
+	}
+
+	// lookup returns the named field, recording a problem if it is unusable.
+	lookup := func(prefix, attr, name string) (Field, bool) {
+		if name == "" {
+			problems = append(problems, fmt.Sprintf("%s: %s is required", prefix, attr))
+			return Field{}, false
+		}
+		f, ok := fields[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: %s references unknown field %q (relation-many and inverse fields cannot be used in rules)", prefix, attr, name))
+		}
+		return f, ok
+	}
+
+	for i, r := range ct.Rules {
+		prefix := fmt.Sprintf("rules[%d] (%s)", i, r.Type)
+
+		if !validRuleTypes[r.Type] {
+			problems = append(problems, fmt.Sprintf("rules[%d]: invalid rule type %q (must be required_if, compare or at_least_one)", i, r.Type))
+			continue
+		}
+
+		// Reject attributes that belong to other rule types.
+		if r.Type != RuleRequiredIf && (r.When != "" || r.Equals != nil) {
+			problems = append(problems, fmt.Sprintf("%s: when and equals are only valid on required_if rules", prefix))
+		}
+		if r.Type != RuleCompare && (r.Operator != "" || r.Other != "") {
+			problems = append(problems, fmt.Sprintf("%s: operator and other are only valid on compare rules", prefix))
+		}
+		if r.Type != RuleAtLeastOne && len(r.Fields) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: fields is only valid on at_least_one rules", prefix))
+		}
+		if r.Type == RuleAtLeastOne && r.Field != "" {
+			problems = append(problems, fmt.Sprintf("%s: field is not valid on at_least_one rules (use fields)", prefix))
+		}
+
+		switch r.Type {
+		case RuleRequiredIf:
+			_, fieldOK := lookup(prefix, "field", r.Field)
+			when, whenOK := lookup(prefix, "when", r.When)
+			if fieldOK && whenOK && r.Field == r.When {
+				problems = append(problems, fmt.Sprintf("%s: field and when must differ", prefix))
+			}
+			if whenOK && r.Equals != nil {
+				if !equalsMatchesType(when, r.Equals) {
+					problems = append(problems, fmt.Sprintf("%s: equals value %v does not match the type of field %q (%s)", prefix, r.Equals, when.Name, when.Type))
+				} else if s, ok := r.Equals.(string); ok && when.Type == FieldTypeEnum && !slices.Contains(when.Values, s) {
+					problems = append(problems, fmt.Sprintf("%s: equals value %q is not one of the values of enum field %q", prefix, s, when.Name))
+				}
 			}
+
+		case RuleCompare:
+			field, fieldOK := lookup(prefix, "field", r.Field)
+			other, otherOK := lookup(prefix, "other", r.Other)
+			if !validCompareOperators[r.Operator] {
+				problems = append(problems, fmt.Sprintf("%s: operator must be one of eq, ne, lt, lte, gt, gte, got %q", prefix, r.Operator))
+			}
+			if fieldOK && otherOK {
+				kind := compareKind(field.Type)
+				switch {
+				case r.Field == r.Other:
+					problems = append(problems, fmt.Sprintf("%s: field and other must differ", prefix))
+				case kind == "" || kind != compareKind(other.Type):
+					problems = append(problems, fmt.Sprintf("%s: cannot compare %q (%s) with %q (%s)", prefix, field.Name, field.Type, other.Name, other.Type))
+				case kind == "string" && r.Operator != CompareEq && r.Operator != CompareNe:
+					problems = append(problems, fmt.Sprintf("%s: text fields only support the eq and ne operators", prefix))
+				}
+			}
+
+		case RuleAtLeastOne:
+			if len(r.Fields) < 2 {
+				problems = append(problems, fmt.Sprintf("%s: fields must list at least two fields", prefix))
+			}
+			seen := make(map[string]bool, len(r.Fields))
+			for _, name := range r.Fields {
+				if seen[name] {
+					problems = append(problems, fmt.Sprintf("%s: duplicate field %q", prefix, name))
+				}
+				seen[name] = true
+				lookup(prefix, "fields", name)
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateInverseField validates an inverse relation field declared on
+// ctName. The field named by inverse_of must exist on the target content type
+// and be a forward relation pointing back at ctName.
+func validateInverseField(prefix, ctName string, f Field, target ContentType) []string {
+	var problems []string
+
+	if f.RelationType != "" {
+		problems = append(problems, fmt.Sprintf("%s: relation_type must not be set on inverse relations (cardinality comes from %s.%s)", prefix, target.Name, f.InverseOf))
+	}
+	if f.Required || f.Unique || f.OnDelete != "" {
+		problems = append(problems, fmt.Sprintf("%s: required, unique and on_delete are not valid on inverse relations", prefix))
+	}
+
+	var forward *Field
+	for i := range target.Fields {
+		if target.Fields[i].Name == f.InverseOf {
+			forward = &target.Fields[i]
+			break
+		}
+	}
+	switch {
+	case forward == nil:
+		problems = append(problems, fmt.Sprintf("%s: inverse_of references unknown field %q on content type %q", prefix, f.InverseOf, target.Name))
+	case forward.Type != FieldTypeRelation || forward.IsInverse() || forward.RelatesTo != ctName:
+		problems = append(problems, fmt.Sprintf("%s: inverse_of field %s.%s must be a relation with relates_to %q", prefix, target.Name, f.InverseOf, ctName))
+	}
+
+	return problems
+}
+
+// validateSanitizePolicy checks a richtext field's HTML allowlist. Tags and
+// attributes that can run script or load other documents are rejected, so
+// a policy can never reintroduce what sanitization exists to remove.
+func validateSanitizePolicy(prefix string, p *SanitizePolicy) []string {
+	var problems []string
+
+	allowed := make(map[string]bool, len(p.AllowedTags))
+	for i, tag := range p.AllowedTags {
+		switch {
+		case !htmlNamePattern.MatchString(tag):
+			problems = append(problems, fmt.Sprintf("%s: sanitize.allowed_tags[%d] %q must be a lowercase HTML tag name", prefix, i, tag))
+		case richtext.UnsafeTag(tag):
+			problems = append(problems, fmt.Sprintf("%s: sanitize.allowed_tags[%d]: tag %q cannot be allowed", prefix, i, tag))
+		}
+		allowed[tag] = true
+	}
+
+	tags := make([]string, 0, len(p.AllowedAttributes))
+	for tag := range p.AllowedAttributes {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags) // Deterministic error order.
+
+	for _, tag := range tags {
+		if p.AllowedTags != nil && !allowed[tag] {
+			problems = append(problems, fmt.Sprintf("%s: sanitize.allowed_attributes: tag %q is not in allowed_tags", prefix, tag))
+		}
+		for _, a := range p.AllowedAttributes[tag] {
+			switch {
+			case !htmlNamePattern.MatchString(a):
+				problems = append(problems, fmt.Sprintf("%s: sanitize.allowed_attributes[%s]: %q must be a lowercase attribute name", prefix, tag, a))
+			case richtext.UnsafeAttribute(a):
+				problems = append(problems, fmt.Sprintf("%s: sanitize.allowed_attributes[%s]: attribute %q cannot be allowed", prefix, tag, a))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateWorkflow checks that a workflow's stages and transitions are
+// consistent.
+func validateWorkflow(w *Workflow) []string {
+	var problems []string
+
+	if len(w.Stages) < 2 {
+		problems = append(problems, "workflow: at least two stages are required")
+	}
+
+	stages := make(map[string]WorkflowStage, len(w.Stages))
+	for i, st := range w.Stages {
+		switch {
+		case st.Name == "":
+			problems = append(problems, fmt.Sprintf("workflow: stages[%d]: name is required", i))
+			continue
+		case !namePattern.MatchString(st.Name):
+			problems = append(problems, fmt.Sprintf("workflow: stage %q: name must match ^[a-z][a-z0-9_]*$", st.Name))
+		}
+		if _, dup := stages[st.Name]; dup {
+			problems = append(problems, fmt.Sprintf("workflow: duplicate stage %q", st.Name))
+			continue
+		}
+		stages[st.Name] = st
+	}
+
+	if initial := w.InitialStage(); initial != "" {
+		if st, ok := stages[initial]; !ok {
+			problems = append(problems, fmt.Sprintf("workflow: initial stage %q does not exist", initial))
+		} else if st.Published {
+			problems = append(problems, fmt.Sprintf("workflow: initial stage %q must not be a published stage", initial))
+		}
+	}
+
+	seen := make(map[[2]string]bool, len(w.Transitions))
+	for i, t := range w.Transitions {
+		prefix := fmt.Sprintf("workflow: transitions[%d] (%s -> %s)", i, t.From, t.To)
+		if _, ok := stages[t.From]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: from references unknown stage %q", prefix, t.From))
+		}
+		if _, ok := stages[t.To]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: to references unknown stage %q", prefix, t.To))
+		}
+		if t.From == t.To {
+			problems = append(problems, fmt.Sprintf("%s: from and to must differ", prefix))
+		}
+		if len(t.Roles) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: at least one role is required", prefix))
+		}
+		for _, r := range t.Roles {
+			if !namePattern.MatchString(r) {
+				problems = append(problems, fmt.Sprintf("%s: role %q must match ^[a-z][a-z0-9_]*$", prefix, r))
+			}
+		}
+		key := [2]string{t.From, t.To}
+		if seen[key] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate transition", prefix))
 		}
+		seen[key] = true
 	}
 
 	return problems
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a token-bucket rate limit: buckets hold up to Limit tokens and
+// refill at Limit tokens per Window. The zero Rate disables limiting.
+type Rate struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRate parses a rate of the form "<limit>/<window>", e.g. "300/1m".
+// "off" and "0" disable limiting.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "0" {
+		return Rate{}, nil
+	}
+	limitStr, windowStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate %q must have the form <limit>/<window>", s)
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return Rate{}, fmt.Errorf("rate %q: limit must be a positive integer", s)
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return Rate{}, fmt.Errorf("rate %q: window must be a positive duration", s)
+	}
+	return Rate{Limit: limit, Window: window}, nil
+}
+
+// Enabled reports whether the rate limits anything.
+func (r Rate) Enabled() bool {
+	return r.Limit > 0 && r.Window > 0
+}
+
+// interval is the time it takes to refill one token.
+func (r Rate) interval() time.Duration {
+	return r.Window / time.Duration(r.Limit)
+}
+
+// RateLimitPolicy is the rate limit applied to a group of routes. All
+// requests are limited per client IP at PerIP by RateLimit; requests made
+// with an API key are additionally limited per key at PerAPIKey by
+// RateLimitAPIKey.
+type RateLimitPolicy struct {
+	// Name identifies the policy's buckets, so route groups sharing a store
+	// are limited independently.
+	Name      string
+	PerIP     Rate
+	PerAPIKey Rate
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until a token is available. Zero if allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds token buckets.
+type RateLimitStore interface {
+	// Take takes a token from the bucket identified by key, creating a full
+	// bucket if none exists.
+	Take(ctx context.Context, key string, rate Rate) (RateLimitResult, error)
+}
+
+// Buckets are stored as their theoretical arrival time (TAT): the time at
+// which the bucket will be full again. This is equivalent to tracking a token
+// count and refill timestamp, but needs a single value.
+
+// takeToken applies one request to a bucket with the given TAT at time now. It
+// returns the bucket's new TAT and the result.
+func takeToken(tat, now time.Time, rate Rate) (time.Time, RateLimitResult) {
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(rate.interval())
+	if next.Sub(now) > rate.Window {
+		return tat, RateLimitResult{
+			Reset:      tat.Sub(now),
+			RetryAfter: next.Sub(now) - rate.Window,
+		}
+	}
+	return next, allowedResult(next, now, rate)
+}
+
+// allowedResult is the result of a request that moved a bucket's TAT to tat.
+func allowedResult(tat, now time.Time, rate Rate) RateLimitResult {
+	debt := tat.Sub(now)
+	return RateLimitResult{
+		Allowed:   true,
+		Remaining: int((rate.Window - debt) / rate.interval()),
+		Reset:     debt,
+	}
+}
+
+// apiKeyContextKey is the context key for the ID of a request's API key.
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a copy of ctx identifying the API key a request was
+// authenticated with. Authentication middleware installed before
+// RateLimitAPIKey uses it so that requests are limited per API key.
+func WithAPIKey(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, keyID)
+}
+
+// APIKeyFromContext returns the API key ID set by WithAPIKey, or "" if the
+// request was not made with an API key.
+func APIKeyFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyContextKey{}).(string)
+	return id
+}
+
+// RateLimit returns a middleware that enforces the per-IP rate of policy
+// using store. It does not depend on authentication, so it should run before
+// it, limiting requests with invalid credentials too. Responses carry
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers; rejected requests get 429 with Retry-After. If the store fails,
+// requests are let through.
+func RateLimit(store RateLimitStore, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return rateLimiter(store, policy.Name, func(r *http.Request) (string, Rate) {
+		return policy.Name + ":ip:" + clientIP(r), policy.PerIP
+	})
+}
+
+// RateLimitAPIKey returns a middleware that enforces the per-API-key rate of
+// policy using store, like RateLimit. It must run after the authentication
+// that identifies the key with WithAPIKey; requests without a key are let
+// through.
+func RateLimitAPIKey(store RateLimitStore, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return rateLimiter(store, policy.Name, func(r *http.Request) (string, Rate) {
+		id := APIKeyFromContext(r.Context())
+		if id == "" {
+			return "", Rate{}
+		}
+		return policy.Name + ":key:" + id, policy.PerAPIKey
+	})
+}
+
+// rateLimiter returns a middleware that takes a token from the bucket that
+// bucket returns for each request. A disabled rate lets the request through.
+func rateLimiter(store RateLimitStore, name string, bucket func(*http.Request) (string, Rate)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, rate := bucket(r)
+			if !rate.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := store.Take(r.Context(), key, rate)
+			if err != nil {
+				slog.Warn("rate limit store error, allowing request", "policy", name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(rate.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rate.Limit, ceilSeconds(rate.Window)))
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				Error(w, http.StatusTooManyRequests, "RATE_LIMITED",
+					"too many requests, retry later", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the client IP of r. RemoteAddr has already been replaced
+// by the forwarded address if the request came through a trusted proxy.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// ceilSeconds rounds d up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// memorySweepInterval is how often MemoryRateLimitStore drops full buckets.
+const memorySweepInterval = time.Minute
+
+// MemoryRateLimitStore is a RateLimitStore that keeps buckets in process
+// memory. Limits are not shared between replicas.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, rate Rate) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		// Full buckets behave exactly like missing ones.
+		for k, tat := range s.buckets {
+			if !tat.After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	tat, res := takeToken(s.buckets[key], now, rate)
+	s.buckets[key] = tat
+	return res, nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/GyroZepelix/mithril-cms/internal/database"
+)
+
+// postgresSweepInterval is how often PostgresRateLimitStore purges full
+// buckets.
+const postgresSweepInterval = 5 * time.Minute
+
+// PostgresRateLimitStore is a RateLimitStore backed by the rate_limits table,
+// so that limits are shared by all replicas using the same database.
+type PostgresRateLimitStore struct {
+	db *database.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresRateLimitStore creates a store backed by db.
+func NewPostgresRateLimitStore(db *database.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db}
+}
+
+// Take implements RateLimitStore. The bucket is updated in a single statement
+// that only advances its TAT if a token is available, so concurrent requests
+// from several replicas cannot overdraw it.
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, rate Rate) (RateLimitResult, error) {
+	s.sweep(ctx)
+
+	interval := rate.interval().Microseconds()
+	window := rate.Window.Microseconds()
+
+	var tat, now time.Time
+	err := s.db.Pool().QueryRow(ctx,
+		`INSERT INTO rate_limits AS b (key, tat)
+		 VALUES ($1, now() + $2 * interval '1 microsecond')
+		 ON CONFLICT (key) DO UPDATE
+		 SET tat = GREATEST(b.tat, now()) + $2 * interval '1 microsecond'
+		 WHERE GREATEST(b.tat, now()) + $2 * interval '1 microsecond' - now()
+		       <= $3 * interval '1 microsecond'
+		 RETURNING tat, now()`,
+		key, interval, window,
+	).Scan(&tat, &now)
+	if err == nil {
+		return allowedResult(tat, now, rate), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return RateLimitResult{}, fmt.Errorf("taking rate limit token: %w", err)
+	}
+
+	// The bucket is empty; compute when it refills.
+	err = s.db.Pool().QueryRow(ctx,
+		`SELECT tat, now() FROM rate_limits WHERE key = $1`,
+		key,
+	).Scan(&tat, &now)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("querying rate limit bucket: %w", err)
+	}
+	_, res := takeToken(tat, now, rate)
+	return res, nil
+}
+
+// sweep purges full buckets if the last purge was long enough ago. Failures
+// are only logged: stale rows do not affect limiting.
+func (s *PostgresRateLimitStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < postgresSweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	if _, err := s.db.Pool().Exec(ctx, `DELETE FROM rate_limits WHERE tat < now()`); err != nil {
+		slog.Warn("failed to purge rate limit buckets", "error", err)
+	}
+}
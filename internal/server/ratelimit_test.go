@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{"300/1m", Rate{Limit: 300, Window: time.Minute}, false},
+		{" 10/30s ", Rate{Limit: 10, Window: 30 * time.Second}, false},
+		{"off", Rate{}, false},
+		{"0", Rate{}, false},
+		{"300", Rate{}, true},
+		{"0/1m", Rate{}, true},
+		{"abc/1m", Rate{}, true},
+		{"10/forever", Rate{}, true},
+		{"10/-1s", Rate{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRate(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStore_Take(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	rate := Rate{Limit: 3, Window: 3 * time.Second}
+	ctx := context.Background()
+
+	// A new bucket allows a burst of Limit requests.
+	for i, wantRemaining := range []int{2, 1, 0} {
+		res, err := store.Take(ctx, "k", rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Remaining != wantRemaining {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", i, res, wantRemaining)
+		}
+	}
+
+	res, _ := store.Take(ctx, "k", rate)
+	if res.Allowed {
+		t.Fatal("expected request over the limit to be rejected")
+	}
+	if res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("got RetryAfter %v, Reset %v; want 1s, 3s", res.RetryAfter, res.Reset)
+	}
+
+	// Other keys have their own bucket.
+	if res, _ := store.Take(ctx, "other", rate); !res.Allowed {
+		t.Error("expected a separate bucket for another key")
+	}
+
+	// One token refills per interval.
+	now = now.Add(time.Second)
+	if res, _ := store.Take(ctx, "k", rate); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("after refill: got %+v, want allowed with 0 remaining", res)
+	}
+
+	// Full buckets are swept.
+	now = now.Add(time.Hour)
+	store.Take(ctx, "k", rate) //nolint:errcheck // memory store never fails
+	if _, ok := store.buckets["other"]; ok {
+		t.Error("expected full bucket to be swept")
+	}
+}
+
+func TestRateLimit_Middleware(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	policy := RateLimitPolicy{
+		Name:      "test",
+		PerIP:     Rate{Limit: 2, Window: time.Minute},
+		PerAPIKey: Rate{Limit: 5, Window: time.Minute},
+	}
+	h := RateLimit(store, policy)(RateLimitAPIKey(store, policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	do := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req = req.WithContext(WithAPIKey(req.Context(), apiKey))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("10.0.0.1:1234", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "30",
+		"RateLimit-Policy":    "2;w=60",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// The port does not distinguish clients.
+	do("10.0.0.1:5678", "")
+	w = do("10.0.0.1:1234", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+
+	// API key requests count against the IP's limit too.
+	w = do("10.0.0.1:1234", "key-1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for API key request from a limited IP, got %d", w.Code)
+	}
+
+	// From other IPs, they are limited per key at the key's rate.
+	for i := 2; i <= 7; i++ {
+		w = do(fmt.Sprintf("10.0.0.%d:1234", i), "key-1")
+		if i <= 6 && w.Code != http.StatusOK {
+			t.Fatalf("request from 10.0.0.%d with API key: expected 200, got %d", i, w.Code)
+		}
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the key's limit is used up, got %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "5" {
+		t.Errorf("RateLimit-Limit = %q, want 5", got)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Rate) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("database down")
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	policy := RateLimitPolicy{Name: "test", PerIP: Rate{Limit: 1, Window: time.Minute}}
+	h := RateLimit(failingStore{}, policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 when the store fails, got %d", w.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.7", "::ffff:172.16.0.1", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	for _, ip := range []string{"10.1.2.3", "192.168.1.7", "172.16.0.1", "::ffff:10.0.0.1", "fd00::1"} {
+		if !proxies.contains(ip) {
+			t.Errorf("contains(%s) = false, want true", ip)
+		}
+	}
+	for _, ip := range []string{"192.168.1.8", "11.0.0.1", "fe80::1", "not-an-ip"} {
+		if proxies.contains(ip) {
+			t.Errorf("contains(%s) = true, want false", ip)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.example.com", "10.0.0"} {
+		if _, err := ParseTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	proxies, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name       string
+		trusted    TrustedProxies
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{"no trusted proxies", nil, "203.0.113.9:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.9:1234"},
+		{"untrusted peer", proxies, "203.0.113.9:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.9:1234"},
+		{"trusted peer", proxies, "10.0.0.2:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"spoofed hops", proxies, "10.0.0.2:1234", []string{"1.1.1.1, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chain of proxies", proxies, "10.0.0.2:1234", []string{"198.51.100.1, 10.0.0.3", "10.0.0.4"}, "", "198.51.100.1"},
+		{"only proxies", proxies, "10.0.0.2:1234", []string{"10.0.0.3"}, "", "10.0.0.3"},
+		{"invalid hop", proxies, "10.0.0.2:1234", []string{"198.51.100.1, garbage"}, "", "10.0.0.2:1234"},
+		{"x-real-ip", proxies, "10.0.0.2:1234", nil, "198.51.100.2", "198.51.100.2"},
+		{"no headers", proxies, "10.0.0.2:1234", nil, "", "10.0.0.2:1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := realIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+
+	"github.com/GyroZepelix/mithril-cms/internal/schema"
 )
 
 // FieldError represents a single field-level validation error in an API response.
@@ -22,6 +24,13 @@ type PaginationMeta struct {
 	TotalPages int `json:"total_pages"`
 }
 
+// CursorMeta holds keyset pagination metadata for list responses. NextCursor
+// is empty on the last page.
+type CursorMeta struct {
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
 // successResponse wraps a single data item.
 type successResponse struct {
 	Data any `json:"data"`
@@ -33,6 +42,13 @@ type paginatedResponse struct {
 	Meta PaginationMeta `json:"meta"`
 }
 
+// cursorPaginatedResponse wraps a list of data items with keyset pagination
+// metadata.
+type cursorPaginatedResponse struct {
+	Data any        `json:"data"`
+	Meta CursorMeta `json:"meta"`
+}
+
 // errorBody is the inner structure of an error response.
 type errorBody struct {
 	Code    string       `json:"code"`
@@ -63,11 +79,31 @@ func Error(w http.ResponseWriter, status int, code string, message string, detai
 	})
 }
 
+// ReferenceDetails converts references into error details of the form
+// {"field": "<type>.<field>", "message": "referenced by entry <id>"} for
+// REFERENCED responses.
+func ReferenceDetails(refs []schema.Reference) []FieldError {
+	details := make([]FieldError, len(refs))
+	for i, ref := range refs {
+		details[i] = FieldError{
+			Field:   ref.ContentType + "." + ref.Field,
+			Message: "referenced by entry " + ref.EntryID,
+		}
+	}
+	return details
+}
+
 // Paginated writes a JSON list response with pagination metadata.
 func Paginated(w http.ResponseWriter, data any, meta PaginationMeta) {
 	writeJSON(w, http.StatusOK, paginatedResponse{Data: data, Meta: meta})
 }
 
+// CursorPaginated writes a JSON list response with keyset pagination
+// metadata.
+func CursorPaginated(w http.ResponseWriter, data any, meta CursorMeta) {
+	writeJSON(w, http.StatusOK, cursorPaginatedResponse{Data: data, Meta: meta})
+}
+
 // writeJSON marshals v to JSON and writes it to the response writer.
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
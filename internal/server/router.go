@@ -19,6 +19,18 @@ type AuthHandler interface {
 	Refresh(w http.ResponseWriter, r *http.Request)
 	Logout(w http.ResponseWriter, r *http.Request)
 	Me(w http.ResponseWriter, r *http.Request)
+	ChangePassword(w http.ResponseWriter, r *http.Request)
+	TwoFactorVerify(w http.ResponseWriter, r *http.Request)
+	TwoFactorSetup(w http.ResponseWriter, r *http.Request)
+	TwoFactorSetupConfirm(w http.ResponseWriter, r *http.Request)
+	TwoFactorStatus(w http.ResponseWriter, r *http.Request)
+	TwoFactorEnroll(w http.ResponseWriter, r *http.Request)
+	TwoFactorEnrollConfirm(w http.ResponseWriter, r *http.Request)
+	TwoFactorDisable(w http.ResponseWriter, r *http.Request)
+	TwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request)
+	ListSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeAllSessions(w http.ResponseWriter, r *http.Request)
 }
 
 // ContentHandler defines the interface for content CRUD HTTP handlers.
@@ -28,22 +40,49 @@ type ContentHandler interface {
 	AdminCreate(w http.ResponseWriter, r *http.Request)
 	AdminUpdate(w http.ResponseWriter, r *http.Request)
 	AdminPublish(w http.ResponseWriter, r *http.Request)
+	AdminDelete(w http.ResponseWriter, r *http.Request)
+	AdminReferences(w http.ResponseWriter, r *http.Request)
+	AdminHistory(w http.ResponseWriter, r *http.Request)
+	AdminTransition(w http.ResponseWriter, r *http.Request)
+	AdminWorkflow(w http.ResponseWriter, r *http.Request)
+	AdminLock(w http.ResponseWriter, r *http.Request)
+	AdminUnlock(w http.ResponseWriter, r *http.Request)
+	AdminGetLock(w http.ResponseWriter, r *http.Request)
+	ReviewQueue(w http.ResponseWriter, r *http.Request)
 	PublicList(w http.ResponseWriter, r *http.Request)
 	PublicGet(w http.ResponseWriter, r *http.Request)
 }
 
+// CommentHandler defines the interface for entry comment and admin
+// notification HTTP handlers.
+type CommentHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	Resolve(w http.ResponseWriter, r *http.Request)
+	Unresolve(w http.ResponseWriter, r *http.Request)
+	Notifications(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+	MarkAllRead(w http.ResponseWriter, r *http.Request)
+}
+
 // MediaHandler defines the interface for media upload, listing, deletion,
 // and public serving HTTP handlers.
 type MediaHandler interface {
 	Upload(w http.ResponseWriter, r *http.Request)
 	List(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
+	References(w http.ResponseWriter, r *http.Request)
 	Serve(w http.ResponseWriter, r *http.Request)
 }
 
 // AuditHandler defines the interface for audit log HTTP handlers.
 type AuditHandler interface {
 	List(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+	Stats(w http.ResponseWriter, r *http.Request)
+	Verify(w http.ResponseWriter, r *http.Request)
 }
 
 // ContentTypeHandler defines the interface for content type introspection HTTP handlers.
@@ -52,6 +91,71 @@ type ContentTypeHandler interface {
 	Get(w http.ResponseWriter, r *http.Request)
 }
 
+// APIKeyHandler defines the interface for API key management HTTP handlers.
+type APIKeyHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+}
+
+// TokenHandler defines the interface for personal access token HTTP
+// handlers: an admin's own tokens, and any admin's for admin management.
+type TokenHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+	AdminList(w http.ResponseWriter, r *http.Request)
+	AdminRevoke(w http.ResponseWriter, r *http.Request)
+}
+
+// RoleHandler defines the interface for role management HTTP handlers.
+type RoleHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Save(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+// AdminHandler defines the interface for admin account management HTTP
+// handlers.
+type AdminHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Disable(w http.ResponseWriter, r *http.Request)
+	Enable(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	ResetTwoFactor(w http.ResponseWriter, r *http.Request)
+	Unlock(w http.ResponseWriter, r *http.Request)
+	ListSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeSessions(w http.ResponseWriter, r *http.Request)
+}
+
+// AccountHandler defines the interface for admin invitation and password
+// reset HTTP handlers.
+type AccountHandler interface {
+	ListInvites(w http.ResponseWriter, r *http.Request)
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	RevokeInvite(w http.ResponseWriter, r *http.Request)
+	AcceptInvite(w http.ResponseWriter, r *http.Request)
+	RequestPasswordReset(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+}
+
+// OIDCHandler defines the interface for single sign-on HTTP handlers.
+type OIDCHandler interface {
+	Status(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+	Callback(w http.ResponseWriter, r *http.Request)
+}
+
+// KeysHandler defines the interface for publishing the access token signing
+// keys.
+type KeysHandler interface {
+	JWKS(w http.ResponseWriter, r *http.Request)
+}
+
 // SchemaHandler defines the interface for schema management HTTP handlers.
 type SchemaHandler interface {
 	Refresh(w http.ResponseWriter, r *http.Request)
@@ -67,10 +171,68 @@ type Dependencies struct {
 	AuthHandler    AuthHandler
 	AuthMiddleware func(http.Handler) http.Handler
 	ContentHandler ContentHandler
+	CommentHandler CommentHandler
 	MediaHandler   MediaHandler
 	AuditHandler       AuditHandler
 	SchemaHandler      SchemaHandler
 	ContentTypeHandler ContentTypeHandler
+	APIKeyHandler      APIKeyHandler
+	TokenHandler       TokenHandler
+	RoleHandler        RoleHandler
+	AdminHandler       AdminHandler
+	AccountHandler     AccountHandler
+	OIDCHandler        OIDCHandler // Nil unless single sign-on is configured.
+	KeysHandler        KeysHandler
+
+	// Authorize returns a middleware that rejects requests whose admin lacks
+	// the permission for action on resource. Resources may contain {param}
+	// placeholders for URL parameters. Permission checks are skipped if it is
+	// nil.
+	Authorize func(resource, action string) func(http.Handler) http.Handler
+
+	// APIKeyMiddleware authenticates public API requests made with an API
+	// key. It runs between the per-IP and the per-key rate limits.
+	APIKeyMiddleware func(http.Handler) http.Handler
+
+	// RateLimitStore holds rate limit buckets. Rate limiting is disabled if
+	// it is nil.
+	RateLimitStore  RateLimitStore
+	PublicRateLimit RateLimitPolicy // Public content API.
+	LoginRateLimit  RateLimitPolicy // Admin login.
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For and
+	// X-Real-IP headers are honored. Client addresses are the connections'
+	// peer addresses if it is empty.
+	TrustedProxies TrustedProxies
+}
+
+// rateLimit returns the rate limiting middleware for policy, or a pass-through
+// middleware if rate limiting is disabled.
+func rateLimit(deps Dependencies, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	if deps.RateLimitStore == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return RateLimit(deps.RateLimitStore, policy)
+}
+
+// apiKeyRateLimit returns the per-API-key rate limiting middleware for
+// policy, or a pass-through middleware if rate limiting is disabled.
+func apiKeyRateLimit(deps Dependencies, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	if deps.RateLimitStore == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return RateLimitAPIKey(deps.RateLimitStore, policy)
+}
+
+// authorizer returns a function building the permission check middleware for
+// a resource and action, or pass-through middlewares if Authorize is nil.
+func authorizer(deps Dependencies) func(resource, action string) func(http.Handler) http.Handler {
+	if deps.Authorize == nil {
+		return func(string, string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler { return next }
+		}
+	}
+	return deps.Authorize
 }
 
 // NewRouter builds the chi router with the full route tree, middleware stack,
@@ -78,10 +240,11 @@ type Dependencies struct {
 // they are built in subsequent tasks.
 func NewRouter(deps Dependencies) chi.Router {
 	r := chi.NewRouter()
+	can := authorizer(deps)
 
 	// --- Global middleware stack ---
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(realIP(deps.TrustedProxies))
 	r.Use(requestLogger)
 	r.Use(middleware.Recoverer)
 	r.Use(corsMiddleware(deps.DevMode))
@@ -89,9 +252,23 @@ func NewRouter(deps Dependencies) chi.Router {
 	// --- Health check ---
 	r.Get("/health", healthHandler(deps))
 
+	// --- Access token verification keys ---
+	if deps.KeysHandler != nil {
+		r.Get("/.well-known/jwks.json", deps.KeysHandler.JWKS)
+	} else {
+		r.Get("/.well-known/jwks.json", notImplemented)
+	}
+
 	// --- Public API ---
 	r.Route("/api", func(r chi.Router) {
 		r.Use(requireJSON)
+		// The per-IP limit runs first so that requests with invalid keys
+		// are limited before they are looked up.
+		r.Use(rateLimit(deps, deps.PublicRateLimit))
+		if deps.APIKeyMiddleware != nil {
+			r.Use(deps.APIKeyMiddleware)
+			r.Use(apiKeyRateLimit(deps, deps.PublicRateLimit))
+		}
 		if deps.ContentHandler != nil {
 			r.Get("/{contentType}", deps.ContentHandler.PublicList)
 			r.Get("/{contentType}/{id}", deps.ContentHandler.PublicGet)
@@ -107,13 +284,52 @@ func NewRouter(deps Dependencies) chi.Router {
 
 		// Public auth routes (no auth middleware required).
 		if deps.AuthHandler != nil {
-			r.Post("/auth/login", deps.AuthHandler.Login)
+			r.With(rateLimit(deps, deps.LoginRateLimit)).Post("/auth/login", deps.AuthHandler.Login)
 			r.Post("/auth/refresh", deps.AuthHandler.Refresh)
 			r.Post("/auth/logout", deps.AuthHandler.Logout)
+
+			// Second login step for two-factor authentication, authorized by
+			// the challenge token from login.
+			r.Group(func(r chi.Router) {
+				r.Use(rateLimit(deps, deps.LoginRateLimit))
+				r.Post("/auth/2fa/verify", deps.AuthHandler.TwoFactorVerify)
+				r.Post("/auth/2fa/setup", deps.AuthHandler.TwoFactorSetup)
+				r.Post("/auth/2fa/setup/confirm", deps.AuthHandler.TwoFactorSetupConfirm)
+			})
 		} else {
 			r.Post("/auth/login", notImplemented)
 			r.Post("/auth/refresh", notImplemented)
 			r.Post("/auth/logout", notImplemented)
+			r.Post("/auth/2fa/verify", notImplemented)
+			r.Post("/auth/2fa/setup", notImplemented)
+			r.Post("/auth/2fa/setup/confirm", notImplemented)
+		}
+
+		// Invitation and password reset redemption. Reset requests share the
+		// login rate limit since they send email.
+		if deps.AccountHandler != nil {
+			r.Post("/auth/invite/accept", deps.AccountHandler.AcceptInvite)
+			r.With(rateLimit(deps, deps.LoginRateLimit)).Post("/auth/password-reset", deps.AccountHandler.RequestPasswordReset)
+			r.With(rateLimit(deps, deps.LoginRateLimit)).Post("/auth/password-reset/confirm", deps.AccountHandler.ResetPassword)
+		} else {
+			r.Post("/auth/invite/accept", notImplemented)
+			r.Post("/auth/password-reset", notImplemented)
+			r.Post("/auth/password-reset/confirm", notImplemented)
+		}
+
+		// Single sign-on. Login and callback are browser navigations that
+		// end in redirects.
+		if deps.OIDCHandler != nil {
+			r.Get("/auth/oidc", deps.OIDCHandler.Status)
+			r.Group(func(r chi.Router) {
+				r.Use(rateLimit(deps, deps.LoginRateLimit))
+				r.Get("/auth/oidc/login", deps.OIDCHandler.Login)
+				r.Get("/auth/oidc/callback", deps.OIDCHandler.Callback)
+			})
+		} else {
+			r.Get("/auth/oidc", notImplemented)
+			r.Get("/auth/oidc/login", notImplemented)
+			r.Get("/auth/oidc/callback", notImplemented)
 		}
 
 		// Protected routes - require valid JWT.
@@ -124,8 +340,37 @@ func NewRouter(deps Dependencies) chi.Router {
 
 			if deps.AuthHandler != nil {
 				r.Get("/auth/me", deps.AuthHandler.Me)
+				r.Post("/auth/password", deps.AuthHandler.ChangePassword)
+				r.Get("/auth/2fa", deps.AuthHandler.TwoFactorStatus)
+				r.Post("/auth/2fa/enroll", deps.AuthHandler.TwoFactorEnroll)
+				r.Post("/auth/2fa/enroll/confirm", deps.AuthHandler.TwoFactorEnrollConfirm)
+				r.Post("/auth/2fa/disable", deps.AuthHandler.TwoFactorDisable)
+				r.Post("/auth/2fa/recovery-codes", deps.AuthHandler.TwoFactorRecoveryCodes)
+				r.Get("/auth/sessions", deps.AuthHandler.ListSessions)
+				r.Delete("/auth/sessions", deps.AuthHandler.RevokeAllSessions)
+				r.Delete("/auth/sessions/{id}", deps.AuthHandler.RevokeSession)
 			} else {
 				r.Get("/auth/me", notImplemented)
+				r.Post("/auth/password", notImplemented)
+				r.Get("/auth/2fa", notImplemented)
+				r.Post("/auth/2fa/enroll", notImplemented)
+				r.Post("/auth/2fa/enroll/confirm", notImplemented)
+				r.Post("/auth/2fa/disable", notImplemented)
+				r.Post("/auth/2fa/recovery-codes", notImplemented)
+				r.Get("/auth/sessions", notImplemented)
+				r.Delete("/auth/sessions", notImplemented)
+				r.Delete("/auth/sessions/{id}", notImplemented)
+			}
+
+			// Personal access tokens of the authenticated admin.
+			if deps.TokenHandler != nil {
+				r.Get("/auth/tokens", deps.TokenHandler.List)
+				r.Post("/auth/tokens", deps.TokenHandler.Create)
+				r.Delete("/auth/tokens/{id}", deps.TokenHandler.Revoke)
+			} else {
+				r.Get("/auth/tokens", notImplemented)
+				r.Post("/auth/tokens", notImplemented)
+				r.Delete("/auth/tokens/{id}", notImplemented)
 			}
 
 			// Content type introspection.
@@ -139,44 +384,186 @@ func NewRouter(deps Dependencies) chi.Router {
 
 			// Content CRUD.
 			r.Route("/content/{contentType}", func(r chi.Router) {
+				const resource = "content:{contentType}"
+				read := r.With(can(resource, "read"))
+				update := r.With(can(resource, "update"))
 				if deps.ContentHandler != nil {
-					r.Get("/", deps.ContentHandler.AdminList)
-					r.Post("/", deps.ContentHandler.AdminCreate)
-					r.Get("/{id}", deps.ContentHandler.AdminGet)
-					r.Put("/{id}", deps.ContentHandler.AdminUpdate)
-					r.Post("/{id}/publish", deps.ContentHandler.AdminPublish)
+					read.Get("/", deps.ContentHandler.AdminList)
+					r.With(can(resource, "create")).Post("/", deps.ContentHandler.AdminCreate)
+					read.Get("/{id}", deps.ContentHandler.AdminGet)
+					update.Put("/{id}", deps.ContentHandler.AdminUpdate)
+					r.With(can(resource, "delete")).Delete("/{id}", deps.ContentHandler.AdminDelete)
+					read.Get("/{id}/references", deps.ContentHandler.AdminReferences)
+					read.Get("/{id}/history", deps.ContentHandler.AdminHistory)
+					r.With(can(resource, "publish")).Post("/{id}/publish", deps.ContentHandler.AdminPublish)
+					read.Get("/{id}/workflow", deps.ContentHandler.AdminWorkflow)
+					update.Post("/{id}/transition", deps.ContentHandler.AdminTransition)
+					read.Get("/{id}/lock", deps.ContentHandler.AdminGetLock)
+					update.Post("/{id}/lock", deps.ContentHandler.AdminLock)
+					update.Delete("/{id}/lock", deps.ContentHandler.AdminUnlock)
 				} else {
 					r.Get("/", notImplemented)
 					r.Post("/", notImplemented)
 					r.Get("/{id}", notImplemented)
 					r.Put("/{id}", notImplemented)
+					r.Delete("/{id}", notImplemented)
+					r.Get("/{id}/references", notImplemented)
+					r.Get("/{id}/history", notImplemented)
 					r.Post("/{id}/publish", notImplemented)
+					r.Get("/{id}/workflow", notImplemented)
+					r.Post("/{id}/transition", notImplemented)
+					r.Get("/{id}/lock", notImplemented)
+					r.Post("/{id}/lock", notImplemented)
+					r.Delete("/{id}/lock", notImplemented)
 				}
+
+				// Entry comments. Anyone who can read an entry can see the
+				// discussion; taking part requires being allowed to update it.
+				r.Route("/{id}/comments", func(r chi.Router) {
+					r.Use(can(resource, "read"))
+					update := r.With(can(resource, "update"))
+					if deps.CommentHandler != nil {
+						r.Get("/", deps.CommentHandler.List)
+						update.Post("/", deps.CommentHandler.Create)
+						update.Put("/{commentID}", deps.CommentHandler.Update)
+						update.Delete("/{commentID}", deps.CommentHandler.Delete)
+						update.Post("/{commentID}/resolve", deps.CommentHandler.Resolve)
+						update.Post("/{commentID}/unresolve", deps.CommentHandler.Unresolve)
+					} else {
+						r.Get("/", notImplemented)
+						r.Post("/", notImplemented)
+						r.Put("/{commentID}", notImplemented)
+						r.Delete("/{commentID}", notImplemented)
+						r.Post("/{commentID}/resolve", notImplemented)
+						r.Post("/{commentID}/unresolve", notImplemented)
+					}
+				})
 			})
 
+			// Comment notifications.
+			if deps.CommentHandler != nil {
+				r.Get("/notifications", deps.CommentHandler.Notifications)
+				r.Post("/notifications/{id}/read", deps.CommentHandler.MarkRead)
+				r.Post("/notifications/read-all", deps.CommentHandler.MarkAllRead)
+			} else {
+				r.Get("/notifications", notImplemented)
+				r.Post("/notifications/{id}/read", notImplemented)
+				r.Post("/notifications/read-all", notImplemented)
+			}
+
+			// Editorial review queue.
+			if deps.ContentHandler != nil {
+				r.Get("/review-queue", deps.ContentHandler.ReviewQueue)
+			} else {
+				r.Get("/review-queue", notImplemented)
+			}
+
 			// Media management.
 			r.Route("/media", func(r chi.Router) {
 				if deps.MediaHandler != nil {
-					r.Post("/", deps.MediaHandler.Upload)
-					r.Get("/", deps.MediaHandler.List)
-					r.Delete("/{id}", deps.MediaHandler.Delete)
+					r.With(can("media", "create")).Post("/", deps.MediaHandler.Upload)
+					r.With(can("media", "read")).Get("/", deps.MediaHandler.List)
+					r.With(can("media", "delete")).Delete("/{id}", deps.MediaHandler.Delete)
+					r.With(can("media", "read")).Get("/{id}/references", deps.MediaHandler.References)
 				} else {
 					r.Post("/", notImplemented)
 					r.Get("/", notImplemented)
 					r.Delete("/{id}", notImplemented)
+					r.Get("/{id}/references", notImplemented)
 				}
 			})
 
 			// Audit log.
 			if deps.AuditHandler != nil {
-				r.Get("/audit-log", deps.AuditHandler.List)
+				r.With(can("audit", "read")).Get("/audit-log", deps.AuditHandler.List)
+				r.With(can("audit", "read")).Get("/audit-log/export", deps.AuditHandler.Export)
+				r.With(can("audit", "read")).Get("/audit-log/stats", deps.AuditHandler.Stats)
+				r.With(can("audit", "read")).Get("/audit-log/verify", deps.AuditHandler.Verify)
 			} else {
 				r.Get("/audit-log", notImplemented)
+				r.Get("/audit-log/export", notImplemented)
+				r.Get("/audit-log/stats", notImplemented)
+				r.Get("/audit-log/verify", notImplemented)
+			}
+
+			// API keys.
+			if deps.APIKeyHandler != nil {
+				r.With(can("api_keys", "read")).Get("/api-keys", deps.APIKeyHandler.List)
+				r.With(can("api_keys", "create")).Post("/api-keys", deps.APIKeyHandler.Create)
+				r.With(can("api_keys", "delete")).Delete("/api-keys/{id}", deps.APIKeyHandler.Revoke)
+			} else {
+				r.Get("/api-keys", notImplemented)
+				r.Post("/api-keys", notImplemented)
+				r.Delete("/api-keys/{id}", notImplemented)
+			}
+
+			// Roles.
+			if deps.RoleHandler != nil {
+				r.With(can("roles", "read")).Get("/roles", deps.RoleHandler.List)
+				r.With(can("roles", "manage")).Put("/roles/{name}", deps.RoleHandler.Save)
+				r.With(can("roles", "manage")).Delete("/roles/{name}", deps.RoleHandler.Delete)
+			} else {
+				r.Get("/roles", notImplemented)
+				r.Put("/roles/{name}", notImplemented)
+				r.Delete("/roles/{name}", notImplemented)
 			}
 
+			// Admin accounts.
+			r.Route("/admins", func(r chi.Router) {
+				if deps.AdminHandler != nil {
+					read := r.With(can("admins", "read"))
+					manage := r.With(can("admins", "manage"))
+					read.Get("/", deps.AdminHandler.List)
+					manage.Post("/", deps.AdminHandler.Create)
+					read.Get("/{id}", deps.AdminHandler.Get)
+					manage.Put("/{id}", deps.AdminHandler.Update)
+					manage.Delete("/{id}", deps.AdminHandler.Delete)
+					manage.Post("/{id}/disable", deps.AdminHandler.Disable)
+					manage.Post("/{id}/enable", deps.AdminHandler.Enable)
+					manage.Delete("/{id}/two-factor", deps.AdminHandler.ResetTwoFactor)
+					manage.Post("/{id}/unlock", deps.AdminHandler.Unlock)
+					read.Get("/{id}/sessions", deps.AdminHandler.ListSessions)
+					manage.Delete("/{id}/sessions", deps.AdminHandler.RevokeSessions)
+					manage.Delete("/{id}/sessions/{sessionID}", deps.AdminHandler.RevokeSession)
+				} else {
+					r.Get("/", notImplemented)
+					r.Post("/", notImplemented)
+					r.Get("/{id}", notImplemented)
+					r.Put("/{id}", notImplemented)
+					r.Delete("/{id}", notImplemented)
+					r.Post("/{id}/disable", notImplemented)
+					r.Post("/{id}/enable", notImplemented)
+					r.Delete("/{id}/two-factor", notImplemented)
+					r.Post("/{id}/unlock", notImplemented)
+					r.Get("/{id}/sessions", notImplemented)
+					r.Delete("/{id}/sessions", notImplemented)
+					r.Delete("/{id}/sessions/{sessionID}", notImplemented)
+				}
+				if deps.TokenHandler != nil {
+					r.With(can("admins", "read")).Get("/{id}/tokens", deps.TokenHandler.AdminList)
+					r.With(can("admins", "manage")).Delete("/{id}/tokens/{tokenID}", deps.TokenHandler.AdminRevoke)
+				} else {
+					r.Get("/{id}/tokens", notImplemented)
+					r.Delete("/{id}/tokens/{tokenID}", notImplemented)
+				}
+			})
+
+			// Admin invitations.
+			r.Route("/invites", func(r chi.Router) {
+				if deps.AccountHandler != nil {
+					r.With(can("admins", "read")).Get("/", deps.AccountHandler.ListInvites)
+					r.With(can("admins", "manage")).Post("/", deps.AccountHandler.CreateInvite)
+					r.With(can("admins", "manage")).Delete("/{id}", deps.AccountHandler.RevokeInvite)
+				} else {
+					r.Get("/", notImplemented)
+					r.Post("/", notImplemented)
+					r.Delete("/{id}", notImplemented)
+				}
+			})
+
 			// Schema refresh.
 			if deps.SchemaHandler != nil {
-				r.Post("/schema/refresh", deps.SchemaHandler.Refresh)
+				r.With(can("schema", "refresh")).Post("/schema/refresh", deps.SchemaHandler.Refresh)
 			} else {
 				r.Post("/schema/refresh", notImplemented)
 			}
@@ -0,0 +1,5 @@
+-- 000002_workflow.down.sql
+-- Drops workflow stages and admin roles.
+
+DROP TABLE IF EXISTS workflow_states;
+ALTER TABLE admins DROP COLUMN IF EXISTS role;
@@ -0,0 +1,18 @@
+-- 000002_workflow.up.sql
+-- Adds admin roles and per-entry workflow stages.
+
+-- Roles decide which workflow transitions an admin may perform.
+ALTER TABLE admins ADD COLUMN role TEXT NOT NULL DEFAULT 'admin';
+
+-- workflow_states: current workflow stage of entries whose content type
+-- defines a workflow. Entries without a row are in the initial stage.
+CREATE TABLE workflow_states (
+    content_type TEXT NOT NULL,
+    entry_id     UUID NOT NULL,
+    stage        TEXT NOT NULL,
+    updated_by   UUID REFERENCES admins(id) ON DELETE SET NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (content_type, entry_id)
+);
+
+CREATE INDEX idx_workflow_states_stage ON workflow_states(content_type, stage);
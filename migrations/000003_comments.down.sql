@@ -0,0 +1,6 @@
+-- 000003_comments.down.sql
+-- Drops comments and notifications in reverse dependency order.
+
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS comment_mentions;
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,43 @@
+-- 000003_comments.up.sql
+-- Adds editorial comments on content entries and admin notifications.
+
+-- comments: discussion threads attached to content entries. Replies point at
+-- the top-level comment of their thread.
+CREATE TABLE comments (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    content_type TEXT NOT NULL,
+    entry_id     UUID NOT NULL,
+    parent_id    UUID REFERENCES comments(id) ON DELETE CASCADE,
+    field        TEXT,
+    body         TEXT NOT NULL,
+    author_id    UUID REFERENCES admins(id) ON DELETE SET NULL,
+    resolved_at  TIMESTAMPTZ,
+    resolved_by  UUID REFERENCES admins(id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_comments_entry ON comments(content_type, entry_id, created_at);
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
+
+-- comment_mentions: admins @mentioned in a comment.
+CREATE TABLE comment_mentions (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    admin_id   UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, admin_id)
+);
+
+-- notifications: per-admin notifications about mentions and replies.
+CREATE TABLE notifications (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id     UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    kind         TEXT NOT NULL,
+    comment_id   UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    actor_id     UUID REFERENCES admins(id) ON DELETE SET NULL,
+    content_type TEXT NOT NULL,
+    entry_id     UUID NOT NULL,
+    read_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_notifications_unread ON notifications(admin_id, created_at) WHERE read_at IS NULL;
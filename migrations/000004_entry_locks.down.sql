@@ -0,0 +1,4 @@
+-- 000004_entry_locks.down.sql
+-- Drops edit locks.
+
+DROP TABLE IF EXISTS entry_locks;
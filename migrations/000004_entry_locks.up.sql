@@ -0,0 +1,16 @@
+-- 000004_entry_locks.up.sql
+-- Adds soft edit locks on content entries.
+
+-- entry_locks: editing leases on content entries. A lease is held until
+-- expires_at and renewed by heartbeats; expired rows are ignored and
+-- replaced by the next lock request.
+CREATE TABLE entry_locks (
+    content_type TEXT NOT NULL,
+    entry_id     UUID NOT NULL,
+    admin_id     UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    acquired_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (content_type, entry_id)
+);
+
+CREATE INDEX idx_entry_locks_expires_at ON entry_locks(expires_at);
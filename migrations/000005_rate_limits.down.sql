@@ -0,0 +1,4 @@
+-- 000005_rate_limits.down.sql
+-- Drops shared rate limit buckets.
+
+DROP TABLE IF EXISTS rate_limits;
@@ -0,0 +1,12 @@
+-- 000005_rate_limits.up.sql
+-- Adds shared token buckets for the Postgres-backed rate limit store.
+
+-- rate_limits: one row per token bucket. tat is the time at which the bucket
+-- is full again; rows with tat in the past are equivalent to missing ones and
+-- are purged periodically.
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_tat ON rate_limits(tat);
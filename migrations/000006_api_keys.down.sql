@@ -0,0 +1,4 @@
+-- 000006_api_keys.down.sql
+-- Drops API keys.
+
+DROP TABLE IF EXISTS api_keys;
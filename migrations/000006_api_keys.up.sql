@@ -0,0 +1,19 @@
+-- 000006_api_keys.up.sql
+-- Adds read-only API keys for the public content API.
+
+-- api_keys: API tokens, stored as SHA256 hashes like refresh tokens. prefix is
+-- the start of the raw token, kept so admins can tell keys apart. Revoked keys
+-- are kept for the record.
+CREATE TABLE api_keys (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          TEXT NOT NULL,
+    token_hash    TEXT NOT NULL UNIQUE,
+    prefix        TEXT NOT NULL,
+    content_types TEXT[] NOT NULL,
+    drafts        BOOLEAN NOT NULL DEFAULT false,
+    created_by    UUID REFERENCES admins(id) ON DELETE SET NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    request_count BIGINT NOT NULL DEFAULT 0
+);
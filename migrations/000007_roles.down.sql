@@ -0,0 +1,6 @@
+-- 000007_roles.down.sql
+-- Drops roles and permissions. Admins keep their role names.
+
+ALTER TABLE admins DROP CONSTRAINT IF EXISTS admins_role_fkey;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
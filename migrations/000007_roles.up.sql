@@ -0,0 +1,66 @@
+-- 000007_roles.up.sql
+-- Adds roles with per-resource permissions.
+
+-- roles: named sets of permissions assigned to admins.
+CREATE TABLE roles (
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- role_permissions: actions a role may perform on a resource. resource is
+-- "content:<type>", "content:*", "media", "audit", "schema", "api_keys",
+-- "roles" or "*"; action "*" grants every action. own limits a content grant
+-- to entries the admin created.
+CREATE TABLE role_permissions (
+    role     TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    resource TEXT NOT NULL,
+    action   TEXT NOT NULL,
+    own      BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (role, resource, action)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin',  'Full access'),
+    ('editor', 'Manages all content and media'),
+    ('author', 'Writes content and edits their own entries'),
+    ('viewer', 'Read-only access');
+
+INSERT INTO role_permissions (role, resource, action, own) VALUES
+    ('admin',  '*',         '*',       false),
+    ('editor', 'content:*', 'read',    false),
+    ('editor', 'content:*', 'create',  false),
+    ('editor', 'content:*', 'update',  false),
+    ('editor', 'content:*', 'publish', false),
+    ('editor', 'content:*', 'delete',  false),
+    ('editor', 'media',     'read',    false),
+    ('editor', 'media',     'create',  false),
+    ('editor', 'media',     'delete',  false),
+    ('editor', 'audit',     'read',    false),
+    ('author', 'content:*', 'read',    false),
+    ('author', 'content:*', 'create',  false),
+    ('author', 'content:*', 'update',  true),
+    ('author', 'content:*', 'delete',  true),
+    ('author', 'media',     'read',    false),
+    ('author', 'media',     'create',  false),
+    ('viewer', 'content:*', 'read',    false),
+    ('viewer', 'media',     'read',    false);
+
+-- Keep roles already assigned to admins (used by workflows). Every admin
+-- could manage content before roles existed, so they start out with the
+-- editor's permissions.
+WITH legacy AS (
+    INSERT INTO roles (name)
+    SELECT DISTINCT role FROM admins
+    ON CONFLICT (name) DO NOTHING
+    RETURNING name
+)
+INSERT INTO role_permissions (role, resource, action, own)
+SELECT l.name, p.resource, p.action, p.own
+FROM legacy l
+CROSS JOIN role_permissions p
+WHERE p.role = 'editor';
+
+ALTER TABLE admins
+    ADD CONSTRAINT admins_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
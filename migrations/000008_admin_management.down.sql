@@ -0,0 +1,5 @@
+-- 000008_admin_management.down.sql
+-- Removes admin account disabling.
+
+ALTER TABLE admins DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,7 @@
+-- 000008_admin_management.up.sql
+-- Adds disabling of admin accounts.
+
+-- disabled_at: set while the account is disabled. Disabled admins cannot log
+-- in or refresh tokens.
+ALTER TABLE admins ADD COLUMN disabled_at TIMESTAMPTZ;
+ALTER TABLE admins ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
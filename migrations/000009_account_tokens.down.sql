@@ -0,0 +1,4 @@
+-- 000009_account_tokens.down.sql
+-- Drops invitation and password reset tokens.
+
+DROP TABLE IF EXISTS account_tokens;
@@ -0,0 +1,20 @@
+-- 000009_account_tokens.up.sql
+-- Adds single-use tokens for admin invitations and password resets.
+
+-- account_tokens: only the SHA256 hash of a token is stored. Invites carry the
+-- email and role of the admin to create; password resets the admin whose
+-- password may be set. A token is spent once used_at is set.
+CREATE TABLE account_tokens (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind       TEXT NOT NULL CHECK (kind IN ('invite', 'password_reset')),
+    token_hash TEXT NOT NULL UNIQUE,
+    email      TEXT NOT NULL,
+    role       TEXT REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    admin_id   UUID REFERENCES admins(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES admins(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_account_tokens_email ON account_tokens(kind, email);
@@ -0,0 +1,7 @@
+-- 000010_two_factor.down.sql
+-- Removes two-factor authentication.
+
+DROP TABLE IF EXISTS admin_recovery_codes;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_secret;
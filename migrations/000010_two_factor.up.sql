@@ -0,0 +1,22 @@
+-- 000010_two_factor.up.sql
+-- Adds TOTP two-factor authentication and recovery codes for admins.
+
+-- totp_secret: base32 TOTP secret, set once enrollment starts.
+-- totp_enabled_at: set once enrollment is confirmed with a valid code; until
+-- then the secret is pending and login does not ask for a code.
+-- totp_last_step: the time step of the last accepted code, so a code cannot
+-- be used twice.
+ALTER TABLE admins ADD COLUMN totp_secret TEXT;
+ALTER TABLE admins ADD COLUMN totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE admins ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- admin_recovery_codes: one-time codes for logging in without the
+-- authenticator. Only the SHA256 hash of a code is stored.
+CREATE TABLE admin_recovery_codes (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id   UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (admin_id, code_hash)
+);
@@ -0,0 +1,5 @@
+-- 000011_admin_sessions.down.sql
+-- Removes admin sessions.
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_id;
+DROP TABLE IF EXISTS admin_sessions;
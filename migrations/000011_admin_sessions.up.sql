@@ -0,0 +1,28 @@
+-- 000011_admin_sessions.up.sql
+-- Adds sessions that group the refresh tokens of one login.
+
+-- admin_sessions: one row per login. Each refresh rotates the session's
+-- token and records the client it was used from. Deleting a session revokes
+-- its refresh token.
+CREATE TABLE admin_sessions (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id     UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    user_agent   TEXT NOT NULL DEFAULT '',
+    ip           TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_admin_sessions_admin_id ON admin_sessions(admin_id);
+
+-- Existing refresh tokens each become a session of their own.
+ALTER TABLE refresh_tokens ADD COLUMN session_id UUID REFERENCES admin_sessions(id) ON DELETE CASCADE;
+
+INSERT INTO admin_sessions (id, admin_id, created_at, last_used_at)
+SELECT id, admin_id, created_at, created_at FROM refresh_tokens;
+
+UPDATE refresh_tokens SET session_id = id;
+
+ALTER TABLE refresh_tokens ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
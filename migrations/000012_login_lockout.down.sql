@@ -0,0 +1,4 @@
+-- 000012_login_lockout.down.sql
+-- Removes login failure tracking.
+
+DROP TABLE IF EXISTS login_failures;
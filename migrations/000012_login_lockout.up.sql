@@ -0,0 +1,15 @@
+-- 000012_login_lockout.up.sql
+-- Adds tracking of failed admin logins for brute-force protection.
+
+-- login_failures: recent failed logins per account (kind 'email', keyed by
+-- the lower-cased email, whether or not an admin has it) and per client IP
+-- (kind 'ip'). Further logins are refused until blocked_until: a short delay
+-- after each failure, or a lock once the failures reach the threshold.
+CREATE TABLE login_failures (
+    kind            TEXT NOT NULL CHECK (kind IN ('email', 'ip')),
+    key             TEXT NOT NULL,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    blocked_until   TIMESTAMPTZ,
+    PRIMARY KEY (kind, key)
+);
@@ -0,0 +1,4 @@
+-- 000013_signing_keys.down.sql
+-- Removes asymmetric signing keys.
+
+DROP TABLE IF EXISTS signing_keys;
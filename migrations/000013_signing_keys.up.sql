@@ -0,0 +1,15 @@
+-- 000013_signing_keys.up.sql
+-- Adds asymmetric keys for signing admin access tokens.
+
+-- signing_keys: key pairs access tokens are signed with when an asymmetric
+-- algorithm is configured. The private key is stored encrypted with a key
+-- derived from the JWT secret. The newest key without retired_at signs new
+-- tokens; retired keys are kept to verify tokens issued before the rotation
+-- until those have expired.
+CREATE TABLE signing_keys (
+    id          TEXT PRIMARY KEY,
+    algorithm   TEXT NOT NULL CHECK (algorithm IN ('EdDSA', 'RS256')),
+    private_key BYTEA NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    retired_at  TIMESTAMPTZ
+);
@@ -0,0 +1,5 @@
+-- 000014_token_revocation.down.sql
+-- Removes access token revocation.
+
+DROP TABLE IF EXISTS revoked_tokens;
+ALTER TABLE admins DROP COLUMN IF EXISTS tokens_valid_after;
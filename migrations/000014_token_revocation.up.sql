@@ -0,0 +1,17 @@
+-- 000014_token_revocation.up.sql
+-- Adds revocation of access tokens before they expire.
+
+-- Access tokens issued before tokens_valid_after are rejected. It is set when
+-- an admin's password changes, when they are disabled, and when they sign
+-- out everywhere. NULL means no cutoff.
+ALTER TABLE admins ADD COLUMN tokens_valid_after TIMESTAMPTZ;
+
+-- revoked_tokens: access tokens revoked on logout, by their jti claim. Rows
+-- are only needed until the token expires.
+CREATE TABLE revoked_tokens (
+    jti        TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,5 @@
+-- 000015_personal_access_tokens.down.sql
+-- Removes personal access tokens.
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS token_id;
+DROP TABLE IF EXISTS personal_access_tokens;
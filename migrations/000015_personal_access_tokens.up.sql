@@ -0,0 +1,28 @@
+-- 000015_personal_access_tokens.up.sql
+-- Adds personal access tokens for automating the admin API.
+
+-- personal_access_tokens: long-lived tokens acting as their admin, limited
+-- to scopes, a JSON list of permissions. They are stored as SHA256 hashes
+-- like API keys; prefix is the start of the raw token, kept so admins can
+-- tell tokens apart. Revoked and expired tokens are kept for the record.
+CREATE TABLE personal_access_tokens (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id      UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    name          TEXT NOT NULL,
+    token_hash    TEXT NOT NULL UNIQUE,
+    prefix        TEXT NOT NULL,
+    scopes        JSONB NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    request_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_personal_access_tokens_admin_id ON personal_access_tokens(admin_id);
+
+-- Actions taken with a personal access token are attributed to it. Like
+-- actor_id, it is kept when the token is deleted with its admin.
+ALTER TABLE audit_log ADD COLUMN token_id UUID;
+
+CREATE INDEX idx_audit_log_token_id ON audit_log(token_id) WHERE token_id IS NOT NULL;
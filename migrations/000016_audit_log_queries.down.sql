@@ -0,0 +1,6 @@
+-- 000016_audit_log_queries.down.sql
+-- Removes the audit log query indexes.
+
+DROP INDEX IF EXISTS idx_audit_log_resource_id;
+DROP INDEX IF EXISTS idx_audit_log_actor_id;
+DROP INDEX IF EXISTS idx_audit_log_created_at_id;
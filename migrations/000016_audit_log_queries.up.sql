@@ -0,0 +1,7 @@
+-- 000016_audit_log_queries.up.sql
+-- Adds indexes for filtering the audit log by actor and resource ID, and for
+-- keyset pagination on (created_at, id).
+
+CREATE INDEX idx_audit_log_created_at_id ON audit_log(created_at, id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log(actor_id, created_at);
+CREATE INDEX idx_audit_log_resource_id ON audit_log(resource_id);
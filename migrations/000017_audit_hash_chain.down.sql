@@ -0,0 +1,5 @@
+-- 000017_audit_hash_chain.down.sql
+-- Removes the audit log hash chain.
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS seq;
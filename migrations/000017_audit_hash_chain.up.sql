@@ -0,0 +1,7 @@
+-- 000017_audit_hash_chain.up.sql
+-- Chains audit log entries to make tampering evident. Entries written from
+-- now on are numbered by seq, and hash is the SHA-256 of the previous entry's
+-- hash and the entry's contents. Existing entries are not part of the chain.
+
+ALTER TABLE audit_log ADD COLUMN seq BIGINT UNIQUE;
+ALTER TABLE audit_log ADD COLUMN hash BYTEA;
@@ -0,0 +1,4 @@
+-- 000018_admin_identities.down.sql
+-- Removes single sign-on identity links.
+
+DROP TABLE IF EXISTS admin_identities;
@@ -0,0 +1,15 @@
+-- 000018_admin_identities.up.sql
+-- Links admins to their single sign-on identities.
+
+-- admin_identities: the identity provider accounts an admin signs in with,
+-- by the issuer and the subject the provider assigned. Admins are matched by
+-- identity rather than email once linked, so a changed or reassigned email
+-- at the provider cannot sign in as another admin.
+CREATE TABLE admin_identities (
+    issuer     TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    admin_id   UUID NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject),
+    UNIQUE (admin_id, issuer)
+);